@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+)
+
+// feedInfo is a feed-set with its items, returned by json api
+type feedInfo struct {
+	Name        string    `json:"name"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Link        string    `json:"link"`
+	Language    string    `json:"language"`
+	Author      string    `json:"author"`
+	RSSLink     string    `json:"rss_link"`
+	Updated     time.Time `json:"updated"`
+	Items       []episode `json:"items"`
+}
+
+// episode is an item of feed-set, returned by json api
+type episode struct {
+	GUID        string    `json:"guid"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description string    `json:"description"`
+	Author      string    `json:"author,omitempty"`
+	FileURL     string    `json:"file_url"`
+	Size        int       `json:"size"`
+	Type        string    `json:"type"`
+	Duration    int       `json:"duration"` // seconds, 0 if unknown
+	Published   time.Time `json:"published"`
+}
+
+// GET /api/feed/{name} - returns feed-set with its items as json
+func (s *Server) getFeedJSONCtrl(w http.ResponseWriter, r *http.Request) {
+	feedName := chi.URLParam(r, "name")
+	if !checkBasicAuth(w, r, s.procFeedAuth(feedName)) {
+		return
+	}
+	fi, err := s.feedInfo(feedName)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, err, "feed "+feedName+" not found")
+		return
+	}
+	s.renderCachedJSON(w, r, fi, fi.Updated)
+}
+
+// GET /api/feed/{name}/episode/{guid} - returns a single item of feed-set by guid as json.
+// Guid should be path escaped, i.e. guid with "/" as "%2F"
+func (s *Server) getEpisodeJSONCtrl(w http.ResponseWriter, r *http.Request) {
+	feedName := chi.URLParam(r, "name")
+	if !checkBasicAuth(w, r, s.procFeedAuth(feedName)) {
+		return
+	}
+	guid, err := url.PathUnescape(chi.URLParam(r, "guid"))
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid guid")
+		return
+	}
+	fi, err := s.feedInfo(feedName)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, err, "feed "+feedName+" not found")
+		return
+	}
+	for _, ep := range fi.Items {
+		if ep.GUID == guid {
+			s.renderCachedJSON(w, r, ep, ep.Published)
+			return
+		}
+	}
+	rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("episode %s not found", guid),
+		"episode "+guid+" not found")
+}
+
+// feedInfo returns feed-set with items loaded from the store, the same items as in rss.
+// Returns error if the feed-set is not configured.
+func (s *Server) feedInfo(feedName string) (feedInfo, error) {
+	feedConf, found := s.Conf.Feeds[feedName]
+	if !found {
+		return feedInfo{}, fmt.Errorf("feed %s not configured", feedName)
+	}
+	res := feedInfo{Name: feedName, Title: feedConf.Title, Description: feedConf.Description, Link: feedConf.Link,
+		Language: feedConf.Language, Author: feedConf.Author, Items: []episode{}}
+	if s.Conf.System.BaseURL != "" {
+		res.RSSLink = strings.TrimSuffix(s.Conf.System.BaseURL, "/") + "/rss/" + feedName
+	}
+
+	items, err := s.Store.Load(feedName, s.Conf.System.MaxTotal, true)
+	if err != nil {
+		log.Printf("[WARN] failed to load items of %s, %v", feedName, err)
+		return res, nil // not updated yet feed-set has no items stored
+	}
+	for _, item := range items {
+		res.Items = append(res.Items, episode{GUID: item.GUID, Title: item.Title, Link: item.Link,
+			Description: string(item.Description), Author: item.Author, FileURL: item.Enclosure.URL,
+			Size: item.Enclosure.Length, Type: item.Enclosure.Type, Duration: parseDuration(item.Duration), Published: item.DT})
+		if item.DT.After(res.Updated) {
+			res.Updated = item.DT
+		}
+	}
+	return res, nil
+}
+
+// renderCachedJSON sends json with ETag and Last-Modified, responds with 304 to conditional requests if not changed
+func (s *Server) renderCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}, modified time.Time) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to marshal json")
+		return
+	}
+	sum := sha1.Sum(b) //nolint
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	http.ServeContent(w, r, "", modified, bytes.NewReader(b))
+}
+
+// parseDuration returns duration of the item in seconds, from seconds, mm:ss or hh:mm:ss. Returns 0 if invalid
+func parseDuration(d string) int {
+	res := 0
+	for _, part := range strings.Split(strings.TrimSpace(d), ":") {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0
+		}
+		res = res*60 + v
+	}
+	return res
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/lcw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/api/mocks"
+	"github.com/umputun/feed-master/app/config"
+	"github.com/umputun/feed-master/app/feed"
+)
+
+func TestServer_getFeedJSONCtrl(t *testing.T) {
+	ts, store := prepJSONTestServer(t)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/api/feed/feed1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.NotEmpty(t, resp.Header.Get("ETag"))
+	assert.Equal(t, "Sat, 02 Apr 2022 10:20:30 GMT", resp.Header.Get("Last-Modified"), "the newest item")
+
+	var res feedInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Equal(t, "feed1", res.Name)
+	assert.Equal(t, "feed title", res.Title)
+	assert.Equal(t, "this is feed1", res.Description)
+	assert.Equal(t, "ru-ru", res.Language)
+	assert.Equal(t, "http://localhost:8080/rss/feed1", res.RSSLink)
+	require.Equal(t, 2, len(res.Items))
+	assert.Equal(t, episode{GUID: "guid1", Title: "title1", Link: "http://example.com/link1", Description: "desc1",
+		FileURL: "http://example.com/1.mp3", Size: 12345, Type: "audio/mpeg", Duration: 3723,
+		Published: time.Date(2022, 4, 2, 10, 20, 30, 0, time.UTC)}, res.Items[0])
+	assert.Equal(t, 125, res.Items[1].Duration)
+	require.Equal(t, 1, len(store.LoadCalls()))
+	assert.Equal(t, "feed1", store.LoadCalls()[0].FmFeed)
+	assert.True(t, store.LoadCalls()[0].SkipJunk)
+
+	req, err := http.NewRequest("GET", ts.URL+"/api/feed/feed1", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp2, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp2.StatusCode, "not changed")
+
+	resp3, err := ts.Client().Get(ts.URL + "/api/feed/unknown")
+	require.NoError(t, err)
+	defer resp3.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp3.StatusCode)
+}
+
+func TestServer_getEpisodeJSONCtrl(t *testing.T) {
+	ts, _ := prepJSONTestServer(t)
+	defer ts.Close()
+
+	tbl := []struct {
+		feed, guid string
+		status     int
+		title      string
+	}{
+		{"feed1", "guid1", http.StatusOK, "title1"},
+		{"feed1", "https://example.com/ep/2", http.StatusOK, "title2"},
+		{"feed1", "guid3", http.StatusNotFound, ""},
+		{"unknown", "guid1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.guid, func(t *testing.T) {
+			resp, err := ts.Client().Get(ts.URL + "/api/feed/" + tt.feed + "/episode/" + url.PathEscape(tt.guid))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tt.status, resp.StatusCode)
+			if tt.status != http.StatusOK {
+				return
+			}
+			var res episode
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+			assert.Equal(t, tt.guid, res.GUID)
+			assert.Equal(t, tt.title, res.Title)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tbl := []struct {
+		in  string
+		res int
+	}{
+		{"", 0}, {"123", 123}, {"02:05", 125}, {"1:02:03", 3723}, {"blah", 0}, {"1:-2", 0},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, parseDuration(tt.in), tt.in)
+	}
+}
+
+func prepJSONTestServer(t *testing.T) (*httptest.Server, *mocks.StoreMock) {
+	store := &mocks.StoreMock{
+		LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
+			return []feed.Item{
+				{GUID: "guid1", Title: "title1", Link: "http://example.com/link1", Description: "desc1", Duration: "1:02:03",
+					Enclosure: feed.Enclosure{URL: "http://example.com/1.mp3", Type: "audio/mpeg", Length: 12345},
+					DT:        time.Date(2022, 4, 2, 10, 20, 30, 0, time.UTC)},
+				{GUID: "https://example.com/ep/2", Title: "title2", Duration: "125",
+					Enclosure: feed.Enclosure{URL: "http://example.com/2.mp3", Type: "audio/mpeg", Length: 12346},
+					DT:        time.Date(2022, 4, 1, 10, 20, 30, 0, time.UTC)},
+			}, nil
+		},
+	}
+	conf := config.Conf{Feeds: map[string]config.Feed{
+		"feed1": {Title: "feed title", Language: "ru-ru", Description: "this is feed1", Link: "http://example.com/feed1"},
+	}}
+	conf.System.BaseURL = "http://localhost:8080"
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", Store: store, cache: lcw.NewNopCache(), Conf: conf}
+	return httptest.NewServer(s.router()), store
+}
@@ -4,6 +4,7 @@
 package mocks
 
 import (
+	"context"
 	"sync"
 
 	"github.com/umputun/feed-master/app/youtube"
@@ -16,14 +17,50 @@ import (
 //
 // 		// make and configure a mocked api.YoutubeSvc
 // 		mockedYoutubeSvc := &YoutubeSvcMock{
-// 			RSSFeedFunc: func(cinfo youtube.FeedInfo) (string, error) {
+// 			AggregateRSSFunc: func(max int) (string, error) {
+// 				panic("mock out the AggregateRSS method")
+// 			},
+// 			BackfillFunc: func(ctx context.Context, feedID string, limit int) (int, error) {
+// 				panic("mock out the Backfill method")
+// 			},
+// 			DeleteEpisodeFunc: func(feedID string, videoID string) (ytfeed.Entry, error) {
+// 				panic("mock out the DeleteEpisode method")
+// 			},
+// 			FailuresFunc: func(feedID string) []youtube.Failure {
+// 				panic("mock out the Failures method")
+// 			},
+// 			FetchErrorsFunc: func() map[string]ytfeed.FetchError {
+// 				panic("mock out the FetchErrors method")
+// 			},
+// 			FileFeedFunc: func(name string) (youtube.FeedInfo, bool) {
+// 				panic("mock out the FileFeed method")
+// 			},
+// 			PinEpisodeFunc: func(feedID string, videoID string, pinned bool) (ytfeed.Entry, error) {
+// 				panic("mock out the PinEpisode method")
+// 			},
+// 			RSSFeedFunc: func(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error) {
 // 				panic("mock out the RSSFeed method")
 // 			},
+// 			ReconcileFunc: func(ctx context.Context, opts youtube.ReconcileOpts) (youtube.ReconcileReport, error) {
+// 				panic("mock out the Reconcile method")
+// 			},
+// 			RedownloadFunc: func(ctx context.Context, feedID string, videoID string) (ytfeed.Entry, error) {
+// 				panic("mock out the Redownload method")
+// 			},
+// 			RegenerateAllFunc: func() (int, error) {
+// 				panic("mock out the RegenerateAll method")
+// 			},
 // 			RemoveEntryFunc: func(entry ytfeed.Entry) error {
 // 				panic("mock out the RemoveEntry method")
 // 			},
-// 			StoreRSSFunc: func(chanID string, rss string) error {
-// 				panic("mock out the StoreRSS method")
+// 			TestDownloadFunc: func(ctx context.Context, url string) (youtube.TestDownloadResult, error) {
+// 				panic("mock out the TestDownload method")
+// 			},
+// 			UpdateFeedFunc: func(feedID string, upd ytfeed.FeedUpdate) (youtube.FeedInfo, error) {
+// 				panic("mock out the UpdateFeed method")
+// 			},
+// 			VerifyFilesFunc: func(ctx context.Context) (youtube.VerifyResult, error) {
+// 				panic("mock out the VerifyFiles method")
 // 			},
 // 		}
 //
@@ -32,64 +69,424 @@ import (
 //
 // 	}
 type YoutubeSvcMock struct {
+	// AggregateRSSFunc mocks the AggregateRSS method.
+	AggregateRSSFunc func(max int) (string, error)
+
+	// BackfillFunc mocks the Backfill method.
+	BackfillFunc func(ctx context.Context, feedID string, limit int) (int, error)
+
+	// DeleteEpisodeFunc mocks the DeleteEpisode method.
+	DeleteEpisodeFunc func(feedID string, videoID string) (ytfeed.Entry, error)
+
+	// FailuresFunc mocks the Failures method.
+	FailuresFunc func(feedID string) []youtube.Failure
+
+	// FetchErrorsFunc mocks the FetchErrors method.
+	FetchErrorsFunc func() map[string]ytfeed.FetchError
+
+	// FileFeedFunc mocks the FileFeed method.
+	FileFeedFunc func(name string) (youtube.FeedInfo, bool)
+
+	// PinEpisodeFunc mocks the PinEpisode method.
+	PinEpisodeFunc func(feedID string, videoID string, pinned bool) (ytfeed.Entry, error)
+
 	// RSSFeedFunc mocks the RSSFeed method.
-	RSSFeedFunc func(cinfo youtube.FeedInfo) (string, error)
+	RSSFeedFunc func(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error)
+
+	// ReconcileFunc mocks the Reconcile method.
+	ReconcileFunc func(ctx context.Context, opts youtube.ReconcileOpts) (youtube.ReconcileReport, error)
+
+	// RedownloadFunc mocks the Redownload method.
+	RedownloadFunc func(ctx context.Context, feedID string, videoID string) (ytfeed.Entry, error)
+
+	// RegenerateAllFunc mocks the RegenerateAll method.
+	RegenerateAllFunc func() (int, error)
 
 	// RemoveEntryFunc mocks the RemoveEntry method.
 	RemoveEntryFunc func(entry ytfeed.Entry) error
 
-	// StoreRSSFunc mocks the StoreRSS method.
-	StoreRSSFunc func(chanID string, rss string) error
+	// TestDownloadFunc mocks the TestDownload method.
+	TestDownloadFunc func(ctx context.Context, url string) (youtube.TestDownloadResult, error)
+
+	// UpdateFeedFunc mocks the UpdateFeed method.
+	UpdateFeedFunc func(feedID string, upd ytfeed.FeedUpdate) (youtube.FeedInfo, error)
+
+	// VerifyFilesFunc mocks the VerifyFiles method.
+	VerifyFilesFunc func(ctx context.Context) (youtube.VerifyResult, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// AggregateRSS holds details about calls to the AggregateRSS method.
+		AggregateRSS []struct {
+			// Max is the max argument value.
+			Max int
+		}
+		// Backfill holds details about calls to the Backfill method.
+		Backfill []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FeedID is the feedID argument value.
+			FeedID string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// DeleteEpisode holds details about calls to the DeleteEpisode method.
+		DeleteEpisode []struct {
+			// FeedID is the feedID argument value.
+			FeedID string
+			// VideoID is the videoID argument value.
+			VideoID string
+		}
+		// Failures holds details about calls to the Failures method.
+		Failures []struct {
+			// FeedID is the feedID argument value.
+			FeedID string
+		}
+		// FetchErrors holds details about calls to the FetchErrors method.
+		FetchErrors []struct {
+		}
+		// FileFeed holds details about calls to the FileFeed method.
+		FileFeed []struct {
+			// Name is the name argument value.
+			Name string
+		}
+		// PinEpisode holds details about calls to the PinEpisode method.
+		PinEpisode []struct {
+			// FeedID is the feedID argument value.
+			FeedID string
+			// VideoID is the videoID argument value.
+			VideoID string
+			// Pinned is the pinned argument value.
+			Pinned bool
+		}
 		// RSSFeed holds details about calls to the RSSFeed method.
 		RSSFeed []struct {
 			// Cinfo is the cinfo argument value.
 			Cinfo youtube.FeedInfo
+			// Filter is the filter argument value.
+			Filter youtube.RSSFilter
+		}
+		// Reconcile holds details about calls to the Reconcile method.
+		Reconcile []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Opts is the opts argument value.
+			Opts youtube.ReconcileOpts
+		}
+		// Redownload holds details about calls to the Redownload method.
+		Redownload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FeedID is the feedID argument value.
+			FeedID string
+			// VideoID is the videoID argument value.
+			VideoID string
+		}
+		// RegenerateAll holds details about calls to the RegenerateAll method.
+		RegenerateAll []struct {
 		}
 		// RemoveEntry holds details about calls to the RemoveEntry method.
 		RemoveEntry []struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
-		// StoreRSS holds details about calls to the StoreRSS method.
-		StoreRSS []struct {
-			// ChanID is the chanID argument value.
-			ChanID string
-			// Rss is the rss argument value.
-			Rss string
+		// TestDownload holds details about calls to the TestDownload method.
+		TestDownload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// URL is the url argument value.
+			URL string
+		}
+		// UpdateFeed holds details about calls to the UpdateFeed method.
+		UpdateFeed []struct {
+			// FeedID is the feedID argument value.
+			FeedID string
+			// Upd is the upd argument value.
+			Upd ytfeed.FeedUpdate
+		}
+		// VerifyFiles holds details about calls to the VerifyFiles method.
+		VerifyFiles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 		}
 	}
-	lockRSSFeed     sync.RWMutex
-	lockRemoveEntry sync.RWMutex
-	lockStoreRSS    sync.RWMutex
+	lockAggregateRSS  sync.RWMutex
+	lockBackfill      sync.RWMutex
+	lockDeleteEpisode sync.RWMutex
+	lockFailures      sync.RWMutex
+	lockFetchErrors   sync.RWMutex
+	lockFileFeed      sync.RWMutex
+	lockPinEpisode    sync.RWMutex
+	lockRSSFeed       sync.RWMutex
+	lockReconcile     sync.RWMutex
+	lockRedownload    sync.RWMutex
+	lockRegenerateAll sync.RWMutex
+	lockRemoveEntry   sync.RWMutex
+	lockTestDownload  sync.RWMutex
+	lockUpdateFeed    sync.RWMutex
+	lockVerifyFiles   sync.RWMutex
+}
+
+// AggregateRSS calls AggregateRSSFunc.
+func (mock *YoutubeSvcMock) AggregateRSS(max int) (string, error) {
+	if mock.AggregateRSSFunc == nil {
+		panic("YoutubeSvcMock.AggregateRSSFunc: method is nil but YoutubeSvc.AggregateRSS was just called")
+	}
+	callInfo := struct {
+		Max int
+	}{
+		Max: max,
+	}
+	mock.lockAggregateRSS.Lock()
+	mock.calls.AggregateRSS = append(mock.calls.AggregateRSS, callInfo)
+	mock.lockAggregateRSS.Unlock()
+	return mock.AggregateRSSFunc(max)
+}
+
+// AggregateRSSCalls gets all the calls that were made to AggregateRSS.
+// Check the length with:
+//     len(mockedYoutubeSvc.AggregateRSSCalls())
+func (mock *YoutubeSvcMock) AggregateRSSCalls() []struct {
+	Max int
+} {
+	var calls []struct {
+		Max int
+	}
+	mock.lockAggregateRSS.RLock()
+	calls = mock.calls.AggregateRSS
+	mock.lockAggregateRSS.RUnlock()
+	return calls
+}
+
+// Backfill calls BackfillFunc.
+func (mock *YoutubeSvcMock) Backfill(ctx context.Context, feedID string, limit int) (int, error) {
+	if mock.BackfillFunc == nil {
+		panic("YoutubeSvcMock.BackfillFunc: method is nil but YoutubeSvc.Backfill was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		FeedID string
+		Limit  int
+	}{
+		Ctx:    ctx,
+		FeedID: feedID,
+		Limit:  limit,
+	}
+	mock.lockBackfill.Lock()
+	mock.calls.Backfill = append(mock.calls.Backfill, callInfo)
+	mock.lockBackfill.Unlock()
+	return mock.BackfillFunc(ctx, feedID, limit)
+}
+
+// BackfillCalls gets all the calls that were made to Backfill.
+// Check the length with:
+//     len(mockedYoutubeSvc.BackfillCalls())
+func (mock *YoutubeSvcMock) BackfillCalls() []struct {
+	Ctx    context.Context
+	FeedID string
+	Limit  int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		FeedID string
+		Limit  int
+	}
+	mock.lockBackfill.RLock()
+	calls = mock.calls.Backfill
+	mock.lockBackfill.RUnlock()
+	return calls
+}
+
+// DeleteEpisode calls DeleteEpisodeFunc.
+func (mock *YoutubeSvcMock) DeleteEpisode(feedID string, videoID string) (ytfeed.Entry, error) {
+	if mock.DeleteEpisodeFunc == nil {
+		panic("YoutubeSvcMock.DeleteEpisodeFunc: method is nil but YoutubeSvc.DeleteEpisode was just called")
+	}
+	callInfo := struct {
+		FeedID  string
+		VideoID string
+	}{
+		FeedID:  feedID,
+		VideoID: videoID,
+	}
+	mock.lockDeleteEpisode.Lock()
+	mock.calls.DeleteEpisode = append(mock.calls.DeleteEpisode, callInfo)
+	mock.lockDeleteEpisode.Unlock()
+	return mock.DeleteEpisodeFunc(feedID, videoID)
+}
+
+// DeleteEpisodeCalls gets all the calls that were made to DeleteEpisode.
+// Check the length with:
+//     len(mockedYoutubeSvc.DeleteEpisodeCalls())
+func (mock *YoutubeSvcMock) DeleteEpisodeCalls() []struct {
+	FeedID  string
+	VideoID string
+} {
+	var calls []struct {
+		FeedID  string
+		VideoID string
+	}
+	mock.lockDeleteEpisode.RLock()
+	calls = mock.calls.DeleteEpisode
+	mock.lockDeleteEpisode.RUnlock()
+	return calls
+}
+
+// Failures calls FailuresFunc.
+func (mock *YoutubeSvcMock) Failures(feedID string) []youtube.Failure {
+	if mock.FailuresFunc == nil {
+		panic("YoutubeSvcMock.FailuresFunc: method is nil but YoutubeSvc.Failures was just called")
+	}
+	callInfo := struct {
+		FeedID string
+	}{
+		FeedID: feedID,
+	}
+	mock.lockFailures.Lock()
+	mock.calls.Failures = append(mock.calls.Failures, callInfo)
+	mock.lockFailures.Unlock()
+	return mock.FailuresFunc(feedID)
+}
+
+// FailuresCalls gets all the calls that were made to Failures.
+// Check the length with:
+//     len(mockedYoutubeSvc.FailuresCalls())
+func (mock *YoutubeSvcMock) FailuresCalls() []struct {
+	FeedID string
+} {
+	var calls []struct {
+		FeedID string
+	}
+	mock.lockFailures.RLock()
+	calls = mock.calls.Failures
+	mock.lockFailures.RUnlock()
+	return calls
+}
+
+// FetchErrors calls FetchErrorsFunc.
+func (mock *YoutubeSvcMock) FetchErrors() map[string]ytfeed.FetchError {
+	if mock.FetchErrorsFunc == nil {
+		panic("YoutubeSvcMock.FetchErrorsFunc: method is nil but YoutubeSvc.FetchErrors was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockFetchErrors.Lock()
+	mock.calls.FetchErrors = append(mock.calls.FetchErrors, callInfo)
+	mock.lockFetchErrors.Unlock()
+	return mock.FetchErrorsFunc()
+}
+
+// FetchErrorsCalls gets all the calls that were made to FetchErrors.
+// Check the length with:
+//     len(mockedYoutubeSvc.FetchErrorsCalls())
+func (mock *YoutubeSvcMock) FetchErrorsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockFetchErrors.RLock()
+	calls = mock.calls.FetchErrors
+	mock.lockFetchErrors.RUnlock()
+	return calls
+}
+
+// FileFeed calls FileFeedFunc.
+func (mock *YoutubeSvcMock) FileFeed(name string) (youtube.FeedInfo, bool) {
+	if mock.FileFeedFunc == nil {
+		panic("YoutubeSvcMock.FileFeedFunc: method is nil but YoutubeSvc.FileFeed was just called")
+	}
+	callInfo := struct {
+		Name string
+	}{
+		Name: name,
+	}
+	mock.lockFileFeed.Lock()
+	mock.calls.FileFeed = append(mock.calls.FileFeed, callInfo)
+	mock.lockFileFeed.Unlock()
+	return mock.FileFeedFunc(name)
+}
+
+// FileFeedCalls gets all the calls that were made to FileFeed.
+// Check the length with:
+//     len(mockedYoutubeSvc.FileFeedCalls())
+func (mock *YoutubeSvcMock) FileFeedCalls() []struct {
+	Name string
+} {
+	var calls []struct {
+		Name string
+	}
+	mock.lockFileFeed.RLock()
+	calls = mock.calls.FileFeed
+	mock.lockFileFeed.RUnlock()
+	return calls
+}
+
+// PinEpisode calls PinEpisodeFunc.
+func (mock *YoutubeSvcMock) PinEpisode(feedID string, videoID string, pinned bool) (ytfeed.Entry, error) {
+	if mock.PinEpisodeFunc == nil {
+		panic("YoutubeSvcMock.PinEpisodeFunc: method is nil but YoutubeSvc.PinEpisode was just called")
+	}
+	callInfo := struct {
+		FeedID  string
+		VideoID string
+		Pinned  bool
+	}{
+		FeedID:  feedID,
+		VideoID: videoID,
+		Pinned:  pinned,
+	}
+	mock.lockPinEpisode.Lock()
+	mock.calls.PinEpisode = append(mock.calls.PinEpisode, callInfo)
+	mock.lockPinEpisode.Unlock()
+	return mock.PinEpisodeFunc(feedID, videoID, pinned)
+}
+
+// PinEpisodeCalls gets all the calls that were made to PinEpisode.
+// Check the length with:
+//     len(mockedYoutubeSvc.PinEpisodeCalls())
+func (mock *YoutubeSvcMock) PinEpisodeCalls() []struct {
+	FeedID  string
+	VideoID string
+	Pinned  bool
+} {
+	var calls []struct {
+		FeedID  string
+		VideoID string
+		Pinned  bool
+	}
+	mock.lockPinEpisode.RLock()
+	calls = mock.calls.PinEpisode
+	mock.lockPinEpisode.RUnlock()
+	return calls
 }
 
 // RSSFeed calls RSSFeedFunc.
-func (mock *YoutubeSvcMock) RSSFeed(cinfo youtube.FeedInfo) (string, error) {
+func (mock *YoutubeSvcMock) RSSFeed(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error) {
 	if mock.RSSFeedFunc == nil {
 		panic("YoutubeSvcMock.RSSFeedFunc: method is nil but YoutubeSvc.RSSFeed was just called")
 	}
 	callInfo := struct {
-		Cinfo youtube.FeedInfo
+		Cinfo  youtube.FeedInfo
+		Filter youtube.RSSFilter
 	}{
-		Cinfo: cinfo,
+		Cinfo:  cinfo,
+		Filter: filter,
 	}
 	mock.lockRSSFeed.Lock()
 	mock.calls.RSSFeed = append(mock.calls.RSSFeed, callInfo)
 	mock.lockRSSFeed.Unlock()
-	return mock.RSSFeedFunc(cinfo)
+	return mock.RSSFeedFunc(cinfo, filter)
 }
 
 // RSSFeedCalls gets all the calls that were made to RSSFeed.
 // Check the length with:
 //     len(mockedYoutubeSvc.RSSFeedCalls())
 func (mock *YoutubeSvcMock) RSSFeedCalls() []struct {
-	Cinfo youtube.FeedInfo
+	Cinfo  youtube.FeedInfo
+	Filter youtube.RSSFilter
 } {
 	var calls []struct {
-		Cinfo youtube.FeedInfo
+		Cinfo  youtube.FeedInfo
+		Filter youtube.RSSFilter
 	}
 	mock.lockRSSFeed.RLock()
 	calls = mock.calls.RSSFeed
@@ -97,6 +494,106 @@ func (mock *YoutubeSvcMock) RSSFeedCalls() []struct {
 	return calls
 }
 
+// Reconcile calls ReconcileFunc.
+func (mock *YoutubeSvcMock) Reconcile(ctx context.Context, opts youtube.ReconcileOpts) (youtube.ReconcileReport, error) {
+	if mock.ReconcileFunc == nil {
+		panic("YoutubeSvcMock.ReconcileFunc: method is nil but YoutubeSvc.Reconcile was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Opts youtube.ReconcileOpts
+	}{
+		Ctx:  ctx,
+		Opts: opts,
+	}
+	mock.lockReconcile.Lock()
+	mock.calls.Reconcile = append(mock.calls.Reconcile, callInfo)
+	mock.lockReconcile.Unlock()
+	return mock.ReconcileFunc(ctx, opts)
+}
+
+// ReconcileCalls gets all the calls that were made to Reconcile.
+// Check the length with:
+//     len(mockedYoutubeSvc.ReconcileCalls())
+func (mock *YoutubeSvcMock) ReconcileCalls() []struct {
+	Ctx  context.Context
+	Opts youtube.ReconcileOpts
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Opts youtube.ReconcileOpts
+	}
+	mock.lockReconcile.RLock()
+	calls = mock.calls.Reconcile
+	mock.lockReconcile.RUnlock()
+	return calls
+}
+
+// Redownload calls RedownloadFunc.
+func (mock *YoutubeSvcMock) Redownload(ctx context.Context, feedID string, videoID string) (ytfeed.Entry, error) {
+	if mock.RedownloadFunc == nil {
+		panic("YoutubeSvcMock.RedownloadFunc: method is nil but YoutubeSvc.Redownload was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		FeedID  string
+		VideoID string
+	}{
+		Ctx:     ctx,
+		FeedID:  feedID,
+		VideoID: videoID,
+	}
+	mock.lockRedownload.Lock()
+	mock.calls.Redownload = append(mock.calls.Redownload, callInfo)
+	mock.lockRedownload.Unlock()
+	return mock.RedownloadFunc(ctx, feedID, videoID)
+}
+
+// RedownloadCalls gets all the calls that were made to Redownload.
+// Check the length with:
+//     len(mockedYoutubeSvc.RedownloadCalls())
+func (mock *YoutubeSvcMock) RedownloadCalls() []struct {
+	Ctx     context.Context
+	FeedID  string
+	VideoID string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		FeedID  string
+		VideoID string
+	}
+	mock.lockRedownload.RLock()
+	calls = mock.calls.Redownload
+	mock.lockRedownload.RUnlock()
+	return calls
+}
+
+// RegenerateAll calls RegenerateAllFunc.
+func (mock *YoutubeSvcMock) RegenerateAll() (int, error) {
+	if mock.RegenerateAllFunc == nil {
+		panic("YoutubeSvcMock.RegenerateAllFunc: method is nil but YoutubeSvc.RegenerateAll was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockRegenerateAll.Lock()
+	mock.calls.RegenerateAll = append(mock.calls.RegenerateAll, callInfo)
+	mock.lockRegenerateAll.Unlock()
+	return mock.RegenerateAllFunc()
+}
+
+// RegenerateAllCalls gets all the calls that were made to RegenerateAll.
+// Check the length with:
+//     len(mockedYoutubeSvc.RegenerateAllCalls())
+func (mock *YoutubeSvcMock) RegenerateAllCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockRegenerateAll.RLock()
+	calls = mock.calls.RegenerateAll
+	mock.lockRegenerateAll.RUnlock()
+	return calls
+}
+
 // RemoveEntry calls RemoveEntryFunc.
 func (mock *YoutubeSvcMock) RemoveEntry(entry ytfeed.Entry) error {
 	if mock.RemoveEntryFunc == nil {
@@ -128,37 +625,103 @@ func (mock *YoutubeSvcMock) RemoveEntryCalls() []struct {
 	return calls
 }
 
-// StoreRSS calls StoreRSSFunc.
-func (mock *YoutubeSvcMock) StoreRSS(chanID string, rss string) error {
-	if mock.StoreRSSFunc == nil {
-		panic("YoutubeSvcMock.StoreRSSFunc: method is nil but YoutubeSvc.StoreRSS was just called")
+// TestDownload calls TestDownloadFunc.
+func (mock *YoutubeSvcMock) TestDownload(ctx context.Context, url string) (youtube.TestDownloadResult, error) {
+	if mock.TestDownloadFunc == nil {
+		panic("YoutubeSvcMock.TestDownloadFunc: method is nil but YoutubeSvc.TestDownload was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		URL string
+	}{
+		Ctx: ctx,
+		URL: url,
+	}
+	mock.lockTestDownload.Lock()
+	mock.calls.TestDownload = append(mock.calls.TestDownload, callInfo)
+	mock.lockTestDownload.Unlock()
+	return mock.TestDownloadFunc(ctx, url)
+}
+
+// TestDownloadCalls gets all the calls that were made to TestDownload.
+// Check the length with:
+//     len(mockedYoutubeSvc.TestDownloadCalls())
+func (mock *YoutubeSvcMock) TestDownloadCalls() []struct {
+	Ctx context.Context
+	URL string
+} {
+	var calls []struct {
+		Ctx context.Context
+		URL string
+	}
+	mock.lockTestDownload.RLock()
+	calls = mock.calls.TestDownload
+	mock.lockTestDownload.RUnlock()
+	return calls
+}
+
+// UpdateFeed calls UpdateFeedFunc.
+func (mock *YoutubeSvcMock) UpdateFeed(feedID string, upd ytfeed.FeedUpdate) (youtube.FeedInfo, error) {
+	if mock.UpdateFeedFunc == nil {
+		panic("YoutubeSvcMock.UpdateFeedFunc: method is nil but YoutubeSvc.UpdateFeed was just called")
+	}
+	callInfo := struct {
+		FeedID string
+		Upd    ytfeed.FeedUpdate
+	}{
+		FeedID: feedID,
+		Upd:    upd,
+	}
+	mock.lockUpdateFeed.Lock()
+	mock.calls.UpdateFeed = append(mock.calls.UpdateFeed, callInfo)
+	mock.lockUpdateFeed.Unlock()
+	return mock.UpdateFeedFunc(feedID, upd)
+}
+
+// UpdateFeedCalls gets all the calls that were made to UpdateFeed.
+// Check the length with:
+//     len(mockedYoutubeSvc.UpdateFeedCalls())
+func (mock *YoutubeSvcMock) UpdateFeedCalls() []struct {
+	FeedID string
+	Upd    ytfeed.FeedUpdate
+} {
+	var calls []struct {
+		FeedID string
+		Upd    ytfeed.FeedUpdate
+	}
+	mock.lockUpdateFeed.RLock()
+	calls = mock.calls.UpdateFeed
+	mock.lockUpdateFeed.RUnlock()
+	return calls
+}
+
+// VerifyFiles calls VerifyFilesFunc.
+func (mock *YoutubeSvcMock) VerifyFiles(ctx context.Context) (youtube.VerifyResult, error) {
+	if mock.VerifyFilesFunc == nil {
+		panic("YoutubeSvcMock.VerifyFilesFunc: method is nil but YoutubeSvc.VerifyFiles was just called")
 	}
 	callInfo := struct {
-		ChanID string
-		Rss    string
+		Ctx context.Context
 	}{
-		ChanID: chanID,
-		Rss:    rss,
+		Ctx: ctx,
 	}
-	mock.lockStoreRSS.Lock()
-	mock.calls.StoreRSS = append(mock.calls.StoreRSS, callInfo)
-	mock.lockStoreRSS.Unlock()
-	return mock.StoreRSSFunc(chanID, rss)
+	mock.lockVerifyFiles.Lock()
+	mock.calls.VerifyFiles = append(mock.calls.VerifyFiles, callInfo)
+	mock.lockVerifyFiles.Unlock()
+	return mock.VerifyFilesFunc(ctx)
 }
 
-// StoreRSSCalls gets all the calls that were made to StoreRSS.
+// VerifyFilesCalls gets all the calls that were made to VerifyFiles.
 // Check the length with:
-//     len(mockedYoutubeSvc.StoreRSSCalls())
-func (mock *YoutubeSvcMock) StoreRSSCalls() []struct {
-	ChanID string
-	Rss    string
+//     len(mockedYoutubeSvc.VerifyFilesCalls())
+func (mock *YoutubeSvcMock) VerifyFilesCalls() []struct {
+	Ctx context.Context
 } {
 	var calls []struct {
-		ChanID string
-		Rss    string
+		Ctx context.Context
 	}
-	mock.lockStoreRSS.RLock()
-	calls = mock.calls.StoreRSS
-	mock.lockStoreRSS.RUnlock()
+	mock.lockVerifyFiles.RLock()
+	calls = mock.calls.VerifyFiles
+	mock.lockVerifyFiles.RUnlock()
 	return calls
 }
@@ -10,8 +10,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/didip/tollbooth/v6"
@@ -42,17 +45,58 @@ type Server struct {
 	YoutubeSvc    YoutubeSvc
 	TemplLocation string
 	AdminPasswd   string
+	YtDlpVersion  string
 
 	httpServer *http.Server
 	cache      lcw.LoadingCache
 	templates  *template.Template
+	ctx        context.Context // canceled on shutdown, stops background jobs started by requests
+	backfills  backfills
+}
+
+// backfills keeps ids of feeds with backfill in progress, to prevent concurrent backfills of the same feed
+type backfills struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// start marks backfill of the feed as running, returns false if it is running already
+func (b *backfills) start(feedID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running[feedID] {
+		return false
+	}
+	if b.running == nil {
+		b.running = map[string]bool{}
+	}
+	b.running[feedID] = true
+	return true
+}
+
+func (b *backfills) done(feedID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.running, feedID)
 }
 
 // YoutubeSvc provides access to youtube's audio rss
 type YoutubeSvc interface {
-	RSSFeed(cinfo youtube.FeedInfo) (string, error)
-	StoreRSS(chanID, rss string) error
+	RSSFeed(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error)
+	AggregateRSS(max int) (string, error)
+	RegenerateAll() (int, error)
 	RemoveEntry(entry ytfeed.Entry) error
+	DeleteEpisode(feedID, videoID string) (ytfeed.Entry, error)
+	Redownload(ctx context.Context, feedID, videoID string) (ytfeed.Entry, error)
+	PinEpisode(feedID, videoID string, pinned bool) (ytfeed.Entry, error)
+	UpdateFeed(feedID string, upd ytfeed.FeedUpdate) (youtube.FeedInfo, error)
+	Backfill(ctx context.Context, feedID string, limit int) (int, error)
+	VerifyFiles(ctx context.Context) (youtube.VerifyResult, error)
+	FileFeed(name string) (youtube.FeedInfo, bool)
+	Reconcile(ctx context.Context, opts youtube.ReconcileOpts) (youtube.ReconcileReport, error)
+	TestDownload(ctx context.Context, url string) (youtube.TestDownloadResult, error)
+	Failures(feedID string) []youtube.Failure
+	FetchErrors() map[string]ytfeed.FetchError
 }
 
 // Store provides access to feed data
@@ -63,6 +107,7 @@ type Store interface {
 // Run starts http server for API with all routes
 func (s *Server) Run(ctx context.Context, port int) {
 	log.Printf("[INFO] starting server on port %d", port)
+	s.ctx = ctx
 	var err error
 	if s.cache, err = lcw.NewExpirableCache(lcw.TTL(time.Minute*3), lcw.MaxCacheSize(10*1024*1024)); err != nil {
 		log.Printf("[PANIC] failed to make loading cache, %v", err)
@@ -121,20 +166,42 @@ func (s *Server) router() *chi.Mux {
 		rrss.Get("/feeds", s.getFeedsPageCtrl)
 	})
 
-	router.Get("/config", func(w http.ResponseWriter, r *http.Request) { rest.RenderJSON(w, s.Conf) })
+	auth := rest.BasicAuth(func(user, passwd string) bool {
+		return (subtle.ConstantTimeCompare([]byte(s.AdminPasswd), []byte(passwd)) +
+			subtle.ConstantTimeCompare([]byte("admin"), []byte(user))) == 2
+	})
 
-	router.Route("/yt", func(r chi.Router) {
+	router.Route("/api", func(rapi chi.Router) {
+		l := logger.New(logger.Log(log.Default()), logger.Prefix("[INFO]"), logger.IPfn(logger.AnonymizeIP))
+		rapi.Use(l.Handler)
+		rapi.Get("/feed/{name}", s.getFeedJSONCtrl)
+		rapi.Get("/feed/{name}/episode/{guid}", s.getEpisodeJSONCtrl)
+		rapi.With(auth).Post("/test-download", s.testDownloadCtrl)
+		rapi.With(auth).Delete("/feeds/{channel}/episodes/{video}", s.deleteEpisodeCtrl)
+	})
 
-		auth := rest.BasicAuth(func(user, passwd string) bool {
-			return (subtle.ConstantTimeCompare([]byte(s.AdminPasswd), []byte(passwd)) +
-				subtle.ConstantTimeCompare([]byte("admin"), []byte(user))) == 2
-		})
+	router.Get("/config", func(w http.ResponseWriter, r *http.Request) { rest.RenderJSON(w, s.Conf) })
+	router.Get("/status", s.getStatusCtrl)
 
+	router.Route("/yt", func(r chi.Router) {
 		l := logger.New(logger.Log(log.Default()), logger.Prefix("[INFO]"), logger.IPfn(logger.AnonymizeIP))
 		r.Use(l.Handler)
+		r.Get("/rss/all", s.getYoutubeAggregateFeedCtrl)
 		r.Get("/rss/{channel}", s.getYoutubeFeedCtrl)
 		r.With(auth).Post("/rss/generate", s.regenerateRSSCtrl)
 		r.With(auth).Delete("/entry/{channel}/{video}", s.removeEntryCtrl)
+		r.With(auth).Delete("/feeds/{channel}/episodes/{video}", s.deleteEpisodeCtrl)
+		r.With(auth).Post("/feeds/{channel}/episodes/{video}/redownload", s.redownloadCtrl)
+		r.With(auth).Post("/feeds/{channel}/episodes/{video}/pin", s.pinEpisodeCtrl)
+		r.With(auth).Delete("/feeds/{channel}/episodes/{video}/pin", s.pinEpisodeCtrl)
+		r.With(auth).Patch("/feeds/{channel}", s.updateFeedCtrl)
+		r.With(auth).Post("/feeds/{channel}/pause", s.pauseFeedCtrl)
+		r.With(auth).Delete("/feeds/{channel}/pause", s.pauseFeedCtrl)
+		r.With(auth).Post("/backfill/{channel}", s.backfillCtrl)
+		r.With(auth).Post("/verify", s.verifyFilesCtrl)
+		r.With(auth).Post("/reconcile", s.reconcileCtrl)
+		r.With(auth).Post("/token/{channel}", s.subscriberTokenCtrl)
+		r.Get("/failures", s.failuresCtrl)
 	})
 
 	if s.Conf.YouTube.BaseURL != "" {
@@ -147,12 +214,7 @@ func (s *Server) router() *chi.Mux {
 			log.Printf("[ERROR] failed to create directory %s, %v", s.Conf.YouTube.FilesLocation, mkdirErr)
 		}
 
-		ytfs, fsErr := rest.NewFileServer(baseYtURL.Path, s.Conf.YouTube.FilesLocation)
-		if fsErr == nil {
-			router.Mount(baseYtURL.Path, ytfs)
-		} else {
-			log.Printf("[WARN] can't start static file server for yt, %v", fsErr)
-		}
+		router.Mount(baseYtURL.Path, s.mediaFileServer(baseYtURL.Path, s.Conf.YouTube.FilesLocation))
 	}
 
 	fs, err := rest.NewFileServer("/static", filepath.Join("webapp", "static"))
@@ -167,6 +229,9 @@ func (s *Server) router() *chi.Mux {
 // GET /rss/{name} - returns rss for given feeds set
 func (s *Server) getFeedCtrl(w http.ResponseWriter, r *http.Request) {
 	feedName := chi.URLParam(r, "name")
+	if !checkBasicAuth(w, r, s.procFeedAuth(feedName)) {
+		return
+	}
 
 	data, err := s.cache.Get("feed::"+feedName, func() (interface{}, error) {
 		items, err := s.Store.Load(feedName, s.Conf.System.MaxTotal, true)
@@ -175,6 +240,9 @@ func (s *Server) getFeedCtrl(w http.ResponseWriter, r *http.Request) {
 		}
 
 		for i, itm := range items {
+			if !itm.DT.IsZero() {
+				items[i].PubDate = feed.FormatDate(itm.DT, s.Conf.System.RFC822Dates)
+			}
 			// add ts suffix to titles
 			switch s.Conf.Feeds[feedName].ExtendDateTitle {
 			case "yyyyddmm":
@@ -192,7 +260,7 @@ func (s *Server) getFeedCtrl(w http.ResponseWriter, r *http.Request) {
 			Language:       s.Conf.Feeds[feedName].Language,
 			Link:           s.Conf.Feeds[feedName].Link,
 			PubDate:        items[0].PubDate,
-			LastBuildDate:  time.Now().Format(time.RFC822Z),
+			LastBuildDate:  feed.FormatDate(time.Now(), s.Conf.System.RFC822Dates),
 			ItunesAuthor:   s.Conf.Feeds[feedName].Author,
 			ItunesExplicit: "no",
 			ItunesOwner: &feed.ItunesOwner{
@@ -271,7 +339,8 @@ func (s *Server) getListCtrl(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, feeds)
 }
 
-// GET /yt/rss/{channel} - returns rss for given youtube channel
+// GET /yt/rss/{channel}?minDuration=1200 - returns rss for given youtube channel. Optional minDuration, seconds
+// or with units like 20m, limits items to episodes of this duration or longer
 func (s *Server) getYoutubeFeedCtrl(w http.ResponseWriter, r *http.Request) {
 	channel := chi.URLParam(r, "channel")
 
@@ -282,8 +351,61 @@ func (s *Server) getYoutubeFeedCtrl(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 	}
+	if !checkBasicAuth(w, r, s.feedAuth(fi)) {
+		return
+	}
+
+	filter := youtube.RSSFilter{}
+	if token := r.URL.Query().Get("token"); token != "" {
+		ts, err := youtube.ParseSubscriberToken(fi.SubscriberSecret, fi.ID, token)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusForbidden, err, "invalid subscriber token")
+			return
+		}
+		filter.Since = ts
+	}
+	if v := r.URL.Query().Get("minDuration"); v != "" {
+		d, err := durationParam(v)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid minDuration")
+			return
+		}
+		filter.MinDuration = d
+	}
+
+	res, err := s.YoutubeSvc.RSSFeed(fi, filter)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to read yt list")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	res = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + res
+	_, _ = fmt.Fprintf(w, "%s", res)
+}
 
-	res, err := s.YoutubeSvc.RSSFeed(fi)
+// durationParam parses duration of query parameter, seconds or with units, i.e. "1200" or "20m"
+func durationParam(v string) (time.Duration, error) {
+	res, err := time.ParseDuration(v)
+	if secs, atoiErr := strconv.Atoi(v); atoiErr == nil {
+		res, err = time.Duration(secs)*time.Second, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if res < 0 {
+		return 0, errors.Errorf("negative duration %s", v)
+	}
+	return res, nil
+}
+
+// GET /yt/rss/all - returns rss with the newest entries of all youtube channels merged together,
+// number of items is limited by system.max_total
+func (s *Server) getYoutubeAggregateFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	if !checkBasicAuth(w, r, s.Conf.YouTube.BasicAuth) {
+		return
+	}
+	res, err := s.YoutubeSvc.AggregateRSS(s.Conf.System.MaxTotal)
 	if err != nil {
 		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to read yt list")
 		return
@@ -296,29 +418,433 @@ func (s *Server) getYoutubeFeedCtrl(w http.ResponseWriter, r *http.Request) {
 
 // POST /yt/rss/generate - generates rss for all (each) youtube channels
 func (s *Server) regenerateRSSCtrl(w http.ResponseWriter, r *http.Request) {
+	count, err := s.YoutubeSvc.RegenerateAll()
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to regenerate yt rss")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "feeds": count})
+}
 
-	for _, f := range s.Conf.YouTube.Channels {
-		res, err := s.YoutubeSvc.RSSFeed(youtube.FeedInfo{ID: f.ID})
+// DELETE /yt/entry/{channel}/{video} - deletes entry from youtube channel and videID
+func (s *Server) removeEntryCtrl(w http.ResponseWriter, r *http.Request) {
+	err := s.YoutubeSvc.RemoveEntry(ytfeed.Entry{ChannelID: chi.URLParam(r, "channel"), VideoID: chi.URLParam(r, "video")})
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to remove entry")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "removed": chi.URLParam(r, "video")})
+}
+
+// DELETE /yt/feeds/{channel}/episodes/{video} - deletes episode with its file and regenerates channel's rss.
+// Unlike removeEntryCtrl, the episode won't be downloaded again. Served as /api/feeds/{channel}/episodes/{video} too
+func (s *Server) deleteEpisodeCtrl(w http.ResponseWriter, r *http.Request) {
+	chanID, videoID := chi.URLParam(r, "channel"), chi.URLParam(r, "video")
+	entry, err := s.YoutubeSvc.DeleteEpisode(chanID, videoID)
+	if errors.Is(err, youtube.ErrNotFound) {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, err, "episode "+videoID+" not found")
+		return
+	}
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to delete episode")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "deleted": entry.VideoID})
+}
+
+// POST /yt/feeds/{channel}/episodes/{video}/pin - pins episode, so it is never removed by keep and max age limits.
+// DELETE with the same path unpins it
+func (s *Server) pinEpisodeCtrl(w http.ResponseWriter, r *http.Request) {
+	chanID, videoID := chi.URLParam(r, "channel"), chi.URLParam(r, "video")
+	pinned := r.Method == http.MethodPost
+	entry, err := s.YoutubeSvc.PinEpisode(chanID, videoID, pinned)
+	if errors.Is(err, youtube.ErrNotFound) {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, err, "episode "+videoID+" not found")
+		return
+	}
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to update episode pin")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "video": entry.VideoID, "pinned": entry.Pinned})
+}
+
+// redownloadWait is how long redownloadCtrl waits for the download to respond with the new file,
+// shorter than server's write timeout
+var redownloadWait = 20 * time.Second
+
+// POST /yt/feeds/{channel}/episodes/{video}/redownload - deletes episode's file and downloads it again.
+// Responds with the new file if the download done in redownloadWait, otherwise with 202 and the download continues in background.
+func (s *Server) redownloadCtrl(w http.ResponseWriter, r *http.Request) {
+	chanID, videoID := chi.URLParam(r, "channel"), chi.URLParam(r, "video")
+	type result struct {
+		entry ytfeed.Entry
+		err   error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		entry, err := s.YoutubeSvc.Redownload(context.Background(), chanID, videoID)
 		if err != nil {
-			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to read yt rss for "+f.ID)
+			log.Printf("[WARN] re-download of %s in %s failed, %v", videoID, chanID, err)
+		}
+		resCh <- result{entry: entry, err: err}
+	}()
+
+	select {
+	case res := <-resCh:
+		if errors.Is(res.err, youtube.ErrNotFound) {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, res.err, "episode "+videoID+" not found")
 			return
 		}
-		if err := s.YoutubeSvc.StoreRSS(f.ID, res); err != nil {
-			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to store yt rss for "+f.ID)
+		if res.err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, res.err, "failed to re-download episode")
 			return
 		}
+		rest.RenderJSON(w, rest.JSON{"status": "ok", "video_id": res.entry.VideoID, "file": res.entry.File,
+			"size": res.entry.FileSize, "duration": res.entry.Duration, "checksum": res.entry.Checksum})
+	case <-time.After(redownloadWait):
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, rest.JSON{"status": "started", "video_id": videoID})
 	}
-	rest.RenderJSON(w, rest.JSON{"status": "ok", "feeds": len(s.Conf.YouTube.Channels)})
 }
 
-// DELETE /yt/entry/{channel}/{video} - deletes entry from youtube channel and videID
-func (s *Server) removeEntryCtrl(w http.ResponseWriter, r *http.Request) {
-	err := s.YoutubeSvc.RemoveEntry(ytfeed.Entry{ChannelID: chi.URLParam(r, "channel"), VideoID: chi.URLParam(r, "video")})
+// PATCH /yt/feeds/{channel} - changes keep, name, language or paused state of the feed at runtime, i.e. {"keep": 20}.
+// The change persisted and applied at once, old entries over the new keep removed
+func (s *Server) updateFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	chanID := chi.URLParam(r, "channel")
+	upd := ytfeed.FeedUpdate{}
+	if err := render.DecodeJSON(r.Body, &upd); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid request")
+		return
+	}
+	s.renderFeedUpdate(w, r, chanID, upd)
+}
+
+// POST /yt/feeds/{channel}/pause - pauses the feed, it is not checked for new entries but its rss is still served.
+// DELETE with the same path resumes the feed. The state persisted, the same as paused set by updateFeedCtrl
+func (s *Server) pauseFeedCtrl(w http.ResponseWriter, r *http.Request) {
+	paused := r.Method == http.MethodPost
+	s.renderFeedUpdate(w, r, chi.URLParam(r, "channel"), ytfeed.FeedUpdate{Paused: &paused})
+}
+
+// renderFeedUpdate applies the update to the feed and responds with the updated settings
+func (s *Server) renderFeedUpdate(w http.ResponseWriter, r *http.Request, chanID string, upd ytfeed.FeedUpdate) {
+	fi, err := s.YoutubeSvc.UpdateFeed(chanID, upd)
+	switch {
+	case errors.Is(err, youtube.ErrNotFound):
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, err, "channel "+chanID+" not found")
+		return
+	case errors.Is(err, youtube.ErrInvalidUpdate):
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, err.Error())
+		return
+	case err != nil:
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to update feed")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "id": fi.ID, "keep": fi.Keep, "name": fi.Name, "language": fi.Language,
+		"paused": fi.Paused})
+}
+
+// POST /yt/backfill/{channel}?limit=N - starts import of the whole channel history in background,
+// limit is optional and caps the number of downloaded entries. Only one backfill of the channel runs at a time,
+// youtube channels can be paginated with api_key only
+func (s *Server) backfillCtrl(w http.ResponseWriter, r *http.Request) {
+	chanID := chi.URLParam(r, "channel")
+	var fi youtube.FeedInfo
+	found := false
+	for _, f := range s.Conf.YouTube.Channels {
+		if f.ID == chanID {
+			fi, found = f, true
+			break
+		}
+	}
+	if !found {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, errors.New("unknown channel"), "channel "+chanID+" not found")
+		return
+	}
+	if fi.Type != ytfeed.FTPeerTube && fi.APIKey == "" {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, ytfeed.ErrPagingNotSupported,
+			"youtube rss has the latest 15 entries only, api_key of the channel needed for backfill")
+		return
+	}
+
+	limit := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid limit")
+			return
+		}
+		limit = v
+	}
+
+	if !s.backfills.start(chanID) {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusConflict, errors.New("backfill in progress"),
+			"backfill of "+chanID+" is in progress already")
+		return
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		defer s.backfills.done(chanID)
+		added, err := s.YoutubeSvc.Backfill(ctx, chanID, limit)
+		if err != nil {
+			log.Printf("[WARN] backfill for %s failed after %d entries, %v", chanID, added, err)
+		}
+	}()
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, rest.JSON{"status": "started", "channel": chanID, "limit": limit})
+}
+
+// POST /yt/verify - re-checks downloaded files against stored checksums, corrupted files re-downloaded. Unreadable
+// files, i.e. missing or remote, reported only
+func (s *Server) verifyFilesCtrl(w http.ResponseWriter, r *http.Request) {
+	rep, err := s.YoutubeSvc.VerifyFiles(r.Context())
 	if err != nil {
-		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to remove entry")
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to verify files")
 		return
 	}
-	rest.RenderJSON(w, rest.JSON{"status": "ok", "removed": chi.URLParam(r, "video")})
+	uids := func(entries []ytfeed.Entry) []string {
+		res := make([]string, 0, len(entries))
+		for _, e := range entries {
+			res = append(res, e.UID())
+		}
+		return res
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "checked": rep.Checked, "corrupted": uids(rep.Corrupted),
+		"failed": uids(rep.Failed), "unreadable": uids(rep.Unreadable)})
+}
+
+// POST /yt/reconcile?remove_orphans=true&purge_dangling=true - reports files without stored entries (orphans) and
+// entries without files (dangling). Dry run by default, orphans removed and dangling purged only if set
+func (s *Server) reconcileCtrl(w http.ResponseWriter, r *http.Request) {
+	opts := youtube.ReconcileOpts{
+		RemoveOrphans: r.URL.Query().Get("remove_orphans") == "true",
+		PurgeDangling: r.URL.Query().Get("purge_dangling") == "true",
+	}
+	rep, err := s.YoutubeSvc.Reconcile(r.Context(), opts)
+	if err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to reconcile files")
+		return
+	}
+	dangling := make([]string, 0, len(rep.Dangling))
+	for _, e := range rep.Dangling {
+		dangling = append(dangling, e.UID())
+	}
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "orphans": rep.Orphans, "dangling": dangling,
+		"removed": rep.Removed, "purged": rep.Purged})
+}
+
+// testDownloadWait limits time of the test download by testDownloadCtrl, shorter than server's write timeout.
+// Longer downloads interrupted, the output shows how far the download got
+var testDownloadWait = 25 * time.Second
+
+// POST /api/test-download - downloads a single video to a temporary location, removed after, and responds with
+// the outcome and output of the download command. Request is {"url": "video url or youtube's id"}. The store is not
+// touched, used to troubleshoot downloads
+func (s *Server) testDownloadCtrl(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		URL string `json:"url"`
+	}{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid request")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), testDownloadWait)
+	defer cancel()
+	res, err := s.YoutubeSvc.TestDownload(ctx, req.URL)
+	switch {
+	case errors.Is(err, youtube.ErrInvalidURL):
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, err.Error())
+		return
+	case err != nil:
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to test download")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"url": res.URL, "ok": res.OK, "error": res.Error, "size": res.Size,
+		"elapsed": res.Elapsed.String(), "output": res.Output})
+}
+
+// POST /yt/token/{channel}?since=RFC3339 - makes subscriber token for the channel with subscriber_secret set.
+// Feed requested with ?token=... contains only entries published after since, default since is now
+func (s *Server) subscriberTokenCtrl(w http.ResponseWriter, r *http.Request) {
+	chanID := chi.URLParam(r, "channel")
+	var fi youtube.FeedInfo
+	found := false
+	for _, f := range s.Conf.YouTube.Channels {
+		if f.ID == chanID {
+			fi, found = f, true
+			break
+		}
+	}
+	if !found {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, errors.New("unknown channel"), "channel "+chanID+" not found")
+		return
+	}
+	if fi.SubscriberSecret == "" {
+		rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, errors.New("no subscriber_secret"),
+			"subscriber tokens not enabled for "+chanID)
+		return
+	}
+
+	since := time.Now()
+	if v := r.URL.Query().Get("since"); v != "" {
+		ts, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid since")
+			return
+		}
+		since = ts
+	}
+	rest.RenderJSON(w, rest.JSON{"channel": chanID, "since": since.UTC().Format(time.RFC3339),
+		"token": youtube.SubscriberToken(fi.SubscriberSecret, chanID, since)})
+}
+
+// GET /yt/failures?feed=id - returns recent failed downloads of the feed, or of all feeds without feed param
+func (s *Server) failuresCtrl(w http.ResponseWriter, r *http.Request) {
+	rest.RenderJSON(w, s.YoutubeSvc.Failures(r.URL.Query().Get("feed")))
+}
+
+// GET /status - returns status info, i.e. versions of feed-master and yt-dlp, recent download failures by feed
+// and the last fetch error of feeds failed to update
+func (s *Server) getStatusCtrl(w http.ResponseWriter, r *http.Request) {
+	ytDlp := s.YtDlpVersion
+	if ytDlp == "" {
+		ytDlp = "unknown"
+	}
+	outdated := s.Conf.YouTube.MinYtDlpVersion != "" && ytfeed.IsOutdated(s.YtDlpVersion, s.Conf.YouTube.MinYtDlpVersion)
+	failures, fetchErrors := map[string]int{}, map[string]rest.JSON{}
+	if s.YoutubeSvc != nil {
+		for _, f := range s.YoutubeSvc.Failures("") {
+			failures[f.FeedID]++
+		}
+		for feedID, fe := range s.YoutubeSvc.FetchErrors() {
+			fetchErrors[feedID] = rest.JSON{"error": fe.Error, "ts": fe.TS, "ago": time.Since(fe.TS).Round(time.Second).String()}
+		}
+	}
+	rest.RenderJSON(w, rest.JSON{"version": s.Version, "yt_dlp": ytDlp, "yt_dlp_outdated": outdated, "yt_failures": failures,
+		"yt_errors": fetchErrors})
+}
+
+// mediaFileServer serves downloaded audio files from the root dir with range requests support, needed for seeking
+// in podcast apps. Directories and anything outside the root dir are not served. Files of feeds with url_signing
+// served by signed, not expired urls only.
+func (s *Server) mediaFileServer(public, root string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(public, "/"))
+		name = path.Clean("/" + name) // cleaned rooted path can't have ".." elements
+		if name == "/" || strings.Contains(name, "\\") {
+			http.NotFound(w, r)
+			return
+		}
+		if !checkBasicAuth(w, r, s.fileAuth(name)) {
+			return
+		}
+		if signer := s.fileSigner(name); signer != nil {
+			if err := signer.Verify(r.URL); err != nil {
+				log.Printf("[WARN] rejected request of %s, %v", name, err)
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+
+		fh, err := os.Open(filepath.Join(root, filepath.FromSlash(name))) //nolint:gosec // name is cleaned and rooted
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer fh.Close() //nolint:gosec // read-only file
+		fi, err := fh.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+
+		if mime := youtube.MediaType(name); mime != "" {
+			w.Header().Set("Content-Type", mime)
+		}
+		if strings.EqualFold(filepath.Ext(name), ".vtt") {
+			w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		}
+		w.Header().Set("Accept-Ranges", "bytes")
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), fh)
+	})
+}
+
+// feedAuth returns basic auth credentials of the youtube feed, feed's own or global
+func (s *Server) feedAuth(fi youtube.FeedInfo) youtube.BasicAuth {
+	if fi.BasicAuth.Enabled() {
+		return fi.BasicAuth
+	}
+	return s.Conf.YouTube.BasicAuth
+}
+
+// procFeedAuth returns credentials of the feed-set, feed's own or global system ones
+func (s *Server) procFeedAuth(name string) youtube.BasicAuth {
+	if f, ok := s.Conf.Feeds[name]; ok && f.BasicAuth.Enabled() {
+		return f.BasicAuth
+	}
+	return s.Conf.System.BasicAuth
+}
+
+// fileAuth returns basic auth credentials of the served file by the feed owning the file, see fileFeed.
+// Files of unknown feeds protected with global credentials only.
+func (s *Server) fileAuth(name string) youtube.BasicAuth {
+	if fi, ok := s.fileFeed(name); ok {
+		return s.feedAuth(fi)
+	}
+	return s.Conf.YouTube.BasicAuth
+}
+
+// fileSigner returns signer verifying urls of the served file by the feed owning the file, for feeds
+// with url_signing set. Nil if urls of the file are not signed, files of unknown feeds never checked.
+func (s *Server) fileSigner(name string) *youtube.HMACSigner {
+	fi, ok := s.fileFeed(name)
+	if !ok || fi.URLSigning.Secret == "" {
+		return nil
+	}
+	return &youtube.HMACSigner{Secret: fi.URLSigning.Secret, TTL: fi.URLSigning.TTL}
+}
+
+// fileFeed returns the feed owning the served file, by the feed's files directory the file is in. Files outside
+// of feeds' directories, i.e. of the flat layout, resolved by stored entries.
+func (s *Server) fileFeed(name string) (youtube.FeedInfo, bool) {
+	for _, fi := range s.Conf.YouTube.Channels {
+		dir := path.Clean("/" + filepath.ToSlash(fi.FilesDir()))
+		if dir != "/" && strings.HasPrefix(name, dir+"/") {
+			return fi, true
+		}
+	}
+	if s.YoutubeSvc == nil {
+		return youtube.FeedInfo{}, false
+	}
+	return s.YoutubeSvc.FileFeed(name)
+}
+
+// checkBasicAuth responds with 401 and returns false if auth enabled and request has no matching credentials,
+// basic auth or access token in "Authorization: Bearer" header or access_token query param
+func checkBasicAuth(w http.ResponseWriter, r *http.Request, auth youtube.BasicAuth) bool {
+	if !auth.Enabled() {
+		return true
+	}
+	if user, passwd, ok := r.BasicAuth(); ok && auth.Check(user, passwd) {
+		return true
+	}
+	if token := r.URL.Query().Get("access_token"); token != "" && auth.CheckToken(token) {
+		return true
+	}
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") && auth.CheckToken(strings.TrimPrefix(h, "Bearer ")) {
+		return true
+	}
+	if auth.User != "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="feed-master", charset="UTF-8"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="feed-master"`)
+	}
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	return false
 }
 
 func (s *Server) feeds() []string {
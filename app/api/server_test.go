@@ -3,23 +3,31 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-pkgz/lcw"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
 
 	"github.com/umputun/feed-master/app/api/mocks"
 	"github.com/umputun/feed-master/app/config"
 	"github.com/umputun/feed-master/app/feed"
 	"github.com/umputun/feed-master/app/youtube"
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/store"
 )
 
 func TestServer_Run(t *testing.T) {
@@ -123,6 +131,38 @@ func TestServer_getFeedCtrl(t *testing.T) {
 	assert.Equal(t, "feed1", store.LoadCalls()[0].FmFeed)
 }
 
+func TestServer_getFeedCtrlDates(t *testing.T) {
+	store := &mocks.StoreMock{
+		LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
+			return []feed.Item{{GUID: "guid1", Title: "title1", PubDate: "Sat, 02 Apr 2022 10:20:30 -0400",
+				DT: time.Date(2022, 4, 2, 10, 20, 30, 0, time.FixedZone("EDT", -4*3600))}}, nil
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", Store: store, cache: lcw.NewNopCache(),
+		Conf: config.Conf{Feeds: map[string]config.Feed{"feed1": {Title: "feed1"}}}}
+
+	get := func() string {
+		ts := httptest.NewServer(s.router())
+		defer ts.Close()
+		resp, err := ts.Client().Get(ts.URL + "/rss/feed1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	body := get()
+	assert.Contains(t, body, "<pubDate>Sat, 02 Apr 2022 10:20:30 -0400</pubDate>")
+	assert.Regexp(t, `<lastBuildDate>\w{3}, \d{2} \w{3} \d{4} \d{2}:\d{2}:\d{2} [+-]\d{4}</lastBuildDate>`, body)
+
+	s.Conf.System.RFC822Dates = true
+	body = get()
+	assert.Contains(t, body, "<pubDate>02 Apr 22 10:20 -0400</pubDate>")
+	assert.Regexp(t, `<lastBuildDate>\d{2} \w{3} \d{2} \d{2}:\d{2} [+-]\d{4}</lastBuildDate>`, body)
+}
+
 func TestServer_getFeedCtrlExtendDateTitle(t *testing.T) {
 
 	store := &mocks.StoreMock{
@@ -269,14 +309,290 @@ func TestServer_getFeedCtrlFeedImage(t *testing.T) {
 	assert.Equal(t, "feed1", store.LoadCalls()[0].FmFeed)
 }
 
-func TestServer_regenerateRSSCtrl(t *testing.T) {
+func TestServer_getYoutubeAggregateFeedCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		AggregateRSSFunc: func(max int) (string, error) {
+			return "<rss>blah</rss>", nil
+		},
+	}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		YoutubeSvc:    yt,
+		Conf:          config.Conf{},
+	}
+	s.Conf.System.MaxTotal = 42
 
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/yt/rss/all")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<rss>blah</rss>", string(body))
+
+	require.Equal(t, 1, len(yt.AggregateRSSCalls()))
+	assert.Equal(t, 42, yt.AggregateRSSCalls()[0].Max)
+}
+
+func TestServer_getYoutubeFeedCtrlToken(t *testing.T) {
 	yt := &mocks.YoutubeSvcMock{
-		RSSFeedFunc: func(cinfo youtube.FeedInfo) (string, error) {
-			return "blah", nil
+		RSSFeedFunc: func(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error) {
+			return "<rss>blah</rss>", nil
 		},
-		StoreRSSFunc: func(chanID string, rss string) error {
-			return nil
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt}
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{{ID: "chan1", SubscriberSecret: "secret"}, {ID: "chan2"}}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	since := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	token := youtube.SubscriberToken("secret", "chan1", since)
+	tbl := []struct {
+		url    string
+		status int
+	}{
+		{"/yt/rss/chan1", http.StatusOK},
+		{"/yt/rss/chan1?token=" + token, http.StatusOK},
+		{"/yt/rss/chan1?token=123.bad", http.StatusForbidden},
+		{"/yt/rss/chan2?token=" + token, http.StatusForbidden},
+	}
+	for _, tt := range tbl {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.url)
+	}
+
+	require.Equal(t, 2, len(yt.RSSFeedCalls()))
+	assert.True(t, yt.RSSFeedCalls()[0].Filter.Since.IsZero())
+	assert.True(t, since.Equal(yt.RSSFeedCalls()[1].Filter.Since))
+}
+
+func TestServer_getYoutubeFeedCtrlMinDuration(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		RSSFeedFunc: func(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error) {
+			return "<rss>blah</rss>", nil
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt}
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{{ID: "chan1"}}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		query  string
+		status int
+		min    time.Duration
+	}{
+		{"", http.StatusOK, 0},
+		{"?minDuration=1200", http.StatusOK, 20 * time.Minute},
+		{"?minDuration=1h30m", http.StatusOK, 90 * time.Minute},
+		{"?minDuration=-60", http.StatusBadRequest, 0},
+		{"?minDuration=long", http.StatusBadRequest, 0},
+	}
+	calls := 0
+	for _, tt := range tbl {
+		resp, err := ts.Client().Get(ts.URL + "/yt/rss/chan1" + tt.query)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, tt.status, resp.StatusCode, tt.query)
+		if tt.status != http.StatusOK {
+			continue
+		}
+		calls++
+		require.Equal(t, calls, len(yt.RSSFeedCalls()))
+		assert.Equal(t, youtube.RSSFilter{MinDuration: tt.min}, yt.RSSFeedCalls()[calls-1].Filter, tt.query)
+	}
+}
+
+func TestServer_youtubeBasicAuth(t *testing.T) {
+	filesDir := t.TempDir()
+	for _, f := range []string{"private/file1.mp3", "chan2/file2.mp3", "file3.mp3", "file4.mp3"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(filesDir, f)), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(filesDir, f), []byte("content"), 0o600))
+	}
+	yt := &mocks.YoutubeSvcMock{
+		RSSFeedFunc:      func(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error) { return "<rss>blah</rss>", nil },
+		AggregateRSSFunc: func(max int) (string, error) { return "<rss>all</rss>", nil },
+		FileFeedFunc: func(name string) (youtube.FeedInfo, bool) {
+			if name == "/file4.mp3" { // flat layout file of the private feed
+				return youtube.FeedInfo{ID: "chan1", BasicAuth: youtube.BasicAuth{User: "user", Passwd: "own"}}, true
+			}
+			return youtube.FeedInfo{}, false
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt}
+	s.Conf.YouTube.BaseURL = "http://localhost:8080/yt/media"
+	s.Conf.YouTube.FilesLocation = filesDir
+	s.Conf.YouTube.BasicAuth = youtube.BasicAuth{User: "user", Passwd: "global"}
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{
+		{ID: "chan1", SubDir: "private", BasicAuth: youtube.BasicAuth{User: "user", Passwd: "own"}},
+		{ID: "chan2"},
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url          string
+		user, passwd string
+		status       int
+	}{
+		{"/yt/rss/chan1", "", "", http.StatusUnauthorized},
+		{"/yt/rss/chan1", "user", "global", http.StatusUnauthorized},
+		{"/yt/rss/chan1", "user", "own", http.StatusOK},
+		{"/yt/rss/chan2", "", "", http.StatusUnauthorized},
+		{"/yt/rss/chan2", "user", "own", http.StatusUnauthorized},
+		{"/yt/rss/chan2", "user", "global", http.StatusOK},
+		{"/yt/rss/all", "bad", "global", http.StatusUnauthorized},
+		{"/yt/rss/all", "user", "global", http.StatusOK},
+		{"/yt/media/private/file1.mp3", "", "", http.StatusUnauthorized},
+		{"/yt/media/private/file1.mp3", "user", "global", http.StatusUnauthorized},
+		{"/yt/media/private/file1.mp3", "user", "own", http.StatusOK},
+		{"/yt/media/private/missing.mp3", "", "", http.StatusUnauthorized},
+		{"/yt/media/chan2/file2.mp3", "", "", http.StatusUnauthorized},
+		{"/yt/media/chan2/file2.mp3", "user", "global", http.StatusOK},
+		{"/yt/media/file3.mp3", "user", "own", http.StatusUnauthorized},
+		{"/yt/media/file3.mp3", "user", "global", http.StatusOK},
+		{"/yt/media/file4.mp3", "user", "global", http.StatusUnauthorized},
+		{"/yt/media/file4.mp3", "user", "own", http.StatusOK},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("GET", ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.passwd)
+		}
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, "%s %s:%s", tt.url, tt.user, tt.passwd)
+		if tt.status == http.StatusUnauthorized {
+			assert.Equal(t, `Basic realm="feed-master", charset="UTF-8"`, resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+	assert.Equal(t, 2, len(yt.RSSFeedCalls()))
+
+	// no global credentials, only the feed with own credentials is protected
+	s.Conf.YouTube.BasicAuth = youtube.BasicAuth{}
+	ts2 := httptest.NewServer(s.router())
+	defer ts2.Close()
+	for url, status := range map[string]int{"/yt/rss/chan1": http.StatusUnauthorized, "/yt/rss/chan2": http.StatusOK,
+		"/yt/media/private/file1.mp3": http.StatusUnauthorized, "/yt/media/chan2/file2.mp3": http.StatusOK} {
+		resp, err := ts2.Client().Get(ts2.URL + url)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, url)
+	}
+}
+
+func TestServer_feedAuth(t *testing.T) {
+	store := &mocks.StoreMock{
+		LoadFunc: func(fmFeed string, max int, skipJunk bool) ([]feed.Item, error) {
+			return []feed.Item{{GUID: "guid1", Title: "title1"}}, nil
+		},
+	}
+	yt := &mocks.YoutubeSvcMock{
+		RSSFeedFunc: func(cinfo youtube.FeedInfo, filter youtube.RSSFilter) (string, error) { return "<rss>blah</rss>", nil },
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", Store: store, YoutubeSvc: yt, cache: lcw.NewNopCache(),
+		Conf: config.Conf{Feeds: map[string]config.Feed{
+			"feed1": {Title: "feed1"},
+			"feed2": {Title: "feed2", BasicAuth: youtube.BasicAuth{Token: "own-token"}},
+		}}}
+	s.Conf.System.BasicAuth = youtube.BasicAuth{User: "user", Passwd: "global", Token: "global-token"}
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{{ID: "chan1", BasicAuth: youtube.BasicAuth{Token: "yt-token"}}}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url     string
+		user    string
+		bearer  string
+		status  int
+		realm   string
+		comment string
+	}{
+		{url: "/rss/feed1", status: http.StatusUnauthorized, realm: `Basic realm="feed-master", charset="UTF-8"`},
+		{url: "/rss/feed1", user: "user:global", status: http.StatusOK},
+		{url: "/rss/feed1?access_token=global-token", status: http.StatusOK},
+		{url: "/rss/feed1", bearer: "global-token", status: http.StatusOK},
+		{url: "/rss/feed1", bearer: "bad", status: http.StatusUnauthorized},
+		{url: "/rss/feed2", user: "user:global", status: http.StatusUnauthorized, realm: `Bearer realm="feed-master"`},
+		{url: "/rss/feed2", user: ":", status: http.StatusUnauthorized, comment: "empty user of token only auth"},
+		{url: "/rss/feed2?access_token=own-token", status: http.StatusOK},
+		{url: "/api/feed/feed2/episode/guid1", status: http.StatusUnauthorized},
+		{url: "/feed/feed2/sources", status: http.StatusUnauthorized},
+		{url: "/yt/rss/chan1?access_token=yt-token", status: http.StatusOK},
+		{url: "/yt/rss/chan1", bearer: "global-token", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("GET", ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		if tt.user != "" {
+			creds := strings.SplitN(tt.user, ":", 2)
+			req.SetBasicAuth(creds[0], creds[1])
+		}
+		if tt.bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+		}
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, "%s %s %s %s", tt.url, tt.user, tt.bearer, tt.comment)
+		if tt.realm != "" {
+			assert.Equal(t, tt.realm, resp.Header.Get("WWW-Authenticate"), tt.url)
+		}
+	}
+}
+
+func TestServer_subscriberTokenCtrl(t *testing.T) {
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", AdminPasswd: "123456"}
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{{ID: "chan1", SubscriberSecret: "secret"}, {ID: "chan2"}}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url, passwd string
+		status      int
+	}{
+		{"/yt/token/chan1", "bad", http.StatusForbidden},
+		{"/yt/token/chan1?since=2022-05-01T10:00:00Z", "123456", http.StatusOK},
+		{"/yt/token/chan1?since=bad", "123456", http.StatusBadRequest},
+		{"/yt/token/chan2", "123456", http.StatusBadRequest},
+		{"/yt/token/chan3", "123456", http.StatusNotFound},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("POST", ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.url)
+		if tt.status == http.StatusOK {
+			assert.Contains(t, string(body), `"token":"`+youtube.SubscriberToken("secret", "chan1",
+				time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC))+`"`)
+		}
+	}
+}
+
+func TestServer_regenerateRSSCtrl(t *testing.T) {
+
+	failed := false
+	yt := &mocks.YoutubeSvcMock{
+		RegenerateAllFunc: func() (int, error) {
+			if failed {
+				return 1, errors.New("failed to save rss for chan2")
+			}
+			return 2, nil
 		},
 	}
 
@@ -309,17 +625,23 @@ func TestServer_regenerateRSSCtrl(t *testing.T) {
 		require.NoError(t, err)
 		defer resp.Body.Close()
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"feeds":2,"status":"ok"}`+"\n", string(body))
 	}
 
-	require.Equal(t, 2, len(yt.RSSFeedCalls()))
-	assert.Equal(t, "chan1", yt.RSSFeedCalls()[0].Cinfo.ID)
-	assert.Equal(t, "chan2", yt.RSSFeedCalls()[1].Cinfo.ID)
-
-	require.Equal(t, 2, len(yt.StoreRSSCalls()))
-	require.Equal(t, "chan1", yt.StoreRSSCalls()[0].ChanID)
-	require.Equal(t, "blah", yt.StoreRSSCalls()[0].Rss)
-	require.Equal(t, "chan2", yt.StoreRSSCalls()[1].ChanID)
-	require.Equal(t, "blah", yt.StoreRSSCalls()[1].Rss)
+	{
+		failed = true
+		req, err := http.NewRequest("POST", ts.URL+"/yt/rss/generate", bytes.NewBuffer(nil))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "123456")
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	require.Equal(t, 2, len(yt.RegenerateAllCalls()))
 }
 
 func TestServer_removeEntryCtrl(t *testing.T) {
@@ -365,6 +687,593 @@ func TestServer_removeEntryCtrl(t *testing.T) {
 	require.Equal(t, "vid1", yt.RemoveEntryCalls()[0].Entry.VideoID)
 }
 
+func TestServer_redownloadCtrl(t *testing.T) {
+	release := make(chan struct{})
+	yt := &mocks.YoutubeSvcMock{
+		RedownloadFunc: func(ctx context.Context, feedID, videoID string) (ytfeed.Entry, error) {
+			switch videoID {
+			case "vid1":
+				return ytfeed.Entry{ChannelID: feedID, VideoID: videoID, File: "/srv/vid1.mp3", FileSize: 123, Duration: 60}, nil
+			case "vid2":
+				return ytfeed.Entry{}, errors.Wrapf(youtube.ErrNotFound, "entry %s in %s", videoID, feedID)
+			case "vid3":
+				return ytfeed.Entry{}, errors.New("failed to re-download")
+			}
+			<-release
+			return ytfeed.Entry{}, nil
+		},
+	}
+	defer close(release)
+	defer func(w time.Duration) { redownloadWait = w }(redownloadWait)
+	redownloadWait = 50 * time.Millisecond
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		YoutubeSvc:    yt,
+		Conf:          config.Conf{},
+		AdminPasswd:   "123456",
+	}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		video, passwd string
+		status        int
+		body          string
+	}{
+		{"vid1", "bad", http.StatusForbidden, ""},
+		{"vid1", "123456", http.StatusOK, `"file":"/srv/vid1.mp3"`},
+		{"vid2", "123456", http.StatusNotFound, "not found"},
+		{"vid3", "123456", http.StatusInternalServerError, "failed to re-download episode"},
+		{"vid4", "123456", http.StatusAccepted, `"status":"started"`},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("POST", ts.URL+"/yt/feeds/chan1/episodes/"+tt.video+"/redownload", http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.video)
+		assert.Contains(t, string(body), tt.body, tt.video)
+	}
+	require.Equal(t, 4, len(yt.RedownloadCalls()))
+	assert.Equal(t, "chan1", yt.RedownloadCalls()[0].FeedID)
+	assert.Equal(t, "vid1", yt.RedownloadCalls()[0].VideoID)
+}
+
+func TestServer_deleteEpisodeCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		DeleteEpisodeFunc: func(feedID, videoID string) (ytfeed.Entry, error) {
+			if videoID != "vid1" {
+				return ytfeed.Entry{}, errors.Wrapf(youtube.ErrNotFound, "entry %s in %s", videoID, feedID)
+			}
+			return ytfeed.Entry{ChannelID: feedID, VideoID: videoID}, nil
+		},
+	}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		YoutubeSvc:    yt,
+		Conf:          config.Conf{},
+		AdminPasswd:   "123456",
+	}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url, passwd string
+		status      int
+	}{
+		{"/yt/feeds/chan1/episodes/vid1", "bad", http.StatusForbidden},
+		{"/yt/feeds/chan1/episodes/vid1", "123456", http.StatusOK},
+		{"/yt/feeds/chan1/episodes/vid2", "123456", http.StatusNotFound},
+		{"/api/feeds/chan1/episodes/vid1", "bad", http.StatusForbidden},
+		{"/api/feeds/chan2/episodes/vid1", "123456", http.StatusOK},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("DELETE", ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.url)
+	}
+
+	require.Equal(t, 3, len(yt.DeleteEpisodeCalls()))
+	assert.Equal(t, "chan1", yt.DeleteEpisodeCalls()[0].FeedID)
+	assert.Equal(t, "vid1", yt.DeleteEpisodeCalls()[0].VideoID)
+	assert.Equal(t, "chan2", yt.DeleteEpisodeCalls()[2].FeedID, "same endpoint under /api")
+}
+
+func TestServer_deleteEpisodeRegeneratesRSS(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	for _, vid := range []string{"vid1", "vid2"} {
+		file := filepath.Join(dir, vid+".mp3")
+		require.NoError(t, os.WriteFile(file, []byte("content of "+vid), 0o600))
+		_, err = boltStore.Save(ytfeed.Entry{ChannelID: "chan1", VideoID: vid, Title: "title " + vid, File: file,
+			Published: time.Now()})
+		require.NoError(t, err)
+	}
+
+	svc := &youtube.Service{
+		Feeds:          []youtube.FeedInfo{{ID: "chan1", Name: "name1"}},
+		Store:          boltStore,
+		KeepPerChannel: 10,
+		RootURL:        "http://localhost:8080/yt",
+		RSSFileStore:   youtube.RSSFileStore{Enabled: true, Location: filepath.Join(dir, "rss")},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: svc, AdminPasswd: "123456"}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/yt/feeds/chan1/episodes/vid1", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	rss, err := os.ReadFile(filepath.Join(dir, "rss", "chan1.xml"))
+	require.NoError(t, err, "rss regenerated")
+	assert.Contains(t, string(rss), "title vid2")
+	assert.NotContains(t, string(rss), "title vid1", "deleted episode not in rss")
+	_, err = os.Stat(filepath.Join(dir, "vid1.mp3"))
+	assert.True(t, os.IsNotExist(err), "file removed")
+	entries, err := boltStore.Load("chan1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "vid2", entries[0].VideoID)
+}
+
+func TestServer_pinEpisodeCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		PinEpisodeFunc: func(feedID, videoID string, pinned bool) (ytfeed.Entry, error) {
+			if videoID != "vid1" {
+				return ytfeed.Entry{}, errors.Wrapf(youtube.ErrNotFound, "entry %s in %s", videoID, feedID)
+			}
+			return ytfeed.Entry{ChannelID: feedID, VideoID: videoID, Pinned: pinned}, nil
+		},
+	}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		YoutubeSvc:    yt,
+		Conf:          config.Conf{},
+		AdminPasswd:   "123456",
+	}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		method, url, passwd string
+		status              int
+		body                string
+	}{
+		{"POST", "/yt/feeds/chan1/episodes/vid1/pin", "bad", http.StatusForbidden, ""},
+		{"POST", "/yt/feeds/chan1/episodes/vid1/pin", "123456", http.StatusOK, `{"pinned":true,"status":"ok","video":"vid1"}`},
+		{"DELETE", "/yt/feeds/chan1/episodes/vid1/pin", "123456", http.StatusOK, `{"pinned":false,"status":"ok","video":"vid1"}`},
+		{"POST", "/yt/feeds/chan1/episodes/vid2/pin", "123456", http.StatusNotFound, ""},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.url)
+		if tt.body != "" {
+			assert.Equal(t, tt.body+"\n", string(body))
+		}
+	}
+
+	require.Equal(t, 3, len(yt.PinEpisodeCalls()))
+	assert.Equal(t, "chan1", yt.PinEpisodeCalls()[0].FeedID)
+	assert.True(t, yt.PinEpisodeCalls()[0].Pinned)
+	assert.False(t, yt.PinEpisodeCalls()[1].Pinned)
+}
+
+func TestServer_updateFeedCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		UpdateFeedFunc: func(feedID string, upd ytfeed.FeedUpdate) (youtube.FeedInfo, error) {
+			if feedID != "chan1" {
+				return youtube.FeedInfo{}, errors.Wrapf(youtube.ErrNotFound, "feed %s", feedID)
+			}
+			if upd.Keep != nil && *upd.Keep == 0 {
+				return youtube.FeedInfo{}, errors.Wrap(youtube.ErrInvalidUpdate, "keep 0")
+			}
+			return youtube.FeedInfo{ID: feedID, Name: "name1", Keep: *upd.Keep}, nil
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt, AdminPasswd: "123456"}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url, body, passwd string
+		status            int
+	}{
+		{"/yt/feeds/chan1", `{"keep": 20}`, "bad", http.StatusForbidden},
+		{"/yt/feeds/chan1", `{"keep": 20}`, "123456", http.StatusOK},
+		{"/yt/feeds/chan1", `{"keep": 0}`, "123456", http.StatusBadRequest},
+		{"/yt/feeds/chan1", `{"keep": "blah"}`, "123456", http.StatusBadRequest},
+		{"/yt/feeds/chan2", `{"keep": 20}`, "123456", http.StatusNotFound},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("PATCH", ts.URL+tt.url, strings.NewReader(tt.body))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.body)
+		if tt.status == http.StatusOK {
+			assert.JSONEq(t, `{"status":"ok","id":"chan1","keep":20,"name":"name1","language":"","paused":false}`, string(body))
+		}
+	}
+	require.Equal(t, 3, len(yt.UpdateFeedCalls()))
+	assert.Equal(t, 20, *yt.UpdateFeedCalls()[0].Upd.Keep)
+}
+
+func TestServer_pauseFeedCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		UpdateFeedFunc: func(feedID string, upd ytfeed.FeedUpdate) (youtube.FeedInfo, error) {
+			if feedID != "chan1" {
+				return youtube.FeedInfo{}, errors.Wrapf(youtube.ErrNotFound, "feed %s", feedID)
+			}
+			return youtube.FeedInfo{ID: feedID, Name: "name1", Keep: 5, Paused: *upd.Paused}, nil
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt, AdminPasswd: "123456"}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		method, url, passwd string
+		status              int
+		paused              bool
+	}{
+		{"POST", "/yt/feeds/chan1/pause", "bad", http.StatusForbidden, false},
+		{"POST", "/yt/feeds/chan1/pause", "123456", http.StatusOK, true},
+		{"DELETE", "/yt/feeds/chan1/pause", "123456", http.StatusOK, false},
+		{"POST", "/yt/feeds/chan2/pause", "123456", http.StatusNotFound, false},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.method+" "+tt.url)
+		if tt.status == http.StatusOK {
+			assert.JSONEq(t, fmt.Sprintf(`{"status":"ok","id":"chan1","keep":5,"name":"name1","language":"","paused":%v}`,
+				tt.paused), string(body))
+		}
+	}
+	require.Equal(t, 3, len(yt.UpdateFeedCalls()))
+	assert.True(t, *yt.UpdateFeedCalls()[0].Upd.Paused)
+	assert.False(t, *yt.UpdateFeedCalls()[1].Upd.Paused)
+	assert.Nil(t, yt.UpdateFeedCalls()[0].Upd.Keep, "only paused changed")
+}
+
+func TestServer_backfillCtrl(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	yt := &mocks.YoutubeSvcMock{
+		BackfillFunc: func(ctx context.Context, feedID string, limit int) (int, error) {
+			close(started)
+			<-release
+			return limit, nil
+		},
+	}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		YoutubeSvc:    yt,
+		Conf:          config.Conf{},
+		AdminPasswd:   "123456",
+	}
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{{ID: "chan1", Name: "name1", APIKey: "key1"},
+		{ID: "chan2", Name: "name2"}}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url    string
+		auth   bool
+		status int
+	}{
+		{url: "/yt/backfill/chan1", auth: false, status: http.StatusUnauthorized},
+		{url: "/yt/backfill/bad", auth: true, status: http.StatusNotFound},
+		{url: "/yt/backfill/chan1?limit=bad", auth: true, status: http.StatusBadRequest},
+		{url: "/yt/backfill/chan2", auth: true, status: http.StatusBadRequest}, // rss only, no api key
+		{url: "/yt/backfill/chan1?limit=50", auth: true, status: http.StatusAccepted},
+		{url: "/yt/backfill/chan1", auth: true, status: http.StatusConflict}, // in progress
+	}
+	for i, tt := range tbl {
+		req, err := http.NewRequest("POST", ts.URL+tt.url, http.NoBody)
+		require.NoError(t, err)
+		if tt.auth {
+			req.SetBasicAuth("admin", "123456")
+		}
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, "case #%d, %s", i, tt.url)
+		if tt.status == http.StatusAccepted {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatal("backfill not called")
+			}
+		}
+	}
+	close(release)
+	require.Equal(t, 1, len(yt.BackfillCalls()))
+	assert.Equal(t, "chan1", yt.BackfillCalls()[0].FeedID)
+	assert.Equal(t, 50, yt.BackfillCalls()[0].Limit)
+}
+
+func TestServer_mediaFileServer(t *testing.T) {
+	dir := t.TempDir()
+	filesDir := filepath.Join(dir, "yt")
+	require.NoError(t, os.MkdirAll(filepath.Join(filesDir, "sub"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(filesDir, "file1.mp3"), []byte("0123456789"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(filesDir, "file1.vtt"), []byte("WEBVTT\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o600))
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		Conf:          config.Conf{},
+	}
+	s.Conf.YouTube.BaseURL = "http://localhost:8080/yt/media"
+	s.Conf.YouTube.FilesLocation = filesDir
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	{ // full file
+		resp, err := ts.Client().Get(ts.URL + "/yt/media/file1.mp3")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "audio/mpeg", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", string(body))
+	}
+
+	{ // subtitles
+		resp, err := ts.Client().Get(ts.URL + "/yt/media/file1.vtt")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/vtt; charset=utf-8", resp.Header.Get("Content-Type"))
+	}
+
+	{ // range request
+		req, err := http.NewRequest("GET", ts.URL+"/yt/media/file1.mp3", http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set("Range", "bytes=2-5")
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "bytes 2-5/10", resp.Header.Get("Content-Range"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "2345", string(body))
+	}
+
+	for _, p := range []string{"/yt/media/", "/yt/media/sub", "/yt/media/nope.mp3", "/yt/media/..%2fsecret.txt",
+		"/yt/media/%2e%2e/secret.txt", "/yt/media/..%5csecret.txt"} {
+		resp, err := ts.Client().Get(ts.URL + p)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, p)
+		assert.NotContains(t, string(body), "secret", p)
+	}
+}
+
+func TestServer_mediaFileServerSigned(t *testing.T) {
+	filesDir := t.TempDir()
+	for _, f := range []string{"chan1/file1.mp3", "chan1/file2.mp3", "chan2/file1.mp3", "flat1.mp3", "flat2.mp3"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(filesDir, filepath.Dir(f)), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(filesDir, f), []byte("0123456789"), 0o600))
+	}
+	yt := &mocks.YoutubeSvcMock{
+		FileFeedFunc: func(name string) (youtube.FeedInfo, bool) {
+			if name == "/flat1.mp3" { // flat layout file of the signed feed
+				return youtube.FeedInfo{ID: "chan1", URLSigning: youtube.URLSigning{Secret: "secret", TTL: time.Hour}}, true
+			}
+			return youtube.FeedInfo{}, false
+		},
+	}
+
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", Conf: config.Conf{}, YoutubeSvc: yt}
+	s.Conf.YouTube.BaseURL = "http://localhost:8080/yt/media"
+	s.Conf.YouTube.FilesLocation = filesDir
+	s.Conf.YouTube.Channels = []youtube.FeedInfo{{ID: "chan1", URLSigning: youtube.URLSigning{Secret: "secret", TTL: time.Hour}},
+		{ID: "chan2"}}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	signed, err := (&youtube.HMACSigner{Secret: "secret", TTL: time.Hour}).Sign(ts.URL + "/yt/media/chan1/file1.mp3")
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+
+	tbl := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"signed", signed, http.StatusOK},
+		{"unsigned", ts.URL + "/yt/media/chan1/file1.mp3", http.StatusForbidden},
+		{"expired", ts.URL + "/yt/media/chan1/file1.mp3?expires=1651402800&signature=" + u.Query().Get("signature"),
+			http.StatusForbidden},
+		{"signature of other file", ts.URL + "/yt/media/chan1/file2.mp3?" + u.RawQuery, http.StatusForbidden},
+		{"feed without signing", ts.URL + "/yt/media/chan2/file1.mp3", http.StatusOK},
+		{"unsigned flat layout file of signed feed", ts.URL + "/yt/media/flat1.mp3", http.StatusForbidden},
+		{"unsigned flat layout file of unknown feed", ts.URL + "/yt/media/flat2.mp3", http.StatusOK},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := ts.Client().Get(tt.url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestServer_verifyFilesCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		VerifyFilesFunc: func(ctx context.Context) (youtube.VerifyResult, error) {
+			return youtube.VerifyResult{Checked: 3, Corrupted: []ytfeed.Entry{{ChannelID: "chan1", VideoID: "vid1"}},
+				Unreadable: []ytfeed.Entry{{ChannelID: "chan1", VideoID: "vid2"}}}, nil
+		},
+	}
+
+	s := Server{
+		Version:       "1.0",
+		TemplLocation: "../webapp/templates/*",
+		YoutubeSvc:    yt,
+		Conf:          config.Conf{},
+		AdminPasswd:   "123456",
+	}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	req, err := http.NewRequest("POST", ts.URL+"/yt/verify", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "123456")
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"checked":3,"corrupted":["chan1::vid1"],"failed":[],"status":"ok","unreadable":["chan1::vid2"]}`+"\n", string(body))
+	assert.Equal(t, 1, len(yt.VerifyFilesCalls()))
+}
+
+func TestServer_reconcileCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		ReconcileFunc: func(ctx context.Context, opts youtube.ReconcileOpts) (youtube.ReconcileReport, error) {
+			res := youtube.ReconcileReport{Orphans: []string{"/srv/chan1/f1.mp3"},
+				Dangling: []ytfeed.Entry{{ChannelID: "chan1", VideoID: "vid1"}}}
+			if opts.RemoveOrphans {
+				res.Removed = 1
+			}
+			return res, nil
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt, AdminPasswd: "123456"}
+
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		query string
+		opts  youtube.ReconcileOpts
+		body  string
+	}{
+		{"", youtube.ReconcileOpts{},
+			`{"dangling":["chan1::vid1"],"orphans":["/srv/chan1/f1.mp3"],"purged":0,"removed":0,"status":"ok"}`},
+		{"?remove_orphans=true&purge_dangling=true", youtube.ReconcileOpts{RemoveOrphans: true, PurgeDangling: true},
+			`{"dangling":["chan1::vid1"],"orphans":["/srv/chan1/f1.mp3"],"purged":0,"removed":1,"status":"ok"}`},
+	}
+	for i, tt := range tbl {
+		req, err := http.NewRequest("POST", ts.URL+"/yt/reconcile"+tt.query, http.NoBody)
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", "123456")
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, tt.body, string(body))
+		require.Equal(t, i+1, len(yt.ReconcileCalls()))
+		assert.Equal(t, tt.opts, yt.ReconcileCalls()[i].Opts)
+	}
+}
+
+func TestServer_testDownloadCtrl(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		TestDownloadFunc: func(ctx context.Context, url string) (youtube.TestDownloadResult, error) {
+			if url == "bad&url" {
+				return youtube.TestDownloadResult{}, fmt.Errorf("%q: %w", url, youtube.ErrInvalidURL)
+			}
+			return youtube.TestDownloadResult{URL: url, OK: false, Error: "exit 1", Elapsed: 2 * time.Second,
+				Output: "ERROR: not available in your country"}, nil
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt, AdminPasswd: "123456"}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		body   string
+		passwd string
+		status int
+		resp   string
+	}{
+		{`{"url": "abcdefghijk"}`, "123456", http.StatusOK, `{"url":"abcdefghijk","ok":false,"error":"exit 1","size":0,` +
+			`"elapsed":"2s","output":"ERROR: not available in your country"}`},
+		{`{"url": "bad&url"}`, "123456", http.StatusBadRequest, ""},
+		{`{"url": `, "123456", http.StatusBadRequest, ""},
+		{`{"url": "abcdefghijk"}`, "bad", http.StatusForbidden, ""},
+	}
+	for _, tt := range tbl {
+		req, err := http.NewRequest("POST", ts.URL+"/api/test-download", strings.NewReader(tt.body))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", tt.passwd)
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.body)
+		if tt.resp != "" {
+			assert.JSONEq(t, tt.resp, string(body))
+		}
+	}
+	require.Equal(t, 2, len(yt.TestDownloadCalls()))
+	assert.Equal(t, "abcdefghijk", yt.TestDownloadCalls()[0].URL)
+}
+
 func TestServer_configCtrl(t *testing.T) {
 
 	store := &mocks.StoreMock{}
@@ -407,3 +1316,92 @@ func TestServer_configCtrl(t *testing.T) {
 	assert.Contains(t, body, "this is feed1")
 	assert.Contains(t, body, "http://example.com/feed1")
 }
+
+func TestServer_statusCtrl(t *testing.T) {
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YtDlpVersion: "2022.03.08"}
+	s.Conf.YouTube.MinYtDlpVersion = "2022.04.01"
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(respBody), `"version":"1.0"`)
+	assert.Contains(t, string(respBody), `"yt_dlp":"2022.03.08"`)
+	assert.Contains(t, string(respBody), `"yt_dlp_outdated":true`)
+	assert.Contains(t, string(respBody), `"yt_failures":{}`)
+	assert.Contains(t, string(respBody), `"yt_errors":{}`)
+}
+
+func TestServer_statusCtrlFetchErrors(t *testing.T) {
+	yt := &mocks.YoutubeSvcMock{
+		FailuresFunc: func(feedID string) []youtube.Failure { return nil },
+		FetchErrorsFunc: func() map[string]ytfeed.FetchError {
+			return map[string]ytfeed.FetchError{"chan1": {Error: "auth error", TS: time.Now().Add(-2 * time.Hour)}}
+		},
+	}
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	res := struct {
+		Errors map[string]struct {
+			Error string    `json:"error"`
+			TS    time.Time `json:"ts"`
+			Ago   string    `json:"ago"`
+		} `json:"yt_errors"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.Equal(t, 1, len(res.Errors))
+	assert.Equal(t, "auth error", res.Errors["chan1"].Error)
+	assert.Equal(t, "2h0m0s", res.Errors["chan1"].Ago)
+	assert.Equal(t, 1, len(yt.FetchErrorsCalls()))
+}
+
+func TestServer_failuresCtrl(t *testing.T) {
+	ts0 := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	yt := &mocks.YoutubeSvcMock{
+		FailuresFunc: func(feedID string) []youtube.Failure {
+			res := []youtube.Failure{
+				{FeedID: "chan1", Feed: "name1", VideoID: "vid2", Title: "title2", Reason: "err2", TS: ts0.Add(time.Minute)},
+				{FeedID: "chan2", Feed: "name2", VideoID: "vid1", Title: "title1", Reason: "err1", TS: ts0},
+			}
+			if feedID == "chan1" {
+				return res[:1]
+			}
+			return res
+		},
+	}
+	yt.FetchErrorsFunc = func() map[string]ytfeed.FetchError { return nil }
+	s := Server{Version: "1.0", TemplLocation: "../webapp/templates/*", YoutubeSvc: yt}
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/yt/failures?feed=chan1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"feed_id":"chan1","feed":"name1","video_id":"vid2","title":"title2","reason":"err2",`+
+		`"ts":"2022-05-01T10:01:00Z"}]`+"\n", string(body))
+
+	resp, err = ts.Client().Get(ts.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"yt_failures":{"chan1":1,"chan2":1}`)
+
+	require.Equal(t, 2, len(yt.FailuresCalls()))
+	assert.Equal(t, "chan1", yt.FailuresCalls()[0].FeedID)
+	assert.Equal(t, "", yt.FailuresCalls()[1].FeedID)
+}
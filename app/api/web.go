@@ -18,6 +18,9 @@ import (
 // GET /feed/{name} - renders page with list of items
 func (s *Server) getFeedPageCtrl(w http.ResponseWriter, r *http.Request) {
 	feedName := chi.URLParam(r, "name")
+	if !checkBasicAuth(w, r, s.procFeedAuth(feedName)) {
+		return
+	}
 
 	data, err := s.cache.Get(feedName, func() (interface{}, error) {
 		items, err := s.Store.Load(feedName, s.Conf.System.MaxTotal, false)
@@ -134,6 +137,9 @@ func (s *Server) getFeedsPageCtrl(w http.ResponseWriter, r *http.Request) {
 // GET /feed/{name}/sources - renders page with feed's list of sources
 func (s *Server) getSourcesPageCtrl(w http.ResponseWriter, r *http.Request) {
 	feedName := chi.URLParam(r, "name")
+	if !checkBasicAuth(w, r, s.procFeedAuth(feedName)) {
+		return
+	}
 	data, err := s.cache.Get(feedName+"-sources", func() (interface{}, error) {
 		if _, ok := s.Conf.Feeds[feedName]; !ok {
 			return nil, fmt.Errorf("feed %s not found", feedName)
@@ -2,39 +2,82 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/url"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/umputun/feed-master/app/feed"
 	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
 
 // Conf for feeds config yml
 type Conf struct {
 	Feeds  map[string]Feed `yaml:"feeds"`
 	System struct {
-		UpdateInterval time.Duration `yaml:"update"`
-		MaxItems       int           `yaml:"max_per_feed"`
-		MaxTotal       int           `yaml:"max_total"`
-		MaxKeepInDB    int           `yaml:"max_keep"`
-		Concurrent     int           `yaml:"concurrent"`
-		BaseURL        string        `yaml:"base_url"`
+		UpdateInterval time.Duration     `yaml:"update"`
+		MaxItems       int               `yaml:"max_per_feed"`
+		MaxTotal       int               `yaml:"max_total"`
+		MaxKeepInDB    int               `yaml:"max_keep"`
+		Concurrent     int               `yaml:"concurrent"`
+		BaseURL        string            `yaml:"base_url"`
+		RFC822Dates    bool              `yaml:"rfc822_dates"` // rss dates with 2 digits year, RFC1123Z by default
+		BasicAuth      youtube.BasicAuth `yaml:"basic_auth"`   // protects feeds without own credentials
 	} `yaml:"system"`
 
 	YouTube struct {
-		DlTemplate      string             `yaml:"dl_template"`
-		BaseChanURL     string             `yaml:"base_chan_url"`
-		BasePlaylistURL string             `yaml:"base_playlist_url"`
-		Channels        []youtube.FeedInfo `yaml:"channels"`
-		BaseURL         string             `yaml:"base_url"`
-		UpdateInterval  time.Duration      `yaml:"update"`
-		MaxItems        int                `yaml:"max_per_channel"`
-		FilesLocation   string             `yaml:"files_location"`
-		RSSLocation     string             `yaml:"rss_location"`
-		SkipShorts      time.Duration      `yaml:"skip_shorts"`
+		DlTemplate        string               `yaml:"dl_template"`
+		BaseChanURL       string               `yaml:"base_chan_url"`
+		BasePlaylistURL   string               `yaml:"base_playlist_url"`
+		Source            ytfeed.Source        `yaml:"source"` // alternative api listing channels, i.e. piped or invidious
+		Channels          []youtube.FeedInfo   `yaml:"channels"`
+		BaseURL           string               `yaml:"base_url"`
+		MediaBaseURL      string               `yaml:"media_base_url"` // base url of enclosures, i.e. cdn, default is base_url
+		UpdateInterval    time.Duration        `yaml:"update"`
+		UpdateJitter      time.Duration        `yaml:"update_jitter"`
+		MaxItems          int                  `yaml:"max_per_channel"`
+		FilesLocation     string               `yaml:"files_location"`
+		RSSLocation       string               `yaml:"rss_location"`
+		RSSMirrors        []string             `yaml:"rss_mirrors"` // extra locations of rss files, i.e. mounted object storage
+		SkipShorts        time.Duration        `yaml:"skip_shorts"`
+		MinYtDlpVersion   string               `yaml:"min_ytdlp_version"`
+		FileNameTmpl      string               `yaml:"file_name_template"`
+		FileNameHash      string               `yaml:"file_name_hash"`
+		FileNameHashLen   int                  `yaml:"file_name_hash_len"`
+		BackfillDelay     time.Duration        `yaml:"backfill_delay"`
+		CompletionWebhook string               `yaml:"completion_webhook"`
+		DownloadTimeout   time.Duration        `yaml:"download_timeout"`
+		DownloadRate      string               `yaml:"download_rate"`
+		ResumeDownloads   bool                 `yaml:"resume_downloads"` // continue interrupted downloads from .part files
+		FeedTimeout       time.Duration        `yaml:"feed_timeout"`
+		ShutdownGrace     time.Duration        `yaml:"shutdown_grace"`
+		PostDlTimeout     time.Duration        `yaml:"post_download_timeout"`
+		MaxPerCycle       int                  `yaml:"max_per_cycle"`
+		MaxDownloads      int                  `yaml:"max_concurrent_downloads"` // downloads running at once, no limit if 0
+		CheckURLs         int                  `yaml:"check_urls"`
+		ListingTTL        time.Duration        `yaml:"listing_ttl"`
+		FetchAttempts     int                  `yaml:"fetch_attempts"`    // attempts to list a feed on transient errors
+		FetchRetryDelay   time.Duration        `yaml:"fetch_retry_delay"` // the first delay between attempts, doubled each time
+		DetectLang        bool                 `yaml:"detect_lang"`
+		StrictEnv         bool                 `yaml:"strict_env"` // reject references to unset env variables, see expandEnv
+		ProcessedMaxAge   time.Duration        `yaml:"processed_max_age"`
+		RemoveConcurrency int                  `yaml:"remove_concurrency"`
+		IncrementalRSS    int                  `yaml:"incremental_rss"`  // rss items to update rss file in place, disabled if 0
+		DownloadBudget    youtube.FileSize     `yaml:"download_budget"`  // bytes downloaded per budget period, no limit if 0
+		BudgetPeriod      youtube.BudgetPeriod `yaml:"budget_period"`    // month (default), week or day
+		FormatFallbacks   []string             `yaml:"format_fallbacks"` // yt-dlp formats tried if formats of dl_template missing
+		BasicAuth         youtube.BasicAuth    `yaml:"basic_auth"`       // protects rss and files of all feeds without own credentials
+		Store             struct {
+			Type string `yaml:"type"` // bolt (default) or sqlite
+			File string `yaml:"file"` // sqlite db file
+		} `yaml:"store"`
 	} `yaml:"youtube"`
 }
 
@@ -46,17 +89,18 @@ type Source struct {
 
 // Feed defines config section for a feed~
 type Feed struct {
-	Title           string   `yaml:"title"`
-	Description     string   `yaml:"description"`
-	Link            string   `yaml:"link"`
-	Image           string   `yaml:"image"`
-	Language        string   `yaml:"language"`
-	TelegramChannel string   `yaml:"telegram_channel"`
-	Filter          Filter   `yaml:"filter"`
-	Sources         []Source `yaml:"sources"`
-	ExtendDateTitle string   `yaml:"ext_date"`
-	Author          string   `yaml:"author"`
-	OwnerEmail      string   `yaml:"owner_email"`
+	Title           string            `yaml:"title"`
+	Description     string            `yaml:"description"`
+	Link            string            `yaml:"link"`
+	Image           string            `yaml:"image"`
+	Language        string            `yaml:"language"`
+	TelegramChannel string            `yaml:"telegram_channel"`
+	Filter          Filter            `yaml:"filter"`
+	Sources         []Source          `yaml:"sources"`
+	ExtendDateTitle string            `yaml:"ext_date"`
+	Author          string            `yaml:"author"`
+	OwnerEmail      string            `yaml:"owner_email"`
+	BasicAuth       youtube.BasicAuth `yaml:"basic_auth"` // protects rss, json and pages of the feed, overrides the global one
 }
 
 // Filter defines feed section for a feed filter~
@@ -86,19 +130,110 @@ type YTChannel struct {
 
 // Load config from file
 func Load(fname string) (res *Conf, err error) {
-	res = &Conf{}
+	if res, err = parse(fname); err != nil {
+		return nil, err
+	}
+	for _, f := range res.YouTube.Channels {
+		if err := checkTemplates(f); err != nil {
+			return nil, err
+		}
+		if err := checkSubDir(f); err != nil {
+			return nil, err
+		}
+		if reservedIDs[f.ID] {
+			return nil, fmt.Errorf("youtube channel id %q is reserved, used by api paths", f.ID)
+		}
+	}
+	if err := res.checkMediaBaseURL(); err != nil {
+		return nil, err
+	}
+	if err := res.checkPeerTubeTemplate(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// parse reads config from file, expands env references and sets defaults, without checks of values
+func parse(fname string) (*Conf, error) {
+	res := &Conf{}
 	data, err := ioutil.ReadFile(fname) // nolint
 	if err != nil {
 		return nil, err
 	}
-
 	if err := yaml.Unmarshal(data, res); err != nil {
 		return nil, err
 	}
+	if err := res.expandEnv(); err != nil {
+		return nil, err
+	}
 	res.setDefaults()
 	return res, nil
 }
 
+// checkMediaBaseURL verifies youtube's media base url, if set, is an absolute http(s) url
+func (c *Conf) checkMediaBaseURL() error {
+	if c.YouTube.MediaBaseURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.YouTube.MediaBaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid media_base_url %q: %w", c.YouTube.MediaBaseURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid media_base_url %q, should be absolute http or https url", c.YouTube.MediaBaseURL)
+	}
+	return nil
+}
+
+// reservedIDs can't be used as youtube channel ids, they would collide with paths of api, i.e. /yt/rss/all
+var reservedIDs = map[string]bool{"all": true}
+
+// checkSubDir verifies sub directory of youtube channel is inside of the files location
+func checkSubDir(f youtube.FeedInfo) error {
+	if f.SubDir == "" {
+		return nil
+	}
+	dir := filepath.Clean(f.SubDir)
+	if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid sub_dir %q for youtube channel %s, should be relative to files location", f.SubDir, f.ID)
+	}
+	return nil
+}
+
+// checkTemplates verifies templates of youtube channel can be parsed
+func checkTemplates(f youtube.FeedInfo) error {
+	if f.PostDownloadCmd != "" {
+		if err := youtube.CheckPostDownloadCmd(f.PostDownloadCmd); err != nil {
+			return fmt.Errorf("invalid post_download_cmd for youtube channel %s: %w", f.ID, err)
+		}
+	}
+	if f.DescriptionTmpl == "" {
+		return nil
+	}
+	if _, err := template.New("description").Parse(f.DescriptionTmpl); err != nil {
+		return fmt.Errorf("invalid description template for youtube channel %s: %w", f.ID, err)
+	}
+	return nil
+}
+
+// dlTemplateURLRe matches {{.URL}} in dl_template, with optional spaces and trim markers
+var dlTemplateURLRe = regexp.MustCompile(`{{-?\s*\.URL\s*-?}}`)
+
+// checkPeerTubeTemplate rejects dl_template without {{.URL}} if any peertube channel configured. Video id of
+// peertube is the full url of the video, templates made of youtube's watch url and {{.ID}} can't download it.
+func (c *Conf) checkPeerTubeTemplate() error {
+	if dlTemplateURLRe.MatchString(c.YouTube.DlTemplate) {
+		return nil
+	}
+	for _, f := range c.YouTube.Channels {
+		if f.Type == ytfeed.FTPeerTube {
+			return fmt.Errorf("dl_template should use {{.URL}} instead of youtube's watch url with {{.ID}} "+
+				"to download peertube channel %s", f.ID)
+		}
+	}
+	return nil
+}
+
 // SingleFeed returns single feed "fake" config for no-config mode
 func SingleFeed(feedURL, ch string, updateInterval time.Duration) *Conf {
 	conf := Conf{}
@@ -160,7 +295,7 @@ func (c *Conf) setDefaults() {
 	}
 
 	if c.YouTube.DlTemplate == "" {
-		c.YouTube.DlTemplate = `yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "https://www.youtube.com/watch?v={{.ID}}" --no-progress -o {{.FileName}}.tmp`
+		c.YouTube.DlTemplate = `yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "{{.URL}}" --no-progress -o {{.FileName}}.tmp`
 	}
 
 	if c.YouTube.BaseChanURL == "" {
@@ -171,6 +306,13 @@ func (c *Conf) setDefaults() {
 		c.YouTube.BasePlaylistURL = "https://www.youtube.com/feeds/videos.xml?playlist_id="
 	}
 
+	if c.YouTube.Store.Type == "" {
+		c.YouTube.Store.Type = "bolt"
+	}
+	if c.YouTube.Store.Type == "sqlite" && c.YouTube.Store.File == "" {
+		c.YouTube.Store.File = "var/feed-master-yt.sqlite"
+	}
+
 	if c.YouTube.FilesLocation == "" {
 		c.YouTube.FilesLocation = "var/yt"
 	}
@@ -179,4 +321,8 @@ func (c *Conf) setDefaults() {
 		c.YouTube.RSSLocation = "var/rss"
 	}
 
+	if c.YouTube.FetchAttempts == 0 {
+		c.YouTube.FetchAttempts = 3
+	}
+
 }
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"regexp/syntax"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 
 	rssfeed "github.com/umputun/feed-master/app/feed"
 	ytfdeed "github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
 
 func TestLoad(t *testing.T) {
@@ -54,6 +58,154 @@ func TestLoadConfigInvalidYaml(t *testing.T) {
 	assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `Not Yaml` into config.Conf")
 }
 
+func TestLoadConfigInvalidDescriptionTemplate(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	data := "youtube:\n  channels:\n  - {id: id1, name: name1, description_template: \"{{.Title\"}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+
+	r, err := Load(fname)
+	assert.Nil(t, r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid description template for youtube channel id1")
+}
+
+func TestLoadConfigInvalidSubDir(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	for _, dir := range []string{"/abs/dir", "../up", "a/../../up", ".."} {
+		data := "youtube:\n  channels:\n  - {id: id1, name: name1, sub_dir: \"" + dir + "\"}\n"
+		require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+		r, err := Load(fname)
+		assert.Nil(t, r, dir)
+		require.Error(t, err, dir)
+		assert.Contains(t, err.Error(), "invalid sub_dir", dir)
+	}
+
+	data := "youtube:\n  channels:\n  - {id: id1, name: name1, sub_dir: \"podcasts/news\"}\n  - {id: id2, name: name2, sub_dir: .}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err := Load(fname)
+	require.NoError(t, err)
+	assert.Equal(t, "podcasts/news", r.YouTube.Channels[0].SubDir)
+}
+
+func TestLoadConfigReservedID(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	data := "youtube:\n  channels:\n  - {id: all, name: name1}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err := Load(fname)
+	assert.Nil(t, r)
+	assert.EqualError(t, err, `youtube channel id "all" is reserved, used by api paths`)
+}
+
+func TestLoadConfigInvalidMediaBaseURL(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	for _, u := range []string{"cdn.example.com/yt", "/yt/media", "ftp://cdn.example.com", "http://%zz"} {
+		data := "youtube:\n  media_base_url: \"" + u + "\"\n"
+		require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+		r, err := Load(fname)
+		assert.Nil(t, r, u)
+		require.Error(t, err, u)
+		assert.Contains(t, err.Error(), "invalid media_base_url", u)
+	}
+
+	data := "youtube:\n  media_base_url: https://cdn.example.com/yt\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err := Load(fname)
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/yt", r.YouTube.MediaBaseURL)
+}
+
+func TestLoadConfigInvalidPostDownloadCmd(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	data := "youtube:\n  channels:\n  - {id: UCxyz, name: name1, post_download_cmd: \"tag {{.File\"}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err := Load(fname)
+	assert.Nil(t, r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid post_download_cmd for youtube channel UCxyz")
+
+	data = "youtube:\n  post_download_timeout: 30s\n  channels:\n  - {id: UCxyz, name: name1, post_download_cmd: \"tag {{.File}}\"}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err = Load(fname)
+	require.NoError(t, err)
+	assert.Equal(t, "tag {{.File}}", r.YouTube.Channels[0].PostDownloadCmd)
+	assert.Equal(t, 30*time.Second, r.YouTube.PostDlTimeout)
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	t.Setenv("FM_TEST_KEY", "key1")
+	t.Setenv("FM_TEST_DIR", "/srv/var")
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	data := `youtube:
+  files_location: ${FM_TEST_DIR}/yt
+  rss_mirrors: [$FM_TEST_DIR/mirror]
+  dl_template: yt-dlp --cookies $FM_TEST_COOKIES {{.URL}}
+  channels:
+  - {id: UCxyz, name: name $FM_TEST_KEY, api_key: "${FM_TEST_KEY}", basic_auth: {user: u1, passwd: "pa$$wd$FM_TEST_UNSET"},
+     filter: {include: "end$"}, headers: {Authorization: "Bearer ${FM_TEST_KEY}"}}
+feeds:
+  first: {title: t1, basic_auth: {token: "${FM_TEST_KEY}"}}
+`
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err := Load(fname)
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/var/yt", r.YouTube.FilesLocation)
+	assert.Equal(t, []string{"/srv/var/mirror"}, r.YouTube.RSSMirrors)
+	assert.Equal(t, "key1", r.YouTube.Channels[0].APIKey)
+	assert.Equal(t, ytfeed.Headers{"Authorization": "Bearer key1"}, r.YouTube.Channels[0].Headers)
+	assert.Equal(t, "pa$wd", r.YouTube.Channels[0].BasicAuth.Passwd, "escaped $ kept, unset var is empty")
+	assert.Equal(t, "key1", r.Feeds["first"].BasicAuth.Token)
+	assert.Equal(t, "name $FM_TEST_KEY", r.YouTube.Channels[0].Name, "not expanded")
+	assert.Equal(t, "end$", r.YouTube.Channels[0].Filter.Include, "not expanded")
+	assert.Equal(t, "yt-dlp --cookies $FM_TEST_COOKIES {{.URL}}", r.YouTube.DlTemplate, "expanded by shell")
+
+	require.NoError(t, os.WriteFile(fname, []byte("youtube:\n  strict_env: true\n"+data[len("youtube:\n"):]), 0o600))
+	r, err = Load(fname)
+	assert.Nil(t, r)
+	assert.EqualError(t, err, "unset environment variables in config: FM_TEST_UNSET")
+}
+
+func TestLoadConfigPeerTubeTemplate(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	tmpl := `yt-dlp --extract-audio "https://www.youtube.com/watch?v={{.ID}}" -o {{.FileName}}.tmp`
+	data := "youtube:\n  dl_template: '" + tmpl + "'\n  channels:\n  - {id: UCxyz, name: name1}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	_, err := Load(fname)
+	assert.NoError(t, err, "youtube channels only")
+
+	data += "  - {id: joinpeertube@framatube.org, name: name2, type: peertube}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	_, err = Load(fname)
+	assert.EqualError(t, err, "dl_template should use {{.URL}} instead of youtube's watch url with {{.ID}} "+
+		"to download peertube channel joinpeertube@framatube.org")
+
+	data = strings.Replace(data, "https://www.youtube.com/watch?v={{.ID}}", "{{ .URL }}", 1)
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	_, err = Load(fname)
+	assert.NoError(t, err)
+
+	data = "youtube:\n  channels:\n  - {id: joinpeertube@framatube.org, name: name2, type: peertube}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	_, err = Load(fname)
+	assert.NoError(t, err, "default template")
+}
+
+func TestLoadConfigInvalidFeedType(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	data := "youtube:\n  channels:\n  - {id: UCxyz, name: name1, type: reels}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+
+	r, err := Load(fname)
+	assert.Nil(t, r)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown feed type "reels"`)
+
+	data = "youtube:\n  channels:\n  - {id: UCxyz, name: name1, type: Shorts}\n"
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	r, err = Load(fname)
+	require.NoError(t, err)
+	assert.Equal(t, ytfeed.FTShorts, r.YouTube.Channels[0].Type)
+}
+
 func TestSingleFeedConf(t *testing.T) {
 	cases := []struct {
 		feedURL, channel string
@@ -87,12 +239,14 @@ func TestSetDefault(t *testing.T) {
 
 	expectedConf := Conf{
 		System: struct {
-			UpdateInterval time.Duration `yaml:"update"`
-			MaxItems       int           `yaml:"max_per_feed"`
-			MaxTotal       int           `yaml:"max_total"`
-			MaxKeepInDB    int           `yaml:"max_keep"`
-			Concurrent     int           `yaml:"concurrent"`
-			BaseURL        string        `yaml:"base_url"`
+			UpdateInterval time.Duration     `yaml:"update"`
+			MaxItems       int               `yaml:"max_per_feed"`
+			MaxTotal       int               `yaml:"max_total"`
+			MaxKeepInDB    int               `yaml:"max_keep"`
+			Concurrent     int               `yaml:"concurrent"`
+			BaseURL        string            `yaml:"base_url"`
+			RFC822Dates    bool              `yaml:"rfc822_dates"` // rss dates with 2 digits year, RFC1123Z by default
+			BasicAuth      ytfdeed.BasicAuth `yaml:"basic_auth"`   // protects feeds without own credentials
 		}{UpdateInterval: time.Minute * 5, MaxItems: 5, MaxTotal: 100, MaxKeepInDB: 5000, Concurrent: 8, BaseURL: ""},
 	}
 
@@ -101,7 +255,7 @@ func TestSetDefault(t *testing.T) {
 	assert.Equal(t, "/yt/media", c.YouTube.BaseURL)
 	assert.Equal(t, "var/yt", c.YouTube.FilesLocation)
 	assert.Equal(t, "var/rss", c.YouTube.RSSLocation)
-	assert.Equal(t, "yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio \"https://www.youtube.com/watch?v={{.ID}}\" --no-progress -o {{.FileName}}.tmp", c.YouTube.DlTemplate)
+	assert.Equal(t, "yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio \"{{.URL}}\" --no-progress -o {{.FileName}}.tmp", c.YouTube.DlTemplate)
 	assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?channel_id=", c.YouTube.BaseChanURL)
 	assert.Equal(t, "https://www.youtube.com/feeds/videos.xml?playlist_id=", c.YouTube.BasePlaylistURL)
 }
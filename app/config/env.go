@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envExpander replaces ${VAR} and $VAR references with values of environment variables,
+// collecting references to unset variables
+type envExpander struct {
+	unset map[string]bool
+}
+
+// expand replaces env references in the value in place, $$ is an escaped $
+func (e *envExpander) expand(val *string) {
+	if !strings.Contains(*val, "$") {
+		return
+	}
+	*val = os.Expand(*val, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			if e.unset == nil {
+				e.unset = map[string]bool{}
+			}
+			e.unset[name] = true
+		}
+		return v
+	})
+}
+
+// expandEnv replaces env references in paths, urls and secrets of the youtube section and channels, and in
+// credentials of feeds, i.e. `api_key: ${YT_API_KEY}`. Templates, commands and filters are kept as is, they may
+// use $ on their own.
+// Unset variables are replaced with empty strings, or rejected if strict_env is set.
+func (c *Conf) expandEnv() error {
+	e := envExpander{}
+	yt := &c.YouTube
+	for _, v := range []*string{&yt.BaseChanURL, &yt.BasePlaylistURL, &yt.Source.URL, &yt.BaseURL, &yt.MediaBaseURL, &yt.FilesLocation,
+		&yt.RSSLocation, &yt.CompletionWebhook, &yt.Store.File, &yt.BasicAuth.User, &yt.BasicAuth.Passwd,
+		&yt.BasicAuth.Token, &c.System.BasicAuth.User, &c.System.BasicAuth.Passwd, &c.System.BasicAuth.Token} {
+		e.expand(v)
+	}
+	for name, f := range c.Feeds {
+		for _, v := range []*string{&f.BasicAuth.User, &f.BasicAuth.Passwd, &f.BasicAuth.Token} {
+			e.expand(v)
+		}
+		c.Feeds[name] = f
+	}
+	for i := range yt.RSSMirrors {
+		e.expand(&yt.RSSMirrors[i])
+	}
+	for i := range yt.Channels {
+		ch := &yt.Channels[i]
+		for _, v := range []*string{&ch.SubDir, &ch.Overrides, &ch.APIKey, &ch.SubscriberSecret, &ch.Source.URL,
+			&ch.BasicAuth.User, &ch.BasicAuth.Passwd, &ch.BasicAuth.Token, &ch.URLSigning.Secret} {
+			e.expand(v)
+		}
+		for name, value := range ch.Headers {
+			e.expand(&value)
+			ch.Headers[name] = value
+		}
+	}
+
+	if !yt.StrictEnv || len(e.unset) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(e.unset))
+	for name := range e.unset {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unset environment variables in config: %s", strings.Join(names, ", "))
+}
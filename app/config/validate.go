@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+var (
+	channelIDRe  = regexp.MustCompile(`^UC[\w-]{22}$`)
+	playlistIDRe = regexp.MustCompile(`^[\w-]{10,}$`)
+	peerTubeIDRe = regexp.MustCompile(`^[\w.-]+@[\w.-]+\.[\w-]+$`)
+)
+
+// namedInt and namedDuration are config values with yaml names, for range checks
+type namedInt struct {
+	name string
+	val  int
+}
+
+type namedDuration struct {
+	name string
+	val  time.Duration
+}
+
+// Validate reads config from file and checks it without starting anything, i.e. required fields of feeds and
+// youtube channels, ids, regexps, templates, urls and numeric ranges. Returns all found problems, empty if
+// the config is valid. Error returned if the config can't be read or parsed at all.
+func Validate(fname string) ([]string, error) {
+	c, err := parse(fname)
+	if err != nil {
+		return nil, err
+	}
+	return c.validate(), nil
+}
+
+// validate checks parsed config with defaults, returns the list of problems
+func (c *Conf) validate() []string {
+	res := []string{}
+	problem := func(format string, args ...interface{}) { res = append(res, fmt.Sprintf(format, args...)) }
+
+	names := make([]string, 0, len(c.Feeds))
+	for name := range c.Feeds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := c.Feeds[name]
+		if len(f.Sources) == 0 {
+			problem("feed %s: no sources", name)
+		}
+		for _, src := range f.Sources {
+			if !isHTTPURL(src.URL) {
+				problem("feed %s: invalid url %q of source %s", name, src.URL, src.Name)
+			}
+		}
+		if _, err := regexp.Compile(f.Filter.Title); err != nil {
+			problem("feed %s: invalid filter title %q, %v", name, f.Filter.Title, err)
+		}
+	}
+	for _, v := range []namedInt{{"max_per_feed", c.System.MaxItems}, {"max_total", c.System.MaxTotal},
+		{"max_keep", c.System.MaxKeepInDB}, {"concurrent", c.System.Concurrent}} {
+		if v.val < 0 {
+			problem("system: negative %s %d", v.name, v.val)
+		}
+	}
+
+	if len(c.YouTube.Channels) == 0 {
+		return res
+	}
+	yt := c.YouTube
+	if err := c.checkMediaBaseURL(); err != nil {
+		problem("youtube: %v", err)
+	}
+	if !ytfeed.IsValidLimitRate(yt.DownloadRate) {
+		problem("youtube: invalid download_rate %q, should be like 500K or 2M", yt.DownloadRate)
+	}
+	for _, f := range yt.FormatFallbacks {
+		if !ytfeed.IsValidFormat(f) {
+			problem("youtube: invalid format_fallbacks %q, should be a yt-dlp format without spaces and quotes", f)
+		}
+	}
+	if yt.Store.Type != "bolt" && yt.Store.Type != "sqlite" {
+		problem("youtube: unknown store type %q, should be bolt or sqlite", yt.Store.Type)
+	}
+	if err := checkSource(yt.Source); err != nil {
+		problem("youtube: %v", err)
+	}
+	for _, v := range []namedInt{{"max_per_channel", yt.MaxItems}, {"file_name_hash_len", yt.FileNameHashLen},
+		{"max_per_cycle", yt.MaxPerCycle}, {"max_concurrent_downloads", yt.MaxDownloads}, {"check_urls", yt.CheckURLs},
+		{"fetch_attempts", yt.FetchAttempts}, {"remove_concurrency", yt.RemoveConcurrency},
+		{"incremental_rss", yt.IncrementalRSS}} {
+		if v.val < 0 {
+			problem("youtube: negative %s %d", v.name, v.val)
+		}
+	}
+	for _, v := range []namedDuration{{"update", yt.UpdateInterval}, {"update_jitter", yt.UpdateJitter},
+		{"download_timeout", yt.DownloadTimeout}, {"feed_timeout", yt.FeedTimeout}, {"listing_ttl", yt.ListingTTL},
+		{"fetch_retry_delay", yt.FetchRetryDelay}, {"shutdown_grace", yt.ShutdownGrace}, {"backfill_delay", yt.BackfillDelay}} {
+		if v.val < 0 {
+			problem("youtube: negative %s %v", v.name, v.val)
+		}
+	}
+
+	ids := map[string]bool{}
+	for i, f := range yt.Channels {
+		name := fmt.Sprintf("youtube channel %d", i+1)
+		if f.ID != "" {
+			name = fmt.Sprintf("youtube channel %d (%s)", i+1, f.ID)
+		}
+		for _, p := range checkChannel(f) {
+			problem("%s: %s", name, p)
+		}
+		if f.ID != "" && ids[f.ID] {
+			problem("%s: duplicate id", name)
+		}
+		ids[f.ID] = true
+	}
+	return res
+}
+
+// checkChannel returns problems of youtube channel's config
+func checkChannel(f youtube.FeedInfo) []string {
+	res := []string{}
+	problem := func(format string, args ...interface{}) { res = append(res, fmt.Sprintf(format, args...)) }
+
+	if f.Name == "" {
+		problem("empty name")
+	}
+	switch {
+	case f.ID == "":
+		problem("empty id")
+	case f.Type == ytfeed.FTPlaylist && !playlistIDRe.MatchString(f.ID):
+		problem("invalid playlist id %q, should be like PL... from the playlist url", f.ID)
+	case f.Type == ytfeed.FTPeerTube && !peerTubeIDRe.MatchString(f.ID):
+		problem("invalid peertube id %q, should be like name@instance.host", f.ID)
+	case f.Type != ytfeed.FTPlaylist && f.Type != ytfeed.FTPeerTube && !channelIDRe.MatchString(f.ID):
+		problem("invalid channel id %q, should be like UC... with 24 characters, not a url or handle", f.ID)
+	}
+	if _, err := regexp.Compile(f.Filter.Include); err != nil {
+		problem("invalid filter include %q, %v", f.Filter.Include, err)
+	}
+	if _, err := regexp.Compile(f.Filter.Exclude); err != nil {
+		problem("invalid filter exclude %q, %v", f.Filter.Exclude, err)
+	}
+	if _, err := regexp.Compile(f.EpisodePattern); err != nil {
+		problem("invalid episode_pattern %q, %v", f.EpisodePattern, err)
+	}
+	if err := checkTemplates(f); err != nil {
+		problem("%v", err)
+	}
+	if err := checkSubDir(f); err != nil {
+		problem("%v", err)
+	}
+	if err := checkSource(f.Source); err != nil {
+		problem("%v", err)
+	}
+	if f.Keep < youtube.KeepAll {
+		problem("invalid keep %d, should be positive or %d to keep all", f.Keep, youtube.KeepAll)
+	}
+	for _, v := range []namedInt{{"feed_items", f.FeedItems}, {"max_description_len", f.MaxDescriptionLen},
+		{"season", f.Season}} {
+		if v.val < 0 {
+			problem("negative %s %d", v.name, v.val)
+		}
+	}
+	for _, v := range []namedDuration{{"max_age", f.MaxAge}, {"interval", f.Interval},
+		{"trim_start", f.TrimStart}, {"trim_end", f.TrimEnd}, {"shorts_duration", f.ShortsDuration}} {
+		if v.val < 0 {
+			problem("negative %s %v", v.name, v.val)
+		}
+	}
+	return res
+}
+
+// checkSource verifies alternative source of channels has url if its type set
+func checkSource(src ytfeed.Source) error {
+	if src.Type == ytfeed.STYouTube {
+		return nil
+	}
+	if !isHTTPURL(src.URL) {
+		return fmt.Errorf("invalid url %q of %s source, should be absolute http or https url", src.URL, src.Type)
+	}
+	return nil
+}
+
+// isHTTPURL checks the string is an absolute http(s) url
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+
+	data := `
+feeds:
+  first:
+    sources: [{name: s1, url: "https://example.com/rss"}]
+youtube:
+  channels:
+  - {id: UCPU28A9z_ka_R5dQfecHJlA, name: name1, filter: {include: "^(news|talk)"}, keep: -1}
+  - {id: PLZdXRHYAVxTJno6oFF9nLGuwXNGYHmE8U, name: name2, type: playlist}
+  - {id: joinpeertube@framatube.org, name: name3, type: peertube}
+`
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	res, err := Validate(fname)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	data = `
+feeds:
+  first:
+    sources: [{name: s1, url: "example.com/rss"}]
+    filter: {title: "[a-"}
+  second: {}
+system: {max_total: -1}
+youtube:
+  media_base_url: /yt
+  download_rate: fast
+  format_fallbacks: [best, "best audio"]
+  update_jitter: -1s
+  source: {type: piped}
+  store: {type: mysql}
+  channels:
+  - {id: "https://www.youtube.com/@handle", name: name1, filter: {include: "(news", exclude: "*"}}
+  - {id: PL1, type: playlist, keep: -2, max_age: -24h, description_template: "{{.Title"}
+  - {id: UCPU28A9z_ka_R5dQfecHJlA, name: name3, source: {type: invidious, url: "invidious"}, sub_dir: ../up}
+  - {id: UCPU28A9z_ka_R5dQfecHJlA, name: name4, feed_items: -1, numbering: title, episode_pattern: "(\\d+", season: -1}
+  - {type: peertube, name: name5}
+`
+	require.NoError(t, os.WriteFile(fname, []byte(data), 0o600))
+	res, err = Validate(fname)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`feed first: invalid url "example.com/rss" of source s1`,
+		"feed first: invalid filter title \"[a-\", error parsing regexp: missing closing ]: `[a-`",
+		"feed second: no sources",
+		"system: negative max_total -1",
+		`youtube: invalid media_base_url "/yt", should be absolute http or https url`,
+		`youtube: invalid download_rate "fast", should be like 500K or 2M`,
+		`youtube: invalid format_fallbacks "best audio", should be a yt-dlp format without spaces and quotes`,
+		`youtube: unknown store type "mysql", should be bolt or sqlite`,
+		`youtube: invalid url "" of piped source, should be absolute http or https url`,
+		"youtube: negative update_jitter -1s",
+		`youtube channel 1 (https://www.youtube.com/@handle): invalid channel id "https://www.youtube.com/@handle", ` +
+			"should be like UC... with 24 characters, not a url or handle",
+		"youtube channel 1 (https://www.youtube.com/@handle): invalid filter include \"(news\", " +
+			"error parsing regexp: missing closing ): `(news`",
+		"youtube channel 1 (https://www.youtube.com/@handle): invalid filter exclude \"*\", " +
+			"error parsing regexp: missing argument to repetition operator: `*`",
+		"youtube channel 2 (PL1): empty name",
+		`youtube channel 2 (PL1): invalid playlist id "PL1", should be like PL... from the playlist url`,
+		"youtube channel 2 (PL1): invalid description template for youtube channel PL1: " +
+			"template: description:1: unclosed action",
+		"youtube channel 2 (PL1): invalid keep -2, should be positive or -1 to keep all",
+		"youtube channel 2 (PL1): negative max_age -24h0m0s",
+		"youtube channel 3 (UCPU28A9z_ka_R5dQfecHJlA): invalid sub_dir \"../up\" for youtube channel " +
+			"UCPU28A9z_ka_R5dQfecHJlA, should be relative to files location",
+		`youtube channel 3 (UCPU28A9z_ka_R5dQfecHJlA): invalid url "invidious" of invidious source, ` +
+			"should be absolute http or https url",
+		"youtube channel 4 (UCPU28A9z_ka_R5dQfecHJlA): invalid episode_pattern \"(\\\\d+\", " +
+			"error parsing regexp: missing closing ): `(\\d+`",
+		"youtube channel 4 (UCPU28A9z_ka_R5dQfecHJlA): negative feed_items -1",
+		"youtube channel 4 (UCPU28A9z_ka_R5dQfecHJlA): negative season -1",
+		"youtube channel 4 (UCPU28A9z_ka_R5dQfecHJlA): duplicate id",
+		"youtube channel 5: empty id",
+	}, res, "all problems reported")
+
+	_, err = Validate("testdata/file.txt")
+	assert.Error(t, err, "not yaml")
+}
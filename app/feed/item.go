@@ -26,7 +26,20 @@ type Item struct {
 	PubDate  string        `xml:"pubDate,omitempty"`
 	Comments string        `xml:"comments,omitempty"`
 	Author   string        `xml:"author,omitempty"`
+	Category string        `xml:"category,omitempty"`
 	Duration string        `xml:"duration,omitempty"`
+	Episode  int           `xml:"itunes:episode,omitempty"` // episode number, for serialized shows
+	Season   int           `xml:"itunes:season,omitempty"`  // season number, for serialized shows
+	Language string        `xml:"dc:language,omitempty"`    // item's language, requires NsDC set in Rss2
+	// OriginalDate is the original upload time of the episode if pubDate differs from it, requires NsDC set in Rss2
+	OriginalDate string `xml:"dc:date,omitempty"`
+	// Transcript links subtitles of the episode, requires NsPodcast set in Rss2
+	Transcript *Transcript `xml:"podcast:transcript,omitempty"`
+	// Chapters links chapters json of the episode, requires NsPodcast set in Rss2
+	Chapters *PodcastChapters `xml:"podcast:chapters,omitempty"`
+	// MediaContent and MediaThumbnail duplicate enclosure and image for media rss, require NsMedia set in Rss2
+	MediaContent   *MediaContent   `xml:"media:content,omitempty"`
+	MediaThumbnail *MediaThumbnail `xml:"media:thumbnail,omitempty"`
 	// Internal
 	DT          time.Time `xml:"-"`
 	Junk        bool      `xml:"-"`
@@ -22,17 +22,23 @@ type Rss2 struct {
 	Version        string          `xml:"version,attr"`
 	NsItunes       string          `xml:"xmlns:itunes,attr"`
 	NsMedia        string          `xml:"xmlns:media,attr"`
+	NsAtom         string          `xml:"xmlns:atom,attr,omitempty"`
+	NsDC           string          `xml:"xmlns:dc,attr,omitempty"`
+	NsPodcast      string          `xml:"xmlns:podcast,attr,omitempty"`
 	Title          string          `xml:"channel>title"`
 	Language       string          `xml:"channel>language"`
 	Link           string          `xml:"channel>link"`
 	Description    string          `xml:"channel>description"`
 	PubDate        string          `xml:"channel>pubDate"`
 	LastBuildDate  string          `xml:"channel>lastBuildDate"`
+	AtomLink       *AtomLink       `xml:"channel>atom:link"`
 	ItunesImage    *ItunesImg      `xml:"channel>itunes:image"`
 	MediaThumbnail *MediaThumbnail `xml:"channel>media:thumbnail"`
 	ItunesAuthor   string          `xml:"channel>itunes:author"`
 	ItunesExplicit string          `xml:"channel>itunes:explicit"`
 	ItunesOwner    *ItunesOwner    `xml:"channel>itunes:owner"`
+	PodcastGUID    string          `xml:"channel>podcast:guid,omitempty"`
+	PodcastLocked  *PodcastLocked  `xml:"channel>podcast:locked"`
 	ItemList       []Item          `xml:"channel>item"`
 }
 
@@ -42,6 +48,14 @@ type ItunesImg struct {
 	URL     string   `xml:"href,attr"`
 }
 
+// AtomLink element for atom namespace, rel "self" is the feed's own url. Requires NsAtom set in Rss2
+type AtomLink struct {
+	XMLName xml.Name `xml:"atom:link"`
+	Href    string   `xml:"href,attr"`
+	Rel     string   `xml:"rel,attr"`
+	Type    string   `xml:"type,attr"`
+}
+
 // ItunesOwner owner element for iTunes
 type ItunesOwner struct {
 	Email string `xml:"itunes:email,omitempty"`
@@ -54,6 +68,38 @@ type MediaThumbnail struct {
 	URL     string   `xml:"url,attr"`
 }
 
+// MediaContent element for media namespace, an alternative to enclosure for media rss aware clients
+type MediaContent struct {
+	XMLName  xml.Name `xml:"media:content"`
+	URL      string   `xml:"url,attr"`
+	FileSize int      `xml:"fileSize,attr,omitempty"`
+	Type     string   `xml:"type,attr"`
+	Medium   string   `xml:"medium,attr"`
+	Duration int      `xml:"duration,attr,omitempty"`
+}
+
+// Transcript element for podcast namespace, link to episode's subtitles
+type Transcript struct {
+	XMLName  xml.Name `xml:"podcast:transcript"`
+	URL      string   `xml:"url,attr"`
+	Type     string   `xml:"type,attr"`
+	Language string   `xml:"language,attr,omitempty"`
+}
+
+// PodcastLocked element for podcast namespace, "yes" prevents importing the feed to other hosting platforms
+type PodcastLocked struct {
+	XMLName xml.Name `xml:"podcast:locked"`
+	Owner   string   `xml:"owner,attr,omitempty"`
+	Value   string   `xml:",chardata"`
+}
+
+// PodcastChapters element for podcast namespace, link to episode's chapters json
+type PodcastChapters struct {
+	XMLName xml.Name `xml:"podcast:chapters"`
+	URL     string   `xml:"url,attr"`
+	Type    string   `xml:"type,attr"`
+}
+
 // Enclosure element from item
 type Enclosure struct {
 	URL    string `xml:"url,attr"`
@@ -182,7 +228,7 @@ func parseFeedContent(content []byte) (Rss2, error) {
 	return v, errors.New("not RSS 2.0")
 }
 
-// Normalize converts to RFC822 = "02 Jan 06 15:04 MST"
+// Normalize converts dates to RFC1123Z = "Mon, 02 Jan 2006 15:04:05 -0700"
 func (rss *Rss2) Normalize() (Rss2, error) {
 
 	dt, err := rss.parseDateTime(rss.LastBuildDate)
@@ -191,13 +237,13 @@ func (rss *Rss2) Normalize() (Rss2, error) {
 		dt, err = rss.parseDateTime(rss.PubDate)
 	}
 	if err == nil {
-		rss.PubDate = dt.Format(time.RFC1123Z)
+		rss.PubDate = FormatDate(dt, false)
 	}
 
 	for i, item := range rss.ItemList {
 		if dt, err := rss.parseDateTime(item.PubDate); err == nil {
 			rss.ItemList[i].DT = dt
-			rss.ItemList[i].PubDate = dt.Format(time.RFC1123Z)
+			rss.ItemList[i].PubDate = FormatDate(dt, false)
 		}
 		rss.ItemList[i].Title = strings.ReplaceAll(item.Title, "\n", "")
 		rss.ItemList[i].Title = strings.TrimSpace(rss.ItemList[i].Title)
@@ -205,6 +251,15 @@ func (rss *Rss2) Normalize() (Rss2, error) {
 	return *rss, nil
 }
 
+// FormatDate formats time for rss pubDate and lastBuildDate as RFC1123Z, with 4 digits year and numeric zone offset.
+// RFC822Z with 2 digits year used if rfc822 set, for compatibility with clients expecting it
+func FormatDate(t time.Time, rfc822 bool) string {
+	if rfc822 {
+		return t.Format(time.RFC822Z)
+	}
+	return t.Format(time.RFC1123Z)
+}
+
 func (rss *Rss2) parseDateTime(dt string) (time.Time, error) {
 	if dt == "" {
 		return time.Now(), fmt.Errorf("can't parse empty date-time")
@@ -129,6 +129,19 @@ func TestParseDateTime(t *testing.T) {
 	}
 }
 
+func TestFormatDate(t *testing.T) {
+	ts := time.Date(2022, 4, 2, 10, 20, 30, 0, time.FixedZone("EDT", -4*3600))
+	assert.Equal(t, "Sat, 02 Apr 2022 10:20:30 -0400", FormatDate(ts, false))
+	assert.Equal(t, "02 Apr 22 10:20 -0400", FormatDate(ts, true))
+	assert.Equal(t, "Sat, 02 Apr 2022 14:20:30 +0000", FormatDate(ts.In(time.UTC), false), "numeric offset for utc")
+
+	rss := Rss2{PubDate: "05 Mar 14 22:08 MST", ItemList: []Item{{PubDate: "Mon, 02 Jan 2006 15:04:05 MST"}}}
+	res, err := rss.Normalize()
+	require.NoError(t, err)
+	assert.Equal(t, "Wed, 05 Mar 2014 22:08:00 +0000", res.PubDate)
+	assert.Equal(t, "Mon, 02 Jan 2006 15:04:05 +0000", res.ItemList[0].PubDate)
+}
+
 func TestNormalizeIfLastBuildDateAndPubDateInvalidFormat(t *testing.T) {
 	cases := []struct {
 		lastBuildDate string
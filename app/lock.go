@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+// lockFileName is the name of lock file in the data directory
+const lockFileName = "feed-master.lock"
+
+// acquireLock takes exclusive lock of the data directory, so two instances can't share the same db and files.
+// Fails immediately if the lock is held by another process. The lock is released by OS if the process dies,
+// so a lock file left after a crash doesn't prevent the start. Returns func releasing the lock.
+func acquireLock(dir string) (release func(), err error) {
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "failed to make data directory %s", dir)
+	}
+	fname := filepath.Join(dir, lockFileName)
+	f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE, 0o600) // nolint
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open lock file %s", fname)
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		pid, _ := io.ReadAll(f)
+		_ = f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, errors.Errorf("data directory %s is locked by another instance of feed-master (pid %s), "+
+				"lock file %s", dir, strings.TrimSpace(string(pid)), fname)
+		}
+		return nil, errors.Wrapf(err, "failed to lock %s", fname)
+	}
+
+	// pid of the lock holder reported to other instances, informational only
+	if err = f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "failed to write pid to %s", fname)
+	}
+
+	return func() {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			log.Printf("[WARN] failed to unlock %s, %v", fname, err)
+		}
+		_ = f.Close()
+	}, nil
+}
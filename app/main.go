@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
+	"syscall"
 	"text/template"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	log "github.com/go-pkgz/lgr"
 	"github.com/google/uuid"
 	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
 
 	"github.com/umputun/feed-master/app/api"
@@ -49,7 +52,16 @@ type options struct {
 
 	AdminPasswd string `long:"admin-passwd" env:"ADMIN_PASSWD" description:"admin password for protected endpoints"`
 
-	Dbg bool `long:"dbg" env:"DEBUG" description:"debug mode"`
+	RegenerateRSS bool   `long:"regenerate-rss" description:"rewrite rss files of all youtube feeds and exit"`
+	ImportDir     string `long:"import-dir" description:"import downloaded mp3 files from the directory to --import-feed and exit"`
+	ImportFeed    string `long:"import-feed" description:"youtube feed id to import files to"`
+	MigrateGUIDs  string `long:"migrate-guids" description:"rewrite guids of stored youtube entries, from:to scheme (uid, video, link), and exit"`
+	DryRun        bool   `long:"dry-run" description:"report guids migration changes without storing"`
+	Validate      bool   `long:"validate" description:"check rss of all youtube feeds against rss spec and exit, fails on problems"`
+	CheckConf     bool   `long:"check-conf" description:"check config for problems without starting and exit, fails on problems"`
+
+	Dbg       bool   `long:"dbg" env:"DEBUG" description:"debug mode"`
+	LogFormat string `long:"log-format" env:"LOG_FORMAT" choice:"text" choice:"json" default:"text" description:"log format"`
 }
 
 var revision = "local"
@@ -60,7 +72,17 @@ func main() {
 	if _, err := flags.Parse(&opts); err != nil {
 		os.Exit(1)
 	}
-	setupLog(opts.Dbg)
+	eventLogger := setupLog(opts.Dbg, opts.LogFormat)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if opts.CheckConf {
+		if problems := checkConf(opts.Conf); problems > 0 {
+			log.Fatalf("[ERROR] config check failed, %d problems", problems)
+		}
+		return
+	}
 
 	var conf = &config.Conf{}
 	if opts.Feed != "" { // single feed (no config) mode
@@ -75,6 +97,12 @@ func main() {
 		}
 	}
 
+	releaseLock, err := acquireLock(path.Dir(opts.DB))
+	if err != nil {
+		log.Fatalf("[ERROR] can't start, %v", err)
+	}
+	defer releaseLock()
+
 	db, err := makeBoltDB(opts.DB)
 	if err != nil {
 		log.Fatalf("[ERROR] can't open db %s, %v", opts.DB, err)
@@ -87,63 +115,154 @@ func main() {
 		log.Fatalf("[ERROR] failed to initialize telegram client %s, %v", opts.TelegramToken, err)
 	}
 
-	p := &proc.Processor{Conf: conf, Store: procStore, TelegramNotif: telegramNotif, TwitterNotif: makeTwitter(opts)}
-	go func() {
-		if err := p.Do(context.Background()); err != nil {
-			log.Printf("[ERROR] processor failed: %v", err)
-		}
-	}()
-
 	var ytSvc youtube.Service
+	var ytDlpVersion string
+	ytDone := make(chan struct{})
 	if len(conf.YouTube.Channels) > 0 {
 		log.Printf("[INFO] starting youtube processor for %d channels", len(conf.YouTube.Channels))
+		ytDlpVersion = checkYtDlpVersion(conf.YouTube.DlTemplate, conf.YouTube.MinYtDlpVersion)
 		outWr := log.ToWriter(log.Default(), "DEBUG")
 		errWr := log.ToWriter(log.Default(), "INFO")
 		dwnl := ytfeed.NewDownloader(conf.YouTube.DlTemplate, outWr, errWr, conf.YouTube.FilesLocation)
+		if !ytfeed.IsValidLimitRate(conf.YouTube.DownloadRate) {
+			log.Fatalf("[ERROR] invalid download_rate %q, should be like 500K or 2M", conf.YouTube.DownloadRate)
+		}
+		if conf.YouTube.DownloadRate != "" {
+			dwnl.LimitRate = conf.YouTube.DownloadRate
+			log.Printf("[INFO] download rate limited to %s per second", dwnl.LimitRate)
+		}
+		dwnl.Resume = conf.YouTube.ResumeDownloads
+		dwnl.FormatFallbacks = conf.YouTube.FormatFallbacks
 		fd := ytfeed.Feed{Client: &http.Client{Timeout: 10 * time.Second},
-			ChannelBaseURL: conf.YouTube.BaseChanURL, PlaylistBaseURL: conf.YouTube.BasePlaylistURL}
+			ChannelBaseURL: conf.YouTube.BaseChanURL, PlaylistBaseURL: conf.YouTube.BasePlaylistURL, Source: conf.YouTube.Source}
+		if fd.Source.Enabled() {
+			log.Printf("[INFO] channels listed with %s api %s", fd.Source.Type, fd.Source.URL)
+		}
 
 		channels := []string{}
 		for _, c := range conf.YouTube.Channels {
 			channels = append(channels, c.ID)
 		}
 		log.Printf("[DEBUG] buckets for youtube store: %s", strings.Join(channels, ", "))
+		ytStore, storeErr := makeYoutubeStore(conf.YouTube.Store.Type, conf.YouTube.Store.File, db, channels)
+		if storeErr != nil {
+			log.Fatalf("[ERROR] can't make youtube store, %v", storeErr)
+		}
 
 		ytSvc = youtube.Service{
 			Feeds:          conf.YouTube.Channels,
 			Downloader:     dwnl,
 			ChannelService: &fd,
-			Store:          &store.BoltDB{DB: db, Channels: channels},
+			Store:          ytStore,
 			CheckDuration:  conf.YouTube.UpdateInterval,
+			CheckJitter:    conf.YouTube.UpdateJitter,
 			KeepPerChannel: conf.YouTube.MaxItems,
 			RootURL:        conf.YouTube.BaseURL,
+			MediaBaseURL:   conf.YouTube.MediaBaseURL,
+			RFC822Dates:    conf.System.RFC822Dates,
 			RSSFileStore: youtube.RSSFileStore{
 				Location: conf.YouTube.RSSLocation,
 				Enabled:  conf.YouTube.RSSLocation != "",
 			},
-			DurationService: &duration.Service{},
-			SkipShorts:      conf.YouTube.SkipShorts,
+			RSSMirrors:             makeRSSMirrors(conf.YouTube.RSSMirrors),
+			DurationService:        &duration.Service{},
+			Splitter:               &ytfeed.Splitter{LogErrWriter: errWr},
+			SkipShorts:             conf.YouTube.SkipShorts,
+			FileNameTemplate:       conf.YouTube.FileNameTmpl,
+			FileNameHash:           conf.YouTube.FileNameHash,
+			FileNameHashLen:        conf.YouTube.FileNameHashLen,
+			FilesLocation:          conf.YouTube.FilesLocation,
+			BackfillDelay:          conf.YouTube.BackfillDelay,
+			CompletionWebhook:      conf.YouTube.CompletionWebhook,
+			DownloadTimeout:        conf.YouTube.DownloadTimeout,
+			FeedTimeout:            conf.YouTube.FeedTimeout,
+			ShutdownGrace:          conf.YouTube.ShutdownGrace,
+			PostDownloadTimeout:    conf.YouTube.PostDlTimeout,
+			MaxPerCycle:            conf.YouTube.MaxPerCycle,
+			MaxConcurrentDownloads: conf.YouTube.MaxDownloads,
+			CheckURLs:              conf.YouTube.CheckURLs,
+			ListingTTL:             conf.YouTube.ListingTTL,
+			FetchAttempts:          conf.YouTube.FetchAttempts,
+			FetchRetryDelay:        conf.YouTube.FetchRetryDelay,
+			DetectLanguage:         conf.YouTube.DetectLang,
+			ProcessedMaxAge:        conf.YouTube.ProcessedMaxAge,
+			RemoveConcurrency:      conf.YouTube.RemoveConcurrency,
+			IncrementalRSS:         conf.YouTube.IncrementalRSS,
+			DownloadBudget:         conf.YouTube.DownloadBudget,
+			BudgetPeriod:           conf.YouTube.BudgetPeriod,
+			Logger:                 eventLogger,
+			URLSigners:             makeURLSigners(conf.YouTube.Channels),
+		}
+		if err = ytSvc.LoadFeedUpdates(); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		if conf.System.BaseURL != "" {
+			ytSvc.RSSURL = strings.TrimSuffix(conf.System.BaseURL, "/") + "/yt/rss"
+		}
+		if opts.RegenerateRSS {
+			if _, regErr := ytSvc.RegenerateAll(); regErr != nil {
+				log.Fatalf("[ERROR] failed to regenerate rss, %v", regErr)
+			}
+			return
+		}
+		if opts.Validate {
+			if problems := validateFeeds(&ytSvc); problems > 0 {
+				log.Fatalf("[ERROR] rss validation failed, %d problems", problems)
+			}
+			return
+		}
+		if opts.MigrateGUIDs != "" {
+			if migErr := migrateGUIDs(&ytSvc, opts.MigrateGUIDs, opts.DryRun); migErr != nil {
+				log.Fatalf("[ERROR] failed to migrate guids, %v", migErr)
+			}
+			return
+		}
+		if opts.ImportDir != "" {
+			res, impErr := ytSvc.Import(ctx, opts.ImportFeed, opts.ImportDir)
+			if impErr != nil {
+				log.Fatalf("[ERROR] failed to import %s, %v", opts.ImportDir, impErr)
+			}
+			for _, f := range res.Unmatched {
+				log.Printf("[WARN] unmatched file %s", f)
+			}
+			return
 		}
 		go func() {
-			if err := ytSvc.Do(context.TODO()); err != nil {
+			defer close(ytDone)
+			if err := ytSvc.Do(ctx); err != nil && !errors.Is(err, context.Canceled) {
 				log.Printf("[ERROR] youtube processor failed: %v", err)
 			}
 		}()
+	} else {
+		if opts.RegenerateRSS || opts.ImportDir != "" || opts.MigrateGUIDs != "" || opts.Validate {
+			log.Fatalf("[ERROR] no youtube channels configured")
+		}
+		close(ytDone)
 	}
 
+	p := &proc.Processor{Conf: conf, Store: procStore, TelegramNotif: telegramNotif, TwitterNotif: makeTwitter(opts)}
+	go func() {
+		if err := p.Do(context.Background()); err != nil {
+			log.Printf("[ERROR] processor failed: %v", err)
+		}
+	}()
+
 	if opts.AdminPasswd == "" {
 		log.Printf("[WARN] admin password is not set, protected endpoints are disabled")
 		opts.AdminPasswd = uuid.New().String() // generate random (uuid) password
 	}
 
 	server := api.Server{
-		Version:     revision,
-		Conf:        *conf,
-		Store:       procStore,
-		YoutubeSvc:  &ytSvc,
-		AdminPasswd: opts.AdminPasswd,
+		Version:      revision,
+		Conf:         *conf,
+		Store:        procStore,
+		YoutubeSvc:   &ytSvc,
+		AdminPasswd:  opts.AdminPasswd,
+		YtDlpVersion: ytDlpVersion,
 	}
-	server.Run(context.Background(), opts.Port)
+	server.Run(ctx, opts.Port)
+	<-ytDone // wait for youtube processor to finish the download in progress
+	log.Printf("[INFO] feed-master stopped")
 }
 
 func makeBoltDB(dbFile string) (*bolt.DB, error) {
@@ -162,6 +281,126 @@ func makeBoltDB(dbFile string) (*bolt.DB, error) {
 	return db, err
 }
 
+// makeURLSigners makes enclosure url signers for feeds with url_signing secret set
+func makeURLSigners(feeds []youtube.FeedInfo) map[string]youtube.URLSigner {
+	res := map[string]youtube.URLSigner{}
+	for _, f := range feeds {
+		if f.URLSigning.Secret == "" {
+			continue
+		}
+		res[f.ID] = &youtube.HMACSigner{Secret: f.URLSigning.Secret, TTL: f.URLSigning.TTL}
+	}
+	return res
+}
+
+// migrateGUIDs rewrites guids of stored youtube entries for "from:to" schemes and regenerates rss files
+func migrateGUIDs(svc *youtube.Service, schemes string, dryRun bool) error {
+	elems := strings.Split(schemes, ":")
+	if len(elems) != 2 {
+		return errors.Errorf("invalid guid schemes %q, expected from:to", schemes)
+	}
+	from, err := youtube.ParseGUIDScheme(elems[0])
+	if err != nil {
+		return err
+	}
+	to, err := youtube.ParseGUIDScheme(elems[1])
+	if err != nil {
+		return err
+	}
+	changes, err := svc.MigrateGUIDs(from, to, dryRun)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] guids migration %s -> %s, %d entries changed, dry run: %v", from, to, len(changes), dryRun)
+	if dryRun || len(changes) == 0 {
+		return nil
+	}
+	_, err = svc.RegenerateAll()
+	return err
+}
+
+// validateFeeds checks rss of all youtube feeds, reports problems and returns their number
+func validateFeeds(svc *youtube.Service) (problems int) {
+	for _, fi := range svc.Feeds {
+		res, err := svc.Validate(fi)
+		if err != nil {
+			log.Printf("[WARN] can't validate %s (%s), %v", fi.ID, fi.Name, err)
+			problems++
+			continue
+		}
+		for _, p := range res {
+			log.Printf("[WARN] %s (%s): %s", fi.ID, fi.Name, p)
+		}
+		problems += len(res)
+		if len(res) == 0 {
+			log.Printf("[INFO] %s (%s) is valid", fi.ID, fi.Name)
+		}
+	}
+	return problems
+}
+
+// checkConf reports problems of the config file, returns the number of problems
+func checkConf(fname string) (problems int) {
+	res, err := config.Validate(fname)
+	if err != nil {
+		log.Printf("[WARN] can't load config %s, %v", fname, err)
+		return 1
+	}
+	for _, p := range res {
+		log.Printf("[WARN] %s", p)
+	}
+	if len(res) == 0 {
+		log.Printf("[INFO] config %s is valid", fname)
+	}
+	return len(res)
+}
+
+// makeRSSMirrors makes rss file stores for mirror locations
+func makeRSSMirrors(locations []string) []youtube.RSSStore {
+	res := make([]youtube.RSSStore, 0, len(locations))
+	for _, loc := range locations {
+		res = append(res, &youtube.RSSFileStore{Location: loc, Enabled: true})
+	}
+	return res
+}
+
+// makeYoutubeStore makes store for youtube metadata, bolt (shared db) or sqlite
+func makeYoutubeStore(storeType, file string, db *bolt.DB, channels []string) (youtube.StoreService, error) {
+	switch storeType {
+	case "", "bolt":
+		return &store.BoltDB{DB: db, Channels: channels}, nil
+	case "sqlite":
+		log.Printf("[INFO] sqlite youtube store, %s", file)
+		if err := os.MkdirAll(path.Dir(file), 0o700); err != nil {
+			return nil, err
+		}
+		return store.NewSQLite(file)
+	}
+	return nil, fmt.Errorf("unknown youtube store type %q", storeType)
+}
+
+// checkYtDlpVersion gets version of the downloader binary (the first word of dl template) and warns if it is
+// older than minVersion. Returns detected version or empty string if it can't be detected.
+func checkYtDlpVersion(dlTemplate, minVersion string) string {
+	cmd := strings.Fields(dlTemplate)
+	if len(cmd) == 0 {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ver, err := ytfeed.Version(ctx, cmd[0])
+	if err != nil {
+		log.Printf("[WARN] can't detect %s version, %v", cmd[0], err)
+		return ""
+	}
+	log.Printf("[INFO] %s version %s", cmd[0], ver)
+	if ytfeed.IsOutdated(ver, minVersion) {
+		log.Printf("[WARN] !!! %s version %s is older than required %s, downloads may fail. Please update it !!!",
+			cmd[0], ver, minVersion)
+	}
+	return ver
+}
+
 func makeTwitter(opts options) *proc.TwitterClient {
 	twitterFmtFn := func(item rssfeed.Item) string {
 		b1 := bytes.Buffer{}
@@ -184,10 +423,21 @@ func makeTwitter(opts options) *proc.TwitterClient {
 	return proc.NewTwitterClient(twiAuth, twitterFmtFn, twitPoster)
 }
 
-func setupLog(dbg bool) {
+// setupLog configures lgr and returns logger for youtube service events, with structured fields in json format
+func setupLog(dbg bool, format string) youtube.EventLogger {
+	if format == "json" {
+		jl := &youtube.JSONLogger{Out: os.Stdout, Debug: dbg}
+		logOpts := []log.Option{log.Format(youtube.JSONLogFormat), log.Out(jl), log.Err(jl)}
+		if dbg {
+			logOpts = append(logOpts, log.Debug)
+		}
+		log.Setup(logOpts...)
+		return jl
+	}
 	if dbg {
 		log.Setup(log.Debug, log.CallerFile, log.Msec, log.LevelBraces)
-		return
+		return youtube.TextLogger{}
 	}
 	log.Setup(log.Msec, log.LevelBraces)
+	return youtube.TextLogger{}
 }
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/youtube"
+	"github.com/umputun/feed-master/app/youtube/store"
 )
 
 func TestMakeTwitter(t *testing.T) {
@@ -21,3 +29,64 @@ func TestMakeTwitter(t *testing.T) {
 	assert.Equal(t, client.AccessToken, "c")
 	assert.Equal(t, client.AccessSecret, "d")
 }
+
+func TestMakeYoutubeStore(t *testing.T) {
+	db, err := makeBoltDB(filepath.Join(t.TempDir(), "test.bdb"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	st, err := makeYoutubeStore("", "", db, []string{"chan1"})
+	require.NoError(t, err)
+	assert.IsType(t, &store.BoltDB{}, st)
+
+	st, err = makeYoutubeStore("sqlite", filepath.Join(t.TempDir(), "sub", "yt.sqlite"), db, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &store.SQLite{}, st)
+
+	_, err = makeYoutubeStore("mongo", "", db, nil)
+	assert.EqualError(t, err, `unknown youtube store type "mongo"`)
+}
+
+func TestMakeURLSigners(t *testing.T) {
+	res := makeURLSigners([]youtube.FeedInfo{
+		{ID: "c1", URLSigning: youtube.URLSigning{Secret: "secret", TTL: time.Hour}},
+		{ID: "c2"},
+	})
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, &youtube.HMACSigner{Secret: "secret", TTL: time.Hour}, res["c1"])
+}
+
+func TestMakeRSSMirrors(t *testing.T) {
+	res := makeRSSMirrors([]string{"/mnt/a", "/mnt/b"})
+	require.Equal(t, 2, len(res))
+	assert.Equal(t, &youtube.RSSFileStore{Location: "/mnt/b", Enabled: true}, res[1])
+	assert.Empty(t, makeRSSMirrors(nil))
+}
+
+func TestMigrateGUIDsInvalidSchemes(t *testing.T) {
+	svc := &youtube.Service{}
+	assert.EqualError(t, migrateGUIDs(svc, "uid", true), `invalid guid schemes "uid", expected from:to`)
+	assert.EqualError(t, migrateGUIDs(svc, "uid:blah", true), `unknown guid scheme "blah"`)
+	assert.NoError(t, migrateGUIDs(svc, "uid:video", true), "no feeds, nothing to migrate")
+}
+
+func TestAcquireLock(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "var")
+	release, err := acquireLock(dir)
+	require.NoError(t, err)
+	pid, err := os.ReadFile(filepath.Join(dir, lockFileName))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(pid))
+
+	_, err = acquireLock(dir)
+	require.Error(t, err, "locked by another instance")
+	assert.Contains(t, err.Error(), "is locked by another instance of feed-master (pid "+strconv.Itoa(os.Getpid())+")")
+
+	release()
+	release2, err := acquireLock(dir)
+	require.NoError(t, err, "lock released")
+	release2()
+
+	_, err = acquireLock("/dev/null/var")
+	assert.Error(t, err)
+}
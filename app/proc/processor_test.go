@@ -18,6 +18,7 @@ import (
 	"github.com/umputun/feed-master/app/feed"
 	"github.com/umputun/feed-master/app/proc/mocks"
 	"github.com/umputun/feed-master/app/youtube"
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
 
 func TestProcessor_DoRemoveOldItems(t *testing.T) {
@@ -65,12 +66,14 @@ func TestProcessor_DoRemoveOldItems(t *testing.T) {
 				},
 			},
 			System: struct {
-				UpdateInterval time.Duration `yaml:"update"`
-				MaxItems       int           `yaml:"max_per_feed"`
-				MaxTotal       int           `yaml:"max_total"`
-				MaxKeepInDB    int           `yaml:"max_keep"`
-				Concurrent     int           `yaml:"concurrent"`
-				BaseURL        string        `yaml:"base_url"`
+				UpdateInterval time.Duration     `yaml:"update"`
+				MaxItems       int               `yaml:"max_per_feed"`
+				MaxTotal       int               `yaml:"max_total"`
+				MaxKeepInDB    int               `yaml:"max_keep"`
+				Concurrent     int               `yaml:"concurrent"`
+				BaseURL        string            `yaml:"base_url"`
+				RFC822Dates    bool              `yaml:"rfc822_dates"` // rss dates with 2 digits year, RFC1123Z by default
+				BasicAuth      youtube.BasicAuth `yaml:"basic_auth"`   // protects feeds without own credentials
 			}{
 				UpdateInterval: time.Second / 2,
 				MaxItems:       5,
@@ -80,16 +83,51 @@ func TestProcessor_DoRemoveOldItems(t *testing.T) {
 				BaseURL:        "baseUrl",
 			},
 			YouTube: struct {
-				DlTemplate      string             `yaml:"dl_template"`
-				BaseChanURL     string             `yaml:"base_chan_url"`
-				BasePlaylistURL string             `yaml:"base_playlist_url"`
-				Channels        []youtube.FeedInfo `yaml:"channels"`
-				BaseURL         string             `yaml:"base_url"`
-				UpdateInterval  time.Duration      `yaml:"update"`
-				MaxItems        int                `yaml:"max_per_channel"`
-				FilesLocation   string             `yaml:"files_location"`
-				RSSLocation     string             `yaml:"rss_location"`
-				SkipShorts      time.Duration      `yaml:"skip_shorts"`
+				DlTemplate        string               `yaml:"dl_template"`
+				BaseChanURL       string               `yaml:"base_chan_url"`
+				BasePlaylistURL   string               `yaml:"base_playlist_url"`
+				Source            ytfeed.Source        `yaml:"source"` // alternative api listing channels, i.e. piped or invidious
+				Channels          []youtube.FeedInfo   `yaml:"channels"`
+				BaseURL           string               `yaml:"base_url"`
+				MediaBaseURL      string               `yaml:"media_base_url"` // base url of enclosures, i.e. cdn, default is base_url
+				UpdateInterval    time.Duration        `yaml:"update"`
+				UpdateJitter      time.Duration        `yaml:"update_jitter"`
+				MaxItems          int                  `yaml:"max_per_channel"`
+				FilesLocation     string               `yaml:"files_location"`
+				RSSLocation       string               `yaml:"rss_location"`
+				RSSMirrors        []string             `yaml:"rss_mirrors"` // extra locations of rss files, i.e. mounted object storage
+				SkipShorts        time.Duration        `yaml:"skip_shorts"`
+				MinYtDlpVersion   string               `yaml:"min_ytdlp_version"`
+				FileNameTmpl      string               `yaml:"file_name_template"`
+				FileNameHash      string               `yaml:"file_name_hash"`
+				FileNameHashLen   int                  `yaml:"file_name_hash_len"`
+				BackfillDelay     time.Duration        `yaml:"backfill_delay"`
+				CompletionWebhook string               `yaml:"completion_webhook"`
+				DownloadTimeout   time.Duration        `yaml:"download_timeout"`
+				DownloadRate      string               `yaml:"download_rate"`
+				ResumeDownloads   bool                 `yaml:"resume_downloads"` // continue interrupted downloads from .part files
+				FeedTimeout       time.Duration        `yaml:"feed_timeout"`
+				ShutdownGrace     time.Duration        `yaml:"shutdown_grace"`
+				PostDlTimeout     time.Duration        `yaml:"post_download_timeout"`
+				MaxPerCycle       int                  `yaml:"max_per_cycle"`
+				MaxDownloads      int                  `yaml:"max_concurrent_downloads"` // downloads running at once, no limit if 0
+				CheckURLs         int                  `yaml:"check_urls"`
+				ListingTTL        time.Duration        `yaml:"listing_ttl"`
+				FetchAttempts     int                  `yaml:"fetch_attempts"`    // attempts to list a feed on transient errors
+				FetchRetryDelay   time.Duration        `yaml:"fetch_retry_delay"` // the first delay between attempts, doubled each time
+				DetectLang        bool                 `yaml:"detect_lang"`
+				StrictEnv         bool                 `yaml:"strict_env"` // reject references to unset env variables, see expandEnv
+				ProcessedMaxAge   time.Duration        `yaml:"processed_max_age"`
+				RemoveConcurrency int                  `yaml:"remove_concurrency"`
+				IncrementalRSS    int                  `yaml:"incremental_rss"`  // rss items to update rss file in place, disabled if 0
+				DownloadBudget    youtube.FileSize     `yaml:"download_budget"`  // bytes downloaded per budget period, no limit if 0
+				BudgetPeriod      youtube.BudgetPeriod `yaml:"budget_period"`    // month (default), week or day
+				FormatFallbacks   []string             `yaml:"format_fallbacks"` // yt-dlp formats tried if formats of dl_template missing
+				BasicAuth         youtube.BasicAuth    `yaml:"basic_auth"`       // protects rss and files of all feeds without own credentials
+				Store             struct {
+					Type string `yaml:"type"` // bolt (default) or sqlite
+					File string `yaml:"file"` // sqlite db file
+				} `yaml:"store"`
 			}{},
 		},
 		Store:         boltStore,
@@ -200,12 +238,14 @@ func TestProcessor_DoLoadMaxItems(t *testing.T) {
 				},
 			},
 			System: struct {
-				UpdateInterval time.Duration `yaml:"update"`
-				MaxItems       int           `yaml:"max_per_feed"`
-				MaxTotal       int           `yaml:"max_total"`
-				MaxKeepInDB    int           `yaml:"max_keep"`
-				Concurrent     int           `yaml:"concurrent"`
-				BaseURL        string        `yaml:"base_url"`
+				UpdateInterval time.Duration     `yaml:"update"`
+				MaxItems       int               `yaml:"max_per_feed"`
+				MaxTotal       int               `yaml:"max_total"`
+				MaxKeepInDB    int               `yaml:"max_keep"`
+				Concurrent     int               `yaml:"concurrent"`
+				BaseURL        string            `yaml:"base_url"`
+				RFC822Dates    bool              `yaml:"rfc822_dates"` // rss dates with 2 digits year, RFC1123Z by default
+				BasicAuth      youtube.BasicAuth `yaml:"basic_auth"`   // protects feeds without own credentials
 			}{
 				UpdateInterval: time.Second / 2,
 				MaxItems:       3,
@@ -215,16 +255,51 @@ func TestProcessor_DoLoadMaxItems(t *testing.T) {
 				BaseURL:        "baseUrl",
 			},
 			YouTube: struct {
-				DlTemplate      string             `yaml:"dl_template"`
-				BaseChanURL     string             `yaml:"base_chan_url"`
-				BasePlaylistURL string             `yaml:"base_playlist_url"`
-				Channels        []youtube.FeedInfo `yaml:"channels"`
-				BaseURL         string             `yaml:"base_url"`
-				UpdateInterval  time.Duration      `yaml:"update"`
-				MaxItems        int                `yaml:"max_per_channel"`
-				FilesLocation   string             `yaml:"files_location"`
-				RSSLocation     string             `yaml:"rss_location"`
-				SkipShorts      time.Duration      `yaml:"skip_shorts"`
+				DlTemplate        string               `yaml:"dl_template"`
+				BaseChanURL       string               `yaml:"base_chan_url"`
+				BasePlaylistURL   string               `yaml:"base_playlist_url"`
+				Source            ytfeed.Source        `yaml:"source"` // alternative api listing channels, i.e. piped or invidious
+				Channels          []youtube.FeedInfo   `yaml:"channels"`
+				BaseURL           string               `yaml:"base_url"`
+				MediaBaseURL      string               `yaml:"media_base_url"` // base url of enclosures, i.e. cdn, default is base_url
+				UpdateInterval    time.Duration        `yaml:"update"`
+				UpdateJitter      time.Duration        `yaml:"update_jitter"`
+				MaxItems          int                  `yaml:"max_per_channel"`
+				FilesLocation     string               `yaml:"files_location"`
+				RSSLocation       string               `yaml:"rss_location"`
+				RSSMirrors        []string             `yaml:"rss_mirrors"` // extra locations of rss files, i.e. mounted object storage
+				SkipShorts        time.Duration        `yaml:"skip_shorts"`
+				MinYtDlpVersion   string               `yaml:"min_ytdlp_version"`
+				FileNameTmpl      string               `yaml:"file_name_template"`
+				FileNameHash      string               `yaml:"file_name_hash"`
+				FileNameHashLen   int                  `yaml:"file_name_hash_len"`
+				BackfillDelay     time.Duration        `yaml:"backfill_delay"`
+				CompletionWebhook string               `yaml:"completion_webhook"`
+				DownloadTimeout   time.Duration        `yaml:"download_timeout"`
+				DownloadRate      string               `yaml:"download_rate"`
+				ResumeDownloads   bool                 `yaml:"resume_downloads"` // continue interrupted downloads from .part files
+				FeedTimeout       time.Duration        `yaml:"feed_timeout"`
+				ShutdownGrace     time.Duration        `yaml:"shutdown_grace"`
+				PostDlTimeout     time.Duration        `yaml:"post_download_timeout"`
+				MaxPerCycle       int                  `yaml:"max_per_cycle"`
+				MaxDownloads      int                  `yaml:"max_concurrent_downloads"` // downloads running at once, no limit if 0
+				CheckURLs         int                  `yaml:"check_urls"`
+				ListingTTL        time.Duration        `yaml:"listing_ttl"`
+				FetchAttempts     int                  `yaml:"fetch_attempts"`    // attempts to list a feed on transient errors
+				FetchRetryDelay   time.Duration        `yaml:"fetch_retry_delay"` // the first delay between attempts, doubled each time
+				DetectLang        bool                 `yaml:"detect_lang"`
+				StrictEnv         bool                 `yaml:"strict_env"` // reject references to unset env variables, see expandEnv
+				ProcessedMaxAge   time.Duration        `yaml:"processed_max_age"`
+				RemoveConcurrency int                  `yaml:"remove_concurrency"`
+				IncrementalRSS    int                  `yaml:"incremental_rss"`  // rss items to update rss file in place, disabled if 0
+				DownloadBudget    youtube.FileSize     `yaml:"download_budget"`  // bytes downloaded per budget period, no limit if 0
+				BudgetPeriod      youtube.BudgetPeriod `yaml:"budget_period"`    // month (default), week or day
+				FormatFallbacks   []string             `yaml:"format_fallbacks"` // yt-dlp formats tried if formats of dl_template missing
+				BasicAuth         youtube.BasicAuth    `yaml:"basic_auth"`       // protects rss and files of all feeds without own credentials
+				Store             struct {
+					Type string `yaml:"type"` // bolt (default) or sqlite
+					File string `yaml:"file"` // sqlite db file
+				} `yaml:"store"`
 			}{},
 		},
 		Store:         boltStore,
@@ -297,12 +372,14 @@ func TestProcessor_DoSkipItems(t *testing.T) {
 				},
 			},
 			System: struct {
-				UpdateInterval time.Duration `yaml:"update"`
-				MaxItems       int           `yaml:"max_per_feed"`
-				MaxTotal       int           `yaml:"max_total"`
-				MaxKeepInDB    int           `yaml:"max_keep"`
-				Concurrent     int           `yaml:"concurrent"`
-				BaseURL        string        `yaml:"base_url"`
+				UpdateInterval time.Duration     `yaml:"update"`
+				MaxItems       int               `yaml:"max_per_feed"`
+				MaxTotal       int               `yaml:"max_total"`
+				MaxKeepInDB    int               `yaml:"max_keep"`
+				Concurrent     int               `yaml:"concurrent"`
+				BaseURL        string            `yaml:"base_url"`
+				RFC822Dates    bool              `yaml:"rfc822_dates"` // rss dates with 2 digits year, RFC1123Z by default
+				BasicAuth      youtube.BasicAuth `yaml:"basic_auth"`   // protects feeds without own credentials
 			}{
 				UpdateInterval: time.Second / 2,
 				MaxItems:       10,
@@ -312,16 +389,51 @@ func TestProcessor_DoSkipItems(t *testing.T) {
 				BaseURL:        "baseUrl",
 			},
 			YouTube: struct {
-				DlTemplate      string             `yaml:"dl_template"`
-				BaseChanURL     string             `yaml:"base_chan_url"`
-				BasePlaylistURL string             `yaml:"base_playlist_url"`
-				Channels        []youtube.FeedInfo `yaml:"channels"`
-				BaseURL         string             `yaml:"base_url"`
-				UpdateInterval  time.Duration      `yaml:"update"`
-				MaxItems        int                `yaml:"max_per_channel"`
-				FilesLocation   string             `yaml:"files_location"`
-				RSSLocation     string             `yaml:"rss_location"`
-				SkipShorts      time.Duration      `yaml:"skip_shorts"`
+				DlTemplate        string               `yaml:"dl_template"`
+				BaseChanURL       string               `yaml:"base_chan_url"`
+				BasePlaylistURL   string               `yaml:"base_playlist_url"`
+				Source            ytfeed.Source        `yaml:"source"` // alternative api listing channels, i.e. piped or invidious
+				Channels          []youtube.FeedInfo   `yaml:"channels"`
+				BaseURL           string               `yaml:"base_url"`
+				MediaBaseURL      string               `yaml:"media_base_url"` // base url of enclosures, i.e. cdn, default is base_url
+				UpdateInterval    time.Duration        `yaml:"update"`
+				UpdateJitter      time.Duration        `yaml:"update_jitter"`
+				MaxItems          int                  `yaml:"max_per_channel"`
+				FilesLocation     string               `yaml:"files_location"`
+				RSSLocation       string               `yaml:"rss_location"`
+				RSSMirrors        []string             `yaml:"rss_mirrors"` // extra locations of rss files, i.e. mounted object storage
+				SkipShorts        time.Duration        `yaml:"skip_shorts"`
+				MinYtDlpVersion   string               `yaml:"min_ytdlp_version"`
+				FileNameTmpl      string               `yaml:"file_name_template"`
+				FileNameHash      string               `yaml:"file_name_hash"`
+				FileNameHashLen   int                  `yaml:"file_name_hash_len"`
+				BackfillDelay     time.Duration        `yaml:"backfill_delay"`
+				CompletionWebhook string               `yaml:"completion_webhook"`
+				DownloadTimeout   time.Duration        `yaml:"download_timeout"`
+				DownloadRate      string               `yaml:"download_rate"`
+				ResumeDownloads   bool                 `yaml:"resume_downloads"` // continue interrupted downloads from .part files
+				FeedTimeout       time.Duration        `yaml:"feed_timeout"`
+				ShutdownGrace     time.Duration        `yaml:"shutdown_grace"`
+				PostDlTimeout     time.Duration        `yaml:"post_download_timeout"`
+				MaxPerCycle       int                  `yaml:"max_per_cycle"`
+				MaxDownloads      int                  `yaml:"max_concurrent_downloads"` // downloads running at once, no limit if 0
+				CheckURLs         int                  `yaml:"check_urls"`
+				ListingTTL        time.Duration        `yaml:"listing_ttl"`
+				FetchAttempts     int                  `yaml:"fetch_attempts"`    // attempts to list a feed on transient errors
+				FetchRetryDelay   time.Duration        `yaml:"fetch_retry_delay"` // the first delay between attempts, doubled each time
+				DetectLang        bool                 `yaml:"detect_lang"`
+				StrictEnv         bool                 `yaml:"strict_env"` // reject references to unset env variables, see expandEnv
+				ProcessedMaxAge   time.Duration        `yaml:"processed_max_age"`
+				RemoveConcurrency int                  `yaml:"remove_concurrency"`
+				IncrementalRSS    int                  `yaml:"incremental_rss"`  // rss items to update rss file in place, disabled if 0
+				DownloadBudget    youtube.FileSize     `yaml:"download_budget"`  // bytes downloaded per budget period, no limit if 0
+				BudgetPeriod      youtube.BudgetPeriod `yaml:"budget_period"`    // month (default), week or day
+				FormatFallbacks   []string             `yaml:"format_fallbacks"` // yt-dlp formats tried if formats of dl_template missing
+				BasicAuth         youtube.BasicAuth    `yaml:"basic_auth"`       // protects rss and files of all feeds without own credentials
+				Store             struct {
+					Type string `yaml:"type"` // bolt (default) or sqlite
+					File string `yaml:"file"` // sqlite db file
+				} `yaml:"store"`
 			}{},
 		},
 		Store:         boltStore,
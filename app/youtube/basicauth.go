@@ -0,0 +1,30 @@
+package youtube
+
+import "crypto/subtle"
+
+// BasicAuth defines credentials protecting rss and files of the feed, http basic auth with User and Passwd and/or
+// access Token passed as bearer token or access_token query param. Disabled if neither User nor Token set
+type BasicAuth struct {
+	User   string `yaml:"user"`
+	Passwd string `yaml:"passwd" json:"-"`
+	Token  string `yaml:"token" json:"-"`
+}
+
+// Enabled returns true if credentials are set
+func (b BasicAuth) Enabled() bool {
+	return b.User != "" || b.Token != ""
+}
+
+// Check compares given user and password with the credentials in constant time, false if User not set
+func (b BasicAuth) Check(user, passwd string) bool {
+	if b.User == "" {
+		return false
+	}
+	return (subtle.ConstantTimeCompare([]byte(b.User), []byte(user)) +
+		subtle.ConstantTimeCompare([]byte(b.Passwd), []byte(passwd))) == 2
+}
+
+// CheckToken compares given token with the access token in constant time, false if Token not set
+func (b BasicAuth) CheckToken(token string) bool {
+	return b.Token != "" && subtle.ConstantTimeCompare([]byte(b.Token), []byte(token)) == 1
+}
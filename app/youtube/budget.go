@@ -0,0 +1,110 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+)
+
+// ErrBudgetExhausted returned by Backfill once DownloadBudget of the current period is reached
+var ErrBudgetExhausted = errors.New("download budget exhausted")
+
+// BudgetPeriod is the period of DownloadBudget, the budget renewed at the start of each period in the local time zone
+type BudgetPeriod string
+
+// enum for budget periods
+const (
+	BPMonth = BudgetPeriod("month") // calendar month, the default
+	BPWeek  = BudgetPeriod("week")  // ISO week, from Monday
+	BPDay   = BudgetPeriod("day")
+)
+
+// UnmarshalYAML checks budget period is known, case insensitive. Empty is the month
+func (p *BudgetPeriod) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	res := BudgetPeriod(strings.ToLower(strings.TrimSpace(s)))
+	switch res {
+	case "":
+		*p = BPMonth
+		return nil
+	case BPMonth, BPWeek, BPDay:
+		*p = res
+		return nil
+	}
+	return errors.Errorf("unknown budget period %q", s)
+}
+
+// key returns key of the period containing t, i.e. "2023-01" for the month, "2023-W02" for the week
+// or "2023-01-15" for the day. Downloaded bytes stored by the key, a new key starts a new budget.
+func (p BudgetPeriod) key(t time.Time) string {
+	switch p {
+	case BPDay:
+		return t.Format("2006-01-02")
+	case BPWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01")
+}
+
+// next returns start of the period after the one containing t, in t's location
+func (p BudgetPeriod) next(t time.Time) time.Time {
+	switch p {
+	case BPDay:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	case BPWeek:
+		daysToMonday := (8 - int(t.Weekday())) % 7
+		if daysToMonday == 0 {
+			daysToMonday = 7
+		}
+		return time.Date(t.Year(), t.Month(), t.Day()+daysToMonday, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+}
+
+// budgetState keeps the period with exhausted budget reported, to log it once per period
+type budgetState struct {
+	mu       sync.Mutex
+	reported string
+}
+
+// budgetExhausted checks if DownloadBudget of the period containing now is reached, no new downloads started
+// until the next period in this case. Downloads running at the moment finished, so the budget may be exceeded
+// by them. Always false if DownloadBudget not set.
+func (s *Service) budgetExhausted(now time.Time) bool {
+	if s.DownloadBudget <= 0 {
+		return false
+	}
+	period := s.BudgetPeriod.key(now)
+	used := s.Store.CountPeriodBytes(period)
+	if used < int64(s.DownloadBudget) {
+		return false
+	}
+	s.budget.mu.Lock()
+	defer s.budget.mu.Unlock()
+	if s.budget.reported != period {
+		s.budget.reported = period
+		s.event("WARN", "quota", fmt.Sprintf("download budget %v exhausted, downloaded %v in %s, "+
+			"new downloads stopped until %s", s.DownloadBudget, FileSize(used), period,
+			s.BudgetPeriod.next(now).Format(time.RFC3339)), Fields{}.with("period", period).with("bytes", used))
+	}
+	return true
+}
+
+// addBudgetBytes counts downloaded bytes in the current period of DownloadBudget
+func (s *Service) addBudgetBytes(size int64) {
+	if s.DownloadBudget <= 0 {
+		return
+	}
+	period := s.BudgetPeriod.key(time.Now())
+	if err := s.Store.AddPeriodBytes(period, size); err != nil {
+		log.Printf("[WARN] failed to update downloaded bytes for period %s: %v", period, err)
+	}
+}
@@ -0,0 +1,137 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestBudgetPeriod(t *testing.T) {
+	loc := time.FixedZone("EST", -5*3600)
+	tbl := []struct {
+		period BudgetPeriod
+		ts     time.Time
+		key    string
+		next   time.Time
+	}{
+		{BPMonth, time.Date(2023, 1, 15, 10, 0, 0, 0, loc), "2023-01", time.Date(2023, 2, 1, 0, 0, 0, 0, loc)},
+		{"", time.Date(2023, 12, 31, 23, 59, 0, 0, loc), "2023-12", time.Date(2024, 1, 1, 0, 0, 0, 0, loc)},
+		{BPWeek, time.Date(2023, 1, 1, 10, 0, 0, 0, loc), "2022-W52", time.Date(2023, 1, 2, 0, 0, 0, 0, loc)},
+		{BPWeek, time.Date(2023, 1, 2, 0, 0, 0, 0, loc), "2023-W01", time.Date(2023, 1, 9, 0, 0, 0, 0, loc)},
+		{BPWeek, time.Date(2023, 1, 7, 23, 0, 0, 0, loc), "2023-W01", time.Date(2023, 1, 9, 0, 0, 0, 0, loc)},
+		{BPDay, time.Date(2023, 2, 28, 10, 0, 0, 0, loc), "2023-02-28", time.Date(2023, 3, 1, 0, 0, 0, 0, loc)},
+	}
+	for _, tt := range tbl {
+		t.Run(string(tt.period)+" "+tt.ts.String(), func(t *testing.T) {
+			assert.Equal(t, tt.key, tt.period.key(tt.ts))
+			assert.Equal(t, tt.next, tt.period.next(tt.ts))
+			assert.NotEqual(t, tt.key, tt.period.key(tt.period.next(tt.ts)), "next is a new period")
+		})
+	}
+
+	var conf struct {
+		Period BudgetPeriod `yaml:"period"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(`period: Week`), &conf))
+	assert.Equal(t, BPWeek, conf.Period)
+	assert.Error(t, yaml.Unmarshal([]byte(`period: year`), &conf))
+}
+
+func TestService_budgetExhausted(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{Store: boltStore, DownloadBudget: 100}
+
+	jan := time.Date(2023, 1, 20, 10, 0, 0, 0, time.UTC)
+	assert.False(t, svc.budgetExhausted(jan))
+	require.NoError(t, boltStore.AddPeriodBytes("2023-01", 99))
+	assert.False(t, svc.budgetExhausted(jan))
+	require.NoError(t, boltStore.AddPeriodBytes("2023-01", 1))
+	assert.True(t, svc.budgetExhausted(jan), "budget reached")
+	assert.True(t, svc.budgetExhausted(jan.Add(10*24*time.Hour)))
+	assert.False(t, svc.budgetExhausted(time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)), "reset on rollover")
+
+	svc.BudgetPeriod = BPDay
+	require.NoError(t, boltStore.AddPeriodBytes("2023-01-20", 200))
+	assert.True(t, svc.budgetExhausted(jan))
+	assert.False(t, svc.budgetExhausted(jan.Add(24*time.Hour)), "reset next day")
+
+	svc.DownloadBudget = 0
+	assert.False(t, svc.budgetExhausted(jan), "no budget")
+}
+
+func TestService_DoBudget(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid3", Title: "title3", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now().Add(-time.Hour)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now().Add(-2 * time.Hour)},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content"), 0o600) // 7 bytes
+		},
+	}
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	// over the budget in the previous period, not counted in the current one
+	require.NoError(t, boltStore.AddPeriodBytes(BPDay.key(time.Now().Add(-24*time.Hour)), 1000))
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1"}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost:8080/yt",
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		DownloadBudget:  10,
+		BudgetPeriod:    BPDay,
+	}
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(downloader.GetCalls()), "stopped once budget exhausted")
+	assert.Equal(t, int64(14), boltStore.CountPeriodBytes(BPDay.key(time.Now())))
+	entries, err := boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(downloader.GetCalls()), "no downloads in the same period")
+
+	found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"})
+	require.NoError(t, err)
+	assert.False(t, found, "left for the next period")
+
+	chans.GetPageFunc = func(ctx context.Context, chanID string, feedType ytfeed.Type, page int, apiKey string) ([]ytfeed.Entry, error) {
+		if page > 0 {
+			return nil, nil
+		}
+		return chans.Get(ctx, chanID, feedType, time.Time{}, apiKey)
+	}
+	_, err = svc.Backfill(context.Background(), "channel1", 0)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+}
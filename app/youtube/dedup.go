@@ -0,0 +1,65 @@
+package youtube
+
+import (
+	"regexp"
+	"strings"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// dedupDurationTolerance is the max difference in seconds between durations of the same content,
+// re-encoded uploads may differ by a second or two
+const dedupDurationTolerance = 2
+
+var nonAlnumRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// fingerprint identifies content of the entry by normalized title and duration, to detect re-uploads
+// of the same video with a new video id.
+type fingerprint struct {
+	title    string
+	duration int // seconds
+}
+
+// normalizeTitle lowercases the title and collapses punctuation and spaces, numbers kept as is
+// to distinguish episodes of a series, i.e. "Episode 12" and "Episode 13"
+func normalizeTitle(title string) string {
+	return strings.TrimSpace(nonAlnumRe.ReplaceAllString(strings.ToLower(title), " "))
+}
+
+func makeFingerprint(title string, duration int) fingerprint {
+	return fingerprint{title: normalizeTitle(title), duration: duration}
+}
+
+// matches returns true if both fingerprints have the same normalized title and close durations.
+// Unknown (zero) duration or empty title never matches, to avoid false positives.
+func (f fingerprint) matches(other fingerprint) bool {
+	if f.title == "" || f.duration <= 0 || other.duration <= 0 || f.title != other.title {
+		return false
+	}
+	diff := f.duration - other.duration
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= dedupDurationTolerance
+}
+
+// findDuplicate looks for a stored entry of the feed with the same content as the entry with given duration.
+// Stored titles have the feed's title prefix, so the entry's title compared with and without the prefix.
+func (s *Service) findDuplicate(entry ytfeed.Entry, fi FeedInfo, duration int) (ytfeed.Entry, bool) {
+	stored, err := s.Store.Load(fi.ID, KeepAll)
+	if err != nil {
+		return ytfeed.Entry{}, false // no entries stored for the feed yet
+	}
+	raw := makeFingerprint(entry.Title, duration)
+	prefixed := makeFingerprint(fi.TitlePrefix.Apply(entry.Title, fi.Name), duration)
+	for _, e := range stored {
+		if e.VideoID == entry.VideoID {
+			continue
+		}
+		fp := makeFingerprint(e.Title, e.Duration)
+		if fp.matches(raw) || fp.matches(prefixed) {
+			return e, true
+		}
+	}
+	return ytfeed.Entry{}, false
+}
@@ -0,0 +1,132 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestFingerprint_matches(t *testing.T) {
+	tbl := []struct {
+		title1, title2       string
+		duration1, duration2 int
+		res                  bool
+	}{
+		{"Some Title", "Some Title", 600, 600, true},
+		{"Some Title!", "some   title", 600, 601, true},
+		{"Some Title (re-upload)", "Some Title", 600, 600, false},
+		{"Some Title", "Some Title", 600, 610, false},
+		{"Episode 12: the title", "Episode 13: the title", 600, 600, false},
+		{"Подкаст №5", "подкаст 5", 1200, 1202, true},
+		{"Some Title", "Some Title", 0, 0, false},
+		{"Some Title", "Some Title", 600, 0, false},
+		{"", "", 600, 600, false},
+		{"???", "!!!", 600, 600, false},
+	}
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(tt.title1, func(t *testing.T) {
+			fp1, fp2 := makeFingerprint(tt.title1, tt.duration1), makeFingerprint(tt.title2, tt.duration2)
+			assert.Equal(t, tt.res, fp1.matches(fp2), "case #%d", i)
+			assert.Equal(t, tt.res, fp2.matches(fp1), "symmetric, case #%d", i)
+		})
+	}
+}
+
+func TestService_findDuplicate(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid1", Title: "name1: Part 1", Duration: 600},
+				{ChannelID: channelID, VideoID: "vid2", Title: "name1: Part 2", Duration: 900},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc}
+	fi := FeedInfo{ID: "channel1", Name: "name1"}
+
+	dup, found := svc.findDuplicate(ytfeed.Entry{VideoID: "vid3", Title: "Part 1"}, fi, 601)
+	require.True(t, found, "re-upload of vid1 with prefix")
+	assert.Equal(t, "vid1", dup.VideoID)
+
+	dup, found = svc.findDuplicate(ytfeed.Entry{VideoID: "vid3", Title: "name1: part 2"}, FeedInfo{ID: "channel1"}, 900)
+	require.True(t, found, "stored title without prefix")
+	assert.Equal(t, "vid2", dup.VideoID)
+
+	_, found = svc.findDuplicate(ytfeed.Entry{VideoID: "vid3", Title: "Part 3"}, fi, 600)
+	assert.False(t, found, "next episode of the series")
+
+	_, found = svc.findDuplicate(ytfeed.Entry{VideoID: "vid3", Title: "Part 1"}, fi, 1500)
+	assert.False(t, found, "same title, different duration")
+
+	_, found = svc.findDuplicate(ytfeed.Entry{VideoID: "vid1", Title: "Part 1"}, fi, 600)
+	assert.False(t, found, "same video id is not a re-upload")
+}
+
+func TestService_DoDedup(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid3", Title: "Episode 1!", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "Episode 2", Published: time.Now().Add(-time.Minute)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "Episode 1", Published: time.Now().Add(-time.Hour)},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte(id), 0o600)
+		},
+	}
+	duration := &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	require.NoError(t, boltStore.SetProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}))
+	_, err = boltStore.Save(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "Episode 1", Duration: 1233,
+		Published: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, Dedup: true}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: duration,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Entries: 3, Processed: 2, Added: 1, Skipped: 1, Ignored: 1, Bytes: 4}, st)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res), "re-upload vid3 skipped")
+	assert.Equal(t, "vid2", res[0].VideoID)
+	assert.Equal(t, "vid1", res[1].VideoID)
+
+	found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid3"})
+	require.NoError(t, err)
+	assert.True(t, found, "re-upload marked processed")
+
+	files, err := filepath.Glob(filepath.Join(dir, "channel1", "*.mp3"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(files), "downloaded re-upload removed")
+}
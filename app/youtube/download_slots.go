@@ -0,0 +1,69 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// downloadSlots is a semaphore of running downloads, made on the first use
+type downloadSlots struct {
+	once sync.Once
+	sem  chan struct{}
+
+	mu     sync.Mutex
+	active map[string]bool // entries being downloaded, by feed and video id
+}
+
+// startDownload marks the entry as being downloaded. Returns ok=false if the entry is downloaded already,
+// i.e. by backfill or re-download running along with the update, so the same file is not written twice.
+// The done func clears the mark.
+func (s *Service) startDownload(entry ytfeed.Entry, fi FeedInfo) (done func(), ok bool) {
+	key := fi.ID + "::" + entry.VideoID
+	s.downloads.mu.Lock()
+	defer s.downloads.mu.Unlock()
+	if s.downloads.active[key] {
+		return nil, false
+	}
+	if s.downloads.active == nil {
+		s.downloads.active = map[string]bool{}
+	}
+	s.downloads.active[key] = true
+	return func() {
+		s.downloads.mu.Lock()
+		delete(s.downloads.active, key)
+		s.downloads.mu.Unlock()
+	}, true
+}
+
+// acquireDownload takes a download slot if MaxConcurrentDownloads set, waiting in queue if all slots are busy.
+// Returns release func to free the slot, ok=false if ctx canceled while waiting.
+func (s *Service) acquireDownload(ctx context.Context, entry ytfeed.Entry, fi FeedInfo) (release func(), ok bool) {
+	if s.MaxConcurrentDownloads <= 0 {
+		return func() {}, true
+	}
+	s.downloads.once.Do(func() { s.downloads.sem = make(chan struct{}, s.MaxConcurrentDownloads) })
+	release = func() { <-s.downloads.sem }
+
+	select {
+	case s.downloads.sem <- struct{}{}:
+		return release, true
+	default:
+	}
+
+	s.event("INFO", "queued", fmt.Sprintf("download of %s queued, %d downloads running", entry.VideoID,
+		s.MaxConcurrentDownloads), entryFields(fi, entry).with("running", s.MaxConcurrentDownloads))
+	st := time.Now()
+	select {
+	case s.downloads.sem <- struct{}{}:
+		log.Printf("[INFO] download of %s started after %v in queue", entry.VideoID, time.Since(st).Round(time.Millisecond))
+		return release, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
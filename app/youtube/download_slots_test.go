@@ -0,0 +1,95 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestService_acquireDownload(t *testing.T) {
+	buf := bytes.Buffer{}
+	svc := Service{MaxConcurrentDownloads: 1, Logger: &JSONLogger{Out: &buf}}
+	fi, entry := FeedInfo{ID: "ch1"}, ytfeed.Entry{ChannelID: "ch1", VideoID: "vid1"}
+
+	release, ok := svc.acquireDownload(context.Background(), entry, fi)
+	require.True(t, ok)
+	assert.Empty(t, buf.String(), "not queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, ok = svc.acquireDownload(ctx, ytfeed.Entry{ChannelID: "ch1", VideoID: "vid2"}, fi)
+	assert.False(t, ok, "canceled while waiting")
+	assert.Contains(t, buf.String(), `"action":"queued"`)
+	assert.Contains(t, buf.String(), `"video_id":"vid2"`)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release()
+	}()
+	release2, ok := svc.acquireDownload(context.Background(), ytfeed.Entry{ChannelID: "ch1", VideoID: "vid3"}, fi)
+	require.True(t, ok, "got the slot after release")
+	release2()
+
+	svc = Service{}
+	for i := 0; i < 10; i++ {
+		_, ok = svc.acquireDownload(context.Background(), entry, fi)
+		require.True(t, ok, "no limit")
+	}
+}
+
+func TestService_acquireDownloadConcurrent(t *testing.T) {
+	svc := Service{MaxConcurrentDownloads: 2}
+	var running, maxRunning int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, ok := svc.acquireDownload(context.Background(), ytfeed.Entry{VideoID: "vid"}, FeedInfo{ID: "ch1"})
+			require.True(t, ok)
+			defer release()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestService_startDownload(t *testing.T) {
+	buf := bytes.Buffer{}
+	svc := Service{Logger: &JSONLogger{Out: &buf}}
+	fi, entry := FeedInfo{ID: "ch1"}, ytfeed.Entry{ChannelID: "ch1", VideoID: "vid1"}
+
+	done, ok := svc.startDownload(entry, fi)
+	require.True(t, ok)
+	_, ok = svc.startDownload(entry, fi)
+	assert.False(t, ok, "in progress already")
+	done2, ok := svc.startDownload(entry, FeedInfo{ID: "ch2"})
+	assert.True(t, ok, "the same video of another feed")
+	done2()
+
+	_, _, saved, err := svc.downloadEntry(context.Background(), entry, fi)
+	require.NoError(t, err)
+	assert.False(t, saved)
+	assert.Contains(t, buf.String(), `"reason":"in_progress"`, "not downloaded twice")
+
+	done()
+	_, ok = svc.startDownload(entry, fi)
+	assert.True(t, ok, "done, can be downloaded again")
+}
@@ -0,0 +1,127 @@
+package youtube
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// defaultFailuresPerFeed is the number of recent failures kept for each feed if Service.FailuresPerFeed not set
+const defaultFailuresPerFeed = 20
+
+// Failure is a failed download of the entry with the reason
+type Failure struct {
+	FeedID  string    `json:"feed_id"`
+	Feed    string    `json:"feed"`
+	VideoID string    `json:"video_id"`
+	Title   string    `json:"title"`
+	Reason  string    `json:"reason"`
+	TS      time.Time `json:"ts"`
+}
+
+// failureRing is a bounded ring buffer of failures, the oldest failure overwritten when full
+type failureRing struct {
+	items []Failure
+	next  int // index of the next write
+	full  bool
+}
+
+func newFailureRing(size int) *failureRing {
+	return &failureRing{items: make([]Failure, size)}
+}
+
+func (r *failureRing) add(f Failure) {
+	r.items[r.next] = f
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns failures from the newest to the oldest
+func (r *failureRing) list() []Failure {
+	count := r.next
+	if r.full {
+		count = len(r.items)
+	}
+	res := make([]Failure, 0, count)
+	for i := 1; i <= count; i++ {
+		res = append(res, r.items[(r.next-i+len(r.items))%len(r.items)])
+	}
+	return res
+}
+
+// failures keeps recent failures by feed id
+type failures struct {
+	mu     sync.Mutex
+	byFeed map[string]*failureRing
+}
+
+func (f *failures) add(size int, failure Failure) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.byFeed == nil {
+		f.byFeed = map[string]*failureRing{}
+	}
+	ring, ok := f.byFeed[failure.FeedID]
+	if !ok {
+		ring = newFailureRing(size)
+		f.byFeed[failure.FeedID] = ring
+	}
+	ring.add(failure)
+}
+
+// list returns failures of the feed, or of all feeds if feedID is empty, from the newest to the oldest
+func (f *failures) list(feedID string) []Failure {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if feedID != "" {
+		ring, ok := f.byFeed[feedID]
+		if !ok {
+			return []Failure{}
+		}
+		return ring.list()
+	}
+	res := []Failure{}
+	for _, ring := range f.byFeed {
+		res = append(res, ring.list()...)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].TS.After(res[j].TS) })
+	return res
+}
+
+// remove drops failures of the video in the feed, the order of the rest kept
+func (f *failures) remove(feedID, videoID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ring, ok := f.byFeed[feedID]
+	if !ok {
+		return
+	}
+	items := ring.list()
+	res := newFailureRing(len(ring.items))
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].VideoID != videoID {
+			res.add(items[i])
+		}
+	}
+	f.byFeed[feedID] = res
+}
+
+// Failures returns recent failed downloads of the feed, or of all feeds if feedID is empty, the newest first.
+// Failures are kept in memory only, up to FailuresPerFeed for each feed.
+func (s *Service) Failures(feedID string) []Failure {
+	return s.failures.list(feedID)
+}
+
+// addFailure records failed download of the entry
+func (s *Service) addFailure(fi FeedInfo, entry ytfeed.Entry, reason string) {
+	size := s.FailuresPerFeed
+	if size <= 0 {
+		size = defaultFailuresPerFeed
+	}
+	s.failures.add(size, Failure{FeedID: fi.ID, Feed: fi.Name, VideoID: entry.VideoID, Title: entry.Title,
+		Reason: reason, TS: time.Now()})
+}
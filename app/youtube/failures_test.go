@@ -0,0 +1,80 @@
+package youtube
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestFailureRing(t *testing.T) {
+	ring := newFailureRing(3)
+	assert.Equal(t, []Failure{}, ring.list(), "empty")
+
+	ring.add(Failure{VideoID: "vid1"})
+	ring.add(Failure{VideoID: "vid2"})
+	assert.Equal(t, []string{"vid2", "vid1"}, videoIDs(ring.list()), "newest first")
+
+	ring.add(Failure{VideoID: "vid3"})
+	assert.Equal(t, []string{"vid3", "vid2", "vid1"}, videoIDs(ring.list()), "full")
+
+	ring.add(Failure{VideoID: "vid4"})
+	ring.add(Failure{VideoID: "vid5"})
+	assert.Equal(t, []string{"vid5", "vid4", "vid3"}, videoIDs(ring.list()), "the oldest overwritten")
+
+	for i := 6; i <= 10; i++ {
+		ring.add(Failure{VideoID: fmt.Sprintf("vid%d", i)})
+	}
+	assert.Equal(t, []string{"vid10", "vid9", "vid8"}, videoIDs(ring.list()), "capacity kept")
+}
+
+func TestService_Failures(t *testing.T) {
+	svc := Service{FailuresPerFeed: 2}
+	fi1, fi2 := FeedInfo{ID: "channel1", Name: "name1"}, FeedInfo{ID: "channel2", Name: "name2"}
+	assert.Equal(t, []Failure{}, svc.Failures(""), "nothing failed")
+
+	svc.addFailure(fi1, ytfeed.Entry{VideoID: "vid1", Title: "title1"}, "err1")
+	time.Sleep(time.Millisecond)
+	svc.addFailure(fi2, ytfeed.Entry{VideoID: "vid2", Title: "title2"}, "err2")
+	time.Sleep(time.Millisecond)
+	svc.addFailure(fi1, ytfeed.Entry{VideoID: "vid3", Title: "title3"}, "err3")
+	time.Sleep(time.Millisecond)
+	svc.addFailure(fi1, ytfeed.Entry{VideoID: "vid4", Title: "title4"}, "err4")
+
+	res := svc.Failures("channel1")
+	require.Equal(t, 2, len(res), "capped per feed")
+	assert.Equal(t, "vid4", res[0].VideoID)
+	assert.Equal(t, "err4", res[0].Reason)
+	assert.Equal(t, "name1", res[0].Feed)
+	assert.Equal(t, "title4", res[0].Title)
+	assert.Equal(t, "vid3", res[1].VideoID)
+
+	assert.Equal(t, []string{"vid2"}, videoIDs(svc.Failures("channel2")))
+	assert.Equal(t, []Failure{}, svc.Failures("unknown"))
+	assert.Equal(t, []string{"vid4", "vid3", "vid2"}, videoIDs(svc.Failures("")), "all feeds, newest first")
+
+	svc.failures.remove("channel1", "vid4")
+	svc.failures.remove("channel3", "vid4")
+	assert.Equal(t, []string{"vid3"}, videoIDs(svc.Failures("channel1")), "failure of re-downloaded video removed")
+	svc.addFailure(fi1, ytfeed.Entry{VideoID: "vid5"}, "err5")
+	svc.addFailure(fi1, ytfeed.Entry{VideoID: "vid6"}, "err6")
+	assert.Equal(t, []string{"vid6", "vid5"}, videoIDs(svc.Failures("channel1")), "capacity kept")
+
+	svc = Service{}
+	for i := 0; i < 30; i++ {
+		svc.addFailure(fi1, ytfeed.Entry{VideoID: fmt.Sprintf("vid%d", i)}, "err")
+	}
+	assert.Equal(t, defaultFailuresPerFeed, len(svc.Failures("channel1")), "default capacity")
+}
+
+func videoIDs(failures []Failure) []string {
+	res := make([]string, 0, len(failures))
+	for _, f := range failures {
+		res = append(res, f.VideoID)
+	}
+	return res
+}
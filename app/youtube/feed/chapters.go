@@ -0,0 +1,166 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// Chapter is a part of the video, defined by the author with timestamps in description or by yt-dlp's metadata
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration // zero means till the end of the file
+}
+
+// chapterRe matches description lines like "01:23 title", "1:02:03 - title" or "(12:34) title"
+var chapterRe = regexp.MustCompile(`^\s*\(?((?:\d{1,2}:)?\d{1,2}:\d{2})\)?\s*[-–—:|]?\s*(.+?)\s*$`)
+
+// ParseChapters extracts chapters from the video description with a timestamp at the beginning of the line.
+// Following youtube's rules, chapters are recognized only if the first one starts at 0:00.
+// End of each chapter is the start of the next one, the last chapter ends with the file.
+func ParseChapters(description string) []Chapter {
+	var res []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		m := chapterRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		start, err := parseTimestamp(m[1])
+		if err != nil {
+			continue
+		}
+		if len(res) > 0 && start <= res[len(res)-1].Start {
+			continue // timestamps should be in ascending order
+		}
+		res = append(res, Chapter{Title: m[2], Start: start})
+	}
+	if len(res) == 0 || res[0].Start != 0 {
+		return nil
+	}
+	for i := 0; i < len(res)-1; i++ {
+		res[i].End = res[i+1].Start
+	}
+	return res
+}
+
+// PlanChapters makes the list of parts to split the file of given duration to.
+// Chapters sorted by start, overlapping, empty and out of the file chapters dropped.
+// Returns nil if the file can't be split to two or more parts.
+func PlanChapters(chapters []Chapter, duration time.Duration) []Chapter {
+	sorted := make([]Chapter, len(chapters))
+	copy(sorted, chapters)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	uniq := make([]Chapter, 0, len(sorted))
+	for _, ch := range sorted {
+		if duration > 0 && ch.Start >= duration {
+			break
+		}
+		if len(uniq) > 0 && ch.Start <= uniq[len(uniq)-1].Start {
+			continue
+		}
+		uniq = append(uniq, ch)
+	}
+
+	var res []Chapter
+	for i, ch := range uniq {
+		if i < len(uniq)-1 && (ch.End == 0 || ch.End > uniq[i+1].Start) {
+			ch.End = uniq[i+1].Start
+		}
+		if duration > 0 && ch.End >= duration {
+			ch.End = 0
+		}
+		if ch.End != 0 && ch.End <= ch.Start {
+			continue
+		}
+		if strings.TrimSpace(ch.Title) == "" {
+			ch.Title = fmt.Sprintf("Part %d", len(res)+1)
+		}
+		res = append(res, ch)
+	}
+	if len(res) < 2 {
+		return nil
+	}
+	res[len(res)-1].End = 0 // the last part always till the end of the file
+	return res
+}
+
+// ShiftChapters moves chapters back by offset, for the file with offset cut from the beginning.
+// Chapters ended before the offset dropped, the one in progress starts at 0.
+func ShiftChapters(chapters []Chapter, offset time.Duration) []Chapter {
+	if offset <= 0 {
+		return chapters
+	}
+	res := make([]Chapter, 0, len(chapters))
+	for _, ch := range chapters {
+		if ch.End != 0 && ch.End <= offset {
+			continue
+		}
+		ch.Start -= offset
+		if ch.Start < 0 {
+			ch.Start = 0
+		}
+		if ch.End != 0 {
+			ch.End -= offset
+		}
+		res = append(res, ch)
+	}
+	return res
+}
+
+func parseTimestamp(ts string) (time.Duration, error) {
+	var res time.Duration
+	for _, elem := range strings.Split(ts, ":") {
+		v, err := strconv.Atoi(elem)
+		if err != nil {
+			return 0, err
+		}
+		res = res*60 + time.Duration(v)*time.Second
+	}
+	return res, nil
+}
+
+// Splitter cuts a part of the audio file with ffmpeg
+type Splitter struct {
+	Bin          string // ffmpeg binary, "ffmpeg" if empty
+	LogErrWriter io.Writer
+}
+
+// Split copies the part of src file between start and end to dst file, zero end means till the end of src.
+func (s *Splitter) Split(ctx context.Context, src, dst string, start, end time.Duration) error {
+	bin := s.Bin
+	if bin == "" {
+		bin = "ffmpeg"
+	}
+	args := []string{"-y", "-loglevel", "error", "-i", src, "-ss", fmtFFmpegTime(start)}
+	if end > 0 {
+		args = append(args, "-to", fmtFFmpegTime(end))
+	}
+	args = append(args, "-map_metadata", "-1", "-c", "copy", dst)
+
+	cmd := exec.CommandContext(ctx, bin, args...) // nolint
+	errBuf := bytes.Buffer{}
+	cmd.Stderr = &errBuf
+	if s.LogErrWriter != nil {
+		cmd.Stderr = io.MultiWriter(s.LogErrWriter, &errBuf)
+	}
+	log.Printf("[DEBUG] executing command: %s %s", bin, strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to split %s to %s: %v, %s", src, dst, err, strings.TrimSpace(errBuf.String()))
+	}
+	return nil
+}
+
+func fmtFFmpegTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
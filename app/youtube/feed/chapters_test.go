@@ -0,0 +1,129 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChapters(t *testing.T) {
+	tbl := []struct {
+		descr string
+		res   []Chapter
+	}{
+		{"", nil},
+		{"no chapters here\njust text", nil},
+		{"0:00 Intro\n1:30 - Topic one\nsome text\n(12:05) Topic two\n1:02:03 | Outro", []Chapter{
+			{Title: "Intro", Start: 0, End: 90 * time.Second},
+			{Title: "Topic one", Start: 90 * time.Second, End: 12*time.Minute + 5*time.Second},
+			{Title: "Topic two", Start: 12*time.Minute + 5*time.Second, End: time.Hour + 2*time.Minute + 3*time.Second},
+			{Title: "Outro", Start: time.Hour + 2*time.Minute + 3*time.Second},
+		}},
+		{"1:00 not from the start\n2:00 second", nil},
+		{"00:00 first\n05:00 second\n03:00 out of order\n07:00 third", []Chapter{
+			{Title: "first", Start: 0, End: 5 * time.Minute},
+			{Title: "second", Start: 5 * time.Minute, End: 7 * time.Minute},
+			{Title: "third", Start: 7 * time.Minute},
+		}},
+	}
+
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(tt.descr, func(t *testing.T) {
+			assert.Equal(t, tt.res, ParseChapters(tt.descr), "case #%d", i)
+		})
+	}
+}
+
+func TestPlanChapters(t *testing.T) {
+	tbl := []struct {
+		name     string
+		chapters []Chapter
+		duration time.Duration
+		res      []Chapter
+	}{
+		{"empty", nil, time.Hour, nil},
+		{"single chapter", []Chapter{{Title: "all", Start: 0, End: time.Hour}}, time.Hour, nil},
+		{"regular", []Chapter{
+			{Title: "one", Start: 0, End: 10 * time.Minute},
+			{Title: "two", Start: 10 * time.Minute, End: 20 * time.Minute},
+			{Title: "three", Start: 20 * time.Minute, End: 30 * time.Minute},
+		}, 30 * time.Minute, []Chapter{
+			{Title: "one", Start: 0, End: 10 * time.Minute},
+			{Title: "two", Start: 10 * time.Minute, End: 20 * time.Minute},
+			{Title: "three", Start: 20 * time.Minute},
+		}},
+		{"unsorted, no ends and empty title", []Chapter{
+			{Title: "two", Start: 10 * time.Minute},
+			{Title: "", Start: 0},
+		}, 0, []Chapter{
+			{Title: "Part 1", Start: 0, End: 10 * time.Minute},
+			{Title: "two", Start: 10 * time.Minute},
+		}},
+		{"overlapping and duplicated", []Chapter{
+			{Title: "one", Start: 0, End: 15 * time.Minute},
+			{Title: "dup", Start: 0, End: 5 * time.Minute},
+			{Title: "two", Start: 10 * time.Minute, End: 20 * time.Minute},
+		}, 20 * time.Minute, []Chapter{
+			{Title: "one", Start: 0, End: 10 * time.Minute},
+			{Title: "two", Start: 10 * time.Minute},
+		}},
+		{"chapters beyond the file", []Chapter{
+			{Title: "one", Start: 0},
+			{Title: "two", Start: 10 * time.Minute},
+			{Title: "three", Start: 30 * time.Minute},
+		}, 20 * time.Minute, []Chapter{
+			{Title: "one", Start: 0, End: 10 * time.Minute},
+			{Title: "two", Start: 10 * time.Minute},
+		}},
+		{"only one chapter within the file", []Chapter{
+			{Title: "one", Start: 0},
+			{Title: "two", Start: 30 * time.Minute},
+		}, 20 * time.Minute, nil},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.res, PlanChapters(tt.chapters, tt.duration))
+		})
+	}
+}
+
+func TestSplitter_Split(t *testing.T) {
+	s := Splitter{Bin: "true"}
+	require.NoError(t, s.Split(context.Background(), "src.mp3", "dst.mp3", time.Minute, 2*time.Minute))
+
+	errBuf := bytes.NewBuffer(nil)
+	s = Splitter{Bin: "false", LogErrWriter: errBuf}
+	err := s.Split(context.Background(), "src.mp3", "dst.mp3", time.Minute, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to split src.mp3 to dst.mp3")
+}
+
+func TestShiftChapters(t *testing.T) {
+	chapters := []Chapter{
+		{Title: "intro", Start: 0, End: time.Minute},
+		{Title: "part1", Start: time.Minute, End: 10 * time.Minute},
+		{Title: "part2", Start: 10 * time.Minute},
+	}
+	assert.Equal(t, chapters, ShiftChapters(chapters, 0))
+	assert.Equal(t, []Chapter{
+		{Title: "part1", Start: 0, End: 9 * time.Minute},
+		{Title: "part2", Start: 9 * time.Minute},
+	}, ShiftChapters(chapters, time.Minute))
+	assert.Equal(t, []Chapter{
+		{Title: "part1", Start: 0, End: 8 * time.Minute},
+		{Title: "part2", Start: 8 * time.Minute},
+	}, ShiftChapters(chapters, 2*time.Minute), "chapter in progress starts at 0")
+	assert.Equal(t, []Chapter{{Title: "part2", Start: 0}}, ShiftChapters(chapters, 20*time.Minute))
+}
+
+func TestFmtFFmpegTime(t *testing.T) {
+	assert.Equal(t, "0.000", fmtFFmpegTime(0))
+	assert.Equal(t, "3723.500", fmtFFmpegTime(time.Hour+2*time.Minute+3500*time.Millisecond))
+}
@@ -8,6 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 
 	log "github.com/go-pkgz/lgr"
@@ -18,14 +22,57 @@ import (
 // ErrSkip is returned when the file is not downloaded
 var ErrSkip = errors.New("skip")
 
+// ErrTooLarge is returned when the file is larger than the size limit, reported by yt-dlp before the download
+var ErrTooLarge = errors.New("file too large")
+
+// ErrNotAvailable is returned when the video is an upcoming premiere or live stream and can't be downloaded yet
+var ErrNotAvailable = errors.New("not available yet")
+
+// ErrFiltered is returned when the video doesn't pass GetOptions.MatchFilter, checked by yt-dlp before the download
+var ErrFiltered = errors.New("filtered out")
+
+// ErrMembersOnly is returned when the video is available to channel's members only and can't be downloaded,
+// i.e. without cookies of a member
+var ErrMembersOnly = errors.New("members-only video")
+
+// membersOnlyMarkers are parts of yt-dlp error messages for members-only videos
+var membersOnlyMarkers = []string{"members-only content", "available to this channel's members"}
+
+// notAvailableMarkers are parts of yt-dlp error messages for upcoming premieres and live streams
+var notAvailableMarkers = []string{"live event will begin", "premieres in", "waiting for scheduled stream"}
+
+// ytWatchURL is the base url of youtube's video page, used for {{.URL}} when id is not a full url
+const ytWatchURL = "https://www.youtube.com/watch?v="
+
 // Downloader executes an external command to download a video and extract its audio.
 type Downloader struct {
+	LimitRate string // max download rate passed to yt-dlp as --limit-rate, i.e. "2M" or "500K". No limit if empty
+	// Resume keeps partial (.part) files of interrupted downloads and continues them with yt-dlp's --continue
+	// on the next attempt of the same file name, instead of downloading from scratch
+	Resume bool
+	// FormatFallbacks are yt-dlp format selectors tried in order if formats of the command are not available,
+	// appended to its -f option, i.e. "-f m4a/bestaudio" becomes "-f 'm4a/bestaudio/ba*/best'". Audio downloads only
+	FormatFallbacks []string
+
 	ytTemplate   string
 	logOutWriter io.Writer
 	logErrWriter io.Writer
 	destination  string
 }
 
+// limitRateRe matches rates accepted by yt-dlp's --limit-rate, bytes per second with optional K, M or G suffix
+var limitRateRe = regexp.MustCompile(`^\d+(\.\d+)?[KMGkmg]?$`)
+
+// IsValidFormat checks if format selector can be used in Downloader.FormatFallbacks, a single word without quotes
+func IsValidFormat(format string) bool {
+	return format != "" && !strings.ContainsAny(format, " \t\n\r\"'`\\")
+}
+
+// IsValidLimitRate checks if rate can be used as Downloader.LimitRate, empty rate means no limit and is valid
+func IsValidLimitRate(rate string) bool {
+	return rate == "" || limitRateRe.MatchString(rate)
+}
+
 // NewDownloader creates a new Downloader with the given template (full command with placeholders for {{.ID}} and {{.Filename}}.
 // Destination is the directory where the audio files will be stored.
 func NewDownloader(tmpl string, logOutWriter, logErrWriter io.Writer, destination string) *Downloader {
@@ -38,38 +85,410 @@ func NewDownloader(tmpl string, logOutWriter, logErrWriter io.Writer, destinatio
 }
 
 // Get downloads a video from youtube and extracts audio.
-// yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "https://www.youtube.com/watch?v={{.ID}}" --no-progress -o {{.Filename}}.tmp
+// yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "{{.URL}}" --no-progress -o {{.Filename}}.tmp
+// id can be youtube's video id or a full url of the video for other (non-youtube) sources, {{.URL}} is set accordingly.
+// fname is relative to the destination and may include a subdirectory, created if missing.
 func (d *Downloader) Get(ctx context.Context, id, fname string) (file string, err error) {
+	return d.GetOpts(ctx, id, fname, GetOptions{})
+}
 
-	if err := os.MkdirAll(d.destination, 0o750); err != nil {
-		return "", errors.Wrapf(err, "failed to create directory %s", d.destination)
+// GetOptions are per-download options of GetOpts
+type GetOptions struct {
+	// MaxSize skips files larger than this size in bytes with yt-dlp's --max-filesize. The size is checked by yt-dlp
+	// before the download if the source reports it, ErrTooLarge returned in this case. No limit if 0
+	MaxSize int64
+	// CopyAudio extracts the best native audio without re-encoding to mp3, with yt-dlp's --audio-format best.
+	// The file has extension of the audio container, i.e. m4a or opus
+	CopyAudio bool
+	// Video downloads the video with audio as mp4, instead of audio only. Audio options of the command dropped,
+	// CopyAudio ignored
+	Video bool
+	// MatchFilter is yt-dlp's --match-filter checked with video's metadata before the download, i.e.
+	// "availability!=?subscriber_only". ErrFiltered returned for videos not passing it. No filter if empty
+	MatchFilter string
+}
+
+// GetOpts downloads like Get, with options of the download
+func (d *Downloader) GetOpts(ctx context.Context, id, fname string, opts GetOptions) (file string, err error) {
+	dir := filepath.Dir(filepath.Join(d.destination, fname))
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", errors.Wrapf(err, "failed to create directory %s", dir)
 	}
 
 	tmplParams := struct {
 		ID       string
+		URL      string
 		FileName string
 	}{
 		ID:       id,
+		URL:      videoURL(id),
 		FileName: fname,
 	}
 	b1 := bytes.Buffer{}
 	if err := template.Must(template.New("youtube-dl").Parse(d.ytTemplate)).Execute(&b1, tmplParams); err != nil { // nolint
 		return "", fmt.Errorf("failed to parse template: %v", err)
 	}
+	command := d.withLimitRate(b1.String())
+	if !opts.Video {
+		command = withFormatFallbacks(command, d.FormatFallbacks)
+	}
+	if opts.MaxSize > 0 {
+		command = withOption(command, fmt.Sprintf("--max-filesize %d", opts.MaxSize))
+	}
+	switch {
+	case opts.Video:
+		command = withVideo(command)
+	case opts.CopyAudio:
+		command = withCopyAudio(command)
+	}
+	if opts.MatchFilter != "" {
+		command = withOption(command, "--match-filter "+shellQuote(opts.MatchFilter))
+	}
+	if d.Resume {
+		command = withOption(command, "--continue")
+		if parts := d.partialFiles(fname, true); len(parts) > 0 {
+			log.Printf("[INFO] resume download of %s from %s", id, strings.Join(parts, ", "))
+		}
+	}
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", b1.String()) // nolint
+	cmd := exec.CommandContext(ctx, "sh", "-c", command) // nolint
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = d.logOutWriter
-	cmd.Stderr = d.logErrWriter
+	outBuf := bytes.Buffer{}
+	cmd.Stdout = io.MultiWriter(d.logOutWriter, &outBuf)
+	errBuf := bytes.Buffer{}
+	cmd.Stderr = io.MultiWriter(d.logErrWriter, &errBuf)
 	cmd.Dir = d.destination
-	log.Printf("[DEBUG] executing command: %s", b1.String())
+	log.Printf("[DEBUG] executing command: %s", command)
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			// interrupted, i.e. on shutdown or timeout. Partial files removed, incomplete mp3 must not be taken
+			// for downloaded one on the next attempt
+			d.removePartial(fname)
+			return "", errors.Wrapf(ctx.Err(), "download of %s interrupted", id)
+		}
+		if isNotAvailable(errBuf.String()) {
+			return "", ErrNotAvailable
+		}
+		if containsAny(errBuf.String(), membersOnlyMarkers) {
+			return "", ErrMembersOnly
+		}
 		return "", fmt.Errorf("failed to execute command: %v", err)
 	}
+	if m := downloadedFormatRe.FindStringSubmatch(outBuf.String()); m != nil {
+		log.Printf("[INFO] downloaded %s with format %s", id, m[1])
+	}
 
 	file = filepath.Join(d.destination, fname+".mp3")
+	switch {
+	case opts.Video:
+		file = d.videoFile(fname)
+	case opts.CopyAudio:
+		file = d.nativeAudioFile(fname)
+	}
 	if _, err := os.Stat(file); os.IsNotExist(err) {
+		if opts.MaxSize > 0 && strings.Contains(outBuf.String(), "larger than max-filesize") {
+			return file, ErrTooLarge
+		}
+		if opts.MatchFilter != "" && strings.Contains(outBuf.String(), "does not pass filter") {
+			return file, ErrFiltered
+		}
 		return file, ErrSkip
 	}
 	return file, nil
 }
+
+// Test downloads the video to dir with default options, for troubleshooting of the download command, i.e. region
+// or cookies issues. Returns the file and the command's output, stdout and stderr combined. Partial downloads of
+// the previous tests not resumed.
+func (d *Downloader) Test(ctx context.Context, id, dir string) (file, output string, err error) {
+	out := &syncBuffer{}
+	td := &Downloader{
+		LimitRate:       d.LimitRate,
+		FormatFallbacks: d.FormatFallbacks,
+		ytTemplate:      d.ytTemplate,
+		logOutWriter:    io.MultiWriter(d.logOutWriter, out),
+		logErrWriter:    io.MultiWriter(d.logErrWriter, out),
+		destination:     dir,
+	}
+	file, err = td.GetOpts(ctx, id, "test", GetOptions{})
+	return file, out.String(), err
+}
+
+// syncBuffer is a buffer safe for concurrent writes, collects stdout and stderr of the command written by exec
+// from separate goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffer
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// String returns the buffer's content
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// NativeAudioExts are extensions of audio files extracted without re-encoding, in the order of lookup
+var NativeAudioExts = []string{".m4a", ".opus", ".ogg", ".webm", ".aac", ".flac", ".wav", ".mp3"}
+
+// nativeAudioFile returns the audio file extracted for fname, fname.mp3 if not found
+func (d *Downloader) nativeAudioFile(fname string) string {
+	for _, ext := range NativeAudioExts {
+		file := filepath.Join(d.destination, fname+ext)
+		if st, err := os.Stat(file); err == nil && st.Mode().IsRegular() {
+			return file
+		}
+	}
+	return filepath.Join(d.destination, fname+".mp3")
+}
+
+// audioFormatRe matches audio format option of yt-dlp, i.e. --audio-format=mp3 or --audio-format mp3
+var audioFormatRe = regexp.MustCompile(`--audio-format[= ]\S+`)
+
+// withCopyAudio replaces audio format of the command with "best", keeping the native audio without re-encoding.
+// Adds --extract-audio and --audio-format if the command has no audio format
+func withCopyAudio(command string) string {
+	if audioFormatRe.MatchString(command) {
+		return audioFormatRe.ReplaceAllString(command, "--audio-format best")
+	}
+	return withOption(command, "--extract-audio --audio-format best")
+}
+
+// audioOptionRe matches yt-dlp options of audio extraction and format selection, replaced for video downloads
+var audioOptionRe = regexp.MustCompile(`\s(-x|--extract-audio|--audio-format[= ]\S+|--audio-quality[= ]\S+|-f\s+\S+|--format[= ]\S+)(\s|$)`)
+
+// videoFormat selects mp4 video with m4a audio, merged to mp4, or the best single mp4 file
+const videoFormat = `-f "bv*[ext=mp4]+ba[ext=m4a]/b[ext=mp4]/b" --merge-output-format mp4`
+
+// withVideo makes the command download video with audio as mp4, dropping audio extraction and format options
+func withVideo(command string) string {
+	for audioOptionRe.MatchString(command) { // loop as adjacent options share the separating space
+		command = audioOptionRe.ReplaceAllString(command, " ")
+	}
+	return withOption(command, videoFormat)
+}
+
+// videoFile returns the video file downloaded for fname as fname.mp4. yt-dlp names the file after the output
+// template, i.e. fname.tmp.mp4 or fname.tmp for "-o fname.tmp", such file renamed to fname.mp4.
+func (d *Downloader) videoFile(fname string) string {
+	file := filepath.Join(d.destination, fname+".mp4")
+	if _, err := os.Stat(file); err == nil {
+		return file
+	}
+	for _, name := range []string{fname + ".tmp.mp4", fname + ".tmp"} {
+		src := filepath.Join(d.destination, name)
+		if st, err := os.Stat(src); err != nil || !st.Mode().IsRegular() {
+			continue
+		}
+		if err := os.Rename(src, file); err != nil {
+			log.Printf("[WARN] failed to rename %s to %s, %v", src, file, err)
+			return src
+		}
+		break
+	}
+	return file
+}
+
+// removePartial removes files left by interrupted download of fname, i.e. fname.tmp.part, fname.tmp.webm or fname.mp3.
+// With Resume, partial downloads (.part and .ytdl files) kept to be continued, only incomplete results removed.
+func (d *Downloader) removePartial(fname string) {
+	for _, file := range d.partialFiles(fname, false) {
+		if d.Resume && isResumable(file) {
+			log.Printf("[INFO] keep partial download %s to resume", file)
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			log.Printf("[WARN] failed to remove partial download %s, %v", file, err)
+			continue
+		}
+		log.Printf("[INFO] removed partial download %s", file)
+	}
+}
+
+// partialFiles returns files of fname's download, i.e. fname.tmp.part or fname.mp3. Only resumable files
+// (.part and .ytdl) returned if resumable set
+func (d *Downloader) partialFiles(fname string, resumable bool) (res []string) {
+	dir, base := filepath.Split(filepath.Join(d.destination, fname))
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("[WARN] failed to list %s for partial downloads, %v", dir, err)
+		return nil
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), base+".") {
+			continue
+		}
+		if resumable && !isResumable(f.Name()) {
+			continue
+		}
+		res = append(res, filepath.Join(dir, f.Name()))
+	}
+	return res
+}
+
+// isResumable checks if the file is yt-dlp's partial download, continued with --continue
+func isResumable(file string) bool {
+	return strings.HasSuffix(file, ".part") || strings.HasSuffix(file, ".ytdl")
+}
+
+// Subtitles downloads subtitles of the video in the given language, manual ones preferred over auto-generated.
+// Uses the binary from the download template, i.e. yt-dlp. Subtitles stored in vtt format next to the audio file,
+// as {fname}.vtt. Returns ErrSkip if the video has no subtitles in this language.
+func (d *Downloader) Subtitles(ctx context.Context, id, fname, lang string) (file string, err error) {
+	fields := strings.Fields(d.ytTemplate)
+	if len(fields) == 0 {
+		return "", errors.New("empty download template")
+	}
+	args := []string{"--skip-download", "--write-subs", "--write-auto-subs", "--sub-langs", lang,
+		"--sub-format", "vtt/best", "--convert-subs", "vtt", "--no-progress", "-o", fname, videoURL(id)}
+	cmd := exec.CommandContext(ctx, fields[0], args...) // nolint
+	cmd.Stdout = d.logOutWriter
+	cmd.Stderr = d.logErrWriter
+	cmd.Dir = d.destination
+	log.Printf("[DEBUG] executing command: %s %s", fields[0], strings.Join(args, " "))
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to execute command: %v", err)
+	}
+
+	// subtitles saved as {fname}.{lang}.vtt, rename to {fname}.vtt
+	dir, base := filepath.Split(filepath.Join(d.destination, fname))
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s", dir)
+	}
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasPrefix(de.Name(), base+".") || !strings.HasSuffix(de.Name(), ".vtt") {
+			continue
+		}
+		file = filepath.Join(d.destination, fname+".vtt")
+		if err = os.Rename(filepath.Join(dir, de.Name()), file); err != nil {
+			return "", errors.Wrapf(err, "failed to rename subtitles %s", de.Name())
+		}
+		return file, nil
+	}
+	return "", ErrSkip
+}
+
+// withLimitRate adds --limit-rate right after the binary of the command, if LimitRate is set
+func (d *Downloader) withLimitRate(command string) string {
+	if d.LimitRate == "" {
+		return command
+	}
+	return withOption(command, "--limit-rate "+d.LimitRate)
+}
+
+// formatOptionRe matches format option of yt-dlp with its value, quoted or not
+var formatOptionRe = regexp.MustCompile(`\s(?:-f|--format)[= ]("[^"]*"|'[^']*'|\S+)`)
+
+// downloadedFormatRe matches format ids reported by yt-dlp before the download, i.e. "Downloading 1 format(s): 140"
+var downloadedFormatRe = regexp.MustCompile(`Downloading \d+ format\(s\): (\S+)`)
+
+// withFormatFallbacks appends fallbacks to the format option of the command, quoted as the selectors may have
+// characters special for the shell. Adds the option with yt-dlp's default "bestaudio/best" first if missing
+func withFormatFallbacks(command string, fallbacks []string) string {
+	if len(fallbacks) == 0 {
+		return command
+	}
+	m := formatOptionRe.FindStringSubmatchIndex(command)
+	if m == nil {
+		return withOption(command, "-f "+shellQuote("bestaudio/best/"+strings.Join(fallbacks, "/")))
+	}
+	value := command[m[2]:m[3]]
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return command[:m[2]] + shellQuote(value+"/"+strings.Join(fallbacks, "/")) + command[m[3]:]
+}
+
+// withOption adds the option right after the binary of the command
+func withOption(command, option string) string {
+	command = strings.TrimSpace(command)
+	idx := strings.IndexAny(command, " \t")
+	if idx < 0 {
+		return command + " " + option
+	}
+	return command[:idx] + " " + option + command[idx:]
+}
+
+// videoURL returns url of the video, id can be youtube's video id or a full url for other sources
+func videoURL(id string) string {
+	if strings.HasPrefix(id, "http://") || strings.HasPrefix(id, "https://") {
+		return id
+	}
+	return ytWatchURL + id
+}
+
+// isNotAvailable checks downloader's error output for upcoming or live stream errors
+func isNotAvailable(out string) bool {
+	return containsAny(out, notAvailableMarkers)
+}
+
+// containsAny checks if the output contains any of markers, case insensitive
+func containsAny(out string, markers []string) bool {
+	out = strings.ToLower(out)
+	for _, m := range markers {
+		if strings.Contains(out, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes the value as a single argument of sh command
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// Version runs "{bin} --version" and returns the reported version of yt-dlp (or compatible) binary
+func Version(ctx context.Context, bin string) (string, error) {
+	out, err := exec.CommandContext(ctx, bin, "--version").Output() // nolint
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get %s version", bin)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// IsOutdated checks if version is older than minVersion. Both versions expected in yt-dlp's date-like format,
+// i.e. 2022.04.08. Empty or unparsable minVersion means no restrictions, unparsable version considered outdated.
+func IsOutdated(version, minVersion string) bool {
+	minParts, err := versionParts(minVersion)
+	if err != nil || len(minParts) == 0 {
+		return false
+	}
+	verParts, err := versionParts(version)
+	if err != nil {
+		return true
+	}
+	for i, m := range minParts {
+		if i >= len(verParts) {
+			return true
+		}
+		if verParts[i] != m {
+			return verParts[i] < m
+		}
+	}
+	return false
+}
+
+func versionParts(v string) ([]int, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	res := []int{}
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version %q", v)
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
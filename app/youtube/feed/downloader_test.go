@@ -3,12 +3,15 @@ package feed
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +35,265 @@ func TestDownloader_Get(t *testing.T) {
 	t.Log(l)
 }
 
+func TestDownloader_GetSubDir(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("touch {{.FileName}}.mp3", lw, lw, loc)
+	res, err := d.Get(context.Background(), "id1", filepath.Join("chan1", "file1"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "chan1", "file1.mp3"), res)
+	_, err = os.Stat(res)
+	assert.NoError(t, err, "sub directory created")
+}
+
+func TestDownloader_Test(t *testing.T) {
+	lw, lwErr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	loc, dir := t.TempDir(), t.TempDir()
+	d := NewDownloader("echo {{.URL}}; echo some warning >&2; touch {{.FileName}}.mp3", lw, lwErr, loc)
+	file, out, err := d.Test(context.Background(), "https://example.com/v/1", dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "test.mp3"), file)
+	assert.Contains(t, out, "https://example.com/v/1\n")
+	assert.Contains(t, out, "some warning\n")
+	assert.Contains(t, lwErr.String(), "some warning", "logged too")
+	_, err = os.Stat(filepath.Join(loc, "test.mp3"))
+	assert.True(t, os.IsNotExist(err), "not in destination")
+
+	d = NewDownloader("echo {{.ID}} failed >&2; exit 1", lw, lwErr, loc)
+	_, out, err = d.Test(context.Background(), "vid1", dir)
+	assert.Error(t, err)
+	assert.Equal(t, "vid1 failed\n", out)
+}
+
+func TestDownloader_GetInterrupted(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(loc, "chan1"), 0o750))
+	other := filepath.Join(loc, "chan1", "file10.mp3")
+	require.NoError(t, os.WriteFile(other, []byte("other"), 0o600))
+
+	d := NewDownloader("touch {{.FileName}}.tmp.part {{.FileName}}.mp3 && exec sleep 10", lw, lw, loc)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := d.Get(ctx, "id1", filepath.Join("chan1", "file1"))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	files, err := filepath.Glob(filepath.Join(loc, "chan1", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{other}, files, "partial files removed, others kept")
+}
+
+func TestDownloader_GetResume(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	fname := filepath.Join("chan1", "file1")
+
+	d := NewDownloader("true {{.ID}}; touch {{.FileName}}.tmp.part {{.FileName}}.tmp.part.ytdl {{.FileName}}.mp3 && exec sleep 10",
+		lw, lw, loc)
+	d.Resume = true
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := d.Get(ctx, "id1", fname)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	files, err := filepath.Glob(filepath.Join(loc, "chan1", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(loc, fname+".tmp.part"), filepath.Join(loc, fname+".tmp.part.ytdl")}, files,
+		"partial download kept, incomplete mp3 removed")
+	assert.Equal(t, files, d.partialFiles(fname, true))
+
+	d = NewDownloader("echo {{.ID}} && mv {{.FileName}}.tmp.part {{.FileName}}.mp3", lw, lw, loc)
+	d.Resume = true
+	lw.Reset()
+	file, err := d.Get(context.Background(), "id1", fname)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, fname+".mp3"), file, "continued from partial file")
+	assert.Equal(t, "--continue id1\n", lw.String())
+}
+
+func TestDownloader_GetURL(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := os.TempDir()
+	fh, err := os.CreateTemp(loc, "downloader_test*.mp3")
+	require.NoError(t, err)
+	defer os.Remove(fh.Name())
+	fname := strings.TrimSuffix(filepath.Base(fh.Name()), ".mp3")
+
+	d := NewDownloader("echo {{.URL}}", lw, lw, loc)
+	_, err = d.Get(context.Background(), "id1", fname)
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.youtube.com/watch?v=id1\n", lw.String())
+
+	lw.Reset()
+	_, err = d.Get(context.Background(), "https://framatube.org/videos/watch/9c9de5e8", fname)
+	require.NoError(t, err)
+	assert.Equal(t, "https://framatube.org/videos/watch/9c9de5e8\n", lw.String())
+}
+
+func TestDownloader_GetLimitRate(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := os.TempDir()
+	fh, err := os.CreateTemp(loc, "downloader_test*.mp3")
+	require.NoError(t, err)
+	defer os.Remove(fh.Name())
+	fname := strings.TrimSuffix(filepath.Base(fh.Name()), ".mp3")
+
+	d := NewDownloader("echo {{.ID}}", lw, lw, loc)
+	d.LimitRate = "2M"
+	_, err = d.Get(context.Background(), "id1", fname)
+	require.NoError(t, err)
+	assert.Equal(t, "--limit-rate 2M id1\n", lw.String())
+}
+
+func TestDownloader_GetOptsMaxSize(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("echo {{.ID}} 'File is larger than max-filesize (2000 bytes > 100 bytes). Aborting.'", lw, lw, loc)
+	_, err := d.GetOpts(context.Background(), "id1", "f1", GetOptions{MaxSize: 100})
+	require.Equal(t, ErrTooLarge, err)
+	assert.Equal(t, "--max-filesize 100 id1 File is larger than max-filesize (2000 bytes > 100 bytes). Aborting.\n", lw.String())
+
+	_, err = d.Get(context.Background(), "id1", "f1")
+	require.Equal(t, ErrSkip, err, "no size limit, not downloaded file is skipped")
+
+	lw.Reset()
+	d = NewDownloader("true {{.ID}}; touch {{.FileName}}.mp3", lw, lw, loc)
+	res, err := d.GetOpts(context.Background(), "id1", "f2", GetOptions{MaxSize: 100})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f2.mp3"), res)
+}
+
+func TestDownloader_GetOptsCopyAudio(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("echo {{.ID}} --audio-format=mp3 -o {{.FileName}}.tmp && touch {{.FileName}}.opus", lw, lw, loc)
+	res, err := d.GetOpts(context.Background(), "id1", "f1", GetOptions{CopyAudio: true})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f1.opus"), res)
+	assert.Equal(t, "id1 --audio-format best -o f1.tmp\n", lw.String())
+
+	d = NewDownloader("true {{.ID}}", lw, lw, loc)
+	_, err = d.GetOpts(context.Background(), "id1", "f2", GetOptions{CopyAudio: true})
+	assert.Equal(t, ErrSkip, err, "no audio file")
+}
+
+func TestWithCopyAudio(t *testing.T) {
+	tbl := []struct {
+		cmd, res string
+	}{
+		{"yt-dlp -x --audio-format=mp3 --audio-quality=0 URL", "yt-dlp -x --audio-format best --audio-quality=0 URL"},
+		{"yt-dlp -x --audio-format mp3 URL", "yt-dlp -x --audio-format best URL"},
+		{"yt-dlp -f bestaudio URL", "yt-dlp --extract-audio --audio-format best -f bestaudio URL"},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, withCopyAudio(tt.cmd))
+	}
+}
+
+func TestWithVideo(t *testing.T) {
+	const format = `-f "bv*[ext=mp4]+ba[ext=m4a]/b[ext=mp4]/b" --merge-output-format mp4`
+	tbl := []struct {
+		cmd, res string
+	}{
+		{`yt-dlp --extract-audio --audio-format=mp3 --audio-quality=0 -f m4a/bestaudio "URL" --no-progress -o f1.tmp`,
+			`yt-dlp ` + format + ` "URL" --no-progress -o f1.tmp`},
+		{"yt-dlp -x --audio-format mp3 --format bestaudio URL", "yt-dlp " + format + " URL"},
+		{"yt-dlp URL -f bestaudio", "yt-dlp " + format + " URL"},
+		{"yt-dlp URL", "yt-dlp " + format + " URL"},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, withVideo(tt.cmd))
+	}
+}
+
+func TestWithFormatFallbacks(t *testing.T) {
+	fallbacks := []string{"ba*", "best[height<=480]"}
+	tbl := []struct {
+		cmd, res string
+	}{
+		{`yt-dlp -x --audio-format=mp3 -f m4a/bestaudio "URL" --no-progress -o f1.tmp`,
+			`yt-dlp -x --audio-format=mp3 -f 'm4a/bestaudio/ba*/best[height<=480]' "URL" --no-progress -o f1.tmp`},
+		{`yt-dlp --format="bestaudio[ext=m4a]" URL`, `yt-dlp --format='bestaudio[ext=m4a]/ba*/best[height<=480]' URL`},
+		{`yt-dlp URL -f 'ba[abr<=128]'`, `yt-dlp URL -f 'ba[abr<=128]/ba*/best[height<=480]'`},
+		{"yt-dlp --format-sort abr URL", "yt-dlp -f 'bestaudio/best/ba*/best[height<=480]' --format-sort abr URL"},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, withFormatFallbacks(tt.cmd, fallbacks))
+	}
+	assert.Equal(t, "yt-dlp -f m4a URL", withFormatFallbacks("yt-dlp -f m4a URL", nil), "no fallbacks")
+}
+
+func TestDownloader_GetFormatFallbacks(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("echo {{.ID}} -f m4a && touch {{.FileName}}.mp3", lw, lw, loc)
+	d.FormatFallbacks = []string{"bestaudio", "best"}
+	_, err := d.Get(context.Background(), "id1", "f1")
+	require.NoError(t, err)
+	assert.Equal(t, "id1 -f m4a/bestaudio/best\n", lw.String(), "fallbacks appended, quotes removed by shell")
+
+	lw.Reset()
+	d = NewDownloader("echo {{.ID}} -f m4a/bestaudio && touch {{.FileName}}.mp4", lw, lw, loc)
+	d.FormatFallbacks = []string{"best"}
+	_, err = d.GetOpts(context.Background(), "id1", "f1", GetOptions{Video: true})
+	require.NoError(t, err)
+	assert.NotContains(t, lw.String(), "/best ", "not for video")
+
+	assert.True(t, IsValidFormat("best[height<=480]"))
+	assert.False(t, IsValidFormat(""))
+	assert.False(t, IsValidFormat("best audio"))
+	assert.False(t, IsValidFormat("best'"))
+}
+
+func TestDownloader_GetOptsVideo(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("echo {{.ID}} -x --audio-format=mp3 && touch {{.FileName}}.tmp.mp4", lw, lw, loc)
+	file, err := d.GetOpts(context.Background(), "id1", "f1", GetOptions{Video: true, CopyAudio: true})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f1.mp4"), file, "merged file renamed")
+	assert.FileExists(t, file)
+	assert.NoFileExists(t, filepath.Join(loc, "f1.tmp.mp4"))
+	assert.Equal(t, `-f bv*[ext=mp4]+ba[ext=m4a]/b[ext=mp4]/b --merge-output-format mp4 id1
+`, lw.String(), "audio options dropped")
+
+	d = NewDownloader("true {{.ID}}; touch {{.FileName}}.tmp", lw, lw, loc)
+	file, err = d.GetOpts(context.Background(), "id2", "f2", GetOptions{Video: true})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "f2.mp4"), file, "single file renamed")
+	assert.FileExists(t, file)
+
+	d = NewDownloader("true {{.ID}}", lw, lw, loc)
+	_, err = d.GetOpts(context.Background(), "id3", "f3", GetOptions{Video: true})
+	assert.Equal(t, ErrSkip, err, "nothing downloaded")
+}
+
+func TestDownloader_withLimitRate(t *testing.T) {
+	tbl := []struct {
+		rate, cmd, res string
+	}{
+		{"", "yt-dlp -x URL", "yt-dlp -x URL"},
+		{"500K", "yt-dlp -x URL", "yt-dlp --limit-rate 500K -x URL"},
+		{"1.5M", " yt-dlp\t-x URL ", "yt-dlp --limit-rate 1.5M\t-x URL"},
+		{"2M", "yt-dlp", "yt-dlp --limit-rate 2M"},
+	}
+	for _, tt := range tbl {
+		d := Downloader{LimitRate: tt.rate}
+		assert.Equal(t, tt.res, d.withLimitRate(tt.cmd))
+	}
+}
+
+func TestIsValidLimitRate(t *testing.T) {
+	for _, r := range []string{"", "1000", "500K", "500k", "2M", "1.5M", "1G"} {
+		assert.True(t, IsValidLimitRate(r), r)
+	}
+	for _, r := range []string{"2MB", "fast", "-1M", "1.M", "2M; rm -rf /", " 2M"} {
+		assert.False(t, IsValidLimitRate(r), r)
+	}
+}
+
 func TestDownloader_GetSkip(t *testing.T) {
 	lw := bytes.NewBuffer(nil)
 	loc := os.TempDir()
@@ -60,3 +322,108 @@ func TestDownloader_GetFailed(t *testing.T) {
 	require.EqualError(t, err, "skip")
 	assert.Equal(t, fh.Name(), res)
 }
+
+func TestDownloader_GetNotAvailable(t *testing.T) {
+	outWr, errWr := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	d := NewDownloader("echo 'ERROR: [youtube] {{.ID}}: Premieres in 2 hours' >&2; exit 1", outWr, errWr, t.TempDir())
+	_, err := d.Get(context.Background(), "id1", "f1")
+	require.Equal(t, ErrNotAvailable, err)
+	assert.Equal(t, "ERROR: [youtube] id1: Premieres in 2 hours\n", errWr.String(), "error output still logged")
+
+	d = NewDownloader("echo 'ERROR: [youtube] {{.ID}}: Video unavailable' >&2; exit 1", outWr, errWr, t.TempDir())
+	_, err = d.Get(context.Background(), "id1", "f1")
+	require.EqualError(t, err, "failed to execute command: exit status 1")
+}
+
+func TestDownloader_GetOptsMembersOnly(t *testing.T) {
+	lw := bytes.NewBuffer(nil)
+	loc := t.TempDir()
+	d := NewDownloader("echo {{.ID}} \"[download] title does not pass filter (availability=subscriber_only), skipping ..\"",
+		lw, lw, loc)
+	_, err := d.GetOpts(context.Background(), "id1", "f1", GetOptions{MatchFilter: "availability=subscriber_only"})
+	require.Equal(t, ErrFiltered, err)
+	assert.Equal(t, "--match-filter availability=subscriber_only id1 [download] title does not pass filter "+
+		"(availability=subscriber_only), skipping ..\n", lw.String(), "filter quoted as a single arg")
+
+	_, err = d.Get(context.Background(), "id1", "f1")
+	require.Equal(t, ErrSkip, err, "no filter")
+
+	d = NewDownloader("echo 'ERROR: [youtube] {{.ID}}: Join this channel to get access to members-only content' >&2; exit 1",
+		lw, lw, loc)
+	_, err = d.Get(context.Background(), "id1", "f1")
+	require.Equal(t, ErrMembersOnly, err)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'availability!=?subscriber_only'`, shellQuote("availability!=?subscriber_only"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestVersion(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "yt-dlp")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho 2022.04.08\n"), 0o700)) // nolint
+	res, err := Version(context.Background(), script)
+	require.NoError(t, err)
+	assert.Equal(t, "2022.04.08", res)
+
+	_, err = Version(context.Background(), "/bad/yt-dlp")
+	assert.Error(t, err)
+}
+
+func TestIsOutdated(t *testing.T) {
+	tbl := []struct {
+		ver, min string
+		res      bool
+	}{
+		{"2022.04.08", "", false},
+		{"2022.04.08", "2022.04.08", false},
+		{"2022.04.08", "2022.03.01", false},
+		{"2022.04.08", "2022.05.01", true},
+		{"2021.12.27", "2022.01.01", true},
+		{"2022.04.08.1", "2022.04.08", false},
+		{"2022.04", "2022.04.08", true},
+		{"blah", "2022.04.08", true},
+		{"2022.04.08", "bad", false},
+	}
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.res, IsOutdated(tt.ver, tt.min))
+		})
+	}
+}
+
+func TestDownloader_Subtitles(t *testing.T) {
+	loc := t.TempDir()
+	bin := filepath.Join(t.TempDir(), "fake-dl")
+	// fake downloader makes {fname}.{lang}.vtt for video "vid1", passed as: ... --sub-langs {lang} ... -o {fname} {url}
+	script := `#!/bin/sh
+lang=""; prev=""
+for a in "$@"; do [ "$prev" = "--sub-langs" ] && lang="$a"; prev="$a"; done
+while [ $# -gt 2 ]; do shift; done
+case "$2" in *vid1) echo "WEBVTT" > "$1.$lang.vtt";; esac
+`
+	require.NoError(t, os.WriteFile(bin, []byte(script), 0o700)) // nolint
+	lw := bytes.NewBuffer(nil)
+	d := NewDownloader(bin+" -f bestaudio {{.URL}} -o {{.FileName}}.tmp", lw, lw, loc)
+
+	res, err := d.Subtitles(context.Background(), "vid1", "file1", "en")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "file1.vtt"), res)
+	data, err := os.ReadFile(res)
+	require.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n", string(data))
+	_, err = os.Stat(filepath.Join(loc, "file1.en.vtt"))
+	assert.True(t, os.IsNotExist(err), "renamed")
+
+	_, err = d.Subtitles(context.Background(), "vid2", "file2", "en")
+	assert.Equal(t, ErrSkip, err, "no subtitles")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(loc, "chan1"), 0o750))
+	res, err = d.Subtitles(context.Background(), "vid1", filepath.Join("chan1", "file4"), "en")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(loc, "chan1", "file4.vtt"), res)
+
+	d = NewDownloader("false", lw, lw, loc)
+	_, err = d.Subtitles(context.Background(), "vid1", "file3", "en")
+	assert.Error(t, err)
+}
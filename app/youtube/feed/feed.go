@@ -8,6 +8,8 @@ import (
 	"html/template"
 	"net/http"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,6 +20,11 @@ type Feed struct {
 	Client          *http.Client
 	ChannelBaseURL  string
 	PlaylistBaseURL string
+	APIBaseURL      string // base url of youtube data api, used for playlists with api key. Default is googleapis.com
+	Source          Source // alternative api listing channels, i.e. Piped or Invidious. Youtube's rss if not set
+
+	mu         sync.Mutex
+	pageTokens map[string]string // page tokens of playlists listed with api, by "id:page"
 }
 
 // Type represents the type of YouTube feed.
@@ -28,11 +35,82 @@ const (
 	FTDefault  = Type("")
 	FTChannel  = Type("channel")
 	FTPlaylist = Type("playlist")
+	FTPeerTube = Type("peertube")
+	FTVideos   = Type("videos")  // channel's regular videos only, no shorts and streams
+	FTShorts   = Type("shorts")  // channel's shorts only
+	FTStreams  = Type("streams") // channel's past live streams only
+)
+
+// tabPlaylistPrefixes maps channel's tab types to prefixes of youtube's auto-generated playlists.
+// Such playlist id is the channel id with "UC" replaced by the prefix, i.e. UCxyz -> UULFxyz for videos tab.
+var tabPlaylistPrefixes = map[Type]string{FTVideos: "UULF", FTShorts: "UUSH", FTStreams: "UULV"}
+
+// uploadsPlaylistPrefix is the prefix of youtube's auto-generated playlist of all uploads of the channel
+const uploadsPlaylistPrefix = "UU"
+
+// IsTab returns true for types fetching a single tab of the channel
+func (t Type) IsTab() bool {
+	_, ok := tabPlaylistPrefixes[t]
+	return ok
+}
+
+// UnmarshalYAML parses feed type case-insensitively and rejects unknown types
+func (t *Type) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	res := Type(strings.ToLower(strings.TrimSpace(s)))
+	switch res {
+	case FTDefault, FTChannel, FTPlaylist, FTPeerTube, FTVideos, FTShorts, FTStreams:
+		*t = res
+		return nil
+	}
+	return errors.Errorf("unknown feed type %q", s)
+}
+
+// LiveStatus represents live state of the entry. Live and upcoming entries can't be downloaded yet.
+type LiveStatus string
+
+// enum for the live states
+const (
+	LSNone     = LiveStatus("")         // regular video, available for download
+	LSUpcoming = LiveStatus("upcoming") // scheduled live stream or premiere
+	LSLive     = LiveStatus("live")     // live stream in progress
 )
 
 // Get xml/rss feed for channel
 // https://www.youtube.com/feeds/videos.xml?channel_id=UCPU28A9z_ka_R5dQfecHJlA
-func (c *Feed) Get(ctx context.Context, id string, feedType Type) ([]Entry, error) {
+// PeerTube channels (FTPeerTube) are listed with PeerTube's api, id should be in name@instance.host form.
+// Tab types (FTVideos, FTShorts, FTStreams) get the channel's tab with youtube's auto-generated tab playlist.
+// Non-zero publishedAfter excludes entries published at or before it. Youtube's rss has no such parameter,
+// so the filtering is done on the client side.
+// Playlists (FTPlaylist) with apiKey are listed with youtube data api, this way unlisted playlists can be used.
+// All pages of such playlists are listed, sorted from the newest.
+func (c *Feed) Get(ctx context.Context, id string, feedType Type, publishedAfter time.Time, apiKey string) ([]Entry, error) {
+
+	if feedType == FTPeerTube {
+		pt := PeerTube{Client: c.Client}
+		return pt.Get(ctx, id, feedType, publishedAfter)
+	}
+	if feedType == FTPlaylist && apiKey != "" {
+		res, err := c.apiPlaylist(ctx, id, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return FilterPublishedAfter(res, publishedAfter), nil
+	}
+	// playlists listed by youtube itself, alternative sources don't report publish dates of playlist's videos
+	if src := c.source(ctx); src.Enabled() && feedType != FTPlaylist {
+		switch src.Type {
+		case STPiped:
+			pp := Piped{Client: c.Client, BaseURL: src.URL}
+			return pp.Get(ctx, id, feedType, publishedAfter)
+		case STInvidious:
+			iv := Invidious{Client: c.Client, BaseURL: src.URL}
+			return iv.Get(ctx, id, feedType, publishedAfter)
+		}
+	}
 
 	feedURL, err := c.url(id, feedType)
 	if err != nil {
@@ -43,11 +121,19 @@ func (c *Feed) Get(ctx context.Context, id string, feedType Type) ([]Entry, erro
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create request for %s", id)
 	}
+	setHeaders(ctx, req)
 	resp, err := c.Client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get channel %s", id)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && feedType == FTPlaylist {
+		return nil, errors.Wrapf(ErrAccessDenied, "playlist %s: %s, not public playlists can be listed with api_key only",
+			id, resp.Status)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Wrapf(ErrFeedNotFound, "feed %s: %s", id, resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, errors.Errorf("failed to get %s: %s", id, resp.Status)
 	}
@@ -68,7 +154,55 @@ func (c *Feed) Get(ctx context.Context, id string, feedType Type) ([]Entry, erro
 		data.Entry[i].ChannelID = id
 	}
 
-	return data.Entry, nil
+	return FilterPublishedAfter(data.Entry, publishedAfter), nil
+}
+
+// GetPage returns the page of channel's history, from the newest to the oldest. Page numbers start from 0,
+// empty result means no more pages. PeerTube channels are paginated through the whole history.
+// Youtube feeds with apiKey are paginated with youtube data api, 50 entries per page. Channels listed by the uploads
+// playlist and tabs by the tab's playlist. Youtube feeds without apiKey fail with ErrPagingNotSupported,
+// youtube's rss has the latest 15 entries only.
+func (c *Feed) GetPage(ctx context.Context, id string, feedType Type, page int, apiKey string) ([]Entry, error) {
+	if feedType == FTPeerTube {
+		pt := PeerTube{Client: c.Client}
+		return pt.GetPage(ctx, id, feedType, page)
+	}
+	if apiKey == "" {
+		return nil, errors.Wrapf(ErrPagingNotSupported, "%s %s: youtube rss has the latest 15 entries only, "+
+			"api_key needed to list the whole history", feedType, id)
+	}
+	playlistID := id
+	switch {
+	case feedType == FTPlaylist:
+	case feedType.IsTab() && strings.HasPrefix(id, "UC"):
+		playlistID = tabPlaylistPrefixes[feedType] + strings.TrimPrefix(id, "UC")
+	case (feedType == FTChannel || feedType == FTDefault) && strings.HasPrefix(id, "UC"):
+		playlistID = uploadsPlaylistPrefix + strings.TrimPrefix(id, "UC")
+	default:
+		return nil, errors.Errorf("invalid channel id %q for %s feed, should start with UC", id, feedType)
+	}
+	res, err := c.apiPlaylistPaged(ctx, playlistID, apiKey, page)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res {
+		res[i].ChannelID = id
+	}
+	return res, nil
+}
+
+// FilterPublishedAfter returns entries published after the given time. Zero publishedAfter means no filtering.
+func FilterPublishedAfter(entries []Entry, publishedAfter time.Time) []Entry {
+	if publishedAfter.IsZero() {
+		return entries
+	}
+	res := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.Published.After(publishedAfter) {
+			res = append(res, e)
+		}
+	}
+	return res
 }
 
 func (c *Feed) url(id string, feedType Type) (string, error) {
@@ -77,10 +211,35 @@ func (c *Feed) url(id string, feedType Type) (string, error) {
 		return c.ChannelBaseURL + id, nil
 	case FTPlaylist:
 		return c.PlaylistBaseURL + id, nil
+	case FTVideos, FTShorts, FTStreams:
+		if !strings.HasPrefix(id, "UC") {
+			return "", errors.Errorf("invalid channel id %q for %s feed, should start with UC", id, feedType)
+		}
+		return c.PlaylistBaseURL + tabPlaylistPrefixes[feedType] + strings.TrimPrefix(id, "UC"), nil
 	}
 	return "", errors.Errorf("unknown feed type %s", feedType)
 }
 
+// FetchError is the last failed fetch of the feed, kept till the next successful fetch
+type FetchError struct {
+	Error string    `json:"error"`
+	TS    time.Time `json:"ts"`
+}
+
+// FeedUpdate is a runtime change of the feed's settings, overriding configured values. Nil fields are not changed
+type FeedUpdate struct {
+	Keep     *int    `json:"keep,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Language *string `json:"language,omitempty"`
+	Paused   *bool   `json:"paused,omitempty"`
+}
+
+// Listing is the last fetched list of the feed's entries, cached to skip fetching on restart
+type Listing struct {
+	Entries []Entry   `json:"entries"`
+	TS      time.Time `json:"ts"`
+}
+
 // Entry represents a YouTube channel entry.
 type Entry struct {
 	ChannelID string `xml:"http://www.youtube.com/xml/schemas/2015 channelId"`
@@ -104,8 +263,25 @@ type Entry struct {
 		URI  string `xml:"uri"`
 	} `xml:"author"`
 
-	File     string
-	Duration int // seconds
+	File      string
+	Duration  int       // seconds
+	Checksum  string    // sha256 of the downloaded file, hex encoded
+	FileSize  int64     // size of the downloaded file, fallback for enclosure length if the file can't be checked
+	Language  string    // language of the entry if known, detected from the source or downloader's metadata
+	Subtitles string    // subtitles file (vtt) if downloaded
+	Chapters  []Chapter // chapters of the video, podcast chapters json stored next to the file
+
+	GUID string `xml:"-"` // rss item guid pinned by guids migration, UID is used if empty
+
+	LiveStatus LiveStatus `xml:"-"` // set by the channel listing if it carries live state, youtube's rss doesn't
+
+	Pinned bool `xml:"-"` // never removed by keep and max age limits, doesn't count in keep
+
+	Episode int `xml:"-"` // itunes episode number set on download for feeds numbered by counter, 0 if not numbered
+
+	// OriginalPublished is the upload time from the source. Published may be reset to the download time
+	// to keep the feed in order, this one is never changed
+	OriginalPublished time.Time `xml:"-"`
 }
 
 // UID returns the unique identifier of the entry.
@@ -113,6 +289,11 @@ func (e *Entry) UID() string {
 	return e.ChannelID + "::" + e.VideoID
 }
 
+// IsAvailable checks if the entry can be downloaded, i.e. it is not an upcoming or in-progress live stream
+func (e *Entry) IsAvailable() bool {
+	return e.LiveStatus == LSNone
+}
+
 func (e *Entry) String() string {
 	tz, _ := time.LoadLocation("Local")
 
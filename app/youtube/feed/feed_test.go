@@ -11,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestChannel_Get(t *testing.T) {
@@ -27,7 +28,7 @@ func TestChannel_Get(t *testing.T) {
 	c := Feed{Client: &http.Client{Timeout: time.Second},
 		ChannelBaseURL: ts.URL + "/blah?channel_id=", PlaylistBaseURL: ts.URL + "/blah?playlist_id="}
 
-	res, err := c.Get(context.Background(), "UCPU28A9z_ka_R5dQfecHJlA", FTChannel)
+	res, err := c.Get(context.Background(), "UCPU28A9z_ka_R5dQfecHJlA", FTChannel, time.Time{}, "")
 	require.NoError(t, err)
 	assert.Equal(t, 15, len(res))
 
@@ -54,6 +55,35 @@ func TestChannel_Get(t *testing.T) {
 	assert.Equal(t, `«Она показала пример». Константин Калачев — об антивоенной акции Овсянниковой в эфире Первого канала`, last.Title)
 	assert.Equal(t, "https://i3.ytimg.com/vi/zBwM0SU1vRk/hqdefault.jpg", last.Media.Thumbnail.URL)
 	assert.Contains(t, last.Media.Description, "за призыв к публичным несанкционированным акциям протеста")
+
+	res, err = c.Get(context.Background(), "UCPU28A9z_ka_R5dQfecHJlA", FTChannel, last.Published, "")
+	require.NoError(t, err)
+	assert.Equal(t, 14, len(res), "entry published at cutoff excluded")
+	for _, e := range res {
+		assert.True(t, e.Published.After(last.Published))
+	}
+
+	res, err = c.Get(context.Background(), "UCPU28A9z_ka_R5dQfecHJlA", FTChannel, first.Published, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(res), "all entries excluded")
+}
+
+func TestChannel_GetFailed(t *testing.T) {
+	status := http.StatusNotFound
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	c := Feed{Client: &http.Client{Timeout: time.Second}, ChannelBaseURL: ts.URL + "/blah?channel_id="}
+
+	_, err := c.Get(context.Background(), "bad-id", FTChannel, time.Time{}, "")
+	require.EqualError(t, err, "feed bad-id: 404 Not Found: feed not found")
+	assert.True(t, errors.Is(err, ErrFeedNotFound), "permanent error")
+
+	status = http.StatusInternalServerError
+	_, err = c.Get(context.Background(), "UCPU28A9z_ka_R5dQfecHJlA", FTChannel, time.Time{}, "")
+	require.EqualError(t, err, "failed to get UCPU28A9z_ka_R5dQfecHJlA: 500 Internal Server Error")
+	assert.False(t, errors.Is(err, ErrFeedNotFound), "transient error")
 }
 
 func TestFeed_url(t *testing.T) {
@@ -67,6 +97,10 @@ func TestFeed_url(t *testing.T) {
 		{"123", FTPlaylist, "https://www.youtube.com/feeds/videos.xml?playlist_id=123", nil},
 		{"blah", FTDefault, "https://www.youtube.com/feeds/videos.xml?channel_id=blah", nil},
 		{"foo", Type("xxxx"), "", errors.New("unknown feed type xxxx")},
+		{"UCabc", FTVideos, "https://www.youtube.com/feeds/videos.xml?playlist_id=UULFabc", nil},
+		{"UCdef", FTShorts, "https://www.youtube.com/feeds/videos.xml?playlist_id=UUSHdef", nil},
+		{"UCghi", FTStreams, "https://www.youtube.com/feeds/videos.xml?playlist_id=UULVghi", nil},
+		{"PLxyz", FTShorts, "", errors.New(`invalid channel id "PLxyz" for shorts feed, should start with UC`)},
 	}
 
 	c := Feed{
@@ -84,3 +118,24 @@ func TestFeed_url(t *testing.T) {
 		})
 	}
 }
+
+func TestType_UnmarshalYAML(t *testing.T) {
+	var res struct {
+		Types []Type `yaml:"types"`
+	}
+	err := yaml.Unmarshal([]byte(`types: [channel, Playlist, peertube, videos, SHORTS, " streams", ""]`), &res)
+	require.NoError(t, err)
+	assert.Equal(t, []Type{FTChannel, FTPlaylist, FTPeerTube, FTVideos, FTShorts, FTStreams, FTDefault}, res.Types)
+
+	err = yaml.Unmarshal([]byte(`types: [channel, reels]`), &res)
+	assert.EqualError(t, err, `unknown feed type "reels"`)
+}
+
+func TestType_IsTab(t *testing.T) {
+	assert.True(t, FTVideos.IsTab())
+	assert.True(t, FTShorts.IsTab())
+	assert.True(t, FTStreams.IsTab())
+	assert.False(t, FTChannel.IsTab())
+	assert.False(t, FTPlaylist.IsTab())
+	assert.False(t, FTDefault.IsTab())
+}
@@ -0,0 +1,47 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Headers are extra http headers of requests to the feed's source, i.e. auth header of a reverse proxy in front of
+// self-hosted Invidious or Piped instance
+type Headers map[string]string
+
+// String returns header names with masked values, safe for logs
+func (h Headers) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		res = append(res, name+": *****")
+	}
+	return strings.Join(res, ", ")
+}
+
+type headersKey struct{}
+
+// WithHeaders returns a copy of ctx with headers added to all requests of Get and GetPage made with this ctx
+func WithHeaders(ctx context.Context, h Headers) context.Context {
+	if len(h) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, headersKey{}, h)
+}
+
+// setHeaders adds headers of ctx, if any, to the request
+func setHeaders(ctx context.Context, req *http.Request) {
+	h, ok := ctx.Value(headersKey{}).(Headers)
+	if !ok {
+		return
+	}
+	for name, value := range h {
+		req.Header.Set(name, value)
+	}
+}
@@ -0,0 +1,44 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaders_String(t *testing.T) {
+	assert.Equal(t, "", Headers{}.String())
+	h := Headers{"X-Token": "secret", "Authorization": "Bearer secret"}
+	assert.Equal(t, "Authorization: *****, X-Token: *****", h.String())
+	assert.NotContains(t, fmt.Sprintf("%v", h), "secret", "masked in logs")
+}
+
+func TestFeed_GetWithHeaders(t *testing.T) {
+	feedXML, err := os.ReadFile("testdata/channel.xml")
+	require.NoError(t, err)
+	var auth []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		_, e := w.Write(feedXML)
+		require.NoError(t, e)
+	}))
+	defer ts.Close()
+	c := Feed{Client: &http.Client{Timeout: time.Second}, ChannelBaseURL: ts.URL + "/blah?channel_id="}
+
+	ctx := WithHeaders(context.Background(), Headers{"Authorization": "Bearer token1"})
+	res, err := c.Get(ctx, "UCPU28A9z_ka_R5dQfecHJlA", FTChannel, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Equal(t, 15, len(res))
+
+	_, err = c.Get(context.Background(), "UCPU28A9z_ka_R5dQfecHJlA", FTChannel, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token1", ""}, auth)
+	assert.Equal(t, context.Background(), WithHeaders(context.Background(), nil), "no headers")
+}
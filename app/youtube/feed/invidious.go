@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Invidious lists videos of youtube channel with Invidious api
+type Invidious struct {
+	Client  *http.Client
+	BaseURL string // base url of Invidious instance, i.e. https://invidious.example.com
+}
+
+// invidiousTabs maps feed types to tabs of Invidious channel api
+var invidiousTabs = map[Type]string{FTDefault: "videos", FTChannel: "videos", FTVideos: "videos", FTShorts: "shorts",
+	FTStreams: "streams"}
+
+// Get videos of the channel's tab, sorted from the newest to the oldest
+// https://invidious.example.com/api/v1/channels/UCPU28A9z_ka_R5dQfecHJlA/videos
+// Non-zero publishedAfter excludes entries published at or before it.
+func (p *Invidious) Get(ctx context.Context, id string, feedType Type, publishedAfter time.Time) ([]Entry, error) {
+	tab, ok := invidiousTabs[feedType]
+	if !ok {
+		return nil, errors.Errorf("feed type %s is not supported by invidious source", feedType)
+	}
+	reqURL := fmt.Sprintf("%s/api/v1/channels/%s/%s", strings.TrimSuffix(p.BaseURL, "/"), url.PathEscape(id), tab)
+	var raw json.RawMessage
+	if err := getJSON(ctx, p.Client, id, reqURL, &raw); err != nil {
+		return nil, err
+	}
+
+	type video struct {
+		VideoID         string `json:"videoId"`
+		Title           string `json:"title"`
+		Description     string `json:"description"`
+		Author          string `json:"author"`
+		AuthorID        string `json:"authorId"`
+		Published       int64  `json:"published"` // unix time
+		LiveNow         bool   `json:"liveNow"`
+		IsUpcoming      bool   `json:"isUpcoming"`
+		VideoThumbnails []struct {
+			Quality string `json:"quality"`
+			URL     string `json:"url"`
+		} `json:"videoThumbnails"`
+	}
+	data := struct {
+		Videos []video `json:"videos"`
+	}{}
+	// newer versions return videos with continuation token, older ones the list of videos
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		if err := json.Unmarshal(raw, &data.Videos); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s", id)
+		}
+	} else if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", id)
+	}
+
+	res := make([]Entry, 0, len(data.Videos))
+	for _, v := range data.Videos {
+		published := time.Unix(v.Published, 0).UTC()
+		e := Entry{ChannelID: id, VideoID: v.VideoID, Title: v.Title, Published: published, Updated: published}
+		e.Link.Href = ytWatchURL + v.VideoID
+		e.Media.Description = template.HTML(v.Description) // nolint
+		for _, th := range v.VideoThumbnails {
+			if th.Quality == "high" || e.Media.Thumbnail.URL == "" {
+				e.Media.Thumbnail.URL = th.URL
+			}
+		}
+		e.Author.Name = v.Author
+		e.Author.URI = "https://www.youtube.com/channel/" + v.AuthorID
+		switch {
+		case v.IsUpcoming:
+			e.LiveStatus = LSUpcoming
+		case v.LiveNow:
+			e.LiveStatus = LSLive
+		}
+		res = append(res, e)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Published.After(res[j].Published)
+	})
+	return FilterPublishedAfter(res, publishedAfter), nil
+}
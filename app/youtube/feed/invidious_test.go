@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvidious_Get(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/api/v1/channels/UCold/videos" { // older versions return the list of videos
+			_, _ = w.Write([]byte(`[{"videoId": "vid0", "title": "old", "published": 1648800000}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"videos": [
+			{"videoId": "vid1", "title": "title1", "description": "desc1", "author": "author1", "authorId": "UCxyz",
+				"published": 1648800000, "videoThumbnails": [{"quality": "maxres", "url": "https://i.ytimg.com/vi/vid1/max.jpg"},
+				{"quality": "high", "url": "https://i.ytimg.com/vi/vid1/hq.jpg"}]},
+			{"videoId": "vid2", "title": "title2", "published": 1648900000, "isUpcoming": true},
+			{"videoId": "vid3", "title": "title3", "published": 1648700000, "liveNow": true}
+		], "continuation": "token"}`))
+	}))
+	defer ts.Close()
+
+	iv := Invidious{Client: &http.Client{Timeout: time.Second}, BaseURL: ts.URL + "/"}
+	res, err := iv.Get(context.Background(), "UCxyz", FTChannel, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+	assert.Equal(t, "vid2", res[0].VideoID, "sorted from the newest")
+	assert.Equal(t, LSUpcoming, res[0].LiveStatus)
+	assert.Equal(t, LSLive, res[2].LiveStatus)
+
+	e := res[1]
+	assert.Equal(t, "UCxyz", e.ChannelID)
+	assert.Equal(t, "vid1", e.VideoID)
+	assert.Equal(t, "title1", e.Title)
+	assert.Equal(t, "desc1", string(e.Media.Description))
+	assert.Equal(t, "https://www.youtube.com/watch?v=vid1", e.Link.Href)
+	assert.Equal(t, "https://i.ytimg.com/vi/vid1/hq.jpg", e.Media.Thumbnail.URL)
+	assert.Equal(t, "author1", e.Author.Name)
+	assert.Equal(t, "https://www.youtube.com/channel/UCxyz", e.Author.URI)
+	assert.Equal(t, time.Date(2022, 4, 1, 8, 0, 0, 0, time.UTC), e.Published)
+
+	res, err = iv.Get(context.Background(), "UCxyz", FTShorts, e.Published)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(res), "published after filter")
+
+	res, err = iv.Get(context.Background(), "UCold", FTVideos, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid0", res[0].VideoID)
+	assert.Equal(t, []string{"/api/v1/channels/UCxyz/videos", "/api/v1/channels/UCxyz/shorts", "/api/v1/channels/UCold/videos"},
+		paths)
+
+	_, err = iv.Get(context.Background(), "PLxyz", FTPlaylist, time.Time{})
+	assert.EqualError(t, err, "feed type playlist is not supported by invidious source")
+}
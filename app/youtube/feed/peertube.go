@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PeerTube lists videos of a PeerTube channel. Channel id is a federated handle, i.e. name@instance.host
+type PeerTube struct {
+	Client  *http.Client
+	BaseURL string // optional, overrides https://{instance.host} from the channel handle
+	Count   int    // number of the latest videos to get, default 15 (same as youtube's rss)
+}
+
+// Get videos of PeerTube channel, sorted from the newest to the oldest
+// https://framatube.org/api/v1/video-channels/joinpeertube@framatube.org/videos?sort=-publishedAt&count=15
+// Non-zero publishedAfter excludes entries published at or before it.
+func (p *PeerTube) Get(ctx context.Context, id string, _ Type, publishedAfter time.Time) ([]Entry, error) {
+	res, err := p.get(ctx, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return FilterPublishedAfter(res, publishedAfter), nil
+}
+
+// GetPage returns the page of channel's videos, from the newest to the oldest. Page numbers start from 0,
+// empty result means no more pages.
+func (p *PeerTube) GetPage(ctx context.Context, id string, _ Type, page int) ([]Entry, error) {
+	return p.get(ctx, id, page*p.count())
+}
+
+func (p *PeerTube) get(ctx context.Context, id string, start int) ([]Entry, error) {
+	baseURL, err := p.baseURL(id)
+	if err != nil {
+		return nil, err
+	}
+	reqURL := fmt.Sprintf("%s/api/v1/video-channels/%s/videos?sort=-publishedAt&count=%d", baseURL, url.PathEscape(id), p.count())
+	if start > 0 {
+		reqURL += fmt.Sprintf("&start=%d", start)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request for %s", id)
+	}
+	setHeaders(ctx, req)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get peertube channel %s", id)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Wrapf(ErrFeedNotFound, "peertube channel %s: %s", id, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get %s: %s", id, resp.Status)
+	}
+
+	data := struct {
+		Data []struct {
+			UUID          string    `json:"uuid"`
+			URL           string    `json:"url"`
+			Name          string    `json:"name"`
+			Description   string    `json:"description"`
+			ThumbnailPath string    `json:"thumbnailPath"`
+			PublishedAt   time.Time `json:"publishedAt"`
+			IsLive        bool      `json:"isLive"`
+			State         struct {
+				ID int `json:"id"`
+			} `json:"state"`
+			Language struct {
+				ID string `json:"id"`
+			} `json:"language"`
+			Channel struct {
+				DisplayName string `json:"displayName"`
+				URL         string `json:"url"`
+			} `json:"channel"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", id)
+	}
+
+	res := make([]Entry, 0, len(data.Data))
+	for _, v := range data.Data {
+		e := Entry{ChannelID: id, VideoID: v.UUID, Title: v.Name, Published: v.PublishedAt, Updated: v.PublishedAt}
+		e.Link.Href = v.URL
+		e.Media.Description = template.HTML(v.Description) // nolint
+		if v.ThumbnailPath != "" {
+			e.Media.Thumbnail.URL = baseURL + v.ThumbnailPath
+		}
+		e.Author.Name = v.Channel.DisplayName
+		e.Author.URI = v.Channel.URL
+		e.Language = v.Language.ID
+		if v.IsLive {
+			e.LiveStatus = LSLive
+			if v.State.ID == ptStateWaitingForLive {
+				e.LiveStatus = LSUpcoming
+			}
+		}
+		res = append(res, e)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Published.After(res[j].Published)
+	})
+	return res, nil
+}
+
+// ptStateWaitingForLive is PeerTube's video state of the scheduled, not started yet live
+const ptStateWaitingForLive = 4
+
+func (p *PeerTube) count() int {
+	if p.Count <= 0 {
+		return 15
+	}
+	return p.Count
+}
+
+func (p *PeerTube) baseURL(id string) (string, error) {
+	if p.BaseURL != "" {
+		return strings.TrimSuffix(p.BaseURL, "/"), nil
+	}
+	elems := strings.Split(id, "@")
+	if len(elems) != 2 || elems[0] == "" || elems[1] == "" {
+		return "", errors.Errorf("invalid peertube channel %q, should be name@instance.host", id)
+	}
+	return "https://" + elems[1], nil
+}
@@ -0,0 +1,119 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerTube_Get(t *testing.T) {
+	data, err := os.ReadFile("testdata/peertube.json")
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Logf("req: %v", r.URL.String())
+		require.Equal(t, "/api/v1/video-channels/joinpeertube@framatube.org/videos?sort=-publishedAt&count=15", r.URL.String())
+		_, e := w.Write(data)
+		require.NoError(t, e)
+	}))
+	defer ts.Close()
+
+	p := PeerTube{Client: &http.Client{Timeout: time.Second}, BaseURL: ts.URL}
+	res, err := p.Get(context.Background(), "joinpeertube@framatube.org", FTPeerTube, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+
+	first := res[0]
+	assert.Equal(t, "joinpeertube@framatube.org", first.ChannelID)
+	assert.Equal(t, "ed96bb6c-1b7e-4f1d-ab60-0e3e2a4e5a3f", first.VideoID)
+	assert.Equal(t, "PeerTube v5: the result of 5 years' handcraft", first.Title)
+	assert.Equal(t, "2022-12-13T10:01:19Z", first.Published.Format(time.RFC3339))
+	assert.Equal(t, "https://framatube.org/videos/watch/ed96bb6c-1b7e-4f1d-ab60-0e3e2a4e5a3f", first.Link.Href)
+	assert.Equal(t, "https://framatube.org/video-channels/joinpeertube", first.Author.URI)
+	assert.Equal(t, "A propos de PeerTube", first.Author.Name)
+	assert.Equal(t, ts.URL+"/static/thumbnails/ed96bb6c-1b7e-4f1d-ab60-0e3e2a4e5a3f.jpg", first.Media.Thumbnail.URL)
+	assert.Equal(t, "Here is PeerTube v5!", string(first.Media.Description))
+
+	assert.Equal(t, "217eefeb-883d-45be-b7fc-a788ad8507d3", res[1].VideoID)
+	assert.Equal(t, "9c9de5e8-0a1e-484a-b099-e80766180a6d", res[2].VideoID)
+
+	res, err = p.Get(context.Background(), "joinpeertube@framatube.org", FTPeerTube, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res), "old entry excluded")
+	assert.Equal(t, "ed96bb6c-1b7e-4f1d-ab60-0e3e2a4e5a3f", res[0].VideoID)
+	assert.Equal(t, "217eefeb-883d-45be-b7fc-a788ad8507d3", res[1].VideoID)
+}
+
+func TestPeerTube_GetFailed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	p := PeerTube{Client: &http.Client{Timeout: time.Second}, BaseURL: ts.URL}
+	_, err := p.Get(context.Background(), "joinpeertube@framatube.org", FTPeerTube, time.Time{})
+	require.EqualError(t, err, "peertube channel joinpeertube@framatube.org: 404 Not Found: feed not found")
+	assert.True(t, errors.Is(err, ErrFeedNotFound))
+
+	p = PeerTube{Client: &http.Client{Timeout: time.Second}}
+	_, err = p.Get(context.Background(), "joinpeertube", FTPeerTube, time.Time{})
+	require.EqualError(t, err, `invalid peertube channel "joinpeertube", should be name@instance.host`)
+}
+
+func TestPeerTube_GetPage(t *testing.T) {
+	data, err := os.ReadFile("testdata/peertube.json")
+	require.NoError(t, err)
+
+	var reqs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r.URL.String())
+		_, e := w.Write(data)
+		require.NoError(t, e)
+	}))
+	defer ts.Close()
+
+	p := PeerTube{Client: &http.Client{Timeout: time.Second}, BaseURL: ts.URL, Count: 3}
+	res, err := p.GetPage(context.Background(), "joinpeertube@framatube.org", FTPeerTube, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+	_, err = p.GetPage(context.Background(), "joinpeertube@framatube.org", FTPeerTube, 2)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"/api/v1/video-channels/joinpeertube@framatube.org/videos?sort=-publishedAt&count=3",
+		"/api/v1/video-channels/joinpeertube@framatube.org/videos?sort=-publishedAt&count=3&start=6",
+	}, reqs)
+}
+
+func TestPeerTube_GetLive(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, e := w.Write([]byte(`{"total": 3, "data": [
+			{"uuid": "vid3", "name": "upcoming", "isLive": true, "state": {"id": 4}, "publishedAt": "2022-12-13T10:01:19Z"},
+			{"uuid": "vid2", "name": "live", "isLive": true, "state": {"id": 1}, "publishedAt": "2022-12-12T10:01:19Z"},
+			{"uuid": "vid1", "name": "regular", "isLive": false, "state": {"id": 1}, "publishedAt": "2022-12-11T10:01:19Z",
+				"language": {"id": "fr", "label": "French"}}
+		]}`))
+		require.NoError(t, e)
+	}))
+	defer ts.Close()
+
+	p := PeerTube{Client: &http.Client{Timeout: time.Second}, BaseURL: ts.URL}
+	res, err := p.Get(context.Background(), "joinpeertube@framatube.org", FTPeerTube, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+	assert.Equal(t, LSUpcoming, res[0].LiveStatus)
+	assert.False(t, res[0].IsAvailable())
+	assert.Equal(t, LSLive, res[1].LiveStatus)
+	assert.False(t, res[1].IsAvailable())
+	assert.Equal(t, LSNone, res[2].LiveStatus)
+	assert.True(t, res[2].IsAvailable())
+	assert.Equal(t, "fr", res[2].Language)
+	assert.Equal(t, "", res[1].Language)
+}
@@ -0,0 +1,98 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Piped lists videos of youtube channel with Piped api
+type Piped struct {
+	Client  *http.Client
+	BaseURL string // base url of Piped api, i.e. https://pipedapi.example.com
+}
+
+// pipedTabs maps feed types to names of Piped channel tabs, the main channel listing is the videos tab
+var pipedTabs = map[Type]string{FTShorts: "shorts", FTStreams: "livestreams"}
+
+// pipedStream is a video of Piped channel listing
+type pipedStream struct {
+	URL              string `json:"url"` // i.e. /watch?v=ID
+	Title            string `json:"title"`
+	Thumbnail        string `json:"thumbnail"`
+	UploaderName     string `json:"uploaderName"`
+	UploaderURL      string `json:"uploaderUrl"` // i.e. /channel/UC...
+	Uploaded         int64  `json:"uploaded"`    // unix time in milliseconds
+	ShortDescription string `json:"shortDescription"`
+	Duration         int    `json:"duration"` // seconds, -1 for live streams
+}
+
+// Get videos of the channel or its tab, sorted from the newest to the oldest
+// https://pipedapi.example.com/channel/UCPU28A9z_ka_R5dQfecHJlA
+// Non-zero publishedAfter excludes entries published at or before it.
+func (p *Piped) Get(ctx context.Context, id string, feedType Type, publishedAfter time.Time) ([]Entry, error) {
+	if feedType != FTDefault && feedType != FTChannel && feedType != FTVideos && pipedTabs[feedType] == "" {
+		return nil, errors.Errorf("feed type %s is not supported by piped source", feedType)
+	}
+	baseURL := strings.TrimSuffix(p.BaseURL, "/")
+	channel := struct {
+		RelatedStreams []pipedStream `json:"relatedStreams"`
+		Tabs           []struct {
+			Name string `json:"name"`
+			Data string `json:"data"`
+		} `json:"tabs"`
+	}{}
+	if err := getJSON(ctx, p.Client, id, fmt.Sprintf("%s/channel/%s", baseURL, url.PathEscape(id)), &channel); err != nil {
+		return nil, err
+	}
+
+	streams := channel.RelatedStreams
+	if tabName, ok := pipedTabs[feedType]; ok {
+		streams = nil
+		for _, tab := range channel.Tabs {
+			if tab.Name != tabName {
+				continue
+			}
+			tabData := struct {
+				Content []pipedStream `json:"content"`
+			}{}
+			reqURL := fmt.Sprintf("%s/channels/tabs?data=%s", baseURL, url.QueryEscape(tab.Data))
+			if err := getJSON(ctx, p.Client, id, reqURL, &tabData); err != nil {
+				return nil, err
+			}
+			streams = tabData.Content
+			break
+		}
+	}
+
+	res := make([]Entry, 0, len(streams))
+	for _, v := range streams {
+		videoID := strings.TrimPrefix(v.URL, "/watch?v=")
+		if videoID == "" || videoID == v.URL {
+			continue // not a video, i.e. playlist or channel
+		}
+		published := time.UnixMilli(v.Uploaded).UTC()
+		e := Entry{ChannelID: id, VideoID: videoID, Title: v.Title, Published: published, Updated: published}
+		e.Link.Href = ytWatchURL + videoID
+		e.Media.Description = template.HTML(v.ShortDescription) // nolint
+		e.Media.Thumbnail.URL = v.Thumbnail
+		e.Author.Name = v.UploaderName
+		e.Author.URI = "https://www.youtube.com" + v.UploaderURL
+		if v.Duration < 0 {
+			e.LiveStatus = LSLive
+		}
+		res = append(res, e)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Published.After(res[j].Published)
+	})
+	return FilterPublishedAfter(res, publishedAfter), nil
+}
@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPiped_Get(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/channel/UCxyz":
+			_, _ = w.Write([]byte(`{"id": "UCxyz", "name": "author1", "relatedStreams": [
+				{"url": "/watch?v=vid1", "title": "title1", "thumbnail": "https://pipedproxy.example.com/vi/vid1/hq.jpg",
+					"uploaderName": "author1", "uploaderUrl": "/channel/UCxyz", "uploaded": 1648800000000,
+					"shortDescription": "desc1", "duration": 300},
+				{"url": "/watch?v=vid2", "title": "title2", "uploaded": 1648900000000, "duration": -1},
+				{"url": "/playlist?list=PL1", "title": "playlist", "uploaded": 1648950000000}
+			], "tabs": [{"name": "shorts", "data": "{\"id\":\"UCxyz\",\"tab\":\"shorts\"}"}]}`))
+		case "/channels/tabs":
+			assert.Equal(t, `{"id":"UCxyz","tab":"shorts"}`, r.URL.Query().Get("data"))
+			_, _ = w.Write([]byte(`{"content": [{"url": "/watch?v=short1", "title": "short", "uploaded": 1648800000000}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	pp := Piped{Client: &http.Client{Timeout: time.Second}, BaseURL: ts.URL}
+	res, err := pp.Get(context.Background(), "UCxyz", FTChannel, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res), "playlist skipped")
+	assert.Equal(t, "vid2", res[0].VideoID)
+	assert.Equal(t, LSLive, res[0].LiveStatus, "negative duration is live stream")
+
+	e := res[1]
+	assert.Equal(t, "UCxyz", e.ChannelID)
+	assert.Equal(t, "vid1", e.VideoID)
+	assert.Equal(t, "title1", e.Title)
+	assert.Equal(t, "desc1", string(e.Media.Description))
+	assert.Equal(t, "https://www.youtube.com/watch?v=vid1", e.Link.Href)
+	assert.Equal(t, "https://pipedproxy.example.com/vi/vid1/hq.jpg", e.Media.Thumbnail.URL)
+	assert.Equal(t, "https://www.youtube.com/channel/UCxyz", e.Author.URI)
+	assert.Equal(t, time.Date(2022, 4, 1, 8, 0, 0, 0, time.UTC), e.Published)
+	assert.Equal(t, LSNone, e.LiveStatus)
+
+	res, err = pp.Get(context.Background(), "UCxyz", FTShorts, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "short1", res[0].VideoID)
+
+	res, err = pp.Get(context.Background(), "UCxyz", FTStreams, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, res, "no livestreams tab")
+
+	_, err = pp.Get(context.Background(), "UCunknown", FTChannel, time.Time{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrFeedNotFound)
+}
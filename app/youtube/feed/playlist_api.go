@@ -0,0 +1,207 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAccessDenied is returned when the source refuses access to the feed, i.e. private playlist or invalid api key
+var ErrAccessDenied = errors.New("access denied")
+
+// ErrPagingNotSupported is returned by GetPage for feeds without paginated listing, i.e. youtube's rss
+var ErrPagingNotSupported = errors.New("paging not supported")
+
+// ErrFeedNotFound is returned when the source has no such feed, i.e. wrong channel id
+var ErrFeedNotFound = errors.New("feed not found")
+
+// ytAPIBaseURL is the base url of youtube data api v3
+const ytAPIBaseURL = "https://www.googleapis.com/youtube/v3"
+
+// apiPageSize is the max number of playlist items per request allowed by youtube data api
+const apiPageSize = 50
+
+// apiPlaylist lists the whole playlist with youtube data api, sorted from the newest. Unlike rss, the api lists
+// unlisted playlists with a plain api key. Private playlists need oauth and refused with ErrAccessDenied, as well as
+// requests with invalid key. Items are listed in the playlist's order, so all pages are needed to get the newest
+// items, i.e. appended to the end. Each page costs one unit of api quota, playlists are limited to 5000 items by
+// youtube, i.e. 100 pages at most.
+func (c *Feed) apiPlaylist(ctx context.Context, id, apiKey string) ([]Entry, error) {
+	res := []Entry{}
+	pageToken := ""
+	for page := 0; ; page++ {
+		resp, err := c.apiPlaylistPage(ctx, id, c.apiPlaylistURL(id, apiKey, pageToken))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, resp.entries(id)...)
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+		c.setPageToken(id, page+1, pageToken)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Published.After(res[j].Published)
+	})
+	return res, nil
+}
+
+// apiPlaylistPaged lists a page of the playlist with youtube data api. Page numbers start from 0, each page has up
+// to 50 items in the playlist's order, from the newest to the oldest within the page. Page tokens returned by
+// the api kept, so the listing continues from the last known page instead of walking all previous pages again.
+func (c *Feed) apiPlaylistPaged(ctx context.Context, id, apiKey string, page int) ([]Entry, error) {
+	start, pageToken := c.pageToken(id, page)
+	for i := start; ; i++ {
+		resp, err := c.apiPlaylistPage(ctx, id, c.apiPlaylistURL(id, apiKey, pageToken))
+		if err != nil {
+			return nil, err
+		}
+		if resp.NextPageToken != "" {
+			c.setPageToken(id, i+1, resp.NextPageToken)
+		}
+		if i == page {
+			return resp.entries(id), nil
+		}
+		if resp.NextPageToken == "" {
+			return nil, nil // no more pages
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// pageToken returns the closest known page to the requested one and its token, page 0 has no token
+func (c *Feed) pageToken(id string, page int) (int, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := page; p > 0; p-- {
+		if token, ok := c.pageTokens[fmt.Sprintf("%s:%d", id, p)]; ok {
+			return p, token
+		}
+	}
+	return 0, ""
+}
+
+func (c *Feed) setPageToken(id string, page int, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pageTokens == nil {
+		c.pageTokens = map[string]string{}
+	}
+	c.pageTokens[fmt.Sprintf("%s:%d", id, page)] = token
+}
+
+func (c *Feed) apiPlaylistURL(id, apiKey, pageToken string) string {
+	baseURL := c.APIBaseURL
+	if baseURL == "" {
+		baseURL = ytAPIBaseURL
+	}
+	params := url.Values{}
+	params.Set("part", "snippet,contentDetails,status")
+	params.Set("maxResults", strconv.Itoa(apiPageSize))
+	params.Set("playlistId", id)
+	params.Set("key", apiKey)
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
+	return baseURL + "/playlistItems?" + params.Encode()
+}
+
+func (c *Feed) apiPlaylistPage(ctx context.Context, id, reqURL string) (*apiPlaylistResp, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request for %s", id)
+	}
+	setHeaders(ctx, req)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get playlist %s", id)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return nil, errors.Wrapf(ErrAccessDenied, "playlist %s: %s %s, only public and unlisted playlists can be "+
+				"listed with api key, check the key and the playlist's privacy", id, resp.Status, apiErr.Error.Message)
+		}
+		return nil, errors.Errorf("failed to get %s: %s %s", id, resp.Status, apiErr.Error.Message)
+	}
+
+	res := apiPlaylistResp{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", id)
+	}
+	return &res, nil
+}
+
+// apiPlaylistResp is a page of playlistItems response of youtube data api
+type apiPlaylistResp struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []struct {
+		Snippet struct {
+			PublishedAt            time.Time `json:"publishedAt"`
+			Title                  string    `json:"title"`
+			Description            string    `json:"description"`
+			VideoOwnerChannelTitle string    `json:"videoOwnerChannelTitle"`
+			VideoOwnerChannelID    string    `json:"videoOwnerChannelId"`
+			Thumbnails             map[string]struct {
+				URL string `json:"url"`
+			} `json:"thumbnails"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			VideoID          string    `json:"videoId"`
+			VideoPublishedAt time.Time `json:"videoPublishedAt"`
+		} `json:"contentDetails"`
+		Status struct {
+			PrivacyStatus string `json:"privacyStatus"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// entries converts playlist items to entries, skipping private and deleted videos, sorted from the newest
+func (r *apiPlaylistResp) entries(id string) []Entry {
+	res := make([]Entry, 0, len(r.Items))
+	for _, item := range r.Items {
+		if item.Status.PrivacyStatus == "private" || item.Status.PrivacyStatus == "privacyStatusUnspecified" {
+			continue // private or deleted video of the playlist, can't be downloaded
+		}
+		published := item.ContentDetails.VideoPublishedAt
+		if published.IsZero() {
+			published = item.Snippet.PublishedAt
+		}
+		e := Entry{ChannelID: id, VideoID: item.ContentDetails.VideoID, Title: item.Snippet.Title,
+			Published: published, Updated: published}
+		e.Link.Href = ytWatchURL + item.ContentDetails.VideoID
+		e.Media.Description = template.HTML(item.Snippet.Description) // nolint
+		for _, size := range []string{"high", "medium", "default"} {
+			if thumb, ok := item.Snippet.Thumbnails[size]; ok {
+				e.Media.Thumbnail.URL = thumb.URL
+				break
+			}
+		}
+		e.Author.Name = item.Snippet.VideoOwnerChannelTitle
+		if item.Snippet.VideoOwnerChannelID != "" {
+			e.Author.URI = "https://www.youtube.com/channel/" + item.Snippet.VideoOwnerChannelID
+		}
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Published.After(res[j].Published)
+	})
+	return res
+}
@@ -0,0 +1,147 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiPlaylistPage1 = `{"nextPageToken": "tok2", "items": [
+ {"snippet": {"publishedAt": "2023-01-10T10:00:00Z", "title": "video 1", "description": "desc 1",
+   "videoOwnerChannelTitle": "owner", "videoOwnerChannelId": "UCowner",
+   "thumbnails": {"default": {"url": "https://i.ytimg.com/vi/vid1/default.jpg"}, "high": {"url": "https://i.ytimg.com/vi/vid1/hq.jpg"}}},
+  "contentDetails": {"videoId": "vid1", "videoPublishedAt": "2022-12-01T10:00:00Z"}, "status": {"privacyStatus": "unlisted"}},
+ {"snippet": {"publishedAt": "2023-01-11T10:00:00Z", "title": "Private video"},
+  "contentDetails": {"videoId": "vid2"}, "status": {"privacyStatus": "private"}},
+ {"snippet": {"publishedAt": "2023-01-12T10:00:00Z", "title": "video 3"},
+  "contentDetails": {"videoId": "vid3", "videoPublishedAt": "2023-01-05T10:00:00Z"}, "status": {"privacyStatus": "public"}}
+]}`
+
+const apiPlaylistPage2 = `{"items": [
+ {"snippet": {"publishedAt": "2023-01-13T10:00:00Z", "title": "video 4"},
+  "contentDetails": {"videoId": "vid4"}, "status": {"privacyStatus": "public"}}
+]}`
+
+func TestFeed_GetPlaylistAPI(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "/playlistItems", r.URL.Path)
+		assert.Equal(t, "PL123", r.URL.Query().Get("playlistId"))
+		assert.Equal(t, "50", r.URL.Query().Get("maxResults"))
+		switch {
+		case r.URL.Query().Get("key") != "key1":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "API key not valid"}}`))
+		case r.URL.Query().Get("pageToken") == "tok2":
+			_, _ = w.Write([]byte(apiPlaylistPage2))
+		default:
+			_, _ = w.Write([]byte(apiPlaylistPage1))
+		}
+	}))
+	defer ts.Close()
+
+	c := Feed{Client: &http.Client{Timeout: time.Second}, PlaylistBaseURL: "http://localhost:1/never", APIBaseURL: ts.URL}
+	res, err := c.Get(context.Background(), "PL123", FTPlaylist, time.Time{}, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests), "all pages listed")
+	require.Equal(t, 3, len(res), "private video skipped")
+	assert.Equal(t, "vid4", res[0].VideoID, "newest item from the last page")
+	assert.Equal(t, "2023-01-13T10:00:00Z", res[0].Published.Format(time.RFC3339), "item's time if no video's time")
+	assert.Equal(t, "vid3", res[1].VideoID, "sorted by video's published time")
+	assert.Equal(t, "vid1", res[2].VideoID)
+	assert.Equal(t, "PL123", res[2].ChannelID)
+	assert.Equal(t, "video 1", res[2].Title)
+	assert.Equal(t, "desc 1", string(res[2].Media.Description))
+	assert.Equal(t, "2022-12-01T10:00:00Z", res[2].Published.Format(time.RFC3339))
+	assert.Equal(t, "https://www.youtube.com/watch?v=vid1", res[2].Link.Href)
+	assert.Equal(t, "https://i.ytimg.com/vi/vid1/hq.jpg", res[2].Media.Thumbnail.URL)
+	assert.Equal(t, "owner", res[2].Author.Name)
+	assert.Equal(t, "https://www.youtube.com/channel/UCowner", res[2].Author.URI)
+
+	res, err = c.Get(context.Background(), "PL123", FTPlaylist, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "key1")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res), "old entry excluded")
+	assert.Equal(t, "vid4", res[0].VideoID)
+	assert.Equal(t, "vid3", res[1].VideoID)
+
+	atomic.StoreInt32(&requests, 0)
+	res, err = c.GetPage(context.Background(), "PL123", FTPlaylist, 0, "key1")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res))
+	assert.Equal(t, "vid3", res[0].VideoID)
+	res, err = c.GetPage(context.Background(), "PL123", FTPlaylist, 1, "key1")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid4", res[0].VideoID)
+	res, err = c.GetPage(context.Background(), "PL123", FTPlaylist, 2, "key1")
+	require.NoError(t, err)
+	assert.Empty(t, res, "no more pages")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests), "page tokens reused, first page not requested again")
+
+	_, err = c.Get(context.Background(), "PL123", FTPlaylist, time.Time{}, "bad")
+	assert.EqualError(t, err, "failed to get PL123: 400 Bad Request API key not valid")
+	assert.False(t, errors.Is(err, ErrAccessDenied))
+}
+
+func TestFeed_GetPageChannel(t *testing.T) {
+	var playlists []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		playlists = append(playlists, r.URL.Query().Get("playlistId"))
+		_, _ = w.Write([]byte(apiPlaylistPage2))
+	}))
+	defer ts.Close()
+	c := Feed{Client: &http.Client{Timeout: time.Second}, ChannelBaseURL: "http://localhost:1/never", APIBaseURL: ts.URL}
+
+	res, err := c.GetPage(context.Background(), "UC123", FTChannel, 0, "key1")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid4", res[0].VideoID)
+	assert.Equal(t, "UC123", res[0].ChannelID, "feed's id, not the uploads playlist")
+	_, err = c.GetPage(context.Background(), "UC123", FTShorts, 0, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"UU123", "UUSH123"}, playlists, "uploads and tab's playlists")
+
+	_, err = c.GetPage(context.Background(), "UC123", FTChannel, 0, "")
+	assert.True(t, errors.Is(err, ErrPagingNotSupported), "no paging of rss")
+	_, err = c.GetPage(context.Background(), "PL123", FTPlaylist, 1, "")
+	assert.True(t, errors.Is(err, ErrPagingNotSupported))
+	_, err = c.GetPage(context.Background(), "bad", FTVideos, 0, "key1")
+	assert.EqualError(t, err, `invalid channel id "bad" for videos feed, should start with UC`)
+	assert.Equal(t, 2, len(playlists))
+}
+
+func TestFeed_GetPlaylistAccessDenied(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/playlistItems" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "The playlist identified with the request's playlistId ` +
+				`parameter cannot be accessed."}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c := Feed{Client: &http.Client{Timeout: time.Second}, PlaylistBaseURL: ts.URL + "/rss?playlist_id=", APIBaseURL: ts.URL}
+	_, err := c.Get(context.Background(), "PL123", FTPlaylist, time.Time{}, "key1")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAccessDenied), err.Error())
+	assert.Contains(t, err.Error(), "playlist PL123: 403 Forbidden The playlist identified")
+
+	_, err = c.Get(context.Background(), "PL123", FTPlaylist, time.Time{}, "")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrAccessDenied), "rss of not public playlist")
+	assert.EqualError(t, err, "playlist PL123: 404 Not Found, not public playlists can be listed with api_key only: access denied")
+
+	_, err = c.Get(context.Background(), "UC123", FTChannel, time.Time{}, "")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrAccessDenied), "channels not affected")
+}
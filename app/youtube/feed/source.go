@@ -0,0 +1,91 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SourceType is a kind of alternative api listing youtube channels
+type SourceType string
+
+// enum of alternative sources
+const (
+	STYouTube   = SourceType("")          // youtube's own rss
+	STPiped     = SourceType("piped")     // Piped api, i.e. https://pipedapi.example.com
+	STInvidious = SourceType("invidious") // Invidious api, i.e. https://invidious.example.com
+)
+
+// UnmarshalYAML parses source type case-insensitively and rejects unknown types
+func (t *SourceType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	switch res := SourceType(strings.ToLower(strings.TrimSpace(s))); res {
+	case STYouTube, "youtube":
+		*t = STYouTube
+		return nil
+	case STPiped, STInvidious:
+		*t = res
+		return nil
+	}
+	return errors.Errorf("unknown source type %q", s)
+}
+
+// Source is an alternative api listing youtube channels instead of youtube itself, i.e. self-hosted Piped or
+// Invidious instance, for rate-limited youtube endpoints. Downloads are not affected
+type Source struct {
+	Type SourceType `yaml:"type"`
+	URL  string     `yaml:"url"` // base url of the api
+}
+
+// Enabled returns true if the source is set
+func (s Source) Enabled() bool {
+	return s.Type != STYouTube && s.URL != ""
+}
+
+type sourceKey struct{}
+
+// WithSource returns a copy of ctx with the source overriding Feed.Source for Get and GetPage made with this ctx
+func WithSource(ctx context.Context, src Source) context.Context {
+	if !src.Enabled() {
+		return ctx
+	}
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// source returns the source of the request, from ctx or Feed.Source
+func (c *Feed) source(ctx context.Context) Source {
+	if src, ok := ctx.Value(sourceKey{}).(Source); ok {
+		return src
+	}
+	return c.Source
+}
+
+// getJSON gets url with headers of ctx and decodes json response to v
+func getJSON(ctx context.Context, client *http.Client, id, reqURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, http.NoBody)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", id)
+	}
+	setHeaders(ctx, req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get %s", id)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.Wrapf(ErrFeedNotFound, "feed %s: %s", id, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to get %s: %s", id, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrapf(err, "failed to decode %s", id)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSourceType_UnmarshalYAML(t *testing.T) {
+	tbl := []struct {
+		yml string
+		res SourceType
+		err bool
+	}{
+		{"{url: http://example.com}", STYouTube, false},
+		{"{type: youtube}", STYouTube, false},
+		{"{type: Piped}", STPiped, false},
+		{"{type: invidious}", STInvidious, false},
+		{"{type: blah}", "", true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.yml, func(t *testing.T) {
+			var src Source
+			err := yaml.Unmarshal([]byte(tt.yml), &src)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, src.Type)
+		})
+	}
+	assert.False(t, Source{Type: STPiped}.Enabled(), "no url")
+	assert.False(t, Source{URL: "http://example.com"}.Enabled(), "youtube")
+	assert.True(t, Source{Type: STPiped, URL: "http://example.com"}.Enabled())
+}
+
+func TestFeed_GetSource(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/channel/UCxyz":
+			_, _ = w.Write([]byte(`{"relatedStreams": [{"url": "/watch?v=piped1", "title": "t1", "uploaded": 1648800000000}]}`))
+		case "/api/v1/channels/UCxyz/videos":
+			_, _ = w.Write([]byte(`[{"videoId": "inv1", "title": "t1", "published": 1648800000}]`))
+		default:
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+		}
+	}))
+	defer ts.Close()
+
+	f := Feed{Client: &http.Client{Timeout: time.Second}, ChannelBaseURL: ts.URL + "/rss?channel_id=",
+		PlaylistBaseURL: ts.URL + "/rss?playlist_id=", Source: Source{Type: STPiped, URL: ts.URL}}
+	res, err := f.Get(context.Background(), "UCxyz", FTChannel, time.Time{}, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "piped1", res[0].VideoID)
+
+	ctx := WithSource(context.Background(), Source{Type: STInvidious, URL: ts.URL})
+	res, err = f.Get(ctx, "UCxyz", FTChannel, time.Time{}, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "inv1", res[0].VideoID, "source of ctx overrides feed's source")
+
+	_, err = f.Get(ctx, "PLxyz", FTPlaylist, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/channel/UCxyz", "/api/v1/channels/UCxyz/videos", "/rss"}, paths, "playlist from youtube rss")
+}
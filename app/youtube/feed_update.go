@@ -0,0 +1,180 @@
+package youtube
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// maxFeedKeep is the max keep allowed by UpdateFeed, larger retention should be set with KeepAll
+const maxFeedKeep = 10000
+
+var langRe = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// ErrInvalidUpdate returned by UpdateFeed if the values are not sane
+var ErrInvalidUpdate = errors.New("invalid feed update")
+
+// feedUpdates keeps runtime changes of feeds' settings by feed id
+type feedUpdates struct {
+	mu     sync.RWMutex
+	byFeed map[string]ytfeed.FeedUpdate
+}
+
+func (f *feedUpdates) get(feedID string) (ytfeed.FeedUpdate, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	upd, ok := f.byFeed[feedID]
+	return upd, ok
+}
+
+func (f *feedUpdates) set(feedID string, upd ytfeed.FeedUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.byFeed == nil {
+		f.byFeed = map[string]ytfeed.FeedUpdate{}
+	}
+	f.byFeed[feedID] = upd
+}
+
+// validateFeedUpdate checks the values are sane, keep is KeepAll or in 1..maxFeedKeep, name is not empty
+// and language is a language tag or empty to reset it
+func validateFeedUpdate(upd ytfeed.FeedUpdate) error {
+	if upd.Keep == nil && upd.Name == nil && upd.Language == nil && upd.Paused == nil {
+		return errors.Wrap(ErrInvalidUpdate, "nothing to update")
+	}
+	if upd.Keep != nil && *upd.Keep != KeepAll && (*upd.Keep < 1 || *upd.Keep > maxFeedKeep) {
+		return errors.Wrapf(ErrInvalidUpdate, "keep %d, should be %d or in 1..%d", *upd.Keep, KeepAll, maxFeedKeep)
+	}
+	if upd.Name != nil && strings.TrimSpace(*upd.Name) == "" {
+		return errors.Wrap(ErrInvalidUpdate, "empty name")
+	}
+	if upd.Language != nil && *upd.Language != "" && !langRe.MatchString(*upd.Language) {
+		return errors.Wrapf(ErrInvalidUpdate, "language %q", *upd.Language)
+	}
+	return nil
+}
+
+// mergeFeedUpdate returns prev with set fields of next replacing it
+func mergeFeedUpdate(prev, next ytfeed.FeedUpdate) ytfeed.FeedUpdate {
+	if next.Keep != nil {
+		prev.Keep = next.Keep
+	}
+	if next.Name != nil {
+		prev.Name = next.Name
+	}
+	if next.Language != nil {
+		prev.Language = next.Language
+	}
+	if next.Paused != nil {
+		prev.Paused = next.Paused
+	}
+	return prev
+}
+
+// applyFeedUpdate returns the feed info with runtime changes applied
+func applyFeedUpdate(fi FeedInfo, upd ytfeed.FeedUpdate) FeedInfo {
+	if upd.Keep != nil {
+		fi.Keep = *upd.Keep
+	}
+	if upd.Name != nil {
+		fi.Name = strings.TrimSpace(*upd.Name)
+	}
+	if upd.Language != nil {
+		fi.Language = *upd.Language
+	}
+	if upd.Paused != nil {
+		fi.Paused = *upd.Paused
+	}
+	return fi
+}
+
+// LoadFeedUpdates loads runtime changes of feeds' settings from the store, should be called before the service used
+func (s *Service) LoadFeedUpdates() error {
+	updates, err := s.Store.FeedUpdates()
+	if err != nil {
+		return errors.Wrap(err, "failed to load feed updates")
+	}
+	for feedID, upd := range updates {
+		s.updates.set(feedID, upd)
+		log.Printf("[INFO] runtime settings of %s: %s", feedID, feedUpdateString(upd))
+	}
+	return nil
+}
+
+// updated returns the feed info with runtime changes, if any
+func (s *Service) updated(fi FeedInfo) FeedInfo {
+	if upd, ok := s.updates.get(fi.ID); ok {
+		return applyFeedUpdate(fi, upd)
+	}
+	return fi
+}
+
+// feeds returns configured feeds with runtime changes
+func (s *Service) feeds() []FeedInfo {
+	res := make([]FeedInfo, 0, len(s.Feeds))
+	for _, fi := range s.Feeds {
+		res = append(res, s.updated(fi))
+	}
+	return res
+}
+
+// UpdateFeed changes keep, name, language or paused state of the feed at runtime. The change is persisted in the store and
+// overrides configured values after restart. Old entries over the new keep are removed and rss regenerated at once.
+func (s *Service) UpdateFeed(feedID string, upd ytfeed.FeedUpdate) (FeedInfo, error) {
+	if err := validateFeedUpdate(upd); err != nil {
+		return FeedInfo{}, err
+	}
+	fi, found := s.findFeed(feedID)
+	if !found {
+		return FeedInfo{}, errors.Wrapf(ErrNotFound, "feed %s", feedID)
+	}
+	prev, _ := s.updates.get(feedID)
+	merged := mergeFeedUpdate(prev, upd)
+	if err := s.Store.SetFeedUpdate(feedID, merged); err != nil {
+		return fi, errors.Wrapf(err, "failed to save update of %s", feedID)
+	}
+	s.updates.set(feedID, merged)
+	fi = s.updated(fi)
+	log.Printf("[INFO] feed %s updated, %s", feedID, feedUpdateString(upd))
+
+	if upd.Keep == nil && upd.Name == nil && upd.Language == nil {
+		return fi, nil // paused state doesn't change rss
+	}
+	if upd.Keep != nil {
+		s.removeOld(fi)
+	}
+	rss, err := s.RSSFeed(fi, RSSFilter{})
+	if err != nil {
+		return fi, errors.Wrapf(err, "failed to generate rss for %s", fi.ID)
+	}
+	if rss == "" {
+		return fi, nil
+	}
+	if err = s.StoreRSS(fi.ID, rss); err != nil {
+		return fi, errors.Wrapf(err, "failed to save rss for %s", fi.ID)
+	}
+	return fi, nil
+}
+
+func feedUpdateString(upd ytfeed.FeedUpdate) string {
+	res := []string{}
+	if upd.Keep != nil {
+		res = append(res, fmt.Sprintf("keep: %d", *upd.Keep))
+	}
+	if upd.Name != nil {
+		res = append(res, fmt.Sprintf("name: %q", *upd.Name))
+	}
+	if upd.Language != nil {
+		res = append(res, fmt.Sprintf("language: %q", *upd.Language))
+	}
+	if upd.Paused != nil {
+		res = append(res, fmt.Sprintf("paused: %v", *upd.Paused))
+	}
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,155 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestValidateFeedUpdate(t *testing.T) {
+	intp := func(v int) *int { return &v }
+	strp := func(v string) *string { return &v }
+	tbl := []struct {
+		name string
+		upd  ytfeed.FeedUpdate
+		err  string
+	}{
+		{"keep", ytfeed.FeedUpdate{Keep: intp(20)}, ""},
+		{"keep all", ytfeed.FeedUpdate{Keep: intp(KeepAll)}, ""},
+		{"all fields", ytfeed.FeedUpdate{Keep: intp(1), Name: strp("name"), Language: strp("pt-BR")}, ""},
+		{"reset language", ytfeed.FeedUpdate{Language: strp("")}, ""},
+		{"paused", ytfeed.FeedUpdate{Paused: new(bool)}, ""},
+		{"empty", ytfeed.FeedUpdate{}, "nothing to update: invalid feed update"},
+		{"zero keep", ytfeed.FeedUpdate{Keep: intp(0)}, "keep 0, should be -1 or in 1..10000: invalid feed update"},
+		{"huge keep", ytfeed.FeedUpdate{Keep: intp(10001)}, "keep 10001, should be -1 or in 1..10000: invalid feed update"},
+		{"empty name", ytfeed.FeedUpdate{Name: strp("  ")}, "empty name: invalid feed update"},
+		{"bad language", ytfeed.FeedUpdate{Language: strp("english!")}, `language "english!": invalid feed update`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFeedUpdate(tt.upd)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+			assert.True(t, errors.Is(err, ErrInvalidUpdate))
+		})
+	}
+}
+
+func TestService_UpdateFeed(t *testing.T) {
+	var stored ytfeed.FeedUpdate
+	storeSvc := &mocks.StoreServiceMock{
+		SetFeedUpdateFunc: func(feedID string, upd ytfeed.FeedUpdate) error {
+			stored = upd
+			return nil
+		},
+		RemoveOldFunc: func(channelID string, keep int) ([]string, error) { return nil, nil },
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", File: "/tmp/file1.mp3", Published: time.Now()}}, nil
+		},
+	}
+	svc := Service{
+		Feeds:          []FeedInfo{{ID: "channel1", Name: "name1", Keep: 10, Language: "en"}, {ID: "channel2", Name: "name2"}},
+		Store:          storeSvc,
+		RootURL:        "http://localhost:8080/yt",
+		KeepPerChannel: 5,
+	}
+
+	keep, name := 3, " new name "
+	fi, err := svc.UpdateFeed("channel1", ytfeed.FeedUpdate{Keep: &keep})
+	require.NoError(t, err)
+	assert.Equal(t, 3, fi.Keep)
+	require.Equal(t, 1, len(storeSvc.RemoveOldCalls()), "keep applied at once")
+	assert.Equal(t, 4, storeSvc.RemoveOldCalls()[0].Keep)
+
+	fi, err = svc.UpdateFeed("channel1", ytfeed.FeedUpdate{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, FeedInfo{ID: "channel1", Name: "new name", Keep: 3, Language: "en"}, fi, "merged with previous update")
+	assert.Equal(t, ytfeed.FeedUpdate{Keep: &keep, Name: &name}, stored, "merged update persisted")
+	assert.Equal(t, 1, len(storeSvc.RemoveOldCalls()), "keep not changed, nothing removed")
+
+	assert.Equal(t, []FeedInfo{{ID: "channel1", Name: "new name", Keep: 3, Language: "en"}, {ID: "channel2", Name: "name2"}},
+		svc.feeds())
+	rss, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Keep: 10}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, "<title>new name</title>", "updates applied to rss")
+	assert.Equal(t, 3, storeSvc.LoadCalls()[len(storeSvc.LoadCalls())-1].Max)
+
+	_, err = svc.UpdateFeed("unknown", ytfeed.FeedUpdate{Keep: &keep})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	keep = 0
+	_, err = svc.UpdateFeed("channel1", ytfeed.FeedUpdate{Keep: &keep})
+	assert.True(t, errors.Is(err, ErrInvalidUpdate))
+}
+
+func TestService_PausedFeed(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test-paused.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	file := filepath.Join(dir, "vid1.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	_, err = boltStore.Save(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: file,
+		Published: time.Now()})
+	require.NoError(t, err)
+
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return nil, nil
+		},
+	}
+	svc := Service{
+		Feeds:          []FeedInfo{{ID: "channel1", Name: "name1", Paused: true}, {ID: "channel2", Name: "name2"}},
+		Store:          boltStore,
+		ChannelService: chans,
+		RootURL:        "http://localhost:8080/yt",
+		KeepPerChannel: 5,
+	}
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(chans.GetCalls()), "paused in config channel1 skipped")
+	assert.Equal(t, "channel2", chans.GetCalls()[0].ChanID)
+
+	rss, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, "title1", "rss of paused feed served")
+
+	resumed, paused := false, true
+	fi, err := svc.UpdateFeed("channel1", ytfeed.FeedUpdate{Paused: &resumed})
+	require.NoError(t, err)
+	assert.False(t, fi.Paused)
+	_, err = svc.UpdateFeed("channel2", ytfeed.FeedUpdate{Paused: &paused})
+	require.NoError(t, err)
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(chans.GetCalls()), "resumed channel1 processed, paused channel2 skipped")
+	assert.Equal(t, "channel1", chans.GetCalls()[1].ChanID)
+}
+
+func TestService_LoadFeedUpdates(t *testing.T) {
+	keep := 7
+	storeSvc := &mocks.StoreServiceMock{
+		FeedUpdatesFunc: func() (map[string]ytfeed.FeedUpdate, error) {
+			return map[string]ytfeed.FeedUpdate{"channel2": {Keep: &keep}}, nil
+		},
+	}
+	svc := Service{Feeds: []FeedInfo{{ID: "channel1", Keep: 1}, {ID: "channel2", Keep: 2}}, Store: storeSvc}
+	require.NoError(t, svc.LoadFeedUpdates())
+	assert.Equal(t, []FeedInfo{{ID: "channel1", Keep: 1}, {ID: "channel2", Keep: 7}}, svc.feeds())
+}
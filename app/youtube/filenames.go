@@ -0,0 +1,94 @@
+package youtube
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// fileNames keeps names (without extension) of files used by stored entries, to check collisions of new file names
+// without loading the whole store for each name, and feeds of the files to find the owner of a served file.
+// Loaded on the first check and reset at the start of each cycle, files of entries saved during the cycle added
+// as they saved.
+type fileNames struct {
+	mu    sync.Mutex
+	names map[string][]string // file name -> uids of entries using it, nil until loaded
+	feeds map[string]string   // relative path of file without extension -> feed id
+}
+
+// isFileNameTaken checks if any stored entry, other than the given one, already uses file with this name
+func (s *Service) isFileNameTaken(fname string, entry ytfeed.Entry) bool {
+	s.fileNames.mu.Lock()
+	defer s.fileNames.mu.Unlock()
+	s.loadFileNames()
+	for _, uid := range s.fileNames.names[fname] {
+		if uid != entry.UID() {
+			return true
+		}
+	}
+	return false
+}
+
+// FileFeed returns the feed of the stored entry using the served file, by the slash separated path of the file
+// relative to the files location. Companion files, i.e. subtitles and chapters, belong to the feed of their media file.
+func (s *Service) FileFeed(name string) (FeedInfo, bool) {
+	s.fileNames.mu.Lock()
+	s.loadFileNames()
+	feedID, ok := s.fileNames.feeds[fileKey(strings.TrimPrefix(path.Clean("/"+name), "/"))]
+	s.fileNames.mu.Unlock()
+	if !ok {
+		return FeedInfo{}, false
+	}
+	return s.findFeed(feedID)
+}
+
+// loadFileNames loads files of all stored entries if not loaded yet, should be called under lock
+func (s *Service) loadFileNames() {
+	if s.fileNames.names != nil {
+		return
+	}
+	s.fileNames.names, s.fileNames.feeds = map[string][]string{}, map[string]string{}
+	for _, fi := range s.feeds() {
+		entries, err := s.Store.Load(fi.ID, -1)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			s.fileNames.add(e, s.relativeFile(e.File))
+		}
+	}
+}
+
+// addFileName adds file of the saved entry to loaded names, nothing to do if names not loaded yet
+func (s *Service) addFileName(entry ytfeed.Entry) {
+	s.fileNames.mu.Lock()
+	defer s.fileNames.mu.Unlock()
+	if s.fileNames.names != nil {
+		s.fileNames.add(entry, s.relativeFile(entry.File))
+	}
+}
+
+// resetFileNames drops loaded names, reloaded from the store on the next check
+func (s *Service) resetFileNames() {
+	s.fileNames.mu.Lock()
+	s.fileNames.names, s.fileNames.feeds = nil, nil
+	s.fileNames.mu.Unlock()
+}
+
+func (f *fileNames) add(e ytfeed.Entry, rel string) {
+	if e.File == "" {
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(e.File), filepath.Ext(e.File))
+	f.names[name] = append(f.names[name], e.UID())
+	f.feeds[fileKey(rel)] = e.ChannelID
+}
+
+// fileKey returns slash separated path of the file without extension, the same for media file and its companions
+func fileKey(rel string) string {
+	rel = strings.TrimSuffix(rel, ".chapters.json")
+	return strings.TrimSuffix(rel, path.Ext(rel))
+}
@@ -0,0 +1,34 @@
+package youtube
+
+import (
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+)
+
+// FileSize is a size in bytes, set in yaml as a number of bytes or with units, i.e. "500MB", "500M" or "2GiB"
+type FileSize int64
+
+// UnmarshalYAML parses size with optional units
+func (fs *FileSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if strings.TrimSpace(s) == "" {
+		*fs = 0
+		return nil
+	}
+	size, err := humanize.ParseBytes(s)
+	if err != nil || size > 1<<62 {
+		return errors.Errorf("invalid size %q", s)
+	}
+	*fs = FileSize(size)
+	return nil
+}
+
+// String returns human readable size, i.e. "500 MB"
+func (fs FileSize) String() string {
+	return humanize.Bytes(uint64(fs))
+}
@@ -0,0 +1,132 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestFileSize_UnmarshalYAML(t *testing.T) {
+	tbl := []struct {
+		yml string
+		res FileSize
+		err bool
+	}{
+		{"{id: c1}", 0, false},
+		{"{id: c1, max_file_size: 1000}", 1000, false},
+		{"{id: c1, max_file_size: 500M}", 500_000_000, false},
+		{"{id: c1, max_file_size: 500 MB}", 500_000_000, false},
+		{"{id: c1, max_file_size: 2GiB}", 2 << 30, false},
+		{"{id: c1, max_file_size: big}", 0, true},
+		{"{id: c1, max_file_size: -1}", 0, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.yml, func(t *testing.T) {
+			var fi FeedInfo
+			err := yaml.Unmarshal([]byte(tt.yml), &fi)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, fi.MaxFileSize)
+		})
+	}
+	assert.Equal(t, "500 MB", FileSize(500_000_000).String())
+}
+
+// limitedDownloader is DownloaderService checking the size before the download, like yt-dlp with --max-filesize
+type limitedDownloader struct {
+	*mocks.DownloaderServiceMock
+	sizes map[string]int64 // reported sizes by video id
+	calls []int64          // max sizes passed to GetOpts
+}
+
+func (d *limitedDownloader) GetOpts(ctx context.Context, id, fname string, opts ytfeed.GetOptions) (string, error) {
+	d.calls = append(d.calls, opts.MaxSize)
+	if d.sizes[id] > opts.MaxSize {
+		return "", ytfeed.ErrTooLarge
+	}
+	return d.Get(ctx, id, fname)
+}
+
+func TestService_DoMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid3", Title: "live stream", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "long episode", Published: time.Now().Add(-time.Minute)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "episode", Published: time.Now().Add(-time.Hour)},
+			}, nil
+		},
+	}
+	downloader := &limitedDownloader{
+		DownloaderServiceMock: &mocks.DownloaderServiceMock{
+			GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+				file := filepath.Join(dir, fname+".mp3")
+				require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+				size := 500
+				if id == "vid2" {
+					size = 2000 // size not reported before the download
+				}
+				return file, os.WriteFile(file, []byte(strings.Repeat("x", size)), 0o600)
+			},
+		},
+		sizes: map[string]int64{"vid3": 1_000_000},
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, MaxFileSize: 1000}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, st.Entries)
+	assert.Equal(t, 1, st.Added)
+	assert.Equal(t, 2, st.Ignored, "too large vid2 and vid3 ignored")
+	assert.Equal(t, []int64{1000, 1000, 1000}, downloader.calls)
+	assert.Equal(t, 2, len(downloader.GetCalls()), "vid3 rejected before the download")
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid1", res[0].VideoID)
+
+	for _, vid := range []string{"vid2", "vid3"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: vid})
+		require.NoError(t, err)
+		assert.True(t, found, "too large %s marked processed, not retried", vid)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "channel1", "*.mp3"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{res[0].File}, files, "too large file removed")
+
+	st, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, st.Added)
+	assert.Equal(t, 2, len(downloader.GetCalls()), "nothing downloaded again")
+}
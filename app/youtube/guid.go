@@ -0,0 +1,106 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// GUIDScheme defines how rss item guid is made from the entry
+type GUIDScheme string
+
+// enum of all supported guid schemes
+const (
+	GSUID   GUIDScheme = "uid"   // channel_id::video_id, the current default
+	GSVideo GUIDScheme = "video" // video id only
+	GSLink  GUIDScheme = "link"  // link to the video
+)
+
+// ParseGUIDScheme checks the scheme name, empty name is the default uid scheme
+func ParseGUIDScheme(name string) (GUIDScheme, error) {
+	switch gs := GUIDScheme(strings.ToLower(strings.TrimSpace(name))); gs {
+	case "":
+		return GSUID, nil
+	case GSUID, GSVideo, GSLink:
+		return gs, nil
+	default:
+		return "", fmt.Errorf("unknown guid scheme %q", name)
+	}
+}
+
+// GUID makes guid of the entry with the scheme
+func (g GUIDScheme) GUID(entry ytfeed.Entry) string {
+	switch g {
+	case GSVideo:
+		return entry.VideoID
+	case GSLink:
+		return entry.Link.Href
+	default:
+		return entry.UID()
+	}
+}
+
+// GUIDChange is a single guid rewrite made (or planned, in dry run) by MigrateGUIDs
+type GUIDChange struct {
+	FeedID  string
+	VideoID string
+	Old     string
+	New     string
+}
+
+// itemGUID returns guid of the rss item for the entry, the pinned one if set by migration
+func itemGUID(entry ytfeed.Entry) string {
+	if entry.GUID != "" {
+		return entry.GUID
+	}
+	return GSUID.GUID(entry)
+}
+
+// guidChange returns the rewrite of the entry's guid from one scheme to another. Only entries with guid
+// made by the "from" scheme are rewritten, so running it again after migration changes nothing.
+func guidChange(entry ytfeed.Entry, from, to GUIDScheme) (GUIDChange, bool) {
+	cur, old, upd := itemGUID(entry), from.GUID(entry), to.GUID(entry)
+	if cur != old || old == upd || upd == "" {
+		return GUIDChange{}, false
+	}
+	return GUIDChange{VideoID: entry.VideoID, Old: old, New: upd}, true
+}
+
+// MigrateGUIDs rewrites guids of all stored entries made by the "from" scheme to the "to" scheme.
+// The new guid is pinned to the entry and used by rss generation from now on, i.e. migrating from uid to video
+// keeps guids of episodes subscribers already have if they were published with video ids.
+// With dryRun the changes only reported, nothing is stored. Rss files should be regenerated after migration.
+func (s *Service) MigrateGUIDs(from, to GUIDScheme, dryRun bool) ([]GUIDChange, error) {
+	res := []GUIDChange{}
+	for _, fi := range s.feeds() {
+		entries, err := s.Store.Load(fi.ID, KeepAll)
+		if err != nil {
+			return res, errors.Wrapf(err, "failed to load entries for %s", fi.ID)
+		}
+		for _, entry := range entries {
+			change, ok := guidChange(entry, from, to)
+			if !ok {
+				continue
+			}
+			change.FeedID = fi.ID
+			res = append(res, change)
+			if dryRun {
+				log.Printf("[INFO] guid of %s/%s will be changed, %s -> %s", fi.ID, entry.VideoID, change.Old, change.New)
+				continue
+			}
+			entry.GUID = change.New
+			if err := s.Store.Remove(entry); err != nil {
+				return res, errors.Wrapf(err, "failed to remove %s for guid update", entry.VideoID)
+			}
+			if _, err := s.Store.Save(entry); err != nil {
+				return res, errors.Wrapf(err, "failed to save %s with updated guid", entry.VideoID)
+			}
+			log.Printf("[INFO] guid of %s/%s changed, %s -> %s", fi.ID, entry.VideoID, change.Old, change.New)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,111 @@
+package youtube
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestParseGUIDScheme(t *testing.T) {
+	tbl := []struct {
+		name string
+		res  GUIDScheme
+		err  bool
+	}{
+		{"", GSUID, false},
+		{"uid", GSUID, false},
+		{" Video ", GSVideo, false},
+		{"link", GSLink, false},
+		{"blah", "", true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseGUIDScheme(tt.name)
+			if tt.err {
+				assert.EqualError(t, err, `unknown guid scheme "blah"`)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func TestGUIDChange(t *testing.T) {
+	entry := ytfeed.Entry{ChannelID: "ch1", VideoID: "vid1"}
+	entry.Link.Href = "https://www.youtube.com/watch?v=vid1"
+	pinned := entry
+	pinned.GUID = "vid1"
+
+	tbl := []struct {
+		name     string
+		entry    ytfeed.Entry
+		from, to GUIDScheme
+		res      GUIDChange
+		ok       bool
+	}{
+		{"uid to video", entry, GSUID, GSVideo, GUIDChange{VideoID: "vid1", Old: "ch1::vid1", New: "vid1"}, true},
+		{"uid to link", entry, GSUID, GSLink,
+			GUIDChange{VideoID: "vid1", Old: "ch1::vid1", New: "https://www.youtube.com/watch?v=vid1"}, true},
+		{"same scheme", entry, GSUID, GSUID, GUIDChange{}, false},
+		{"not made by from", entry, GSVideo, GSUID, GUIDChange{}, false},
+		{"already migrated", pinned, GSUID, GSVideo, GUIDChange{}, false},
+		{"migrate back", pinned, GSVideo, GSUID, GUIDChange{VideoID: "vid1", Old: "vid1", New: "ch1::vid1"}, true},
+		{"empty new guid", ytfeed.Entry{ChannelID: "ch1", VideoID: "vid1"}, GSUID, GSLink, GUIDChange{}, false},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			res, ok := guidChange(tt.entry, tt.from, tt.to)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func TestService_MigrateGUIDs(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	for i, vid := range []string{"vid1", "vid2"} {
+		_, err = boltStore.Save(ytfeed.Entry{ChannelID: "channel1", VideoID: vid, Title: "title " + vid,
+			Published: time.Now().Add(-time.Duration(i) * time.Hour)})
+		require.NoError(t, err)
+	}
+	svc := Service{Feeds: []FeedInfo{{ID: "channel1"}}, Store: boltStore}
+
+	res, err := svc.MigrateGUIDs(GSUID, GSVideo, true)
+	require.NoError(t, err)
+	assert.Equal(t, []GUIDChange{
+		{FeedID: "channel1", VideoID: "vid1", Old: "channel1::vid1", New: "vid1"},
+		{FeedID: "channel1", VideoID: "vid2", Old: "channel1::vid2", New: "vid2"},
+	}, res)
+	entries, err := boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.Empty(t, e.GUID, "dry run doesn't change entries")
+	}
+
+	res, err = svc.MigrateGUIDs(GSUID, GSVideo, false)
+	require.NoError(t, err)
+	assert.Len(t, res, 2)
+	entries, err = boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.Equal(t, e.VideoID, e.GUID)
+		assert.Equal(t, e.VideoID, svc.rssItem(e, FeedInfo{ID: "channel1"}, 0).GUID)
+		assert.Equal(t, "title "+e.VideoID, e.Title, "other fields kept")
+	}
+
+	res, err = svc.MigrateGUIDs(GSUID, GSVideo, false)
+	require.NoError(t, err)
+	assert.Empty(t, res, "second run changes nothing")
+}
@@ -0,0 +1,202 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// defaultPostDownloadTimeout limits run time of the post-download command if PostDownloadTimeout not set
+const defaultPostDownloadTimeout = time.Minute
+
+// maxHookOutput is the max length of the post-download command's output logged
+const maxHookOutput = 1024
+
+// CommandRunner runs an external command with args, without shell. Returns combined stdout and stderr
+type CommandRunner interface {
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner is CommandRunner executing commands with os/exec
+type ExecRunner struct{}
+
+// Run executes the command, killed on ctx cancellation
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput() // nolint
+}
+
+// postDownloadParams are fields available in PostDownloadCmd template
+type postDownloadParams struct {
+	ID        string // video id
+	FeedID    string
+	FeedName  string
+	Title     string
+	File      string // full path of the downloaded file
+	URL       string // link to the video
+	Published string // RFC3339
+	Duration  int    // seconds
+}
+
+// splitCmd splits the command to fields by spaces, except spaces inside quotes or template actions, i.e.
+// `tag --title "{{ .Title }}"` is ["tag", "--title", "{{ .Title }}"]. Quotes are removed.
+func splitCmd(cmd string) ([]string, error) {
+	res := []string{}
+	field, inField, quote, actions := strings.Builder{}, false, rune(0), 0
+	for i, r := range cmd {
+		switch {
+		case strings.HasPrefix(cmd[i:], "{{"):
+			actions++
+		case strings.HasPrefix(cmd[i:], "}}") && actions > 0:
+			actions--
+		}
+		switch {
+		case actions == 0 && quote == 0 && (r == '"' || r == '\''):
+			quote, inField = r, true
+			continue
+		case actions == 0 && r == quote:
+			quote = 0
+			continue
+		case actions == 0 && quote == 0 && (r == ' ' || r == '\t' || r == '\n'):
+			if inField {
+				res = append(res, field.String())
+				field.Reset()
+				inField = false
+			}
+			continue
+		}
+		field.WriteRune(r)
+		inField = true
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("unterminated quote in %q", cmd)
+	}
+	if inField {
+		res = append(res, field.String())
+	}
+	return res, nil
+}
+
+// parsePostDownloadCmd splits the command and parses each field as a template
+func parsePostDownloadCmd(cmd string) ([]*template.Template, error) {
+	fields, err := splitCmd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("empty command")
+	}
+	res := make([]*template.Template, 0, len(fields))
+	for i, f := range fields {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Parse(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid template %q", f)
+		}
+		res = append(res, tmpl)
+	}
+	return res, nil
+}
+
+// CheckPostDownloadCmd verifies the command can be split and its templates parsed
+func CheckPostDownloadCmd(cmd string) error {
+	_, err := parsePostDownloadCmd(cmd)
+	return err
+}
+
+// postDownloadArgs renders the command's fields with entry params. Each field is a separate arg, the command never
+// passed to shell, so entry values like titles can't inject anything
+func postDownloadArgs(cmd string, entry ytfeed.Entry, fi FeedInfo) ([]string, error) {
+	tmpls, err := parsePostDownloadCmd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	params := postDownloadParams{ID: entry.VideoID, FeedID: fi.ID, FeedName: fi.Name, Title: entry.Title, File: entry.File,
+		URL: entry.Link.Href, Published: entry.Published.Format(time.RFC3339), Duration: entry.Duration}
+	res := make([]string, 0, len(tmpls))
+	for _, tmpl := range tmpls {
+		buf := bytes.Buffer{}
+		if err := tmpl.Execute(&buf, params); err != nil {
+			return nil, errors.Wrapf(err, "failed to execute template %s", tmpl.Name())
+		}
+		res = append(res, buf.String())
+	}
+	return res, nil
+}
+
+// postDownload runs feed's PostDownloadCmd for the saved entry, limited by PostDownloadTimeout.
+// Failures and output are logged only, the entry stays saved regardless of the command's result.
+// The command may rewrite the file (i.e. tagging scripts), so checksum and size of the file are refreshed after,
+// returns the entry with updated values.
+func (s *Service) postDownload(ctx context.Context, entry ytfeed.Entry, fi FeedInfo) ytfeed.Entry {
+	if fi.PostDownloadCmd == "" {
+		return entry
+	}
+	args, err := postDownloadArgs(fi.PostDownloadCmd, entry, fi)
+	if err != nil {
+		s.event("WARN", "post_download", fmt.Sprintf("can't make post-download command for %s, %v", entry.VideoID, err),
+			entryFields(fi, entry))
+		return entry
+	}
+	timeout := s.PostDownloadTimeout
+	if timeout <= 0 {
+		timeout = defaultPostDownloadTimeout
+	}
+	runner := s.CmdRunner
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	st := time.Now()
+	out, err := runner.Run(cmdCtx, args[0], args[1:]...)
+	entry = s.refreshFileInfo(entry)
+	output := strings.TrimSpace(string(out))
+	if len(output) > maxHookOutput {
+		output = output[:maxHookOutput] + "..."
+	}
+	if err != nil {
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			err = errors.Errorf("timed out after %v", timeout)
+		}
+		s.event("WARN", "post_download", fmt.Sprintf("post-download command for %s failed, %v, output: %s",
+			entry.VideoID, err, output), entryFields(fi, entry).with("error", err.Error()).with("output", output))
+		return entry
+	}
+	s.event("INFO", "post_download", fmt.Sprintf("post-download command for %s done in %v, output: %s", entry.VideoID,
+		time.Since(st).Round(time.Millisecond), output), entryFields(fi, entry).with("output", output))
+	return entry
+}
+
+// refreshFileInfo updates checksum and size of the stored entry if its file changed.
+// The store doesn't overwrite entries, the changed entry removed and saved again.
+func (s *Service) refreshFileInfo(entry ytfeed.Entry) ytfeed.Entry {
+	checksum, err := fileChecksum(entry.File)
+	if err != nil || checksum == entry.Checksum {
+		return entry
+	}
+	st, err := os.Stat(entry.File)
+	if err != nil {
+		return entry
+	}
+	updated := entry
+	updated.Checksum, updated.FileSize = checksum, st.Size()
+	if err := s.Store.Remove(entry); err != nil {
+		log.Printf("[WARN] failed to remove %s to update checksum, %v", entry.VideoID, err)
+		return entry
+	}
+	if _, err := s.Store.Save(updated); err != nil {
+		log.Printf("[WARN] failed to save %s with updated checksum, %v", entry.VideoID, err)
+		return entry
+	}
+	log.Printf("[INFO] file of %s changed, size: %d, sha256: %s", entry.VideoID, updated.FileSize, updated.Checksum)
+	return updated
+}
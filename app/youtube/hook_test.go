@@ -0,0 +1,159 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestSplitCmd(t *testing.T) {
+	tbl := []struct {
+		cmd string
+		res []string
+		err bool
+	}{
+		{"tag {{.File}}", []string{"tag", "{{.File}}"}, false},
+		{"  tag   --title {{ .Title }}  ", []string{"tag", "--title", "{{ .Title }}"}, false},
+		{`tag --comment "my podcast" 'single quoted'`, []string{"tag", "--comment", "my podcast", "single quoted"}, false},
+		{`tag --title="{{.Title}}" ""`, []string{"tag", "--title={{.Title}}", ""}, false},
+		{`tag {{printf "%s - %s" .FeedName .Title}}`, []string{"tag", `{{printf "%s - %s" .FeedName .Title}}`}, false},
+		{"", []string{}, false},
+		{`tag "unterminated`, nil, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.cmd, func(t *testing.T) {
+			res, err := splitCmd(tt.cmd)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func TestCheckPostDownloadCmd(t *testing.T) {
+	assert.NoError(t, CheckPostDownloadCmd("tag {{.File}} {{.Title}}"))
+	assert.EqualError(t, CheckPostDownloadCmd("  "), "empty command")
+	assert.Error(t, CheckPostDownloadCmd("tag {{.File"))
+	assert.Error(t, CheckPostDownloadCmd(`tag "{{.File}}`))
+}
+
+func TestPostDownloadArgs(t *testing.T) {
+	entry := ytfeed.Entry{VideoID: "vid1", Title: `title"; rm -rf / $(reboot)`, File: "/srv/files/ch1/file 1.mp3",
+		Published: time.Date(2022, 4, 6, 10, 20, 30, 0, time.UTC), Duration: 123}
+	entry.Link.Href = "https://www.youtube.com/watch?v=vid1"
+	fi := FeedInfo{ID: "ch1", Name: "name1"}
+
+	res, err := postDownloadArgs(`/usr/bin/tag --title "{{.Title}}" --album={{.FeedName}} {{.File}} {{.ID}} {{.FeedID}} `+
+		`{{.URL}} {{.Published}} {{.Duration}}`, entry, fi)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/usr/bin/tag", "--title", `title"; rm -rf / $(reboot)`, "--album=name1",
+		"/srv/files/ch1/file 1.mp3", "vid1", "ch1", "https://www.youtube.com/watch?v=vid1", "2022-04-06T10:20:30Z", "123"},
+		res, "values with spaces and shell syntax are single args as is")
+
+	_, err = postDownloadArgs("tag {{.Blah}}", entry, fi)
+	assert.Error(t, err)
+}
+
+func TestService_postDownload(t *testing.T) {
+	runner := &mocks.CommandRunnerMock{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			switch args[0] {
+			case "vid1":
+				return []byte("tagged\n"), nil
+			case "vid2":
+				return []byte("no such file"), errors.New("exit status 1")
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	buf := bytes.Buffer{}
+	svc := Service{CmdRunner: runner, PostDownloadTimeout: 50 * time.Millisecond, Logger: &JSONLogger{Out: &buf}}
+	fi := FeedInfo{ID: "ch1", Name: "name1", PostDownloadCmd: "tag {{.ID}} {{.File}}"}
+
+	svc.postDownload(context.Background(), ytfeed.Entry{VideoID: "vid1", File: "/srv/file1.mp3"}, fi)
+	require.Equal(t, 1, len(runner.RunCalls()))
+	assert.Equal(t, "tag", runner.RunCalls()[0].Name)
+	assert.Equal(t, []string{"vid1", "/srv/file1.mp3"}, runner.RunCalls()[0].Args)
+	assert.Contains(t, buf.String(), `"output":"tagged"`)
+	assert.Contains(t, buf.String(), `"action":"post_download"`)
+
+	buf.Reset()
+	svc.postDownload(context.Background(), ytfeed.Entry{VideoID: "vid2"}, fi)
+	assert.Contains(t, buf.String(), `"level":"WARN"`)
+	assert.Contains(t, buf.String(), `"error":"exit status 1"`)
+	assert.Contains(t, buf.String(), `"output":"no such file"`)
+
+	buf.Reset()
+	st := time.Now()
+	svc.postDownload(context.Background(), ytfeed.Entry{VideoID: "vid3"}, fi)
+	assert.Less(t, int64(time.Since(st)), int64(time.Second), "killed on timeout")
+	assert.Contains(t, buf.String(), `"error":"timed out after 50ms"`)
+
+	svc.postDownload(context.Background(), ytfeed.Entry{VideoID: "vid4"}, FeedInfo{ID: "ch2"})
+	assert.Equal(t, 3, len(runner.RunCalls()), "no command for feed without post_download_cmd")
+}
+
+func TestService_postDownloadRefreshesFile(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	file := filepath.Join(dir, "vid1.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	checksum, err := fileChecksum(file)
+	require.NoError(t, err)
+	entry := ytfeed.Entry{ChannelID: "ch1", VideoID: "vid1", File: file, Checksum: checksum, FileSize: 7, Published: time.Now()}
+	_, err = boltStore.Save(entry)
+	require.NoError(t, err)
+
+	runner := &mocks.CommandRunnerMock{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, os.WriteFile(args[0], []byte("tagged content"), 0o600)
+		},
+	}
+	svc := Service{CmdRunner: runner, Store: boltStore}
+	res := svc.postDownload(context.Background(), entry, FeedInfo{ID: "ch1", PostDownloadCmd: "tag {{.File}}"})
+	require.Equal(t, 1, len(runner.RunCalls()))
+	assert.Equal(t, int64(14), res.FileSize)
+	assert.NotEqual(t, checksum, res.Checksum)
+
+	stored, err := boltStore.Load("ch1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(stored))
+	assert.Equal(t, res.Checksum, stored[0].Checksum, "checksum of rewritten file stored")
+	assert.Equal(t, int64(14), stored[0].FileSize)
+
+	vres, err := (&Service{Feeds: []FeedInfo{{ID: "ch1"}}, Store: boltStore}).VerifyFiles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{Checked: 1}, vres, "file rewritten by the hook is not corrupted")
+}
+
+func TestExecRunner(t *testing.T) {
+	out, err := ExecRunner{}.Run(context.Background(), "echo", "a b", "$(c)")
+	require.NoError(t, err)
+	assert.Equal(t, "a b $(c)", strings.TrimSpace(string(out)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = ExecRunner{}.Run(ctx, "sleep", "10")
+	assert.Error(t, err)
+}
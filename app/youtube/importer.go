@@ -0,0 +1,194 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	htmltmpl "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// ImportResult is a report of Import
+type ImportResult struct {
+	Imported  []string `json:"imported"`  // video ids of imported files
+	Skipped   []string `json:"skipped"`   // video ids already stored or processed
+	Unmatched []string `json:"unmatched"` // files without video id in the name or sidecar info json
+}
+
+// videoIDRe matches youtube video id in brackets, yt-dlp's default file name is "title [id].ext"
+var videoIDRe = regexp.MustCompile(`\[([A-Za-z0-9_-]{11})\]`)
+
+// bareVideoIDRe matches file name made of the video id only
+var bareVideoIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// importInfo is a part of yt-dlp's info json used for import
+type importInfo struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	UploadDate  string `json:"upload_date"` // YYYYMMDD
+	Channel     string `json:"channel"`
+	ChannelURL  string `json:"channel_url"`
+	Language    string `json:"language"`
+}
+
+// Import adds previously downloaded audio files from dir to the feed without downloading them again.
+// Video id of each mp3 file is taken from yt-dlp's info json next to the file (name.info.json), or from the file name,
+// i.e. "title [id].mp3" or "id.mp3". Files are moved to the feed's directory, already stored entries skipped,
+// files without video id reported as unmatched. RSS of the feed is regenerated if anything imported.
+func (s *Service) Import(ctx context.Context, feedID, dir string) (res ImportResult, err error) {
+	fi, found := s.findFeed(feedID)
+	if !found {
+		return res, errors.Errorf("feed %s not found", feedID)
+	}
+	if s.FilesLocation == "" {
+		return res, errors.New("files location not set")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"+mediaExt))
+	if err != nil {
+		return res, errors.Wrapf(err, "failed to list files in %s", dir)
+	}
+	log.Printf("[INFO] import %d files from %s to %s", len(files), dir, fi.Name)
+
+	defer func() {
+		if len(res.Imported) == 0 {
+			return
+		}
+		s.numberEpisodes(fi)
+		rss, rssErr := s.RSSFeed(fi, RSSFilter{})
+		if rssErr != nil {
+			log.Printf("[WARN] failed to generate rss for %s: %s", fi.Name, rssErr)
+			return
+		}
+		if saveErr := s.StoreRSS(fi.ID, rss); saveErr != nil {
+			log.Printf("[WARN] failed to save rss for %s: %s", fi.Name, saveErr)
+		}
+	}()
+
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		entry, ok := importEntry(file, fi)
+		if !ok {
+			log.Printf("[WARN] no video id for %s, not imported", file)
+			res.Unmatched = append(res.Unmatched, file)
+			continue
+		}
+		isNew, err := s.isNew(entry, fi)
+		if err != nil {
+			return res, errors.Wrapf(err, "failed to check if entry %s exists", entry.VideoID)
+		}
+		if !isNew {
+			log.Printf("[DEBUG] skip import of %s, %s already stored", file, entry.VideoID)
+			res.Skipped = append(res.Skipped, entry.VideoID)
+			continue
+		}
+
+		dest := filepath.Join(s.FilesLocation, s.subDir(fi), filepath.Base(file))
+		if err = moveFile(file, dest); err != nil {
+			return res, errors.Wrapf(err, "failed to move %s", file)
+		}
+		entry = s.update(entry, dest, fi)
+		if fileInfo, statErr := os.Stat(dest); statErr == nil {
+			entry.FileSize = fileInfo.Size()
+		}
+		if entry.Checksum, err = fileChecksum(dest); err != nil {
+			log.Printf("[WARN] failed to get checksum for %s: %v", dest, err)
+		}
+		if err = s.saveEntry(entry); err != nil {
+			return res, err
+		}
+		if procErr := s.Store.SetProcessed(entry); procErr != nil {
+			log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+		}
+		res.Imported = append(res.Imported, entry.VideoID)
+	}
+	log.Printf("[INFO] import to %s completed, imported: %d, skipped: %d, unmatched: %d", fi.Name,
+		len(res.Imported), len(res.Skipped), len(res.Unmatched))
+	return res, nil
+}
+
+// importEntry makes the entry for the file from sidecar info json or the file name, false if video id not found
+func importEntry(file string, fi FeedInfo) (ytfeed.Entry, bool) {
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	info := importInfo{}
+	if data, err := os.ReadFile(strings.TrimSuffix(file, filepath.Ext(file)) + ".info.json"); err == nil { // nolint
+		if err = json.Unmarshal(data, &info); err != nil {
+			log.Printf("[WARN] failed to parse info json of %s, %v", file, err)
+		}
+	}
+	if info.ID == "" {
+		if m := videoIDRe.FindStringSubmatch(base); m != nil {
+			info.ID = m[1]
+		} else if bareVideoIDRe.MatchString(base) {
+			info.ID = base
+		}
+	}
+	if info.ID == "" {
+		return ytfeed.Entry{}, false
+	}
+
+	entry := ytfeed.Entry{ChannelID: fi.ID, VideoID: info.ID, Title: info.Title, Language: info.Language}
+	if entry.Title == "" {
+		entry.Title = strings.TrimSpace(strings.ReplaceAll(videoIDRe.ReplaceAllString(base, ""), "_", " "))
+		if entry.Title == "" {
+			entry.Title = info.ID
+		}
+	}
+	entry.Link.Href = "https://www.youtube.com/watch?v=" + info.ID
+	entry.Media.Description = htmltmpl.HTML(info.Description) // nolint
+	entry.Author.Name, entry.Author.URI = info.Channel, info.ChannelURL
+	if ts, err := time.Parse("20060102", info.UploadDate); err == nil {
+		entry.Published = ts
+	} else if fileInfo, statErr := os.Stat(file); statErr == nil {
+		entry.Published = fileInfo.ModTime()
+	}
+	return entry, true
+}
+
+// moveFile moves src to dst, copies and removes src if rename fails, i.e. across file systems.
+// Does nothing if src is dst already.
+func moveFile(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return errors.Errorf("destination %s exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return errors.Wrapf(err, "failed to make directory for %s", dst)
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src) // nolint
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", src)
+	}
+	defer in.Close()                                                       // nolint
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644) // nolint
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", dst)
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return errors.Wrapf(err, "failed to copy %s to %s", src, dst)
+	}
+	if err = out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return errors.Wrapf(err, "failed to close %s", dst)
+	}
+	return errors.Wrapf(os.Remove(src), "failed to remove %s", src)
+}
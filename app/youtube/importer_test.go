@@ -0,0 +1,123 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestService_Import(t *testing.T) {
+	srcDir, filesDir := t.TempDir(), t.TempDir()
+	for name, content := range map[string]string{
+		"Some episode [abcdefghijk].mp3": "content1",
+		"old_one.mp3":                    "content2",
+		"old_one.info.json": `{"id":"lmnopqrstuv","title":"Old one","description":"about","upload_date":"20220406",` +
+			`"channel":"author1","channel_url":"https://www.youtube.com/channel/channel1"}`,
+		"vid-3_xyzAB.mp3":          "content3",
+		"no id here.mp3":           "content4",
+		"cover.jpg":                "image",
+		"stored [storedvid01].mp3": "content5",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0o600))
+	}
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	stored := ytfeed.Entry{ChannelID: "channel1", VideoID: "storedvid01", Title: "stored"}
+	_, err = boltStore.Save(stored)
+	require.NoError(t, err)
+	require.NoError(t, boltStore.SetProcessed(stored))
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}},
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		FilesLocation:   filesDir,
+		RootURL:         "http://localhost:8080/yt",
+		RSSFileStore:    RSSFileStore{Enabled: true, Location: t.TempDir()},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	_, err = svc.Import(context.Background(), "unknown", srcDir)
+	assert.EqualError(t, err, "feed unknown not found")
+
+	res, err := svc.Import(context.Background(), "channel1", srcDir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"abcdefghijk", "lmnopqrstuv", "vid-3_xyzAB"}, res.Imported)
+	assert.Equal(t, []string{"storedvid01"}, res.Skipped)
+	assert.Equal(t, []string{filepath.Join(srcDir, "no id here.mp3")}, res.Unmatched)
+
+	entries, err := boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(entries))
+	byID := map[string]ytfeed.Entry{}
+	for _, e := range entries {
+		byID[e.VideoID] = e
+	}
+
+	old := byID["lmnopqrstuv"]
+	assert.Equal(t, "name1: Old one", old.Title, "title from info json")
+	assert.Equal(t, "about", string(old.Media.Description))
+	assert.Equal(t, "author1", old.Author.Name)
+	assert.Equal(t, time.Date(2022, 4, 6, 0, 0, 0, 0, time.UTC), old.Published.UTC())
+	assert.Equal(t, filepath.Join(filesDir, "channel1", "old_one.mp3"), old.File)
+	assert.Equal(t, 1234, old.Duration)
+	assert.Equal(t, int64(len("content2")), old.FileSize)
+	assert.NotEmpty(t, old.Checksum)
+
+	assert.Equal(t, "name1: Some episode", byID["abcdefghijk"].Title, "title from file name")
+	assert.Equal(t, "https://www.youtube.com/watch?v=abcdefghijk", byID["abcdefghijk"].Link.Href)
+	assert.Equal(t, "name1: vid-3 xyzAB", byID["vid-3_xyzAB"].Title, "bare id as title")
+	assert.Equal(t, filepath.Join(filesDir, "channel1", "vid-3_xyzAB.mp3"), byID["vid-3_xyzAB"].File)
+
+	for _, id := range res.Imported {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: id})
+		require.NoError(t, err)
+		assert.True(t, found, "%s processed", id)
+	}
+	_, err = os.Stat(filepath.Join(srcDir, "old_one.mp3"))
+	assert.True(t, os.IsNotExist(err), "file moved")
+	_, err = os.Stat(filepath.Join(srcDir, "no id here.mp3"))
+	assert.NoError(t, err, "unmatched file kept")
+
+	rss, err := os.ReadFile(filepath.Join(svc.RSSFileStore.Location, "channel1.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(rss), "http://localhost:8080/yt/channel1/old_one.mp3")
+
+	// files in the feed's dir imported in place, already stored skipped. Info json is not moved with the file
+	res, err = svc.Import(context.Background(), "channel1", filepath.Join(filesDir, "channel1"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(res.Imported))
+	assert.ElementsMatch(t, []string{"abcdefghijk", "vid-3_xyzAB"}, res.Skipped)
+	assert.Equal(t, []string{filepath.Join(filesDir, "channel1", "old_one.mp3")}, res.Unmatched)
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mp3")
+	require.NoError(t, os.WriteFile(src, []byte("content"), 0o600))
+
+	require.NoError(t, moveFile(src, src), "same file")
+	dst := filepath.Join(dir, "sub", "dst.mp3")
+	require.NoError(t, moveFile(src, dst))
+	data, err := os.ReadFile(dst) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+	_, err = os.Stat(src)
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, os.WriteFile(src, []byte("content2"), 0o600))
+	assert.EqualError(t, moveFile(src, dst), "destination "+dst+" exists")
+}
@@ -0,0 +1,162 @@
+package youtube
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// langDetectEntries is the number of the newest entries used to detect language of the feed
+const langDetectEntries = 10
+
+// detectedLangs keeps detected language by feed id, detected once per feed
+type detectedLangs struct {
+	mu     sync.Mutex
+	byFeed map[string]string
+}
+
+func (d *detectedLangs) get(feedID string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lang, ok := d.byFeed[feedID]
+	return lang, ok
+}
+
+func (d *detectedLangs) set(feedID, lang string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.byFeed == nil {
+		d.byFeed = map[string]string{}
+	}
+	d.byFeed[feedID] = lang
+}
+
+// feedLanguage returns language of the feed for rss. Configured language is used as is, otherwise, with DetectLanguage
+// set, the language detected from the entries. Empty if not configured and not detected.
+func (s *Service) feedLanguage(fi FeedInfo, entries []ytfeed.Entry) string {
+	if fi.Language != "" || !s.DetectLanguage {
+		return fi.Language
+	}
+	if lang, ok := s.langs.get(fi.ID); ok {
+		return lang
+	}
+	if len(entries) > langDetectEntries {
+		entries = entries[:langDetectEntries]
+	}
+	lang := detectLanguage(entries)
+	if lang != "" {
+		s.langs.set(fi.ID, lang) // not cached if undetected, retried with more entries later
+		s.event("INFO", "lang", "detected language "+lang+" of "+fi.Name, feedFields(fi).with("lang", lang))
+	}
+	return lang
+}
+
+// detectLanguage returns the most common language of the entries if known (yt-dlp's metadata or peertube),
+// otherwise guesses it from titles and descriptions. Empty if can't be guessed.
+func detectLanguage(entries []ytfeed.Entry) string {
+	counts := map[string]int{}
+	for _, e := range entries {
+		if e.Language != "" {
+			counts[strings.ToLower(e.Language)]++
+		}
+	}
+	if lang := topCount(counts, 1); lang != "" {
+		return lang
+	}
+
+	texts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		texts = append(texts, e.Title, string(e.Media.Description))
+	}
+	return guessLanguage(strings.Join(texts, " "))
+}
+
+// scriptLangs maps non-latin scripts to the most likely language
+var scriptLangs = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Cyrillic, "ru"}, {unicode.Greek, "el"}, {unicode.Arabic, "ar"}, {unicode.Hebrew, "he"},
+	{unicode.Hangul, "ko"}, {unicode.Hiragana, "ja"}, {unicode.Katakana, "ja"}, {unicode.Han, "zh"},
+	{unicode.Thai, "th"}, {unicode.Devanagari, "hi"}, {unicode.Armenian, "hy"}, {unicode.Georgian, "ka"},
+}
+
+// stopWords are frequent short words of latin script languages
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "with", "for", "this", "that", "what", "how", "you", "we"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "ich", "wir", "auf", "für", "wie"},
+	"fr": {"le", "la", "les", "et", "est", "une", "des", "du", "pour", "dans", "avec", "pas", "qui", "nous"},
+	"es": {"el", "los", "las", "y", "es", "una", "del", "por", "para", "con", "que", "cómo", "qué", "como"},
+	"it": {"il", "gli", "di", "e", "è", "una", "del", "per", "con", "che", "non", "come", "sono", "della"},
+	"pt": {"o", "os", "as", "e", "é", "um", "uma", "do", "da", "para", "com", "não", "que", "como"},
+}
+
+// guessLanguage guesses language of the text by the dominant script, and by stop words for latin script
+func guessLanguage(text string) string {
+	scripts := map[string]int{}
+	latin, letters := 0, 0
+	ukrainian := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, sl := range scriptLangs {
+			if unicode.Is(sl.table, r) {
+				scripts[sl.lang]++
+				break
+			}
+		}
+		if strings.ContainsRune("іїєґІЇЄҐ", r) {
+			ukrainian = true
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	if scripts["ja"] > 0 && scripts["zh"] > 0 {
+		scripts["ja"] += scripts["zh"] // kanji are han characters, kana means japanese
+		delete(scripts, "zh")
+	}
+	if lang := topCount(scripts, letters/2+1); lang != "" {
+		if lang == "ru" && ukrainian {
+			return "uk"
+		}
+		return lang
+	}
+	if latin <= letters/2 {
+		return ""
+	}
+
+	words := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		words[w]++
+	}
+	hits := map[string]int{}
+	for lang, sw := range stopWords {
+		for _, w := range sw {
+			hits[lang] += words[w]
+		}
+	}
+	return topCount(hits, 3)
+}
+
+// topCount returns the key with the largest count, if the count is at least min and larger than any other count
+func topCount(counts map[string]int, min int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+	if len(keys) == 0 || counts[keys[0]] < min || (len(keys) > 1 && counts[keys[0]] == counts[keys[1]]) {
+		return ""
+	}
+	return keys[0]
+}
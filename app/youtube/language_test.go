@@ -0,0 +1,82 @@
+package youtube
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+func TestGuessLanguage(t *testing.T) {
+	tbl := []struct {
+		text string
+		lang string
+	}{
+		{"How to make the best coffee at home, and what you need for this", "en"},
+		{"Wie wir das Haus mit einer Wärmepumpe heizen und was nicht funktioniert", "de"},
+		{"Comment faire le pain à la maison avec des ingrédients simples pour les enfants", "fr"},
+		{"Cómo hacer el pan en casa con los ingredientes que tienes y es fácil", "es"},
+		{"Новости дня: что произошло в мире", "ru"},
+		{"Новини дня: що відбулося у світі", "uk"},
+		{"今日のニュース、東京の天気", "ja"},
+		{"今天的新闻", "zh"},
+		{"오늘의 뉴스", "ko"},
+		{"Σήμερα στις ειδήσεις", "el"},
+		{"Episode 12 Live", ""},
+		{"12 34 !!", ""},
+		{"", ""},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.lang, guessLanguage(tt.text), tt.text)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	entries := []ytfeed.Entry{
+		{Title: "The news of the day", Language: "de"},
+		{Title: "What is new in the world", Language: "DE"},
+		{Title: "How to stay calm", Language: "en"},
+	}
+	assert.Equal(t, "de", detectLanguage(entries), "entries' language preferred")
+
+	entries = []ytfeed.Entry{{Title: "Новости"}, {Title: "Episode 2"}}
+	entries[1].Media.Description = template.HTML("<p>Что произошло за неделю</p>")
+	assert.Equal(t, "ru", detectLanguage(entries), "guessed from titles and descriptions")
+
+	assert.Equal(t, "", detectLanguage(nil))
+}
+
+func TestService_feedLanguage(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", Title: "Как приготовить хлеб дома", File: "/tmp/file1.mp3"}}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10, DetectLanguage: true}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, "<language>ru</language>", "detected")
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Language: "uk-ua"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, "<language>uk-ua</language>", "configured language is authoritative")
+
+	lang, ok := svc.langs.get("channel1")
+	assert.True(t, ok)
+	assert.Equal(t, "ru", lang, "cached")
+	assert.Equal(t, "ru", svc.feedLanguage(FeedInfo{ID: "channel1"}, nil), "from cache")
+
+	assert.Equal(t, "", svc.feedLanguage(FeedInfo{ID: "channel2"}, []ytfeed.Entry{{Title: "12"}}), "undetected")
+	_, ok = svc.langs.get("channel2")
+	assert.False(t, ok, "undetected not cached")
+
+	svc.DetectLanguage = false
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel3", Name: "name3"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, "<language></language>", "detection disabled")
+}
@@ -0,0 +1,96 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// listEntries returns entries of the feed from the source. With ListingTTL set, the listing is cached in the store,
+// and the first check of the feed after start reuses the cached listing if it is not older than ListingTTL.
+// This way restarts don't hit the source with all feeds at once.
+func (s *Service) listEntries(ctx context.Context, fi FeedInfo) ([]ytfeed.Entry, error) {
+	if s.ListingTTL <= 0 {
+		return s.fetchEntries(ctx, fi)
+	}
+
+	key := listingKey(fi)
+	firstCheck := !s.listed[key]
+	if s.listed == nil {
+		s.listed = map[string]bool{}
+	}
+	s.listed[key] = true
+
+	if firstCheck {
+		cached, err := s.Store.Listing(key)
+		if err != nil {
+			log.Printf("[WARN] failed to load cached listing of %s, %v", fi.Name, err)
+		}
+		if err == nil && !cached.TS.IsZero() && time.Since(cached.TS) < s.ListingTTL {
+			s.event("INFO", "fetch", fmt.Sprintf("use cached listing of %s, fetched %v ago", fi.Name,
+				time.Since(cached.TS).Round(time.Second)), feedFields(fi).with("cached", cached.TS))
+			return cached.Entries, nil
+		}
+	}
+
+	entries, err := s.fetchEntries(ctx, fi)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Store.SetListing(key, ytfeed.Listing{Entries: entries, TS: time.Now()}); err != nil {
+		log.Printf("[WARN] failed to cache listing of %s, %v", fi.Name, err)
+	}
+	return entries, nil
+}
+
+// fetchEntries gets entries of the feed from the source, up to FetchAttempts times with exponential backoff
+// from FetchRetryDelay. Permanent errors, i.e. wrong feed id or denied access, returned without retries.
+func (s *Service) fetchEntries(ctx context.Context, fi FeedInfo) ([]ytfeed.Entry, error) {
+	delay := s.FetchRetryDelay
+	if delay <= 0 {
+		delay = 5 * time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		// no publishedAfter cutoff, none of the sources filters on the server side. All listed entries checked against
+		// processed markers, this way failed, deferred and old videos added to playlists are picked up on later cycles
+		entries, err := s.ChannelService.Get(s.sourceContext(ctx, fi), fi.ID, fi.Type, time.Time{}, fi.APIKey)
+		if err == nil || attempt >= s.FetchAttempts || ctx.Err() != nil || isPermanentFetchError(err) {
+			return entries, err
+		}
+		s.event("WARN", "fetch", fmt.Sprintf("failed to get entries of %s, attempt %d of %d, retry in %v: %v",
+			fi.Name, attempt, s.FetchAttempts, delay, err), feedFields(fi).with("attempt", attempt).with("error", err.Error()))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// sourceContext returns ctx for requests to the feed's source, with the feed's headers and alternative source if set
+func (s *Service) sourceContext(ctx context.Context, fi FeedInfo) context.Context {
+	if len(fi.Headers) > 0 {
+		log.Printf("[DEBUG] get %s with headers %v", fi.Name, fi.Headers)
+		ctx = ytfeed.WithHeaders(ctx, fi.Headers)
+	}
+	return ytfeed.WithSource(ctx, fi.Source)
+}
+
+// isPermanentFetchError checks if the error of listing can't be fixed by retry
+func isPermanentFetchError(err error) bool {
+	return errors.Is(err, ytfeed.ErrFeedNotFound) || errors.Is(err, ytfeed.ErrAccessDenied)
+}
+
+// listingKey makes the key of the feed's cached listing, feeds may share id with different types
+func listingKey(fi FeedInfo) string {
+	if fi.Type == "" {
+		return fi.ID + "::" + string(ytfeed.FTChannel)
+	}
+	return fi.ID + "::" + string(fi.Type)
+}
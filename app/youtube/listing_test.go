@@ -0,0 +1,164 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestService_listEntries(t *testing.T) {
+	failing := false
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			if failing {
+				return nil, errors.New("quota exceeded")
+			}
+			return []ytfeed.Entry{{ChannelID: chanID, VideoID: "vid-" + string(feedType), Title: "title1"}}, nil
+		},
+	}
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	fi := FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}
+	shorts := FeedInfo{ID: "channel1", Name: "name1 shorts", Type: ytfeed.FTShorts}
+
+	svc := Service{ChannelService: chans, Store: boltStore, ListingTTL: time.Hour}
+	entries, err := svc.listEntries(context.Background(), fi)
+	require.NoError(t, err)
+	assert.Equal(t, "vid-channel", entries[0].VideoID)
+	require.Equal(t, 1, len(chans.GetCalls()), "cache miss, nothing cached yet")
+
+	// restart within ttl
+	svc = Service{ChannelService: chans, Store: boltStore, ListingTTL: time.Hour}
+	failing = true
+	entries, err = svc.listEntries(context.Background(), fi)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "vid-channel", entries[0].VideoID)
+	assert.Equal(t, "title1", entries[0].Title)
+	assert.Equal(t, 1, len(chans.GetCalls()), "cache hit on the first check")
+
+	_, err = svc.listEntries(context.Background(), shorts)
+	assert.EqualError(t, err, "quota exceeded", "cached per feed type")
+	assert.Equal(t, 2, len(chans.GetCalls()))
+
+	failing = false
+	_, err = svc.listEntries(context.Background(), fi)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(chans.GetCalls()), "fetched on next checks")
+
+	// restart after ttl
+	require.NoError(t, boltStore.SetListing(listingKey(fi), ytfeed.Listing{Entries: entries, TS: time.Now().Add(-2 * time.Hour)}))
+	svc = Service{ChannelService: chans, Store: boltStore, ListingTTL: time.Hour}
+	_, err = svc.listEntries(context.Background(), fi)
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(chans.GetCalls()), "cache miss, expired")
+	cached, err := boltStore.Listing(listingKey(fi))
+	require.NoError(t, err)
+	assert.True(t, time.Since(cached.TS) < time.Minute, "cache refreshed")
+
+	// cache disabled
+	svc = Service{ChannelService: chans, Store: boltStore}
+	_, err = svc.listEntries(context.Background(), fi)
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(chans.GetCalls()))
+}
+
+func TestService_fetchEntriesRetry(t *testing.T) {
+	var errs []error
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			if len(errs) > 0 {
+				err := errs[0]
+				errs = errs[1:]
+				return nil, err
+			}
+			return []ytfeed.Entry{{ChannelID: chanID, VideoID: "vid1", Title: "title1"}}, nil
+		},
+	}
+	fi := FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	svc := Service{ChannelService: chans, Store: &store.BoltDB{DB: db}, FetchAttempts: 3, FetchRetryDelay: time.Millisecond}
+
+	errs = []error{errors.New("503 Service Unavailable"), errors.New("timeout")}
+	entries, err := svc.fetchEntries(context.Background(), fi)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, 3, len(chans.GetCalls()), "succeeded on the last attempt")
+
+	errs = []error{errors.New("e1"), errors.New("e2"), errors.New("e3"), errors.New("e4")}
+	_, err = svc.fetchEntries(context.Background(), fi)
+	assert.EqualError(t, err, "e3", "failed after all attempts")
+	assert.Equal(t, 6, len(chans.GetCalls()))
+
+	errs = []error{fmt.Errorf("channel bad-id: 404 Not Found: %w", ytfeed.ErrFeedNotFound)}
+	_, err = svc.fetchEntries(context.Background(), fi)
+	assert.True(t, errors.Is(err, ytfeed.ErrFeedNotFound))
+	assert.Equal(t, 7, len(chans.GetCalls()), "permanent error not retried")
+
+	errs = []error{fmt.Errorf("private: %w", ytfeed.ErrAccessDenied)}
+	_, err = svc.fetchEntries(context.Background(), fi)
+	assert.True(t, errors.Is(err, ytfeed.ErrAccessDenied))
+	assert.Equal(t, 8, len(chans.GetCalls()), "permanent error not retried")
+
+	errs = []error{errors.New("e1"), errors.New("e2")}
+	svc.FetchRetryDelay = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = svc.fetchEntries(ctx, fi)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "retry interrupted by context")
+	assert.Equal(t, 9, len(chans.GetCalls()))
+
+	errs = []error{errors.New("e1")}
+	svc.FetchAttempts = 0
+	_, err = svc.fetchEntries(context.Background(), fi)
+	assert.EqualError(t, err, "e1", "single attempt if not set")
+	assert.Equal(t, 10, len(chans.GetCalls()))
+}
+
+func TestService_fetchEntriesHeaders(t *testing.T) {
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, e := w.Write([]byte(`<feed><entry><id>yt:video:vid1</id><title>title1</title></entry></feed>`))
+		require.NoError(t, e)
+	}))
+	defer ts.Close()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	svc := Service{Store: &store.BoltDB{DB: db},
+		ChannelService: &ytfeed.Feed{Client: &http.Client{Timeout: time.Second}, ChannelBaseURL: ts.URL + "?channel_id="}}
+
+	fi := FeedInfo{ID: "channel1", Name: "name1", Headers: ytfeed.Headers{"Authorization": "Bearer token1"}}
+	entries, err := svc.fetchEntries(context.Background(), fi)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "Bearer token1", auth)
+
+	_, err = svc.fetchEntries(context.Background(), FeedInfo{ID: "channel2", Name: "name2"})
+	require.NoError(t, err)
+	assert.Equal(t, "", auth, "headers of the feed only")
+}
+
+func TestListingKey(t *testing.T) {
+	assert.Equal(t, "channel1::channel", listingKey(FeedInfo{ID: "channel1"}))
+	assert.Equal(t, "channel1::channel", listingKey(FeedInfo{ID: "channel1", Type: ytfeed.FTChannel}))
+	assert.Equal(t, "channel1::shorts", listingKey(FeedInfo{ID: "channel1", Type: ytfeed.FTShorts}))
+}
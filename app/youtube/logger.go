@@ -0,0 +1,121 @@
+package youtube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// Fields are structured attributes of the event, i.e. feed name, video id or stats
+type Fields map[string]interface{}
+
+// EventLogger records processing events of the service with the action and structured fields
+type EventLogger interface {
+	Event(level, action, msg string, fields Fields)
+}
+
+// TextLogger reports events as text lines with lgr, fields are not printed as the message has all the details.
+// Used by default.
+type TextLogger struct {
+	L log.L // lgr.Default() if nil
+}
+
+// Event prints "[LEVEL] msg"
+func (t TextLogger) Event(level, _, msg string, _ Fields) {
+	l := t.L
+	if l == nil {
+		l = log.Default()
+	}
+	l.Logf("[%s] %s", level, msg)
+}
+
+// JSONLogger writes events as json lines with ts, level, action, msg and all the fields of the event.
+// It is also io.Writer for lgr's output formatted with JSONLogFormat, so all log lines are json.
+type JSONLogger struct {
+	Out   io.Writer
+	Debug bool // write DEBUG events, skipped otherwise
+
+	mu  sync.Mutex
+	now func() time.Time // for tests
+}
+
+// JSONLogFormat is lgr's format expected by JSONLogger.Write
+const JSONLogFormat = "{{.Level}} {{.Message}}"
+
+// Event writes the event as a single json line
+func (j *JSONLogger) Event(level, action, msg string, fields Fields) {
+	if level == "DEBUG" && !j.Debug {
+		return
+	}
+	now := time.Now
+	if j.now != nil {
+		now = j.now
+	}
+	rec := make(map[string]interface{}, len(fields)+4)
+	for k, v := range fields {
+		rec[k] = v
+	}
+	rec["ts"] = now().Format(time.RFC3339Nano)
+	rec["level"] = level
+	rec["msg"] = msg
+	if action != "" {
+		rec["action"] = action
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"level":"ERROR","msg":%q}`, "failed to marshal log record: "+err.Error()))
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, _ = j.Out.Write(append(data, '\n'))
+}
+
+// Write converts lgr's "LEVEL message" line to json record
+func (j *JSONLogger) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	level, msg := "INFO", line
+	if elems := strings.SplitN(line, " ", 2); len(elems) == 2 {
+		switch elems[0] {
+		case "TRACE", "DEBUG", "INFO", "WARN", "ERROR", "PANIC", "FATAL":
+			level, msg = elems[0], strings.TrimSpace(elems[1])
+		}
+	}
+	j.Event(level, "", msg, nil)
+	return len(p), nil
+}
+
+// event reports the event with Logger, TextLogger if not set
+func (s *Service) event(level, action, msg string, fields Fields) {
+	if s.Logger == nil {
+		TextLogger{}.Event(level, action, msg, fields)
+		return
+	}
+	s.Logger.Event(level, action, msg, fields)
+}
+
+// with adds the field, returns the same map for chaining
+func (f Fields) with(key string, val interface{}) Fields {
+	f[key] = val
+	return f
+}
+
+// feedFields makes fields of the event for the feed
+func feedFields(fi FeedInfo) Fields {
+	return Fields{"feed": fi.Name, "feed_id": fi.ID}
+}
+
+// entryFields makes fields of the event for the feed's entry
+func entryFields(fi FeedInfo, entry ytfeed.Entry) Fields {
+	res := feedFields(fi)
+	res["video_id"] = entry.VideoID
+	res["title"] = entry.Title
+	return res
+}
@@ -0,0 +1,129 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+func TestJSONLogger_Event(t *testing.T) {
+	buf := bytes.Buffer{}
+	ts := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	jl := &JSONLogger{Out: &buf, now: func() time.Time { return ts }}
+
+	jl.Event("INFO", "new", "new entry vid1", Fields{"feed": "name1", "video_id": "vid1", "stats": Stats{Added: 2}})
+	jl.Event("DEBUG", "filter", "skipped", nil)
+	jl.Event("WARN", "", "no action", nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, 2, len(lines), "debug event skipped")
+	assert.Equal(t, `{"action":"new","feed":"name1","level":"INFO","msg":"new entry vid1",`+
+		`"stats":{"entries":0,"processed":0,"added":2,"removed":0,"ignored":0,"skipped":0,"deferred":0,"bytes":0},`+
+		`"ts":"2022-05-01T10:00:00Z","video_id":"vid1"}`, lines[0])
+	assert.Equal(t, `{"level":"WARN","msg":"no action","ts":"2022-05-01T10:00:00Z"}`, lines[1])
+
+	buf.Reset()
+	jl.Debug = true
+	jl.Event("DEBUG", "filter", "skipped", nil)
+	assert.Contains(t, buf.String(), `"level":"DEBUG"`)
+}
+
+func TestJSONLogger_Write(t *testing.T) {
+	buf := bytes.Buffer{}
+	jl := &JSONLogger{Out: &buf}
+	l := log.New(log.Format(JSONLogFormat), log.Out(jl), log.Err(jl))
+	l.Logf("[WARN] something \"quoted\" happened")
+	l.Logf("no level")
+	l.Logf("[ERROR] multi\nline")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, 3, len(lines), buf.String())
+	recs := make([]map[string]string, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &recs[i]), line)
+	}
+	assert.Equal(t, "WARN", recs[0]["level"])
+	assert.Equal(t, `something "quoted" happened`, recs[0]["msg"])
+	assert.Equal(t, "INFO", recs[1]["level"])
+	assert.Equal(t, "no level", recs[1]["msg"])
+	assert.Equal(t, "ERROR", recs[2]["level"])
+	assert.Equal(t, "multi\nline", recs[2]["msg"])
+}
+
+func TestService_JSONEvents(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()}}, nil
+		},
+	}
+	dir := t.TempDir()
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content"), 0o600)
+		},
+	}
+	storeSvc := &mocks.StoreServiceMock{
+		CheckProcessedFunc: func(entry ytfeed.Entry) (bool, time.Time, error) { return false, time.Time{}, nil },
+		ExistFunc:          func(entry ytfeed.Entry) (bool, error) { return false, nil },
+		SaveFunc:           func(entry ytfeed.Entry) (bool, error) { return true, nil },
+		SetProcessedFunc:   func(entry ytfeed.Entry) error { return nil },
+		LoadFunc:           func(channelID string, max int) ([]ytfeed.Entry, error) { return nil, nil },
+		RemoveOldFunc:      func(channelID string, keep int) ([]string, error) { return nil, nil },
+		AddBytesFunc:       func(channelID string, size int64) error { return nil },
+		CountBytesFunc:     func(channelID string) int64 { return 7 },
+		CountProcessedFunc: func() int { return 1 },
+		SetFetchErrorFunc:  func(channelID string, fe ytfeed.FetchError) error { return nil },
+	}
+
+	buf := bytes.Buffer{}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1"}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           storeSvc,
+		KeepPerChannel:  10,
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		Logger:          &JSONLogger{Out: &buf},
+	}
+	_, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+
+	events := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		rec := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		events[rec["action"].(string)] = rec
+	}
+	t.Logf("%s", buf.String())
+
+	require.Contains(t, events, "fetch")
+	assert.Equal(t, "name1", events["fetch"]["feed"])
+	assert.Equal(t, "channel1", events["fetch"]["feed_id"])
+	assert.Equal(t, float64(1), events["fetch"]["entries"])
+
+	for _, action := range []string{"new", "download"} {
+		require.Contains(t, events, action)
+		assert.Equal(t, "name1", events[action]["feed"], action)
+		assert.Equal(t, "vid1", events[action]["video_id"], action)
+		assert.Equal(t, "INFO", events[action]["level"], action)
+	}
+
+	require.Contains(t, events, "cycle_processed")
+	stats := events["cycle_processed"]["stats"].(map[string]interface{})
+	assert.Equal(t, float64(1), stats["added"])
+	assert.Equal(t, float64(7), events["cycle_processed"]["lifetime_bytes"])
+}
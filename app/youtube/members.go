@@ -0,0 +1,62 @@
+package youtube
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MembersOnly defines how members-only videos of the feed, available to paying members of the channel, are handled
+type MembersOnly string
+
+// enum for members-only modes
+const (
+	MOInclude = MembersOnly("")     // members-only videos downloaded like others, if cookies of a member allow
+	MOSkip    = MembersOnly("skip") // members-only videos excluded
+	MOOnly    = MembersOnly("only") // only members-only videos downloaded
+)
+
+// availabilityMembers is yt-dlp's availability of members-only videos
+const availabilityMembers = "subscriber_only"
+
+// UnmarshalYAML checks members-only mode is known, case insensitive
+func (m *MembersOnly) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	res := MembersOnly(strings.ToLower(strings.TrimSpace(s)))
+	switch res {
+	case MOInclude, MOSkip, MOOnly:
+		*m = res
+		return nil
+	}
+	return errors.Errorf("unknown members_only %q", s)
+}
+
+// matchFilter returns yt-dlp's match filter excluding videos by the mode before the download, empty for MOInclude.
+// Videos with unknown availability are not skipped by MOSkip
+func (m MembersOnly) matchFilter() string {
+	switch m {
+	case MOSkip:
+		return "availability!=?" + availabilityMembers
+	case MOOnly:
+		return "availability=" + availabilityMembers
+	}
+	return ""
+}
+
+// allows checks yt-dlp's availability of the downloaded video against the mode, for downloaders without match
+// filter support. Unknown (empty) availability allowed
+func (m MembersOnly) allows(availability string) bool {
+	if availability == "" {
+		return true
+	}
+	switch m {
+	case MOSkip:
+		return availability != availabilityMembers
+	case MOOnly:
+		return availability == availabilityMembers
+	}
+	return true
+}
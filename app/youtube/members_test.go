@@ -0,0 +1,114 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestMembersOnly(t *testing.T) {
+	var fi FeedInfo
+	require.NoError(t, yaml.Unmarshal([]byte("{id: c1, members_only: Skip}"), &fi))
+	assert.Equal(t, MOSkip, fi.MembersOnly)
+	require.NoError(t, yaml.Unmarshal([]byte("{id: c1, members_only: only}"), &fi))
+	assert.Equal(t, MOOnly, fi.MembersOnly)
+	assert.Error(t, yaml.Unmarshal([]byte("{id: c1, members_only: true}"), &fi))
+
+	tbl := []struct {
+		mode          MembersOnly
+		filter        string
+		public, membr bool // allowed public and members-only videos
+	}{
+		{MOInclude, "", true, true},
+		{MOSkip, "availability!=?subscriber_only", true, false},
+		{MOOnly, "availability=subscriber_only", false, true},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.filter, tt.mode.matchFilter(), tt.mode)
+		assert.Equal(t, tt.public, tt.mode.allows("public"), tt.mode)
+		assert.Equal(t, tt.membr, tt.mode.allows("subscriber_only"), tt.mode)
+		assert.True(t, tt.mode.allows(""), "unknown availability allowed")
+	}
+}
+
+func TestService_DoMembersOnly(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid4", Title: "members-only, no filter support", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid3", Title: "members-only, no cookies", Published: time.Now().Add(-time.Minute)},
+				{ChannelID: chanID, VideoID: "vid2", Title: "members-only", Published: time.Now().Add(-2 * time.Minute)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "public", Published: time.Now().Add(-time.Hour)},
+			}, nil
+		},
+	}
+	downloader := &optsDownloader{DownloaderServiceMock: &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			switch id {
+			case "vid2":
+				return "", ytfeed.ErrFiltered
+			case "vid3":
+				return "", ytfeed.ErrMembersOnly
+			}
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			if id == "vid4" {
+				info := strings.TrimSuffix(file, ".mp3") + ".info.json"
+				require.NoError(t, os.WriteFile(info, []byte(`{"availability": "subscriber_only"}`), 0o600))
+			}
+			return file, os.WriteFile(file, []byte("content"), 0o600)
+		},
+	}}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, MembersOnly: MOSkip}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added)
+	assert.Equal(t, 3, st.Ignored)
+	require.Equal(t, 4, len(downloader.opts))
+	assert.Equal(t, "availability!=?subscriber_only", downloader.opts[0].MatchFilter)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid1", res[0].VideoID)
+	for _, vid := range []string{"vid2", "vid3", "vid4"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: vid})
+		require.NoError(t, err)
+		assert.True(t, found, "members-only %s marked processed", vid)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "channel1", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{res[0].File}, files, "members-only file and its info json removed")
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(downloader.GetCalls()), "skipped videos not retried")
+}
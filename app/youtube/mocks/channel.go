@@ -6,6 +6,7 @@ package mocks
 import (
 	"context"
 	"sync"
+	"time"
 
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 )
@@ -16,9 +17,12 @@ import (
 //
 // 		// make and configure a mocked youtube.ChannelService
 // 		mockedChannelService := &ChannelServiceMock{
-// 			GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type) ([]ytfeed.Entry, error) {
+// 			GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
 // 				panic("mock out the Get method")
 // 			},
+// 			GetPageFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, page int, apiKey string) ([]ytfeed.Entry, error) {
+// 				panic("mock out the GetPage method")
+// 			},
 // 		}
 //
 // 		// use mockedChannelService in code that requires youtube.ChannelService
@@ -27,7 +31,10 @@ import (
 // 	}
 type ChannelServiceMock struct {
 	// GetFunc mocks the Get method.
-	GetFunc func(ctx context.Context, chanID string, feedType ytfeed.Type) ([]ytfeed.Entry, error)
+	GetFunc func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error)
+
+	// GetPageFunc mocks the GetPage method.
+	GetPageFunc func(ctx context.Context, chanID string, feedType ytfeed.Type, page int, apiKey string) ([]ytfeed.Entry, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -39,46 +46,119 @@ type ChannelServiceMock struct {
 			ChanID string
 			// FeedType is the feedType argument value.
 			FeedType ytfeed.Type
+			// PublishedAfter is the publishedAfter argument value.
+			PublishedAfter time.Time
+			// ApiKey is the apiKey argument value.
+			ApiKey string
+		}
+		// GetPage holds details about calls to the GetPage method.
+		GetPage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ChanID is the chanID argument value.
+			ChanID string
+			// FeedType is the feedType argument value.
+			FeedType ytfeed.Type
+			// Page is the page argument value.
+			Page int
+			// ApiKey is the apiKey argument value.
+			ApiKey string
 		}
 	}
-	lockGet sync.RWMutex
+	lockGet     sync.RWMutex
+	lockGetPage sync.RWMutex
 }
 
 // Get calls GetFunc.
-func (mock *ChannelServiceMock) Get(ctx context.Context, chanID string, feedType ytfeed.Type) ([]ytfeed.Entry, error) {
+func (mock *ChannelServiceMock) Get(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
 	if mock.GetFunc == nil {
 		panic("ChannelServiceMock.GetFunc: method is nil but ChannelService.Get was just called")
 	}
 	callInfo := struct {
-		Ctx      context.Context
-		ChanID   string
-		FeedType ytfeed.Type
+		Ctx            context.Context
+		ChanID         string
+		FeedType       ytfeed.Type
+		PublishedAfter time.Time
+		ApiKey         string
 	}{
-		Ctx:      ctx,
-		ChanID:   chanID,
-		FeedType: feedType,
+		Ctx:            ctx,
+		ChanID:         chanID,
+		FeedType:       feedType,
+		PublishedAfter: publishedAfter,
+		ApiKey:         apiKey,
 	}
 	mock.lockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	mock.lockGet.Unlock()
-	return mock.GetFunc(ctx, chanID, feedType)
+	return mock.GetFunc(ctx, chanID, feedType, publishedAfter, apiKey)
 }
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
 //     len(mockedChannelService.GetCalls())
 func (mock *ChannelServiceMock) GetCalls() []struct {
+	Ctx            context.Context
+	ChanID         string
+	FeedType       ytfeed.Type
+	PublishedAfter time.Time
+	ApiKey         string
+} {
+	var calls []struct {
+		Ctx            context.Context
+		ChanID         string
+		FeedType       ytfeed.Type
+		PublishedAfter time.Time
+		ApiKey         string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// GetPage calls GetPageFunc.
+func (mock *ChannelServiceMock) GetPage(ctx context.Context, chanID string, feedType ytfeed.Type, page int, apiKey string) ([]ytfeed.Entry, error) {
+	if mock.GetPageFunc == nil {
+		panic("ChannelServiceMock.GetPageFunc: method is nil but ChannelService.GetPage was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ChanID   string
+		FeedType ytfeed.Type
+		Page     int
+		ApiKey   string
+	}{
+		Ctx:      ctx,
+		ChanID:   chanID,
+		FeedType: feedType,
+		Page:     page,
+		ApiKey:   apiKey,
+	}
+	mock.lockGetPage.Lock()
+	mock.calls.GetPage = append(mock.calls.GetPage, callInfo)
+	mock.lockGetPage.Unlock()
+	return mock.GetPageFunc(ctx, chanID, feedType, page, apiKey)
+}
+
+// GetPageCalls gets all the calls that were made to GetPage.
+// Check the length with:
+//     len(mockedChannelService.GetPageCalls())
+func (mock *ChannelServiceMock) GetPageCalls() []struct {
 	Ctx      context.Context
 	ChanID   string
 	FeedType ytfeed.Type
+	Page     int
+	ApiKey   string
 } {
 	var calls []struct {
 		Ctx      context.Context
 		ChanID   string
 		FeedType ytfeed.Type
+		Page     int
+		ApiKey   string
 	}
-	mock.lockGet.RLock()
-	calls = mock.calls.Get
-	mock.lockGet.RUnlock()
+	mock.lockGetPage.RLock()
+	calls = mock.calls.GetPage
+	mock.lockGetPage.RUnlock()
 	return calls
 }
@@ -17,6 +17,9 @@ import (
 // 			GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
 // 				panic("mock out the Get method")
 // 			},
+// 			SubtitlesFunc: func(ctx context.Context, id string, fname string, lang string) (string, error) {
+// 				panic("mock out the Subtitles method")
+// 			},
 // 		}
 //
 // 		// use mockedDownloaderService in code that requires youtube.DownloaderService
@@ -27,6 +30,9 @@ type DownloaderServiceMock struct {
 	// GetFunc mocks the Get method.
 	GetFunc func(ctx context.Context, id string, fname string) (string, error)
 
+	// SubtitlesFunc mocks the Subtitles method.
+	SubtitlesFunc func(ctx context.Context, id string, fname string, lang string) (string, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// Get holds details about calls to the Get method.
@@ -38,8 +44,20 @@ type DownloaderServiceMock struct {
 			// Fname is the fname argument value.
 			Fname string
 		}
+		// Subtitles holds details about calls to the Subtitles method.
+		Subtitles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID string
+			// Fname is the fname argument value.
+			Fname string
+			// Lang is the lang argument value.
+			Lang string
+		}
 	}
-	lockGet sync.RWMutex
+	lockGet       sync.RWMutex
+	lockSubtitles sync.RWMutex
 }
 
 // Get calls GetFunc.
@@ -80,3 +98,46 @@ func (mock *DownloaderServiceMock) GetCalls() []struct {
 	mock.lockGet.RUnlock()
 	return calls
 }
+
+// Subtitles calls SubtitlesFunc.
+func (mock *DownloaderServiceMock) Subtitles(ctx context.Context, id string, fname string, lang string) (string, error) {
+	if mock.SubtitlesFunc == nil {
+		panic("DownloaderServiceMock.SubtitlesFunc: method is nil but DownloaderService.Subtitles was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    string
+		Fname string
+		Lang  string
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Fname: fname,
+		Lang:  lang,
+	}
+	mock.lockSubtitles.Lock()
+	mock.calls.Subtitles = append(mock.calls.Subtitles, callInfo)
+	mock.lockSubtitles.Unlock()
+	return mock.SubtitlesFunc(ctx, id, fname, lang)
+}
+
+// SubtitlesCalls gets all the calls that were made to Subtitles.
+// Check the length with:
+//     len(mockedDownloaderService.SubtitlesCalls())
+func (mock *DownloaderServiceMock) SubtitlesCalls() []struct {
+	Ctx   context.Context
+	ID    string
+	Fname string
+	Lang  string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    string
+		Fname string
+		Lang  string
+	}
+	mock.lockSubtitles.RLock()
+	calls = mock.calls.Subtitles
+	mock.lockSubtitles.RUnlock()
+	return calls
+}
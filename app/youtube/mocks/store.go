@@ -16,18 +16,42 @@ import (
 //
 // 		// make and configure a mocked youtube.StoreService
 // 		mockedStoreService := &StoreServiceMock{
+// 			AddBytesFunc: func(channelID string, size int64) error {
+// 				panic("mock out the AddBytes method")
+// 			},
+// 			AddPeriodBytesFunc: func(period string, size int64) error {
+// 				panic("mock out the AddPeriodBytes method")
+// 			},
 // 			CheckProcessedFunc: func(entry ytfeed.Entry) (bool, time.Time, error) {
 // 				panic("mock out the CheckProcessed method")
 // 			},
+// 			CountBytesFunc: func(channelID string) int64 {
+// 				panic("mock out the CountBytes method")
+// 			},
+// 			CountPeriodBytesFunc: func(period string) int64 {
+// 				panic("mock out the CountPeriodBytes method")
+// 			},
 // 			CountProcessedFunc: func() int {
 // 				panic("mock out the CountProcessed method")
 // 			},
 // 			ExistFunc: func(entry ytfeed.Entry) (bool, error) {
 // 				panic("mock out the Exist method")
 // 			},
+// 			FeedUpdatesFunc: func() (map[string]ytfeed.FeedUpdate, error) {
+// 				panic("mock out the FeedUpdates method")
+// 			},
+// 			FetchErrorsFunc: func() (map[string]ytfeed.FetchError, error) {
+// 				panic("mock out the FetchErrors method")
+// 			},
+// 			ListingFunc: func(feedKey string) (ytfeed.Listing, error) {
+// 				panic("mock out the Listing method")
+// 			},
 // 			LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
 // 				panic("mock out the Load method")
 // 			},
+// 			PruneProcessedFunc: func(maxAge time.Duration) (int, error) {
+// 				panic("mock out the PruneProcessed method")
+// 			},
 // 			RemoveFunc: func(entry ytfeed.Entry) error {
 // 				panic("mock out the Remove method")
 // 			},
@@ -40,6 +64,15 @@ import (
 // 			SaveFunc: func(entry ytfeed.Entry) (bool, error) {
 // 				panic("mock out the Save method")
 // 			},
+// 			SetFeedUpdateFunc: func(feedID string, upd ytfeed.FeedUpdate) error {
+// 				panic("mock out the SetFeedUpdate method")
+// 			},
+// 			SetFetchErrorFunc: func(channelID string, fe ytfeed.FetchError) error {
+// 				panic("mock out the SetFetchError method")
+// 			},
+// 			SetListingFunc: func(feedKey string, l ytfeed.Listing) error {
+// 				panic("mock out the SetListing method")
+// 			},
 // 			SetProcessedFunc: func(entry ytfeed.Entry) error {
 // 				panic("mock out the SetProcessed method")
 // 			},
@@ -50,18 +83,42 @@ import (
 //
 // 	}
 type StoreServiceMock struct {
+	// AddBytesFunc mocks the AddBytes method.
+	AddBytesFunc func(channelID string, size int64) error
+
+	// AddPeriodBytesFunc mocks the AddPeriodBytes method.
+	AddPeriodBytesFunc func(period string, size int64) error
+
 	// CheckProcessedFunc mocks the CheckProcessed method.
 	CheckProcessedFunc func(entry ytfeed.Entry) (bool, time.Time, error)
 
+	// CountBytesFunc mocks the CountBytes method.
+	CountBytesFunc func(channelID string) int64
+
+	// CountPeriodBytesFunc mocks the CountPeriodBytes method.
+	CountPeriodBytesFunc func(period string) int64
+
 	// CountProcessedFunc mocks the CountProcessed method.
 	CountProcessedFunc func() int
 
 	// ExistFunc mocks the Exist method.
 	ExistFunc func(entry ytfeed.Entry) (bool, error)
 
+	// FeedUpdatesFunc mocks the FeedUpdates method.
+	FeedUpdatesFunc func() (map[string]ytfeed.FeedUpdate, error)
+
+	// FetchErrorsFunc mocks the FetchErrors method.
+	FetchErrorsFunc func() (map[string]ytfeed.FetchError, error)
+
+	// ListingFunc mocks the Listing method.
+	ListingFunc func(feedKey string) (ytfeed.Listing, error)
+
 	// LoadFunc mocks the Load method.
 	LoadFunc func(channelID string, max int) ([]ytfeed.Entry, error)
 
+	// PruneProcessedFunc mocks the PruneProcessed method.
+	PruneProcessedFunc func(maxAge time.Duration) (int, error)
+
 	// RemoveFunc mocks the Remove method.
 	RemoveFunc func(entry ytfeed.Entry) error
 
@@ -74,16 +131,49 @@ type StoreServiceMock struct {
 	// SaveFunc mocks the Save method.
 	SaveFunc func(entry ytfeed.Entry) (bool, error)
 
+	// SetFeedUpdateFunc mocks the SetFeedUpdate method.
+	SetFeedUpdateFunc func(feedID string, upd ytfeed.FeedUpdate) error
+
+	// SetFetchErrorFunc mocks the SetFetchError method.
+	SetFetchErrorFunc func(channelID string, fe ytfeed.FetchError) error
+
+	// SetListingFunc mocks the SetListing method.
+	SetListingFunc func(feedKey string, l ytfeed.Listing) error
+
 	// SetProcessedFunc mocks the SetProcessed method.
 	SetProcessedFunc func(entry ytfeed.Entry) error
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// AddBytes holds details about calls to the AddBytes method.
+		AddBytes []struct {
+			// ChannelID is the channelID argument value.
+			ChannelID string
+			// Size is the size argument value.
+			Size int64
+		}
+		// AddPeriodBytes holds details about calls to the AddPeriodBytes method.
+		AddPeriodBytes []struct {
+			// Period is the period argument value.
+			Period string
+			// Size is the size argument value.
+			Size int64
+		}
 		// CheckProcessed holds details about calls to the CheckProcessed method.
 		CheckProcessed []struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
+		// CountBytes holds details about calls to the CountBytes method.
+		CountBytes []struct {
+			// ChannelID is the channelID argument value.
+			ChannelID string
+		}
+		// CountPeriodBytes holds details about calls to the CountPeriodBytes method.
+		CountPeriodBytes []struct {
+			// Period is the period argument value.
+			Period string
+		}
 		// CountProcessed holds details about calls to the CountProcessed method.
 		CountProcessed []struct {
 		}
@@ -92,6 +182,17 @@ type StoreServiceMock struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
+		// FeedUpdates holds details about calls to the FeedUpdates method.
+		FeedUpdates []struct {
+		}
+		// FetchErrors holds details about calls to the FetchErrors method.
+		FetchErrors []struct {
+		}
+		// Listing holds details about calls to the Listing method.
+		Listing []struct {
+			// FeedKey is the feedKey argument value.
+			FeedKey string
+		}
 		// Load holds details about calls to the Load method.
 		Load []struct {
 			// ChannelID is the channelID argument value.
@@ -99,6 +200,11 @@ type StoreServiceMock struct {
 			// Max is the max argument value.
 			Max int
 		}
+		// PruneProcessed holds details about calls to the PruneProcessed method.
+		PruneProcessed []struct {
+			// MaxAge is the maxAge argument value.
+			MaxAge time.Duration
+		}
 		// Remove holds details about calls to the Remove method.
 		Remove []struct {
 			// Entry is the entry argument value.
@@ -121,21 +227,123 @@ type StoreServiceMock struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
+		// SetFeedUpdate holds details about calls to the SetFeedUpdate method.
+		SetFeedUpdate []struct {
+			// FeedID is the feedID argument value.
+			FeedID string
+			// Upd is the upd argument value.
+			Upd ytfeed.FeedUpdate
+		}
+		// SetFetchError holds details about calls to the SetFetchError method.
+		SetFetchError []struct {
+			// ChannelID is the channelID argument value.
+			ChannelID string
+			// Fe is the fe argument value.
+			Fe ytfeed.FetchError
+		}
+		// SetListing holds details about calls to the SetListing method.
+		SetListing []struct {
+			// FeedKey is the feedKey argument value.
+			FeedKey string
+			// L is the l argument value.
+			L ytfeed.Listing
+		}
 		// SetProcessed holds details about calls to the SetProcessed method.
 		SetProcessed []struct {
 			// Entry is the entry argument value.
 			Entry ytfeed.Entry
 		}
 	}
-	lockCheckProcessed sync.RWMutex
-	lockCountProcessed sync.RWMutex
-	lockExist          sync.RWMutex
-	lockLoad           sync.RWMutex
-	lockRemove         sync.RWMutex
-	lockRemoveOld      sync.RWMutex
-	lockResetProcessed sync.RWMutex
-	lockSave           sync.RWMutex
-	lockSetProcessed   sync.RWMutex
+	lockAddBytes         sync.RWMutex
+	lockAddPeriodBytes   sync.RWMutex
+	lockCheckProcessed   sync.RWMutex
+	lockCountBytes       sync.RWMutex
+	lockCountPeriodBytes sync.RWMutex
+	lockCountProcessed   sync.RWMutex
+	lockExist            sync.RWMutex
+	lockFeedUpdates      sync.RWMutex
+	lockFetchErrors      sync.RWMutex
+	lockListing          sync.RWMutex
+	lockLoad             sync.RWMutex
+	lockPruneProcessed   sync.RWMutex
+	lockRemove           sync.RWMutex
+	lockRemoveOld        sync.RWMutex
+	lockResetProcessed   sync.RWMutex
+	lockSave             sync.RWMutex
+	lockSetFeedUpdate    sync.RWMutex
+	lockSetFetchError    sync.RWMutex
+	lockSetListing       sync.RWMutex
+	lockSetProcessed     sync.RWMutex
+}
+
+// AddBytes calls AddBytesFunc.
+func (mock *StoreServiceMock) AddBytes(channelID string, size int64) error {
+	if mock.AddBytesFunc == nil {
+		panic("StoreServiceMock.AddBytesFunc: method is nil but StoreService.AddBytes was just called")
+	}
+	callInfo := struct {
+		ChannelID string
+		Size      int64
+	}{
+		ChannelID: channelID,
+		Size:      size,
+	}
+	mock.lockAddBytes.Lock()
+	mock.calls.AddBytes = append(mock.calls.AddBytes, callInfo)
+	mock.lockAddBytes.Unlock()
+	return mock.AddBytesFunc(channelID, size)
+}
+
+// AddBytesCalls gets all the calls that were made to AddBytes.
+// Check the length with:
+//     len(mockedStoreService.AddBytesCalls())
+func (mock *StoreServiceMock) AddBytesCalls() []struct {
+	ChannelID string
+	Size      int64
+} {
+	var calls []struct {
+		ChannelID string
+		Size      int64
+	}
+	mock.lockAddBytes.RLock()
+	calls = mock.calls.AddBytes
+	mock.lockAddBytes.RUnlock()
+	return calls
+}
+
+// AddPeriodBytes calls AddPeriodBytesFunc.
+func (mock *StoreServiceMock) AddPeriodBytes(period string, size int64) error {
+	if mock.AddPeriodBytesFunc == nil {
+		panic("StoreServiceMock.AddPeriodBytesFunc: method is nil but StoreService.AddPeriodBytes was just called")
+	}
+	callInfo := struct {
+		Period string
+		Size   int64
+	}{
+		Period: period,
+		Size:   size,
+	}
+	mock.lockAddPeriodBytes.Lock()
+	mock.calls.AddPeriodBytes = append(mock.calls.AddPeriodBytes, callInfo)
+	mock.lockAddPeriodBytes.Unlock()
+	return mock.AddPeriodBytesFunc(period, size)
+}
+
+// AddPeriodBytesCalls gets all the calls that were made to AddPeriodBytes.
+// Check the length with:
+//     len(mockedStoreService.AddPeriodBytesCalls())
+func (mock *StoreServiceMock) AddPeriodBytesCalls() []struct {
+	Period string
+	Size   int64
+} {
+	var calls []struct {
+		Period string
+		Size   int64
+	}
+	mock.lockAddPeriodBytes.RLock()
+	calls = mock.calls.AddPeriodBytes
+	mock.lockAddPeriodBytes.RUnlock()
+	return calls
 }
 
 // CheckProcessed calls CheckProcessedFunc.
@@ -169,6 +377,68 @@ func (mock *StoreServiceMock) CheckProcessedCalls() []struct {
 	return calls
 }
 
+// CountBytes calls CountBytesFunc.
+func (mock *StoreServiceMock) CountBytes(channelID string) int64 {
+	if mock.CountBytesFunc == nil {
+		panic("StoreServiceMock.CountBytesFunc: method is nil but StoreService.CountBytes was just called")
+	}
+	callInfo := struct {
+		ChannelID string
+	}{
+		ChannelID: channelID,
+	}
+	mock.lockCountBytes.Lock()
+	mock.calls.CountBytes = append(mock.calls.CountBytes, callInfo)
+	mock.lockCountBytes.Unlock()
+	return mock.CountBytesFunc(channelID)
+}
+
+// CountBytesCalls gets all the calls that were made to CountBytes.
+// Check the length with:
+//     len(mockedStoreService.CountBytesCalls())
+func (mock *StoreServiceMock) CountBytesCalls() []struct {
+	ChannelID string
+} {
+	var calls []struct {
+		ChannelID string
+	}
+	mock.lockCountBytes.RLock()
+	calls = mock.calls.CountBytes
+	mock.lockCountBytes.RUnlock()
+	return calls
+}
+
+// CountPeriodBytes calls CountPeriodBytesFunc.
+func (mock *StoreServiceMock) CountPeriodBytes(period string) int64 {
+	if mock.CountPeriodBytesFunc == nil {
+		panic("StoreServiceMock.CountPeriodBytesFunc: method is nil but StoreService.CountPeriodBytes was just called")
+	}
+	callInfo := struct {
+		Period string
+	}{
+		Period: period,
+	}
+	mock.lockCountPeriodBytes.Lock()
+	mock.calls.CountPeriodBytes = append(mock.calls.CountPeriodBytes, callInfo)
+	mock.lockCountPeriodBytes.Unlock()
+	return mock.CountPeriodBytesFunc(period)
+}
+
+// CountPeriodBytesCalls gets all the calls that were made to CountPeriodBytes.
+// Check the length with:
+//     len(mockedStoreService.CountPeriodBytesCalls())
+func (mock *StoreServiceMock) CountPeriodBytesCalls() []struct {
+	Period string
+} {
+	var calls []struct {
+		Period string
+	}
+	mock.lockCountPeriodBytes.RLock()
+	calls = mock.calls.CountPeriodBytes
+	mock.lockCountPeriodBytes.RUnlock()
+	return calls
+}
+
 // CountProcessed calls CountProcessedFunc.
 func (mock *StoreServiceMock) CountProcessed() int {
 	if mock.CountProcessedFunc == nil {
@@ -226,6 +496,89 @@ func (mock *StoreServiceMock) ExistCalls() []struct {
 	return calls
 }
 
+// FeedUpdates calls FeedUpdatesFunc.
+func (mock *StoreServiceMock) FeedUpdates() (map[string]ytfeed.FeedUpdate, error) {
+	if mock.FeedUpdatesFunc == nil {
+		panic("StoreServiceMock.FeedUpdatesFunc: method is nil but StoreService.FeedUpdates was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockFeedUpdates.Lock()
+	mock.calls.FeedUpdates = append(mock.calls.FeedUpdates, callInfo)
+	mock.lockFeedUpdates.Unlock()
+	return mock.FeedUpdatesFunc()
+}
+
+// FeedUpdatesCalls gets all the calls that were made to FeedUpdates.
+// Check the length with:
+//     len(mockedStoreService.FeedUpdatesCalls())
+func (mock *StoreServiceMock) FeedUpdatesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockFeedUpdates.RLock()
+	calls = mock.calls.FeedUpdates
+	mock.lockFeedUpdates.RUnlock()
+	return calls
+}
+
+// FetchErrors calls FetchErrorsFunc.
+func (mock *StoreServiceMock) FetchErrors() (map[string]ytfeed.FetchError, error) {
+	if mock.FetchErrorsFunc == nil {
+		panic("StoreServiceMock.FetchErrorsFunc: method is nil but StoreService.FetchErrors was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockFetchErrors.Lock()
+	mock.calls.FetchErrors = append(mock.calls.FetchErrors, callInfo)
+	mock.lockFetchErrors.Unlock()
+	return mock.FetchErrorsFunc()
+}
+
+// FetchErrorsCalls gets all the calls that were made to FetchErrors.
+// Check the length with:
+//     len(mockedStoreService.FetchErrorsCalls())
+func (mock *StoreServiceMock) FetchErrorsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockFetchErrors.RLock()
+	calls = mock.calls.FetchErrors
+	mock.lockFetchErrors.RUnlock()
+	return calls
+}
+
+// Listing calls ListingFunc.
+func (mock *StoreServiceMock) Listing(feedKey string) (ytfeed.Listing, error) {
+	if mock.ListingFunc == nil {
+		panic("StoreServiceMock.ListingFunc: method is nil but StoreService.Listing was just called")
+	}
+	callInfo := struct {
+		FeedKey string
+	}{
+		FeedKey: feedKey,
+	}
+	mock.lockListing.Lock()
+	mock.calls.Listing = append(mock.calls.Listing, callInfo)
+	mock.lockListing.Unlock()
+	return mock.ListingFunc(feedKey)
+}
+
+// ListingCalls gets all the calls that were made to Listing.
+// Check the length with:
+//     len(mockedStoreService.ListingCalls())
+func (mock *StoreServiceMock) ListingCalls() []struct {
+	FeedKey string
+} {
+	var calls []struct {
+		FeedKey string
+	}
+	mock.lockListing.RLock()
+	calls = mock.calls.Listing
+	mock.lockListing.RUnlock()
+	return calls
+}
+
 // Load calls LoadFunc.
 func (mock *StoreServiceMock) Load(channelID string, max int) ([]ytfeed.Entry, error) {
 	if mock.LoadFunc == nil {
@@ -261,6 +614,37 @@ func (mock *StoreServiceMock) LoadCalls() []struct {
 	return calls
 }
 
+// PruneProcessed calls PruneProcessedFunc.
+func (mock *StoreServiceMock) PruneProcessed(maxAge time.Duration) (int, error) {
+	if mock.PruneProcessedFunc == nil {
+		panic("StoreServiceMock.PruneProcessedFunc: method is nil but StoreService.PruneProcessed was just called")
+	}
+	callInfo := struct {
+		MaxAge time.Duration
+	}{
+		MaxAge: maxAge,
+	}
+	mock.lockPruneProcessed.Lock()
+	mock.calls.PruneProcessed = append(mock.calls.PruneProcessed, callInfo)
+	mock.lockPruneProcessed.Unlock()
+	return mock.PruneProcessedFunc(maxAge)
+}
+
+// PruneProcessedCalls gets all the calls that were made to PruneProcessed.
+// Check the length with:
+//     len(mockedStoreService.PruneProcessedCalls())
+func (mock *StoreServiceMock) PruneProcessedCalls() []struct {
+	MaxAge time.Duration
+} {
+	var calls []struct {
+		MaxAge time.Duration
+	}
+	mock.lockPruneProcessed.RLock()
+	calls = mock.calls.PruneProcessed
+	mock.lockPruneProcessed.RUnlock()
+	return calls
+}
+
 // Remove calls RemoveFunc.
 func (mock *StoreServiceMock) Remove(entry ytfeed.Entry) error {
 	if mock.RemoveFunc == nil {
@@ -389,6 +773,111 @@ func (mock *StoreServiceMock) SaveCalls() []struct {
 	return calls
 }
 
+// SetFeedUpdate calls SetFeedUpdateFunc.
+func (mock *StoreServiceMock) SetFeedUpdate(feedID string, upd ytfeed.FeedUpdate) error {
+	if mock.SetFeedUpdateFunc == nil {
+		panic("StoreServiceMock.SetFeedUpdateFunc: method is nil but StoreService.SetFeedUpdate was just called")
+	}
+	callInfo := struct {
+		FeedID string
+		Upd    ytfeed.FeedUpdate
+	}{
+		FeedID: feedID,
+		Upd:    upd,
+	}
+	mock.lockSetFeedUpdate.Lock()
+	mock.calls.SetFeedUpdate = append(mock.calls.SetFeedUpdate, callInfo)
+	mock.lockSetFeedUpdate.Unlock()
+	return mock.SetFeedUpdateFunc(feedID, upd)
+}
+
+// SetFeedUpdateCalls gets all the calls that were made to SetFeedUpdate.
+// Check the length with:
+//     len(mockedStoreService.SetFeedUpdateCalls())
+func (mock *StoreServiceMock) SetFeedUpdateCalls() []struct {
+	FeedID string
+	Upd    ytfeed.FeedUpdate
+} {
+	var calls []struct {
+		FeedID string
+		Upd    ytfeed.FeedUpdate
+	}
+	mock.lockSetFeedUpdate.RLock()
+	calls = mock.calls.SetFeedUpdate
+	mock.lockSetFeedUpdate.RUnlock()
+	return calls
+}
+
+// SetFetchError calls SetFetchErrorFunc.
+func (mock *StoreServiceMock) SetFetchError(channelID string, fe ytfeed.FetchError) error {
+	if mock.SetFetchErrorFunc == nil {
+		panic("StoreServiceMock.SetFetchErrorFunc: method is nil but StoreService.SetFetchError was just called")
+	}
+	callInfo := struct {
+		ChannelID string
+		Fe        ytfeed.FetchError
+	}{
+		ChannelID: channelID,
+		Fe:        fe,
+	}
+	mock.lockSetFetchError.Lock()
+	mock.calls.SetFetchError = append(mock.calls.SetFetchError, callInfo)
+	mock.lockSetFetchError.Unlock()
+	return mock.SetFetchErrorFunc(channelID, fe)
+}
+
+// SetFetchErrorCalls gets all the calls that were made to SetFetchError.
+// Check the length with:
+//     len(mockedStoreService.SetFetchErrorCalls())
+func (mock *StoreServiceMock) SetFetchErrorCalls() []struct {
+	ChannelID string
+	Fe        ytfeed.FetchError
+} {
+	var calls []struct {
+		ChannelID string
+		Fe        ytfeed.FetchError
+	}
+	mock.lockSetFetchError.RLock()
+	calls = mock.calls.SetFetchError
+	mock.lockSetFetchError.RUnlock()
+	return calls
+}
+
+// SetListing calls SetListingFunc.
+func (mock *StoreServiceMock) SetListing(feedKey string, l ytfeed.Listing) error {
+	if mock.SetListingFunc == nil {
+		panic("StoreServiceMock.SetListingFunc: method is nil but StoreService.SetListing was just called")
+	}
+	callInfo := struct {
+		FeedKey string
+		L       ytfeed.Listing
+	}{
+		FeedKey: feedKey,
+		L:       l,
+	}
+	mock.lockSetListing.Lock()
+	mock.calls.SetListing = append(mock.calls.SetListing, callInfo)
+	mock.lockSetListing.Unlock()
+	return mock.SetListingFunc(feedKey, l)
+}
+
+// SetListingCalls gets all the calls that were made to SetListing.
+// Check the length with:
+//     len(mockedStoreService.SetListingCalls())
+func (mock *StoreServiceMock) SetListingCalls() []struct {
+	FeedKey string
+	L       ytfeed.Listing
+} {
+	var calls []struct {
+		FeedKey string
+		L       ytfeed.Listing
+	}
+	mock.lockSetListing.RLock()
+	calls = mock.calls.SetListing
+	mock.lockSetListing.RUnlock()
+	return calls
+}
+
 // SetProcessed calls SetProcessedFunc.
 func (mock *StoreServiceMock) SetProcessed(entry ytfeed.Entry) error {
 	if mock.SetProcessedFunc == nil {
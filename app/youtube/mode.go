@@ -0,0 +1,33 @@
+package youtube
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FeedMode defines what is downloaded for the feed's episodes, audio by default
+type FeedMode string
+
+// enum of feed modes
+const (
+	ModeAudio = FeedMode("audio") // audio only, the default
+	ModeVideo = FeedMode("video") // video with audio as mp4, for video podcasts
+)
+
+// UnmarshalYAML parses mode case-insensitively and rejects unknown modes, empty mode is audio
+func (m *FeedMode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	switch res := FeedMode(strings.ToLower(strings.TrimSpace(s))); res {
+	case "", ModeAudio:
+		*m = ModeAudio
+		return nil
+	case ModeVideo:
+		*m = res
+		return nil
+	}
+	return errors.Errorf("unknown feed mode %q", s)
+}
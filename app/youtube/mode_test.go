@@ -0,0 +1,35 @@
+package youtube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestFeedMode_UnmarshalYAML(t *testing.T) {
+	tbl := []struct {
+		yml string
+		res FeedMode
+		err bool
+	}{
+		{"{id: c1}", "", false},
+		{"{id: c1, mode: audio}", ModeAudio, false},
+		{"{id: c1, mode: ''}", ModeAudio, false},
+		{"{id: c1, mode: Video}", ModeVideo, false},
+		{"{id: c1, mode: film}", "", true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.yml, func(t *testing.T) {
+			var fi FeedInfo
+			err := yaml.Unmarshal([]byte(tt.yml), &fi)
+			if tt.err {
+				assert.EqualError(t, err, `unknown feed mode "film"`)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, fi.Mode)
+		})
+	}
+}
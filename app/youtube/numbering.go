@@ -0,0 +1,125 @@
+package youtube
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// Numbering defines how itunes:episode numbers of the feed's episodes made
+type Numbering string
+
+// enum for numbering strategies
+const (
+	NumNone    = Numbering("")        // episodes not numbered
+	NumCounter = Numbering("counter") // episodes numbered by a counter stored with entries, 1 for the first one
+	NumTitle   = Numbering("title")   // numbers parsed from titles with EpisodePattern
+)
+
+// DefaultEpisodePattern matches numbers like "#12", "Ep. 12", "Episode 12" or "Part 12" in titles
+const DefaultEpisodePattern = `(?i)(?:#|\bep(?:isode)?\.?\s*|\bpart\s*)(\d+)`
+
+// UnmarshalYAML checks numbering strategy is known, case insensitive
+func (n *Numbering) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	res := Numbering(strings.ToLower(strings.TrimSpace(s)))
+	switch res {
+	case NumNone, NumCounter, NumTitle:
+		*n = res
+		return nil
+	}
+	return errors.Errorf("unknown numbering %q", s)
+}
+
+// episodePattern returns compiled pattern of episode numbers in titles, DefaultEpisodePattern if not set
+func (fi FeedInfo) episodePattern() (*regexp.Regexp, error) {
+	if fi.EpisodePattern == "" {
+		return regexp.MustCompile(DefaultEpisodePattern), nil
+	}
+	return regexp.Compile(fi.EpisodePattern)
+}
+
+// episodeNumber returns itunes episode and season numbers of the entry, zero if not numbered or season not set.
+// Counter numbers are stored with the entry on download. Title numbers parsed by the pattern's "episode" group
+// or the first group, "season" group overrides the feed's Season if matched.
+func episodeNumber(entry ytfeed.Entry, fi FeedInfo) (episode, season int) {
+	season = fi.Season
+	switch fi.Numbering {
+	case NumCounter:
+		return entry.Episode, season
+	case NumTitle:
+		re, err := fi.episodePattern()
+		if err != nil {
+			return 0, season
+		}
+		m := re.FindStringSubmatch(entry.Title)
+		if m == nil {
+			return 0, season
+		}
+		episodeIdx := re.SubexpIndex("episode")
+		if episodeIdx < 0 && len(m) > 1 {
+			episodeIdx = 1
+		}
+		if episodeIdx > 0 {
+			episode, _ = strconv.Atoi(m[episodeIdx])
+		}
+		if idx := re.SubexpIndex("season"); idx > 0 && m[idx] != "" {
+			if v, err := strconv.Atoi(m[idx]); err == nil {
+				season = v
+			}
+		}
+		return episode, season
+	}
+	return 0, season
+}
+
+// numberEpisodes sets counter numbers of the feed's stored entries without numbers, in order of original publishing
+// after the largest stored number. Called after new entries saved, so entries downloaded together numbered
+// from the oldest one. Does nothing if the feed not numbered by counter.
+func (s *Service) numberEpisodes(fi FeedInfo) {
+	if fi.Numbering != NumCounter {
+		return
+	}
+	s.episodeMu.Lock()
+	defer s.episodeMu.Unlock()
+
+	entries, err := s.Store.Load(fi.ID, KeepAll)
+	if err != nil {
+		log.Printf("[WARN] failed to load entries of %s for numbering, %v", fi.ID, err)
+		return
+	}
+	last, unnumbered := 0, []ytfeed.Entry{}
+	for _, e := range entries {
+		if e.Episode > last {
+			last = e.Episode
+		}
+		if e.Episode == 0 {
+			unnumbered = append(unnumbered, e)
+		}
+	}
+	sort.SliceStable(unnumbered, func(i, j int) bool {
+		return originalPublished(unnumbered[i]).Before(originalPublished(unnumbered[j]))
+	})
+	for _, e := range unnumbered {
+		last++
+		e.Episode = last
+		if err := s.Store.Remove(e); err != nil {
+			log.Printf("[WARN] failed to remove %s for numbering, %v", e.VideoID, err)
+			return
+		}
+		if _, err := s.Store.Save(e); err != nil {
+			log.Printf("[WARN] failed to save %s with episode number, %v", e.VideoID, err)
+			return
+		}
+		log.Printf("[DEBUG] episode %d of %s: %s", e.Episode, fi.Name, e.String())
+	}
+}
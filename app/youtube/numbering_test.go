@@ -0,0 +1,135 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestNumbering_UnmarshalYAML(t *testing.T) {
+	tbl := []struct {
+		yml string
+		res Numbering
+		err bool
+	}{
+		{"{id: c1}", NumNone, false},
+		{"{id: c1, numbering: counter}", NumCounter, false},
+		{"{id: c1, numbering: Title}", NumTitle, false},
+		{"{id: c1, numbering: blah}", NumNone, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.yml, func(t *testing.T) {
+			var fi FeedInfo
+			err := yaml.Unmarshal([]byte(tt.yml), &fi)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, fi.Numbering)
+		})
+	}
+}
+
+func TestEpisodeNumber(t *testing.T) {
+	tbl := []struct {
+		title   string
+		fi      FeedInfo
+		episode int
+		season  int
+	}{
+		{"Show #12: guest", FeedInfo{Numbering: NumTitle}, 12, 0},
+		{"Ep. 7 - the end", FeedInfo{Numbering: NumTitle, Season: 2}, 7, 2},
+		{"Episode 103", FeedInfo{Numbering: NumTitle}, 103, 0},
+		{"Making of, part 3", FeedInfo{Numbering: NumTitle}, 3, 0},
+		{"Deep dive in 2023", FeedInfo{Numbering: NumTitle, Season: 1}, 0, 1},
+		{"S03E05 title", FeedInfo{Numbering: NumTitle, EpisodePattern: `S(?P<season>\d+)E(?P<episode>\d+)`, Season: 1}, 5, 3},
+		{"[42] title", FeedInfo{Numbering: NumTitle, EpisodePattern: `^\[(\d+)\]`}, 42, 0},
+		{"[42] title", FeedInfo{Numbering: NumTitle, EpisodePattern: `^\[(\d+`}, 0, 0},
+		{"Show #12", FeedInfo{Season: 4}, 0, 4},
+		{"Show #12", FeedInfo{}, 0, 0},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.title, func(t *testing.T) {
+			episode, season := episodeNumber(ytfeed.Entry{Title: tt.title, Episode: 5}, tt.fi)
+			assert.Equal(t, tt.episode, episode)
+			assert.Equal(t, tt.season, season)
+		})
+	}
+
+	episode, season := episodeNumber(ytfeed.Entry{Title: "Show #12", Episode: 5}, FeedInfo{Numbering: NumCounter, Season: 2})
+	assert.Equal(t, 5, episode, "stored counter")
+	assert.Equal(t, 2, season)
+}
+
+func TestService_DoNumberingCounter(t *testing.T) {
+	dir := t.TempDir()
+	listed := []ytfeed.Entry{
+		{ChannelID: "channel1", VideoID: "vid3", Title: "third", Published: time.Now()},
+		{ChannelID: "channel1", VideoID: "vid2", Title: "second", Published: time.Now().Add(-time.Hour)},
+	}
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return listed, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content"), 0o600)
+		},
+	}
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	_, err = boltStore.Save(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "first",
+		File: filepath.Join(dir, "vid1.mp3"), Published: time.Now().Add(-24 * time.Hour)})
+	require.NoError(t, err)
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Numbering: NumCounter, Season: 2}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost:8080/yt",
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+	episodes := func() map[string]int {
+		entries, e := boltStore.Load("channel1", KeepAll)
+		require.NoError(t, e)
+		res := map[string]int{}
+		for _, e := range entries {
+			res[e.VideoID] = e.Episode
+		}
+		return res
+	}
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"vid1": 1, "vid2": 2, "vid3": 3}, episodes(), "numbered in order of publishing")
+
+	listed = append([]ytfeed.Entry{{ChannelID: "channel1", VideoID: "vid4", Title: "fourth", Published: time.Now()}}, listed...)
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"vid1": 1, "vid2": 2, "vid3": 3, "vid4": 4}, episodes(), "counter continued")
+
+	rss, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, "<itunes:episode>4</itunes:episode>")
+	assert.Contains(t, rss, "<itunes:episode>3</itunes:episode>")
+	assert.Contains(t, rss, "<itunes:season>2</itunes:season>")
+}
@@ -0,0 +1,98 @@
+package youtube
+
+import (
+	htmltmpl "html/template"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// Override replaces scraped title and/or description of the entry, empty fields are not replaced
+type Override struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+}
+
+// overrides keeps loaded override files (videoID -> Override) and reloads them on modification
+type overrides struct {
+	mu    sync.Mutex
+	files map[string]overridesFile
+}
+
+type overridesFile struct {
+	modTime time.Time
+	size    int64
+	data    map[string]Override
+}
+
+// get returns overrides from yaml or json file, reloaded if the file changed since the last call.
+// Missing file means no overrides, on broken file the previously loaded overrides are kept.
+func (o *overrides) get(file string) map[string]Override {
+	if file == "" {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cached, found := o.files[file]
+	fi, err := os.Stat(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] can't access overrides file %s, %v", file, err)
+		}
+		delete(o.files, file)
+		return nil
+	}
+	if found && fi.ModTime().Equal(cached.modTime) && fi.Size() == cached.size {
+		return cached.data
+	}
+
+	data, err := loadOverrides(file)
+	if err != nil {
+		log.Printf("[WARN] failed to load overrides, %v", err)
+		return cached.data
+	}
+	log.Printf("[INFO] loaded %d overrides from %s", len(data), file)
+	if o.files == nil {
+		o.files = map[string]overridesFile{}
+	}
+	o.files[file] = overridesFile{modTime: fi.ModTime(), size: fi.Size(), data: data}
+	return data
+}
+
+func loadOverrides(file string) (map[string]Override, error) {
+	body, err := os.ReadFile(file) // nolint
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", file)
+	}
+	res := map[string]Override{}
+	if err = yaml.Unmarshal(body, &res); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", file)
+	}
+	return res, nil
+}
+
+// applyOverride replaces entry's title and description with feed's override for this video, if any.
+// Stored entries have title prefix of the feed already, withPrefix adds it to the overridden title as well.
+func (s *Service) applyOverride(entry ytfeed.Entry, fi FeedInfo, withPrefix bool) ytfeed.Entry {
+	ovr, ok := s.overrides.get(fi.Overrides)[entry.VideoID]
+	if !ok {
+		return entry
+	}
+	if ovr.Title != "" {
+		entry.Title = ovr.Title
+		if withPrefix {
+			entry.Title = fi.TitlePrefix.Apply(ovr.Title, fi.Name)
+		}
+	}
+	if ovr.Description != "" {
+		entry.Media.Description = htmltmpl.HTML(ovr.Description) // nolint
+	}
+	return entry
+}
@@ -0,0 +1,85 @@
+package youtube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+func TestOverrides_get(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "overrides.yml")
+	o := overrides{}
+
+	assert.Nil(t, o.get(""), "no file set")
+	assert.Nil(t, o.get(file), "missing file")
+
+	require.NoError(t, os.WriteFile(file, []byte("vid1: {title: \"clean title\"}\nvid2:\n  description: new desc\n"), 0o600))
+	res := o.get(file)
+	assert.Equal(t, map[string]Override{"vid1": {Title: "clean title"}, "vid2": {Description: "new desc"}}, res)
+
+	// reloaded on change
+	require.NoError(t, os.WriteFile(file, []byte(`{"vid1": {"title": "json title", "description": "json desc"}}`), 0o600))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	assert.Equal(t, map[string]Override{"vid1": {Title: "json title", Description: "json desc"}}, o.get(file))
+
+	// broken file keeps previous overrides
+	require.NoError(t, os.WriteFile(file, []byte(`{"vid1": [`), 0o600))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Minute)))
+	assert.Equal(t, map[string]Override{"vid1": {Title: "json title", Description: "json desc"}}, o.get(file))
+
+	require.NoError(t, os.Remove(file))
+	assert.Nil(t, o.get(file), "removed file")
+}
+
+func TestService_applyOverride(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "overrides.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"vid1": {"title": "clean title", "description": "clean desc"},
+		"vid2": {"description": "desc only"}}`), 0o600))
+	svc := Service{}
+	fi := FeedInfo{ID: "channel1", Name: "name1", Overrides: file}
+
+	entry := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "name1: MESSY title!!!"}
+	entry.Media.Description = "orig desc"
+	res := svc.applyOverride(entry, fi, true)
+	assert.Equal(t, "name1: clean title", res.Title)
+	assert.Equal(t, "clean desc", string(res.Media.Description))
+	assert.Equal(t, "clean title", svc.applyOverride(entry, fi, false).Title, "no prefix for new entries")
+
+	entry.VideoID = "vid2"
+	res = svc.applyOverride(entry, fi, true)
+	assert.Equal(t, "name1: MESSY title!!!", res.Title, "title not overridden")
+	assert.Equal(t, "desc only", string(res.Media.Description))
+
+	entry.VideoID = "vid3"
+	assert.Equal(t, entry, svc.applyOverride(entry, fi, true), "no override")
+	assert.Equal(t, entry, svc.applyOverride(entry, FeedInfo{ID: "channel1"}, true), "no overrides file")
+}
+
+func TestService_RSSFeedOverride(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "overrides.yml")
+	require.NoError(t, os.WriteFile(file, []byte("vid1: {title: clean title}\n"), 0o600))
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid1", Title: "MESSY title", File: "/tmp/file1.mp3"},
+				{ChannelID: channelID, VideoID: "vid2", Title: "title2", File: "/tmp/file2.mp3"},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Overrides: file, TitlePrefix: TitlePrefix{Disabled: true}},
+		RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, "<title>clean title</title>")
+	assert.NotContains(t, res, "MESSY title")
+	assert.Contains(t, res, "<title>title2</title>")
+}
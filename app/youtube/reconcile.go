@@ -0,0 +1,155 @@
+package youtube
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// reconcileMinAge is the min age of orphaned file to report it, newer files may be downloaded at the moment
+const reconcileMinAge = time.Hour
+
+// ReconcileOpts sets what Reconcile fixes, nothing changed by default (dry run)
+type ReconcileOpts struct {
+	RemoveOrphans bool // remove media files without stored entries
+	PurgeDangling bool // remove stored entries without files, they will be downloaded again if still listed
+}
+
+// ReconcileReport lists disagreements between the store and files found by Reconcile
+type ReconcileReport struct {
+	Orphans  []string       // media files in FilesLocation without stored entries
+	Dangling []ytfeed.Entry // stored entries of configured feeds without files
+	Removed  int            // removed orphaned files
+	Purged   int            // purged dangling entries
+}
+
+// Reconcile compares stored entries of configured feeds with media files in FilesLocation. Orphans are files
+// without entries, i.e. left by a crash or by a feed removed from config. Dangling are entries without files, i.e.
+// removed manually. Both reported, orphans removed and dangling purged only if opted in by opts.
+// Entries of files moved to remote storage are dangling too, PurgeDangling should not be used in this case.
+// Orphans not looked up if FilesLocation not set.
+func (s *Service) Reconcile(ctx context.Context, opts ReconcileOpts) (res ReconcileReport, err error) {
+	known := map[string]bool{}
+	changed := []FeedInfo{}
+	for _, fi := range s.feeds() {
+		entries, err := s.Store.Load(fi.ID, KeepAll)
+		if err != nil {
+			return res, errors.Wrapf(err, "failed to load entries for %s", fi.ID)
+		}
+		purged := false
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			known[absPath(entry.File)] = true
+			if _, statErr := os.Stat(entry.File); statErr == nil || !os.IsNotExist(statErr) {
+				continue
+			}
+			res.Dangling = append(res.Dangling, entry)
+			log.Printf("[WARN] dangling entry %s, no file %s", entry.String(), entry.File)
+			if !opts.PurgeDangling {
+				continue
+			}
+			if err := s.RemoveEntry(entry); err != nil {
+				return res, errors.Wrapf(err, "failed to purge dangling entry %s", entry.VideoID)
+			}
+			removeCompanions(entry.File)
+			res.Purged++
+			purged = true
+		}
+		if purged {
+			changed = append(changed, fi)
+		}
+	}
+
+	for _, fi := range changed {
+		if err := s.storeFeedRSS(fi); err != nil {
+			log.Printf("[WARN] failed to update rss of %s after purge, %v", fi.ID, err)
+		}
+	}
+
+	if res.Orphans, err = s.orphanedFiles(ctx, known); err != nil {
+		return res, err
+	}
+	for _, file := range res.Orphans {
+		log.Printf("[WARN] orphaned file %s", file)
+		if !opts.RemoveOrphans {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			log.Printf("[WARN] failed to remove orphaned file %s, %v", file, err)
+			continue
+		}
+		removeCompanions(file)
+		res.Removed++
+	}
+	log.Printf("[INFO] reconciled files, orphans: %d, removed: %d, dangling: %d, purged: %d",
+		len(res.Orphans), res.Removed, len(res.Dangling), res.Purged)
+	return res, nil
+}
+
+// orphanedFiles returns sorted media files in FilesLocation and its sub directories not in known files.
+// Files modified in the last reconcileMinAge skipped, as they may be downloaded and not stored yet.
+func (s *Service) orphanedFiles(ctx context.Context, known map[string]bool) ([]string, error) {
+	res := []string{}
+	if s.FilesLocation == "" {
+		return res, nil
+	}
+	err := filepath.WalkDir(s.FilesLocation, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.Type().IsRegular() || !isMediaFile(file) || known[absPath(file)] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if time.Since(info.ModTime()) < reconcileMinAge {
+			return nil
+		}
+		res = append(res, file)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list files in %s", s.FilesLocation)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// isMediaFile checks the file has extension of downloaded audio or video
+func isMediaFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == mediaExt || ext == ".mp4" {
+		return true
+	}
+	for _, e := range ytfeed.NativeAudioExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath returns absolute clean path of the file, or the clean path if it can't be made absolute
+func absPath(file string) string {
+	res, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.Clean(file)
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestService_Reconcile(t *testing.T) {
+	prep := func(t *testing.T) (svc *Service, boltStore *store.BoltDB, dir string) {
+		dir = t.TempDir()
+		db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		boltStore = &store.BoltDB{DB: db}
+
+		old := time.Now().Add(-2 * time.Hour)
+		for _, f := range []string{"channel1/vid1.mp3", "channel1/vid1.vtt", "channel1/orphan.mp3", "channel1/orphan.vtt",
+			"orphan2.m4a", "notes.txt", "channel1/fresh.mp3"} {
+			file := filepath.Join(dir, f)
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+			if f != "channel1/fresh.mp3" {
+				require.NoError(t, os.Chtimes(file, old, old))
+			}
+		}
+		for _, e := range []ytfeed.Entry{
+			{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: filepath.Join(dir, "channel1", "vid1.mp3"),
+				Published: time.Now()},
+			{ChannelID: "channel1", VideoID: "vid2", Title: "title2", File: filepath.Join(dir, "channel1", "vid2.mp3"),
+				Published: time.Now().Add(-time.Hour)},
+		} {
+			_, err = boltStore.Save(e)
+			require.NoError(t, err)
+			require.NoError(t, boltStore.SetProcessed(e))
+		}
+
+		svc = &Service{
+			Feeds:          []FeedInfo{{ID: "channel1", Name: "name1"}},
+			Store:          boltStore,
+			KeepPerChannel: 10,
+			FilesLocation:  dir,
+			RSSFileStore:   RSSFileStore{Enabled: true, Location: t.TempDir()},
+		}
+		return svc, boltStore, dir
+	}
+	exists := func(file string) bool {
+		_, err := os.Stat(file)
+		return err == nil
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		svc, boltStore, dir := prep(t)
+		res, err := svc.Reconcile(context.Background(), ReconcileOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "channel1", "orphan.mp3"), filepath.Join(dir, "orphan2.m4a")},
+			res.Orphans, "fresh and not media files skipped")
+		require.Equal(t, 1, len(res.Dangling))
+		assert.Equal(t, "vid2", res.Dangling[0].VideoID)
+		assert.Equal(t, 0, res.Removed)
+		assert.Equal(t, 0, res.Purged)
+
+		assert.True(t, exists(filepath.Join(dir, "channel1", "orphan.mp3")), "orphan kept")
+		entries, err := boltStore.Load("channel1", KeepAll)
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(entries), "dangling kept")
+	})
+
+	t.Run("remove orphans", func(t *testing.T) {
+		svc, boltStore, dir := prep(t)
+		res, err := svc.Reconcile(context.Background(), ReconcileOpts{RemoveOrphans: true})
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(res.Orphans))
+		assert.Equal(t, 2, res.Removed)
+		assert.Equal(t, 0, res.Purged)
+
+		assert.False(t, exists(filepath.Join(dir, "channel1", "orphan.mp3")))
+		assert.False(t, exists(filepath.Join(dir, "channel1", "orphan.vtt")), "companion removed")
+		assert.False(t, exists(filepath.Join(dir, "orphan2.m4a")))
+		for _, f := range []string{"channel1/vid1.mp3", "channel1/vid1.vtt", "channel1/fresh.mp3", "notes.txt"} {
+			assert.True(t, exists(filepath.Join(dir, f)), f)
+		}
+		entries, err := boltStore.Load("channel1", KeepAll)
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(entries), "dangling kept")
+	})
+
+	t.Run("purge dangling", func(t *testing.T) {
+		svc, boltStore, dir := prep(t)
+		res, err := svc.Reconcile(context.Background(), ReconcileOpts{PurgeDangling: true})
+		require.NoError(t, err)
+		assert.Equal(t, 0, res.Removed)
+		assert.Equal(t, 1, res.Purged)
+		assert.True(t, exists(filepath.Join(dir, "channel1", "orphan.mp3")), "orphan kept")
+
+		entries, err := boltStore.Load("channel1", KeepAll)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, "vid1", entries[0].VideoID)
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"})
+		require.NoError(t, err)
+		assert.False(t, found, "purged entry will be downloaded again")
+
+		rss, err := os.ReadFile(filepath.Join(svc.RSSFileStore.Location, "channel1.xml"))
+		require.NoError(t, err)
+		assert.Contains(t, string(rss), "title1")
+		assert.NotContains(t, string(rss), "title2", "rss updated")
+	})
+
+	t.Run("no files location", func(t *testing.T) {
+		svc, _, _ := prep(t)
+		svc.FilesLocation = ""
+		res, err := svc.Reconcile(context.Background(), ReconcileOpts{RemoveOrphans: true})
+		require.NoError(t, err)
+		assert.Empty(t, res.Orphans)
+		assert.Equal(t, 1, len(res.Dangling))
+	})
+}
@@ -5,16 +5,22 @@ import (
 	"path/filepath"
 
 	log "github.com/go-pkgz/lgr"
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 )
 
+// RSSStore saves rss of the feed, i.e. to a mirror location
+type RSSStore interface {
+	Save(chanID, rss string) error
+}
+
 // RSSFileStore is a store for RSS feed files
 type RSSFileStore struct {
 	Location string
 	Enabled  bool
 }
 
-// Save RSS feed file to the FS
+// Save RSS feed file to the FS. The file replaced at once, so readers never see it partially written
 func (s *RSSFileStore) Save(chanID, rss string) error {
 	if !s.Enabled {
 		return nil
@@ -24,14 +30,39 @@ func (s *RSSFileStore) Save(chanID, rss string) error {
 	}
 
 	fname := filepath.Join(s.Location, chanID+".xml")
-	fh, err := os.Create(fname) //nolint:gosec // tolerable security risk
+	fh, err := os.CreateTemp(s.Location, chanID+".xml.*.tmp")
 	if err != nil {
-		return errors.Wrapf(err, "failed to create file %s", fname)
+		return errors.Wrapf(err, "failed to create temp file for %s", fname)
 	}
-	defer fh.Close() // nolint
+	defer os.Remove(fh.Name()) // nolint // removed if not renamed
 	if _, err = fh.WriteString(rss); err != nil {
+		_ = fh.Close()
 		return errors.Wrapf(err, "failed to write to file %s", fname)
 	}
+	if err = fh.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close file %s", fname)
+	}
+	if err = os.Chmod(fh.Name(), 0o644); err != nil { //nolint:gosec // rss is public
+		return errors.Wrapf(err, "failed to set mode of %s", fname)
+	}
+	if err = os.Rename(fh.Name(), fname); err != nil {
+		return errors.Wrapf(err, "failed to rename to %s", fname)
+	}
 	log.Printf("[INFO] rss feed file saved to %s", fname)
 	return nil
 }
+
+// StoreRSS saves RSS feed to RSSFileStore and all RSSMirrors. Failed store doesn't prevent saving to the others,
+// errors of all stores combined
+func (s *Service) StoreRSS(chanID, rss string) error {
+	errs := new(multierror.Error)
+	if err := s.RSSFileStore.Save(chanID, rss); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	for i, m := range s.RSSMirrors {
+		if err := m.Save(chanID, rss); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "mirror %d", i+1))
+		}
+	}
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,42 @@
+package youtube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_StoreRSS(t *testing.T) {
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(notDir, []byte("not a dir"), 0o600))
+
+	svc := Service{
+		RSSFileStore: RSSFileStore{Location: filepath.Join(dir, "main"), Enabled: true},
+		RSSMirrors: []RSSStore{
+			&RSSFileStore{Location: filepath.Join(notDir, "broken"), Enabled: true},
+			&RSSFileStore{Location: filepath.Join(dir, "mirror"), Enabled: true},
+		},
+	}
+	err := svc.StoreRSS("chan1", "<rss>1</rss>")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mirror 1: failed to create dir")
+
+	for _, loc := range []string{"main", "mirror"} {
+		data, e := os.ReadFile(filepath.Join(dir, loc, "chan1.xml"))
+		require.NoError(t, e, loc)
+		assert.Equal(t, "<rss>1</rss>", string(data), "failed mirror doesn't stop others")
+	}
+
+	svc.RSSMirrors = nil
+	require.NoError(t, svc.StoreRSS("chan1", "<rss>2</rss>"), "single store")
+	data, err := os.ReadFile(filepath.Join(dir, "main", "chan1.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, "<rss>2</rss>", string(data))
+
+	svc.RSSFileStore.Location = filepath.Join(notDir, "broken")
+	assert.Error(t, svc.StoreRSS("chan1", "<rss>3</rss>"))
+}
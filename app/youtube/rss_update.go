@@ -0,0 +1,187 @@
+package youtube
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	rssfeed "github.com/umputun/feed-master/app/feed"
+)
+
+var rssItemGUIDRe = regexp.MustCompile(`<guid>([^<]*)</guid>`)
+
+// rssUpdates keeps feeds with rss made in full since start, only those updated incrementally.
+// The state of feed's overrides file at the full update kept too, changed overrides need the full update.
+type rssUpdates struct {
+	mu   sync.Mutex
+	full map[string]string // feed id -> state of overrides file
+}
+
+// feedRSS returns rss of the feed after new entries added or old removed. Feeds with IncrementalRSS items or more
+// updated from the stored rss file, see updateRSS. Full rss made for other feeds, for the first update after start,
+// after change of overrides and if the stored file can't be used. Feeds with signed enclosure urls always made in
+// full, signatures of stored items expire.
+func (s *Service) feedRSS(fi FeedInfo) (string, error) {
+	if _, signed := s.URLSigners[fi.ID]; signed || s.IncrementalRSS <= 0 || !s.RSSFileStore.Enabled ||
+		fi.RepeatedTitles != RTKeep {
+		return s.RSSFeed(fi, RSSFilter{})
+	}
+	s.rssUpdates.mu.Lock()
+	defer s.rssUpdates.mu.Unlock()
+	ovrState := overridesState(fi.Overrides)
+	if state, found := s.rssUpdates.full[fi.ID]; found && state == ovrState {
+		rss, ok, err := s.updateRSS(fi)
+		if err != nil || ok {
+			return rss, err
+		}
+	}
+	rss, err := s.RSSFeed(fi, RSSFilter{})
+	if err != nil {
+		return "", err
+	}
+	if s.rssUpdates.full == nil {
+		s.rssUpdates.full = map[string]string{}
+	}
+	s.rssUpdates.full[fi.ID] = ovrState
+	return rss, nil
+}
+
+// overridesState returns modification time and size of the overrides file, empty if no such file
+func overridesState(file string) string {
+	if file == "" {
+		return ""
+	}
+	st, err := os.Stat(file)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", st.ModTime().UnixNano(), st.Size())
+}
+
+// updateRSS makes rss of the feed from the stored rss file, with items of new entries added and items of entries
+// no longer listed in rss dropped. Items of entries stored in the file are reused as is, only new items made.
+// Returns false if the stored rss can't be used, not found or has fewer than IncrementalRSS items.
+func (s *Service) updateRSS(fi FeedInfo) (rss string, ok bool, err error) {
+	prev, err := os.ReadFile(filepath.Join(s.RSSFileStore.Location, fi.ID+".xml"))
+	if err != nil {
+		log.Printf("[DEBUG] no stored rss of %s for update, %v", fi.ID, err)
+		return "", false, nil
+	}
+	stored, ok := rssItems(string(prev))
+	if !ok || len(stored) < s.IncrementalRSS {
+		return "", false, nil
+	}
+
+	fi = s.updated(fi)
+	entries, err := s.rssEntries(fi)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to get channel entries")
+	}
+	if len(entries) == 0 {
+		return "", false, nil
+	}
+	items, added := make([]string, 0, len(entries)), 0
+	for i := range entries {
+		entries[i] = s.applyOverride(entries[i], fi, true)
+		if item, found := stored[escapeXML(itemGUID(entries[i]))]; found {
+			items = append(items, item)
+			continue
+		}
+		size, sizeErr := entrySize(entries[i])
+		if sizeErr != nil {
+			log.Printf("[WARN] no size of %s (%s %s), zero enclosure length: %v", entries[i].File, entries[i].VideoID,
+				entries[i].Title, sizeErr)
+		}
+		item, err := marshalRSSItem(s.rssItem(entries[i], fi, size))
+		if err != nil {
+			return "", false, err
+		}
+		items = append(items, item)
+		added++
+	}
+	rss, err = marshalRSSWithItems(s.rssChannel(fi, entries), items)
+	if err != nil {
+		return "", false, err
+	}
+	log.Printf("[DEBUG] rss of %s updated, %d of %d items added", fi.Name, added, len(items))
+	return rss, true, nil
+}
+
+// rssItems returns items of rss made by marshalRSS, by escaped guid. Returns false if rss is not complete
+func rssItems(rss string) (map[string]string, bool) {
+	if !strings.HasSuffix(strings.TrimSpace(rss), "</rss>") {
+		return nil, false
+	}
+	res := map[string]string{}
+	for {
+		start := strings.Index(rss, "<item>")
+		if start < 0 {
+			return res, true
+		}
+		end := strings.Index(rss[start:], "</item>")
+		if end < 0 {
+			return nil, false
+		}
+		item := rss[start : start+end+len("</item>")]
+		m := rssItemGUIDRe.FindStringSubmatch(item)
+		if m == nil {
+			return nil, false
+		}
+		res[m[1]] = item
+		rss = rss[start+end+len("</item>"):]
+	}
+}
+
+// marshalRSSItem returns xml of the item indented the same way as items of marshalRSS
+func marshalRSSItem(item rssfeed.Item) (string, error) {
+	buf := bytes.Buffer{}
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("    ", "  ")
+	if err := enc.EncodeElement(item, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+		return "", errors.Wrap(err, "failed to marshal rss item")
+	}
+	res := strings.TrimSpace(buf.String())
+	res = strings.Replace(res, "<duration>", "<itunes:duration>", -1)
+	res = strings.Replace(res, "</duration>", "</itunes:duration>", -1)
+	return res, nil
+}
+
+// marshalRSSWithItems returns rss with items marshalled before, i.e. by marshalRSSItem, placed after channel's info
+func marshalRSSWithItems(rss rssfeed.Rss2, items []string) (string, error) {
+	for _, item := range items {
+		if strings.Contains(item, "<dc:") {
+			rss.NsDC = "http://purl.org/dc/elements/1.1/"
+		}
+		if strings.Contains(item, "<podcast:") {
+			rss.NsPodcast = "https://podcastindex.org/namespace/1.0"
+		}
+	}
+	rss.ItemList = nil
+	res, err := marshalRSS(rss)
+	if err != nil {
+		return "", err
+	}
+	pos := strings.LastIndex(res, "\n  </channel>")
+	if pos < 0 {
+		return "", errors.New("no channel in rss")
+	}
+	if len(items) == 0 {
+		return res, nil
+	}
+	return res[:pos] + "\n    " + strings.Join(items, "\n    ") + res[pos:], nil
+}
+
+// escapeXML returns the text escaped as xml text, the same way as the xml encoder does
+func escapeXML(s string) string {
+	buf := bytes.Buffer{}
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
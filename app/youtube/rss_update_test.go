@@ -0,0 +1,169 @@
+package youtube
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestService_feedRSS(t *testing.T) {
+	buildDateRe := regexp.MustCompile(`<lastBuildDate>[^<]*</lastBuildDate>`)
+	noBuildDate := func(rss string) string { return buildDateRe.ReplaceAllString(rss, "") }
+
+	prep := func(t *testing.T) (*Service, *store.BoltDB, func(vid string, published time.Time) ytfeed.Entry) {
+		dir := t.TempDir()
+		db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		boltStore := &store.BoltDB{DB: db}
+		add := func(vid string, published time.Time) ytfeed.Entry {
+			file := filepath.Join(dir, vid+".mp3")
+			require.NoError(t, os.WriteFile(file, []byte("content of "+vid), 0o600))
+			entry := ytfeed.Entry{ChannelID: "channel1", VideoID: vid, Title: "title <" + vid + "> & more",
+				File: file, Published: published}
+			entry.Media.Description = template.HTML("desc of " + vid + " & \"quoted\"") //nolint:gosec // test data
+			_, err := boltStore.Save(entry)
+			require.NoError(t, err)
+			return entry
+		}
+		base := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+		for i, vid := range []string{"vid1", "vid2", "vid3"} {
+			add(vid, base.Add(time.Duration(i)*time.Hour))
+		}
+		svc := &Service{
+			Feeds:          []FeedInfo{{ID: "channel1", Name: "name1"}},
+			Store:          boltStore,
+			KeepPerChannel: 10,
+			RootURL:        "http://localhost:8080/yt",
+			RSSFileStore:   RSSFileStore{Enabled: true, Location: filepath.Join(dir, "rss")},
+			IncrementalRSS: 2,
+		}
+		return svc, boltStore, add
+	}
+
+	t.Run("updated in place", func(t *testing.T) {
+		svc, boltStore, add := prep(t)
+		fi := svc.Feeds[0]
+		rss, err := svc.feedRSS(fi)
+		require.NoError(t, err)
+		require.NoError(t, svc.StoreRSS(fi.ID, rss))
+
+		add("vid4", time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC))
+		_, ok, err := svc.updateRSS(fi)
+		require.NoError(t, err)
+		assert.True(t, ok, "stored rss used")
+		rss, err = svc.feedRSS(fi)
+		require.NoError(t, err)
+		full, err := svc.RSSFeed(fi, RSSFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, noBuildDate(full), noBuildDate(rss), "same as full rss with new item")
+		assert.Contains(t, rss, "vid4")
+		require.NoError(t, svc.StoreRSS(fi.ID, rss))
+
+		require.NoError(t, boltStore.Remove(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}))
+		rss, err = svc.feedRSS(fi)
+		require.NoError(t, err)
+		full, err = svc.RSSFeed(fi, RSSFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, noBuildDate(full), noBuildDate(rss), "same as full rss without removed item")
+		assert.NotContains(t, rss, "vid1")
+	})
+
+	t.Run("fallback to full rss", func(t *testing.T) {
+		svc, _, add := prep(t)
+		fi := svc.Feeds[0]
+		_, ok, err := svc.updateRSS(fi)
+		require.NoError(t, err)
+		assert.False(t, ok, "no stored rss")
+
+		rss, err := svc.feedRSS(fi)
+		require.NoError(t, err)
+		require.NoError(t, svc.StoreRSS(fi.ID, rss[:len(rss)/2]))
+		_, ok, err = svc.updateRSS(fi)
+		require.NoError(t, err)
+		assert.False(t, ok, "incomplete stored rss")
+
+		add("vid4", time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC))
+		rss, err = svc.feedRSS(fi)
+		require.NoError(t, err)
+		assert.Contains(t, rss, "vid4", "full rss made")
+		require.NoError(t, svc.StoreRSS(fi.ID, rss))
+
+		svc.IncrementalRSS = 5
+		_, ok, err = svc.updateRSS(fi)
+		require.NoError(t, err)
+		assert.False(t, ok, "fewer items than threshold")
+	})
+
+	t.Run("first update is full", func(t *testing.T) {
+		svc, _, _ := prep(t)
+		fi := svc.Feeds[0]
+		require.NoError(t, svc.StoreRSS(fi.ID, "<rss><channel><item><guid>stale</guid></item></channel></rss>"))
+		rss, err := svc.feedRSS(fi)
+		require.NoError(t, err)
+		assert.NotContains(t, rss, "stale")
+		assert.Contains(t, rss, "vid3")
+	})
+
+	t.Run("full after overrides change", func(t *testing.T) {
+		svc, _, _ := prep(t)
+		ovrFile := filepath.Join(t.TempDir(), "overrides.yml")
+		svc.Feeds[0].Overrides = ovrFile
+		fi := svc.Feeds[0]
+		rss, err := svc.feedRSS(fi)
+		require.NoError(t, err)
+		require.NoError(t, svc.StoreRSS(fi.ID, rss))
+
+		require.NoError(t, os.WriteFile(ovrFile, []byte("vid2: {title: \"fixed title\"}\n"), 0o600))
+		rss, err = svc.feedRSS(fi)
+		require.NoError(t, err)
+		assert.Contains(t, rss, "fixed title", "overridden title of stored item")
+		require.NoError(t, svc.StoreRSS(fi.ID, rss))
+
+		require.NoError(t, os.WriteFile(ovrFile, []byte("vid2: {title: \"fixed again\"}\n"), 0o600))
+		require.NoError(t, os.Chtimes(ovrFile, time.Now(), time.Now().Add(time.Second)))
+		rss, err = svc.feedRSS(fi)
+		require.NoError(t, err)
+		assert.Contains(t, rss, "fixed again")
+		assert.NotContains(t, rss, "fixed title")
+	})
+
+	t.Run("signed urls always full", func(t *testing.T) {
+		svc, _, _ := prep(t)
+		svc.URLSigners = map[string]URLSigner{"channel1": fakeSigner{token: "t1"}}
+		fi := svc.Feeds[0]
+		rss, err := svc.feedRSS(fi)
+		require.NoError(t, err)
+		require.NoError(t, svc.StoreRSS(fi.ID, rss))
+		assert.Contains(t, rss, "token=t1")
+
+		svc.URLSigners = map[string]URLSigner{"channel1": fakeSigner{token: "t2"}}
+		rss, err = svc.feedRSS(fi)
+		require.NoError(t, err)
+		assert.Contains(t, rss, "token=t2", "fresh signatures")
+		assert.NotContains(t, rss, "token=t1")
+	})
+}
+
+func TestRSSItems(t *testing.T) {
+	items, ok := rssItems("<rss><channel><title>t</title>\n<item><title>a</title><guid>id1</guid></item>\n" +
+		"<item><guid>id&amp;2</guid></item></channel></rss>\n")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"id1": "<item><title>a</title><guid>id1</guid></item>",
+		"id&amp;2": "<item><guid>id&amp;2</guid></item>"}, items)
+
+	_, ok = rssItems("<rss><channel><item><guid>id1</guid></item><item><guid>id2</guid>")
+	assert.False(t, ok, "truncated")
+	_, ok = rssItems("<rss><channel><item><title>a</title></item></channel></rss>")
+	assert.False(t, ok, "no guid")
+}
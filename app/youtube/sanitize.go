@@ -0,0 +1,96 @@
+package youtube
+
+import (
+	htmltmpl "html/template"
+	"regexp"
+	"strings"
+	"unicode"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// TitleSanitizer defines cleanup rules of scraped titles, each rule enabled separately. Zero value keeps titles as is.
+type TitleSanitizer struct {
+	StripEmoji     bool `yaml:"strip_emoji"`     // remove emoji and pictographs
+	CollapseSpaces bool `yaml:"collapse_spaces"` // replace runs of whitespace with a single space, trim both ends
+	DeShout        bool `yaml:"de_shout"`        // title-case the title if it is all caps
+	TrimHashtags   bool `yaml:"trim_hashtags"`   // remove hashtags at the end of the title
+}
+
+var (
+	// pictographs, symbols and dingbats, regional indicators (flags), variation selectors, joiners and tags
+	emojiRe = regexp.MustCompile(`[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{2300}-\x{23FF}\x{2B00}-\x{2BFF}` +
+		`\x{FE00}-\x{FE0F}\x{200D}\x{20E3}\x{E0000}-\x{E007F}]+`)
+	trailingHashtagRe = regexp.MustCompile(`(\s*#[\p{L}\p{N}_]+)+\s*$`)
+	spacesRe          = regexp.MustCompile(`\s+`)
+)
+
+// Enabled returns true if any rule is set
+func (ts TitleSanitizer) Enabled() bool {
+	return ts.StripEmoji || ts.CollapseSpaces || ts.DeShout || ts.TrimHashtags
+}
+
+// Apply cleans the title with enabled rules. The original title returned if nothing left after the cleanup.
+func (ts TitleSanitizer) Apply(title string) string {
+	res := title
+	if ts.StripEmoji {
+		res = strings.TrimSpace(emojiRe.ReplaceAllString(res, ""))
+	}
+	if ts.TrimHashtags {
+		res = strings.TrimSpace(trailingHashtagRe.ReplaceAllString(res, ""))
+	}
+	if ts.DeShout && isShouting(res) {
+		res = titleCase(res)
+	}
+	if ts.CollapseSpaces {
+		res = strings.TrimSpace(spacesRe.ReplaceAllString(res, " "))
+	}
+	if res == "" {
+		return title
+	}
+	return res
+}
+
+// isShouting returns true if the title has a few letters and all of them upper case
+func isShouting(title string) bool {
+	letters := 0
+	for _, r := range title {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsUpper(r) {
+			letters++
+		}
+	}
+	return letters > 3
+}
+
+// titleCase makes the first letter of each word upper case and the rest lower case
+func titleCase(title string) string {
+	res := []rune(strings.ToLower(title))
+	for i, r := range res {
+		if i == 0 || !unicode.IsLetter(res[i-1]) && !unicode.IsDigit(res[i-1]) && res[i-1] != '\'' {
+			res[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(res)
+}
+
+// sanitizeTitle applies feed's title sanitizer to the entry. The original title added to the end of the description
+// if changed, to keep it for reference.
+func sanitizeTitle(entry ytfeed.Entry, fi FeedInfo) ytfeed.Entry {
+	if !fi.SanitizeTitle.Enabled() {
+		return entry
+	}
+	title := fi.SanitizeTitle.Apply(entry.Title)
+	if title == entry.Title {
+		return entry
+	}
+	orig := "Original title: " + htmltmpl.HTMLEscapeString(entry.Title)
+	if entry.Media.Description != "" {
+		orig = "\n\n" + orig
+	}
+	entry.Media.Description += htmltmpl.HTML(orig) // nolint
+	entry.Title = title
+	return entry
+}
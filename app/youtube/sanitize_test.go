@@ -0,0 +1,59 @@
+package youtube
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+func TestTitleSanitizer_Apply(t *testing.T) {
+	all := TitleSanitizer{StripEmoji: true, CollapseSpaces: true, DeShout: true, TrimHashtags: true}
+	tbl := []struct {
+		ts    TitleSanitizer
+		title string
+		res   string
+	}{
+		{TitleSanitizer{}, "🔥 THIS IS   INSANE 🔥 #shorts", "🔥 THIS IS   INSANE 🔥 #shorts"},
+		{TitleSanitizer{StripEmoji: true}, "🔥 Big news 🇺🇸 today ❤️", "Big news  today"},
+		{TitleSanitizer{StripEmoji: true}, "👨‍👩‍👧 family", "family"},
+		{TitleSanitizer{CollapseSpaces: true}, "  some \t title\n here ", "some title here"},
+		{TitleSanitizer{DeShout: true}, "THIS IS INSANE", "This Is Insane"},
+		{TitleSanitizer{DeShout: true}, "DON'T DO IT, EPISODE 12", "Don't Do It, Episode 12"},
+		{TitleSanitizer{DeShout: true}, "ЭТО ПРОСТО ЖЕСТЬ", "Это Просто Жесть"},
+		{TitleSanitizer{DeShout: true}, "NASA and SpaceX", "NASA and SpaceX"},
+		{TitleSanitizer{DeShout: true}, "AI 2", "AI 2"},
+		{TitleSanitizer{TrimHashtags: true}, "some title #shorts #news", "some title"},
+		{TitleSanitizer{TrimHashtags: true}, "#1 title of the week", "#1 title of the week"},
+		{TitleSanitizer{TrimHashtags: true}, "#shorts #news", "#shorts #news"},
+		{all, "🔥 THIS IS   INSANE 🔥 #shorts", "This Is Insane"},
+		{all, "🔥🔥🔥", "🔥🔥🔥"},
+		{all, "regular title", "regular title"},
+	}
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.res, tt.ts.Apply(tt.title))
+		})
+	}
+}
+
+func TestSanitizeTitle(t *testing.T) {
+	fi := FeedInfo{ID: "channel1", SanitizeTitle: TitleSanitizer{StripEmoji: true, DeShout: true}}
+	entry := ytfeed.Entry{VideoID: "vid1", Title: "BIG & LOUD 🔥"}
+	entry.Media.Description = "some description"
+
+	res := sanitizeTitle(entry, fi)
+	assert.Equal(t, "Big & Loud", res.Title)
+	assert.Equal(t, "some description\n\nOriginal title: BIG &amp; LOUD 🔥", string(res.Media.Description))
+
+	entry.Media.Description = ""
+	res = sanitizeTitle(entry, fi)
+	assert.Equal(t, "Original title: BIG &amp; LOUD 🔥", string(res.Media.Description), "empty description")
+
+	entry.Title = "clean title"
+	assert.Equal(t, entry, sanitizeTitle(entry, fi), "nothing changed")
+	assert.Equal(t, ytfeed.Entry{Title: "BIG 🔥"}, sanitizeTitle(ytfeed.Entry{Title: "BIG 🔥"}, FeedInfo{}), "disabled")
+}
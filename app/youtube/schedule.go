@@ -0,0 +1,119 @@
+package youtube
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// jitterRand is seeded with the start time, so instances started together get different delays.
+// Used by Do's goroutine only, not safe for concurrent use.
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano())) // nolint
+
+// schedule keeps the next check time of each feed, by index in Service.Feeds. Feeds may share id with different types,
+// so the index is used as the key.
+type schedule struct {
+	next []time.Time
+}
+
+// wait returns duration until the earliest next check, 0 if a feed is due already
+func (sc *schedule) wait(now time.Time) time.Duration {
+	if len(sc.next) == 0 {
+		return 0
+	}
+	earliest := sc.next[0]
+	for _, t := range sc.next[1:] {
+		if t.Before(earliest) {
+			earliest = t
+		}
+	}
+	if res := earliest.Sub(now); res > 0 {
+		return res
+	}
+	return 0
+}
+
+// dueFeeds returns feeds to check at now and schedules their next check. All feeds are due on the first call.
+func (s *Service) dueFeeds(sc *schedule, now time.Time) []FeedInfo {
+	feeds := s.feeds()
+	if len(sc.next) != len(feeds) {
+		sc.next = make([]time.Time, len(feeds))
+	}
+	res := []FeedInfo{}
+	for i, fi := range feeds {
+		if now.Before(sc.next[i]) {
+			continue
+		}
+		res = append(res, fi)
+		sc.next[i] = s.nextCheck(fi, now)
+	}
+	return res
+}
+
+// nextCheck returns the next check time of the feed checked at now. Feeds with CheckAt checked at the next time
+// of day, the same after restarts, other feeds after their interval.
+func (s *Service) nextCheck(fi FeedInfo, now time.Time) time.Time {
+	if len(fi.CheckAt) > 0 {
+		return nextAt(now, fi.CheckAt).Add(s.jitter())
+	}
+	return now.Add(s.interval(fi) + s.jitter())
+}
+
+// DayTime is a time of day, "15:04" in yaml, in the local time zone
+type DayTime struct {
+	Hour   int
+	Minute int
+}
+
+// UnmarshalYAML parses time of day as "15:04"
+func (d *DayTime) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return errors.Errorf("invalid time of day %q, expected hh:mm", s)
+	}
+	*d = DayTime{Hour: t.Hour(), Minute: t.Minute()}
+	return nil
+}
+
+// String returns time of day as "15:04"
+func (d DayTime) String() string {
+	return fmt.Sprintf("%02d:%02d", d.Hour, d.Minute)
+}
+
+// nextAt returns the earliest of times of day after now, in now's location. Times of day skipped or repeated
+// by daylight saving changes normalized by time.Date.
+func nextAt(now time.Time, at []DayTime) time.Time {
+	res := time.Time{}
+	for _, d := range at {
+		next := time.Date(now.Year(), now.Month(), now.Day(), d.Hour, d.Minute, 0, 0, now.Location())
+		if !next.After(now) {
+			next = time.Date(now.Year(), now.Month(), now.Day()+1, d.Hour, d.Minute, 0, 0, now.Location())
+		}
+		if res.IsZero() || next.Before(res) {
+			res = next
+		}
+	}
+	return res
+}
+
+// jitter returns random delay in [0, CheckJitter) added to the next check, 0 if CheckJitter not set
+func (s *Service) jitter() time.Duration {
+	if s.CheckJitter <= 0 {
+		return 0
+	}
+	return time.Duration(jitterRand.Int63n(int64(s.CheckJitter)))
+}
+
+// interval returns check interval of the feed, the feed's own or the service's default
+func (s *Service) interval(fi FeedInfo) time.Duration {
+	if fi.Interval > 0 {
+		return fi.Interval
+	}
+	return s.CheckDuration
+}
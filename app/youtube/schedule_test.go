@@ -0,0 +1,176 @@
+package youtube
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestService_dueFeeds(t *testing.T) {
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "news"},
+			{ID: "channel2", Name: "monthly", Interval: time.Hour},
+			{ID: "channel2", Name: "monthly shorts", Type: ytfeed.FTShorts, Interval: 3 * time.Minute},
+		},
+		CheckDuration: time.Minute,
+	}
+	names := func(feeds []FeedInfo) []string {
+		res := []string{}
+		for _, f := range feeds {
+			res = append(res, f.Name)
+		}
+		return res
+	}
+
+	sc := schedule{}
+	start := time.Date(2022, 4, 6, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), sc.wait(start), "nothing scheduled")
+	assert.Equal(t, []string{"news", "monthly", "monthly shorts"}, names(svc.dueFeeds(&sc, start)), "all due first")
+	assert.Equal(t, time.Minute, sc.wait(start))
+	assert.Equal(t, []string{}, names(svc.dueFeeds(&sc, start.Add(30*time.Second))), "nothing due")
+	assert.Equal(t, 30*time.Second, sc.wait(start.Add(30*time.Second)))
+
+	counts := map[string]int{}
+	for now := start.Add(time.Minute); now.Before(start.Add(2 * time.Hour)); now = now.Add(sc.wait(now)) {
+		for _, name := range names(svc.dueFeeds(&sc, now)) {
+			counts[name]++
+		}
+	}
+	assert.Equal(t, map[string]int{"news": 119, "monthly": 1, "monthly shorts": 39}, counts)
+
+	assert.Equal(t, time.Duration(0), sc.wait(start.Add(3*time.Hour)), "overdue")
+}
+
+func TestService_dueFeedsJitter(t *testing.T) {
+	svc := Service{
+		Feeds:         []FeedInfo{{ID: "channel1"}, {ID: "channel2"}, {ID: "channel3"}},
+		CheckDuration: time.Minute,
+		CheckJitter:   30 * time.Second,
+	}
+	start := time.Date(2022, 4, 6, 10, 0, 0, 0, time.UTC)
+	offsets := map[time.Duration]bool{}
+	for i := 0; i < 10; i++ {
+		sc := schedule{}
+		require.Equal(t, 3, len(svc.dueFeeds(&sc, start)))
+		for _, next := range sc.next {
+			offset := next.Sub(start)
+			assert.True(t, offset >= time.Minute && offset < time.Minute+30*time.Second, "offset %v", offset)
+			offsets[offset] = true
+		}
+	}
+	assert.True(t, len(offsets) > 1, "randomized")
+
+	svc.CheckJitter = 0
+	assert.Equal(t, time.Duration(0), svc.jitter())
+}
+
+func TestService_DoIntervals(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{}, nil
+		},
+	}
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel, Interval: 400 * time.Millisecond},
+		},
+		ChannelService: chans,
+		Store:          &store.BoltDB{DB: db},
+		CheckDuration:  100 * time.Millisecond,
+		KeepPerChannel: 10,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 950*time.Millisecond)
+	defer cancel()
+	assert.EqualError(t, svc.Do(ctx), "context deadline exceeded")
+
+	counts := map[string]int{}
+	for _, call := range chans.GetCalls() {
+		counts[call.ChanID]++
+	}
+	assert.InDelta(t, 10, counts["channel1"], 1, "checked every 100ms")
+	assert.Equal(t, 3, counts["channel2"], "checked every 400ms")
+}
+
+func TestNextAt(t *testing.T) {
+	loc := time.FixedZone("EST", -5*3600)
+	at := []DayTime{{Hour: 18}, {Hour: 6, Minute: 30}}
+	tbl := []struct {
+		now, next time.Time
+	}{
+		{time.Date(2022, 4, 6, 1, 0, 0, 0, loc), time.Date(2022, 4, 6, 6, 30, 0, 0, loc)},
+		{time.Date(2022, 4, 6, 6, 30, 0, 0, loc), time.Date(2022, 4, 6, 18, 0, 0, 0, loc)},
+		{time.Date(2022, 4, 6, 12, 0, 0, 0, loc), time.Date(2022, 4, 6, 18, 0, 0, 0, loc)},
+		{time.Date(2022, 4, 6, 23, 59, 0, 0, loc), time.Date(2022, 4, 7, 6, 30, 0, 0, loc)},
+		{time.Date(2022, 12, 31, 19, 0, 0, 0, loc), time.Date(2023, 1, 1, 6, 30, 0, 0, loc)},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.now.String(), func(t *testing.T) {
+			assert.Equal(t, tt.next, nextAt(tt.now, at))
+		})
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err == nil {
+		next := nextAt(time.Date(2022, 3, 12, 7, 0, 0, 0, ny), []DayTime{{Hour: 6}})
+		assert.Equal(t, time.Date(2022, 3, 13, 6, 0, 0, 0, ny), next, "daylight saving day")
+		assert.Equal(t, 22*time.Hour, next.Sub(time.Date(2022, 3, 12, 7, 0, 0, 0, ny)), "an hour shorter")
+	}
+}
+
+func TestService_dueFeedsCheckAt(t *testing.T) {
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "daily", CheckAt: []DayTime{{Hour: 6}}, Interval: time.Hour},
+			{ID: "channel2", Name: "hourly", Interval: time.Hour},
+		},
+		CheckDuration: time.Minute,
+	}
+
+	// restarts at different times keep checks of the daily feed at 06:00
+	for _, start := range []time.Time{time.Date(2022, 4, 6, 10, 0, 0, 0, time.UTC),
+		time.Date(2022, 4, 6, 13, 17, 0, 0, time.UTC), time.Date(2022, 4, 7, 5, 59, 0, 0, time.UTC)} {
+		sc := schedule{}
+		require.Equal(t, 2, len(svc.dueFeeds(&sc, start)), "all due on start")
+		assert.Equal(t, start.Add(time.Hour), sc.next[1])
+
+		checks := []time.Time{}
+		for now := start.Add(sc.wait(start)); now.Before(time.Date(2022, 4, 9, 0, 0, 0, 0, time.UTC)); now = now.Add(sc.wait(now)) {
+			for _, fi := range svc.dueFeeds(&sc, now) {
+				if fi.Name == "daily" {
+					checks = append(checks, now)
+				}
+			}
+		}
+		assert.Equal(t, time.Date(2022, 4, 8, 6, 0, 0, 0, time.UTC), checks[len(checks)-1], "start %v", start)
+		for _, c := range checks {
+			assert.Equal(t, "06:00", c.Format("15:04"), "start %v", start)
+		}
+	}
+}
+
+func TestDayTime_UnmarshalYAML(t *testing.T) {
+	var fi FeedInfo
+	require.NoError(t, yaml.Unmarshal([]byte(`{id: c1, check_at: ["06:00", "18:45"]}`), &fi))
+	assert.Equal(t, []DayTime{{Hour: 6}, {Hour: 18, Minute: 45}}, fi.CheckAt)
+	assert.Equal(t, "18:45", fi.CheckAt[1].String())
+
+	assert.Error(t, yaml.Unmarshal([]byte(`{id: c1, check_at: ["25:00"]}`), &fi))
+	assert.Error(t, yaml.Unmarshal([]byte(`{id: c1, check_at: ["6am"]}`), &fi))
+}
@@ -2,20 +2,37 @@
 package youtube
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	htmltmpl "html/template"
+	"io"
+	"math"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/bogem/id3v2/v2"
+	"github.com/dustin/go-humanize"
 	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/syncs"
 	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 
 	rssfeed "github.com/umputun/feed-master/app/feed"
@@ -26,6 +43,8 @@ import (
 //go:generate moq -out mocks/channel.go -pkg mocks -skip-ensure -fmt goimports . ChannelService
 //go:generate moq -out mocks/store.go -pkg mocks -skip-ensure -fmt goimports . StoreService
 //go:generate moq -out mocks/duration.go -pkg mocks -skip-ensure -fmt goimports . DurationService
+//go:generate moq -out mocks/splitter.go -pkg mocks -skip-ensure -fmt goimports . SplitterService
+//go:generate moq -out mocks/cmd_runner.go -pkg mocks -skip-ensure -fmt goimports . CommandRunner
 
 // Service loads audio from youtube channels
 type Service struct {
@@ -34,13 +53,131 @@ type Service struct {
 	ChannelService  ChannelService
 	Store           StoreService
 	CheckDuration   time.Duration
+	CheckJitter     time.Duration // random delay up to this duration added to each check, spreads load on the source
 	RSSFileStore    RSSFileStore
+	RSSMirrors      []RSSStore // additional stores of rss files, i.e. mirrors, saved along with RSSFileStore
 	DurationService DurationService
+	Splitter        SplitterService // required for feeds with SplitChapters, TrimStart or TrimEnd only
 	KeepPerChannel  int
 	RootURL         string
-	SkipShorts      time.Duration
+	// MediaBaseURL is the base url of enclosures (audio, subtitles and chapters), i.e. cdn serving files_location.
+	// Optional, RootURL used if empty
+	MediaBaseURL string
+	// RSSURL is the base url of feeds' rss, i.e. http://example.com/yt/rss, used for atom:link self.
+	// Optional, made from RootURL's parent with "rss" added if empty
+	RSSURL     string
+	SkipShorts time.Duration
+
+	// FileNameTemplate defines readable file names, i.e. "{{.Title}}-{{.Date}}-{{.ID}}". Empty means hash of entry's UID.
+	// Available fields: Title, Date (published, YYYY-MM-DD), ID (video id), ChannelID and Hash (short hash of entry's UID)
+	FileNameTemplate string
+
+	// FileNameHash is a hash function for file names, "sha256" (default) or "sha1" (legacy)
+	FileNameHash string
+	// FileNameHashLen truncates hash-based file names to this number of hex chars, extended on collision. 0 means full hash
+	FileNameHashLen int
+
+	// FilesLocation is the directory of downloaded files, used to find files downloaded before but missing in the store.
+	// Optional, files always downloaded if empty
+	FilesLocation string
+
+	// URLSigners makes signed enclosure urls for feeds, by feed id. Feeds without signer have unsigned urls
+	URLSigners map[string]URLSigner
+
+	// CompletionWebhook is an url to POST stats to at the end of each processing cycle, optional
+	CompletionWebhook string
+
+	// BackfillDelay is a pause between downloads made by Backfill, to avoid hitting rate limits
+	BackfillDelay time.Duration
+
+	// DownloadTimeout limits time of a single download, timed out download treated as failed. No limit if 0
+	DownloadTimeout time.Duration
+
+	// FeedTimeout is a time budget of a single feed in each update cycle. Once exhausted, the service moves on
+	// to the next feed and the rest of entries processed on the next cycle. No limit if 0
+	FeedTimeout time.Duration
+
+	// MaxPerCycle caps new downloads of a single feed per update cycle, the rest of new entries downloaded
+	// on the next cycles. Overridden by feed's MaxPerCycle. No limit if 0
+	MaxPerCycle int
+
+	// DownloadBudget limits bytes downloaded in each BudgetPeriod, i.e. for a monthly egress cap. Once reached,
+	// no new downloads started until the next period, feeds are still served. No limit if 0
+	DownloadBudget FileSize
+	// BudgetPeriod is the period of DownloadBudget, month if empty
+	BudgetPeriod BudgetPeriod
+
+	// Logger records processing events with structured fields, TextLogger (lgr) if nil
+	Logger EventLogger
+
+	// ShutdownGrace lets the download in progress finish on ctx cancellation, up to this duration.
+	// No new downloads started after cancellation. Download interrupted right away if 0
+	ShutdownGrace time.Duration
+
+	// PostDownloadTimeout limits run time of feeds' PostDownloadCmd, default is 1 minute
+	PostDownloadTimeout time.Duration
+
+	// CmdRunner runs PostDownloadCmd, ExecRunner if nil
+	CmdRunner CommandRunner
+
+	// FailuresPerFeed is the number of recent failed downloads kept for each feed, see Failures. Default is 20
+	FailuresPerFeed int
+
+	// CheckURLs is the max number of enclosure urls checked with HEAD request after the first update cycle,
+	// to detect misconfigured root url early. Disabled if 0
+	CheckURLs int
+
+	// DetectLanguage sets rss language of feeds without configured language from entries' language,
+	// or guessed from titles and descriptions
+	DetectLanguage bool
+
+	// ProcessedMaxAge removes processed markers of entries published (downloaded) more than this duration ago,
+	// checked once a day. Pruned entries downloaded again if the source still lists them, so it should exceed the time
+	// entries stay in the source. Values below minProcessedMaxAge raised to it. Disabled if 0
+	ProcessedMaxAge time.Duration
+
+	// MaxConcurrentDownloads limits downloads running at once by all feeds, backfill and re-downloads together,
+	// as each downloader's process loads cpu with transcoding. Downloads over the limit wait in queue. No limit if 0
+	MaxConcurrentDownloads int
+
+	// RemoveConcurrency is the number of old files removed in parallel, for slow networked storage. Sequential if 0
+	RemoveConcurrency int
+
+	// ListingTTL enables cache of fetched listings in the store. On restart, the first check of a feed reuses
+	// the cached listing if it is younger than ListingTTL, instead of fetching the source again. Disabled if 0
+	ListingTTL time.Duration
+
+	// FetchAttempts is the number of attempts to list a feed on transient errors, with exponential backoff
+	// starting from FetchRetryDelay (5s by default). Single attempt if 0
+	FetchAttempts   int
+	FetchRetryDelay time.Duration
+
+	// IncrementalRSS is the number of items in rss of the feed to update its rss file in place, with only new items made
+	// and items of removed entries dropped. Speeds up archival feeds with thousands of items. Changed overrides
+	// trigger full regeneration, feeds with signed urls always regenerated in full. Disabled if 0
+	IncrementalRSS int
+
+	// RFC822Dates formats rss dates as RFC822Z with 2 digits year, for compatibility with old clients. RFC1123Z if false
+	RFC822Dates bool
+
+	webhookRetryDelay  time.Duration // delay between webhook delivery attempts, default 5s
+	urlCheckRetryDelay time.Duration // delay between url check attempts, default 5s
+	overrides          overrides     // loaded overrides files of feeds
+	failures           failures      // recent failed downloads by feed
+	langs              detectedLangs // detected languages of feeds without configured language
+	updates            feedUpdates   // runtime changes of feeds' settings, see UpdateFeed
+	downloads          downloadSlots // running downloads, limited by MaxConcurrentDownloads
+	episodeMu          sync.Mutex    // serializes counter numbering of stored entries, see numberEpisodes
+	rssUpdates         rssUpdates    // feeds with full rss made since start, see feedRSS
+	budget             budgetState   // period with exhausted DownloadBudget reported
+	fileNames          fileNames     // names of stored files, to check collisions of new names
+
+	listed map[string]bool // feed keys checked since start, cached listing used for the first check only
 }
 
+// KeepAll is a special value for keep, meaning all entries should be kept forever (archival feeds)
+const KeepAll = -1
+
 // FeedInfo contains channel or feed ID, readable name and other per-feed info
 type FeedInfo struct {
 	Name     string      `yaml:"name"`
@@ -49,8 +186,195 @@ type FeedInfo struct {
 	Keep     int         `yaml:"keep"`
 	Language string      `yaml:"lang"`
 	Filter   FeedFilter  `yaml:"filter"`
+
+	// FeedItems limits entries in rss to the newest ones, while Keep entries stay stored with files.
+	// Keep is used if 0 or larger than Keep
+	FeedItems int `yaml:"feed_items"`
+
+	// MaxAge removes entries published earlier than this duration ago, in addition to Keep limit. No limit if 0
+	MaxAge time.Duration `yaml:"max_age"`
+
+	// StartDate skips entries published before this date, i.e. "2023-01-15" to subscribe from the middle of a series.
+	// Unlike MaxAge it is a fixed floor. Skipped entries are marked as processed. No limit if not set
+	StartDate time.Time `yaml:"start_date"`
+
+	// Interval is the check interval of the feed, Service.CheckDuration if 0
+	Interval time.Duration `yaml:"interval"`
+
+	// CheckAt lists times of day to check the feed at, i.e. ["06:00", "18:00"], instead of Interval. Unlike Interval
+	// the schedule doesn't shift on restarts. All feeds checked on start anyway, to catch up after downtime
+	CheckAt []DayTime `yaml:"check_at"`
+
+	// MaxPerCycle caps new downloads of the feed per update cycle, overrides Service.MaxPerCycle.
+	// 0 means the service's value, negative means no limit
+	MaxPerCycle int `yaml:"max_per_cycle"`
+
+	// DescriptionTmpl is a template for rss item description, executed with ytfeed.Entry.
+	// Empty means DefaultDescriptionTmpl, i.e. the original description of the video.
+	DescriptionTmpl string `yaml:"description_template"`
+
+	// MaxDescriptionLen truncates the video's description in rss to this number of characters, at a word boundary
+	// with ellipsis. The stored description is kept in full. No truncation if 0
+	MaxDescriptionLen int `yaml:"max_description_len"`
+
+	TitlePrefix   TitlePrefix    `yaml:"title_prefix"`
+	SanitizeTitle TitleSanitizer `yaml:"sanitize_title"`
+	URLSigning    URLSigning     `yaml:"url_signing"`
+
+	// Subtitles is a language code of subtitles to download and link in rss as transcript, i.e. "en".
+	// "auto" uses language of the entry or the feed. Empty means no subtitles
+	Subtitles string `yaml:"subtitles"`
+
+	// SplitChapters makes a separate episode from each chapter of the video. Videos without chapters kept as is
+	SplitChapters bool `yaml:"split_chapters"`
+
+	// TrimStart and TrimEnd cut fixed intro and outro from each episode with ffmpeg, no trimming if 0
+	TrimStart time.Duration `yaml:"trim_start"`
+	TrimEnd   time.Duration `yaml:"trim_end"`
+
+	// RepeatedTitles makes identical titles of episodes distinct in rss, "number" adds " #N" counted from the oldest
+	// episode with the title, "date" adds the original published date. Empty keeps titles as is
+	RepeatedTitles RepeatedTitles `yaml:"repeated_titles"`
+
+	// EnclosureMIME overrides mime type of enclosures in rss, i.e. "audio/x-m4a" for picky clients.
+	// Empty means the type inferred from the file extension
+	EnclosureMIME string `yaml:"enclosure_mime"`
+
+	// SubDir is the directory of feed's files, relative to the files location. Default is the feed's id,
+	// "." keeps files in the files location itself, like all feeds did before
+	SubDir string `yaml:"sub_dir"`
+
+	// SkipShorts excludes youtube shorts, detected by /shorts/ link or duration before the download, or by duration
+	// of the downloaded file. Skipped shorts are marked as processed and not retried
+	SkipShorts bool `yaml:"skip_shorts"`
+
+	// ShortsDuration is the max duration of shorts for SkipShorts, DefaultShortsDuration if 0
+	ShortsDuration time.Duration `yaml:"shorts_duration"`
+
+	// MembersOnly is "skip" to exclude members-only videos or "only" to download just them, detected by yt-dlp's
+	// availability before the download. Skipped videos are marked as processed. Empty keeps all videos
+	MembersOnly MembersOnly `yaml:"members_only"`
+
+	// Paused feed is not checked for new entries, its rss with already downloaded entries is still served.
+	// Can be changed at runtime with UpdateFeed, the runtime state persisted and overrides configured one
+	Paused bool `yaml:"paused"`
+
+	// MaxFileSize rejects downloaded files larger than this size, i.e. of hours long live streams. Rejected entries
+	// are marked as processed and not retried. Checked before the download if the downloader can. No limit if 0
+	MaxFileSize FileSize `yaml:"max_file_size"`
+
+	// Numbering is "counter" to number episodes in itunes:episode by a stored counter, in order of publishing
+	// for entries downloaded together, or "title" to parse numbers from titles with EpisodePattern.
+	// Episodes not numbered if empty
+	Numbering Numbering `yaml:"numbering"`
+
+	// EpisodePattern is a regex of episode numbers in titles for "title" numbering, with the number in "episode" or
+	// the first group and optional "season" group. DefaultEpisodePattern if empty
+	EpisodePattern string `yaml:"episode_pattern"`
+
+	// Season is the itunes:season of the feed's episodes, not set if 0
+	Season int `yaml:"season"`
+
+	// Mode is "video" to download video with audio as mp4 and make video podcast, "audio" (default) for audio only
+	Mode FeedMode `yaml:"mode"`
+
+	// CopyAudio keeps the best native audio of the video (i.e. m4a or opus) without re-encoding to mp3, faster and
+	// without quality loss. Enclosure type set by the file extension, mp3 tags are not written
+	CopyAudio bool `yaml:"copy_audio"`
+
+	// Dedup skips re-uploads, i.e. new video ids with the same normalized title and duration as a stored entry
+	Dedup bool `yaml:"dedup"`
+
+	// OriginalDate adds dc:date with the original upload time to items, pubDate may be reset to the download time
+	OriginalDate bool `yaml:"original_date"`
+
+	// MediaRSS adds media:content and media:thumbnail to items, for clients ignoring enclosures
+	MediaRSS bool `yaml:"media_rss"`
+
+	// Locked sets podcast:locked to "yes", asking podcast platforms to refuse import of the feed
+	Locked bool `yaml:"locked"`
+
+	// Overrides is a yaml or json file with videoID -> {title, description} replacing scraped values.
+	// The file reloaded on change, missing file means no overrides
+	Overrides string `yaml:"overrides"`
+
+	// BasicAuth protects rss and files of the feed with http basic auth, overrides global credentials.
+	// Feeds with own credentials are excluded from the aggregated rss
+	BasicAuth BasicAuth `yaml:"basic_auth"`
+
+	// PostDownloadCmd is executed after each downloaded and saved episode, i.e. for custom tagging or uploading.
+	// Each space separated field (quotes keep spaces) is a template of an arg with entry's ID, FeedID, FeedName,
+	// Title, File, URL, Published and Duration. The command executed without shell, output logged
+	PostDownloadCmd string `yaml:"post_download_cmd"`
+
+	// APIKey is youtube data api key to list playlist with the api instead of rss, for unlisted playlists.
+	// Also needed to backfill the history of youtube feeds. Private playlists can't be listed with api key
+	APIKey string `yaml:"api_key" json:"-"`
+
+	// SubscriberSecret enables subscriber tokens for the feed, see SubscriberToken. Disabled if empty
+	SubscriberSecret string `yaml:"subscriber_secret" json:"-"`
+
+	// Headers are added to requests listing the feed, i.e. auth header of a reverse proxy in front of self-hosted
+	// Invidious or Piped instance used as the source. Values are secrets, masked in logs
+	Headers ytfeed.Headers `yaml:"headers" json:"-"`
+
+	// Source is an alternative api listing the channel, i.e. self-hosted Piped or Invidious instance, overrides
+	// the global one. Youtube's rss or the global source if not set
+	Source ytfeed.Source `yaml:"source"`
+}
+
+// FilesDir returns directory of feed's files relative to the files location, SubDir or sanitized feed's id by default
+func (fi FeedInfo) FilesDir() string {
+	if fi.SubDir != "" {
+		return filepath.Clean(fi.SubDir)
+	}
+	return sanitizeFileName(fi.ID, maxFileNameLen)
+}
+
+// TitlePrefix defines how the channel name added to the entry's title. Zero value prepends the name with ": "
+// separator. The name is not added if the title already contains it as a whole word, in any case.
+type TitlePrefix struct {
+	Disabled  bool   `yaml:"disabled"`  // keep original titles
+	Append    bool   `yaml:"append"`    // add the name to the end of the title instead of the beginning
+	Separator string `yaml:"separator"` // separator between the name and the title, default ": " or " - " for append
+}
+
+// Apply adds channel name to the title, if enabled and title doesn't contain the name already
+func (tp TitlePrefix) Apply(title, name string) string {
+	if tp.Disabled || name == "" || containsWord(title, name) {
+		return title
+	}
+	sep := tp.Separator
+	if tp.Append {
+		if sep == "" {
+			sep = " - "
+		}
+		return title + sep + name
+	}
+	if sep == "" {
+		sep = ": "
+	}
+	return name + sep + title
+}
+
+// containsWord checks if the text contains the phrase case-insensitively, not as a part of a longer word,
+// i.e. "TED" is in "Talk by ted" but not in "United". Edges of the phrase made of non-alphanumeric characters,
+// like "[TED]", match without word boundaries.
+func containsWord(text, phrase string) bool {
+	isAlnum := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	pattern := regexp.QuoteMeta(phrase)
+	if r, _ := utf8.DecodeRuneInString(phrase); isAlnum(r) {
+		pattern = `(^|[^\p{L}\p{N}])` + pattern
+	}
+	if r, _ := utf8.DecodeLastRuneInString(phrase); isAlnum(r) {
+		pattern += `($|[^\p{L}\p{N}])`
+	}
+	return regexp.MustCompile("(?i)" + pattern).MatchString(text) // quoted phrase, always valid
 }
 
+// DefaultDescriptionTmpl is the default template for rss item description, keeps the original description
+const DefaultDescriptionTmpl = "{{.Media.Description}}"
+
 // FeedFilter contains filter criteria for the feed
 type FeedFilter struct {
 	Include string `yaml:"include"`
@@ -60,11 +384,20 @@ type FeedFilter struct {
 // DownloaderService is an interface for downloading audio from youtube
 type DownloaderService interface {
 	Get(ctx context.Context, id string, fname string) (file string, err error)
+	Subtitles(ctx context.Context, id, fname, lang string) (file string, err error)
+}
+
+// OptionsDownloader is implemented by downloaders supporting per-download options, i.e. checking the size
+// of the file before the download or keeping native audio. GetOpts returns ytfeed.ErrTooLarge if the file
+// is larger than opts.MaxSize
+type OptionsDownloader interface {
+	GetOpts(ctx context.Context, id string, fname string, opts ytfeed.GetOptions) (file string, err error)
 }
 
 // ChannelService is an interface for getting channel entries, i.e. the list of videos
 type ChannelService interface {
-	Get(ctx context.Context, chanID string, feedType ytfeed.Type) ([]ytfeed.Entry, error)
+	Get(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error)
+	GetPage(ctx context.Context, chanID string, feedType ytfeed.Type, page int, apiKey string) ([]ytfeed.Entry, error)
 }
 
 // StoreService is an interface for storing and loading metadata about downloaded audio
@@ -78,6 +411,17 @@ type StoreService interface {
 	ResetProcessed(entry ytfeed.Entry) error
 	CheckProcessed(entry ytfeed.Entry) (found bool, ts time.Time, err error)
 	CountProcessed() (count int)
+	PruneProcessed(maxAge time.Duration) (count int, err error)
+	AddBytes(channelID string, size int64) error
+	CountBytes(channelID string) (count int64)
+	AddPeriodBytes(period string, size int64) error
+	CountPeriodBytes(period string) (count int64)
+	SetFetchError(channelID string, fe ytfeed.FetchError) error
+	FetchErrors() (map[string]ytfeed.FetchError, error)
+	SetListing(feedKey string, l ytfeed.Listing) error
+	Listing(feedKey string) (ytfeed.Listing, error)
+	SetFeedUpdate(feedID string, upd ytfeed.FeedUpdate) error
+	FeedUpdates() (map[string]ytfeed.FeedUpdate, error)
 }
 
 // DurationService is an interface for getting duration of audio file
@@ -85,6 +429,11 @@ type DurationService interface {
 	File(fname string) int
 }
 
+// SplitterService cuts a part of audio file, used to split videos by chapters
+type SplitterService interface {
+	Split(ctx context.Context, src, dst string, start, end time.Duration) error
+}
+
 // Do is a blocking function that downloads audio from youtube channels and updates metadata
 func (s *Service) Do(ctx context.Context) error {
 	log.Printf("[INFO] starting youtube service")
@@ -92,267 +441,1512 @@ func (s *Service) Do(ctx context.Context) error {
 	if s.SkipShorts > 0 {
 		log.Printf("[DEBUG] skip youtube episodes shorter than %v", s.SkipShorts)
 	}
-	for _, f := range s.Feeds {
+	for _, f := range s.feeds() {
 		log.Printf("[INFO] youtube feed %+v", f)
 	}
 
-	tick := time.NewTicker(s.CheckDuration)
-	defer tick.Stop()
-
-	if err := s.procChannels(ctx); err != nil {
-		return errors.Wrap(err, "failed to process channels")
-	}
-
-	for {
+	// each feed checked on its own interval, the timer set to the next due feed
+	sched := schedule{}
+	lastPrune := time.Time{}
+	for cycle := 0; ; cycle++ {
+		if _, err := s.procFeeds(ctx, s.dueFeeds(&sched, time.Now())); err != nil {
+			return errors.Wrap(err, "failed to process channels")
+		}
+		if s.ProcessedMaxAge > 0 && time.Since(lastPrune) >= pruneInterval {
+			s.pruneProcessed()
+			lastPrune = time.Now()
+		}
+		if cycle == 0 && s.CheckURLs > 0 {
+			go s.checkEnclosures(ctx, s.CheckURLs)
+		}
+		timer := time.NewTimer(sched.wait(time.Now()))
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return ctx.Err()
-		case <-tick.C:
-			if err := s.procChannels(ctx); err != nil {
-				return errors.Wrap(err, "failed to process channels")
-			}
+		case <-timer.C:
+		}
+	}
+}
+
+// RSSFilter limits items of RSSFeed at request time, the stored entries and rss file not changed
+type RSSFilter struct {
+	Since       time.Time     // entries published after it only, i.e. for subscriber's token. No limit if zero
+	MinDuration time.Duration // entries of this duration or longer only, entries of unknown duration kept. No limit if 0
+}
+
+// match checks if the entry passes the filter
+func (f RSSFilter) match(entry ytfeed.Entry) bool {
+	if !f.Since.IsZero() && !entry.Published.After(f.Since) {
+		return false
+	}
+	if f.MinDuration > 0 && entry.Duration > 0 && time.Duration(entry.Duration)*time.Second < f.MinDuration {
+		return false
+	}
+	return true
+}
+
+// RSSFeed generates RSS feed for given channel, with items limited by the filter.
+func (s *Service) RSSFeed(fi FeedInfo, filter RSSFilter) (string, error) {
+	fi = s.updated(fi)
+	entries, err := s.rssEntries(fi)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get channel entries")
+	}
+
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	for i := range entries {
+		entries[i] = s.applyOverride(entries[i], fi, true)
+	}
+	titles := distinctTitles(entries, fi.RepeatedTitles) // numbered over all entries, the same with filter or without
+
+	items := []rssfeed.Item{}
+	for _, entry := range entries {
+		if !filter.match(entry) {
+			continue
+		}
+		if title, ok := titles[entry.UID()]; ok {
+			entry.Title = title
+		}
+		size, sizeErr := entrySize(entry)
+		if sizeErr != nil {
+			log.Printf("[WARN] no size of %s (%s %s), zero enclosure length: %v", entry.File, entry.VideoID,
+				entry.Title, sizeErr)
+		}
+		items = append(items, s.rssItem(entry, fi, size))
+	}
+
+	rss := s.rssChannel(fi, entries)
+	rss.ItemList = items
+	return marshalRSS(rss)
+}
+
+// rssChannel makes rss of the feed without items, channel's info taken from the newest entry
+func (s *Service) rssChannel(fi FeedInfo, entries []ytfeed.Entry) rssfeed.Rss2 {
+	rss := rssfeed.Rss2{
+		Version:        "2.0",
+		NsItunes:       "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		NsMedia:        "http://search.yahoo.com/mrss/",
+		Title:          fi.Name,
+		Description:    "generated by feed-master",
+		Link:           entries[0].Author.URI,
+		PubDate:        s.rssDate(entries[0].Published),
+		LastBuildDate:  s.rssDate(time.Now()),
+		Language:       s.feedLanguage(fi, entries),
+		ItunesAuthor:   entries[0].Author.Name,
+		ItunesExplicit: "no",
+		PodcastGUID:    podcastGUID(fi.ID),
+		PodcastLocked:  &rssfeed.PodcastLocked{Value: "no"},
+		AtomLink:       s.selfLink(fi.ID),
+	}
+	if fi.Locked {
+		rss.PodcastLocked.Value = "yes"
+	}
+
+	// set image from channel as rss thumbnail
+	// TODO: we may want to load it locally in case if youtube doesn't like such remote usage of images
+	if image := entries[0].Media.Thumbnail.URL; image != "" {
+		rss.ItunesImage = &rssfeed.ItunesImg{URL: image}
+		rss.MediaThumbnail = &rssfeed.MediaThumbnail{URL: image}
+	}
+
+	if fi.Type == ytfeed.FTPlaylist {
+		rss.Link = "https://www.youtube.com/playlist?list=" + fi.ID
+	}
+	if fi.Type.IsTab() {
+		rss.Link = "https://www.youtube.com/channel/" + fi.ID + "/" + string(fi.Type)
+	}
+	return rss
+}
+
+// AggregateRSS returns rss with the newest entries of all feeds merged together, sorted by published time.
+// Entries without local file and without stored size are skipped. Max limits the number of items, max <= 0 means no limit.
+// Each item has the name of its feed as category, and as author if the entry has no author.
+func (s *Service) AggregateRSS(max int) (string, error) {
+	type feedEntry struct {
+		entry ytfeed.Entry
+		fi    FeedInfo
+	}
+	var all []feedEntry
+	for _, fi := range s.feeds() {
+		if fi.BasicAuth.Enabled() {
+			continue // private feed, not mixed with others
+		}
+		entries, err := s.rssEntries(fi)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get channel entries for %s", fi.ID)
+		}
+		for _, entry := range entries {
+			all = append(all, feedEntry{entry: entry, fi: fi})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].entry.Published.After(all[j].entry.Published)
+	})
+
+	items := []rssfeed.Item{}
+	for _, fe := range all {
+		if max > 0 && len(items) >= max {
+			break
+		}
+		size, err := entrySize(fe.entry)
+		if err != nil {
+			log.Printf("[DEBUG] skip %s (%s) in aggregated rss, %v", fe.entry.VideoID, fe.entry.Title, err)
+			continue
+		}
+		item := s.rssItem(s.applyOverride(fe.entry, fe.fi, true), fe.fi, size)
+		item.Category = fe.fi.Name
+		if item.Author == "" {
+			item.Author = fe.fi.Name
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+
+	rss := rssfeed.Rss2{
+		Version:        "2.0",
+		NsItunes:       "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		NsMedia:        "http://search.yahoo.com/mrss/",
+		ItemList:       items,
+		Title:          "All channels",
+		Description:    "generated by feed-master",
+		Link:           s.RootURL,
+		PubDate:        items[0].PubDate,
+		LastBuildDate:  s.rssDate(time.Now()),
+		ItunesAuthor:   "feed-master",
+		ItunesExplicit: "no",
+		AtomLink:       s.selfLink("all"),
+	}
+	return marshalRSS(rss)
+}
+
+// rssDate formats time for rss in UTC, as RFC1123Z or RFC822Z if RFC822Dates set
+func (s *Service) rssDate(t time.Time) string {
+	return rssfeed.FormatDate(t.In(time.UTC), s.RFC822Dates)
+}
+
+// rssItem makes rss item for the stored entry
+func (s *Service) rssItem(entry ytfeed.Entry, fi FeedInfo, fileSize int) rssfeed.Item {
+	duration := ""
+	if entry.Duration > 0 {
+		duration = fmt.Sprintf("%d", entry.Duration)
+	}
+	lang := entry.Language
+	if lang == "" {
+		lang = fi.Language
+	}
+	var transcript *rssfeed.Transcript
+	if entry.Subtitles != "" {
+		transcript = &rssfeed.Transcript{URL: s.fileURL(entry.Subtitles, fi), Type: "text/vtt", Language: lang}
+	}
+	var chapters *rssfeed.PodcastChapters
+	if len(entry.Chapters) > 0 {
+		chapters = &rssfeed.PodcastChapters{URL: s.fileURL(chaptersFile(entry.File), fi), Type: "application/json+chapters"}
+	}
+	var mediaContent *rssfeed.MediaContent
+	var mediaThumbnail *rssfeed.MediaThumbnail
+	if fi.MediaRSS {
+		mediaContent = &rssfeed.MediaContent{URL: s.fileURL(entry.File, fi), FileSize: fileSize,
+			Type: enclosureType(entry.File, fi), Medium: "audio", Duration: entry.Duration}
+		if fi.Mode == ModeVideo {
+			mediaContent.Medium = "video"
+		}
+		if entry.Media.Thumbnail.URL != "" {
+			mediaThumbnail = &rssfeed.MediaThumbnail{URL: entry.Media.Thumbnail.URL}
+		}
+	}
+	episode, season := episodeNumber(entry, fi)
+	originalDate := ""
+	if fi.OriginalDate && !entry.OriginalPublished.IsZero() {
+		originalDate = entry.OriginalPublished.In(time.UTC).Format(time.RFC3339)
+	}
+
+	return rssfeed.Item{
+		Title:       entry.Title,
+		Description: s.itemDescription(entry, fi),
+		Link:        entry.Link.Href,
+		PubDate:     s.rssDate(entry.Published),
+		GUID:        itemGUID(entry),
+		Author:      entry.Author.Name,
+		Enclosure: rssfeed.Enclosure{
+			URL:    s.fileURL(entry.File, fi),
+			Type:   enclosureType(entry.File, fi),
+			Length: fileSize,
+		},
+		Duration:       duration,
+		Episode:        episode,
+		Season:         season,
+		Language:       lang,
+		OriginalDate:   originalDate,
+		Transcript:     transcript,
+		Chapters:       chapters,
+		MediaContent:   mediaContent,
+		MediaThumbnail: mediaThumbnail,
+		DT:             time.Now(),
+	}
+}
+
+// fileURL returns url of the entry's file (audio or subtitles), signed if the feed has signer
+func (s *Service) fileURL(file string, fi FeedInfo) string {
+	base := s.MediaBaseURL
+	if base == "" {
+		base = s.RootURL
+	}
+	fileURL := base + "/" + s.relativeFile(file)
+	signer, ok := s.URLSigners[fi.ID]
+	if !ok || signer == nil {
+		return fileURL
+	}
+	signed, err := signer.Sign(fileURL)
+	if err != nil {
+		log.Printf("[WARN] failed to sign url %s for %s, %v", fileURL, fi.ID, err)
+		return fileURL
+	}
+	return signed
+}
+
+// selfLink returns atom:link to the feed's own rss, nil if neither RSSURL nor RootURL set
+func (s *Service) selfLink(feedID string) *rssfeed.AtomLink {
+	if s.RSSURL != "" {
+		return &rssfeed.AtomLink{Href: strings.TrimSuffix(s.RSSURL, "/") + "/" + feedID, Rel: "self", Type: "application/rss+xml"}
+	}
+	if s.RootURL == "" {
+		return nil
+	}
+	u, err := url.Parse(strings.TrimSuffix(s.RootURL, "/"))
+	if err != nil {
+		log.Printf("[WARN] failed to parse root url %s, %v", s.RootURL, err)
+		return nil
+	}
+	// root url is the base of media files, i.e. http://example.com/yt/media, rss served from the sibling path
+	u.Path = path.Join("/", path.Dir(u.Path), "rss", feedID)
+	return &rssfeed.AtomLink{Href: u.String(), Rel: "self", Type: "application/rss+xml"}
+}
+
+// entrySize returns size of entry's file for enclosure length, of the local file or the size stored at the download.
+// The stored size used for files moved to remote storage, i.e. s3 or cdn, so rss doesn't need local files.
+// Local file checked first, it may be changed after the download, i.e. tagged by post_download_cmd
+func entrySize(entry ytfeed.Entry) (int, error) {
+	size, err := fileSize(entry.File)
+	if err == nil {
+		return size, nil
+	}
+	if entry.FileSize > 0 {
+		return int(entry.FileSize), nil
+	}
+	return 0, errors.Wrap(err, "no stored size")
+}
+
+func fileSize(file string) (int, error) {
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+	return int(fileInfo.Size()), nil
+}
+
+// fileChecksum returns hex encoded sha256 of the file content
+func fileChecksum(file string) (string, error) {
+	fh, err := os.Open(file) //nolint:gosec // file name is made by the service
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", file)
+	}
+	defer fh.Close() //nolint:gosec // read-only file
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return "", errors.Wrapf(err, "failed to read %s", file)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func marshalRSS(rss rssfeed.Rss2) (string, error) {
+	if rss.AtomLink != nil {
+		rss.NsAtom = "http://www.w3.org/2005/Atom"
+	}
+	if rss.PodcastGUID != "" || rss.PodcastLocked != nil {
+		rss.NsPodcast = "https://podcastindex.org/namespace/1.0"
+	}
+	for _, item := range rss.ItemList {
+		if item.Language != "" || item.OriginalDate != "" {
+			rss.NsDC = "http://purl.org/dc/elements/1.1/"
+		}
+		if item.Transcript != nil || item.Chapters != nil {
+			rss.NsPodcast = "https://podcastindex.org/namespace/1.0"
+		}
+	}
+	b, err := xml.MarshalIndent(&rss, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal rss")
+	}
+
+	res := string(b)
+	// this hack to avoid having different items for marshal and unmarshal due to "itunes" namespace
+	res = strings.Replace(res, "<duration>", "<itunes:duration>", -1)
+	res = strings.Replace(res, "</duration>", "</itunes:duration>", -1)
+	return res, nil
+}
+
+// podcastNamespace is uuid namespace defined by podcast namespace spec for podcast:guid
+var podcastNamespace = uuid.MustParse("ead4c236-bf58-58c6-a2c6-a6b28d128cb6")
+
+// podcastGUID returns stable podcast:guid of the feed, uuid v5 of feed id
+func podcastGUID(feedID string) string {
+	return uuid.NewSHA1(podcastNamespace, []byte(feedID)).String()
+}
+
+// itemDescription makes rss item description from the entry with feed's description template.
+// Falls back to the original description if the template failed. The video's description truncated
+// to MaxDescriptionLen, if set.
+func (s *Service) itemDescription(entry ytfeed.Entry, fi FeedInfo) htmltmpl.HTML {
+	entry.Media.Description = htmltmpl.HTML(truncateText(string(entry.Media.Description), fi.MaxDescriptionLen)) // nolint
+	if fi.DescriptionTmpl == "" || fi.DescriptionTmpl == DefaultDescriptionTmpl {
+		return entry.Media.Description
+	}
+	tmpl, err := template.New("description").Parse(fi.DescriptionTmpl)
+	if err != nil {
+		log.Printf("[WARN] failed to parse description template for %s, %v", fi.ID, err)
+		return entry.Media.Description
+	}
+	b := bytes.Buffer{}
+	if err = tmpl.Execute(&b, entry); err != nil {
+		log.Printf("[WARN] failed to execute description template for %s (%s), %v", fi.ID, entry.VideoID, err)
+		return entry.Media.Description
+	}
+	return htmltmpl.HTML(b.String()) // nolint
+}
+
+// truncateText cuts the text to maxLen characters at the last word boundary and adds ellipsis. Text without spaces
+// cut in the middle of the word. Text not longer than maxLen and any text with maxLen <= 0 returned as is.
+func truncateText(text string, maxLen int) string {
+	runes := []rune(text)
+	if maxLen <= 0 || len(runes) <= maxLen {
+		return text
+	}
+	cut := string(runes[:maxLen])
+	if next := runes[maxLen]; !unicode.IsSpace(next) { // cut in the middle of the word, drop its part
+		if idx := strings.LastIndexFunc(cut, unicode.IsSpace); idx > 0 {
+			cut = cut[:idx]
 		}
 	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
+
+// procChannels processes all channels, downloads audio, updates metadata and stores RSS.
+// Returns stats aggregated for all channels.
+func (s *Service) procChannels(ctx context.Context) (Stats, error) {
+	return s.procFeeds(ctx, s.feeds())
+}
+
+// procFeeds processes given feeds, see procChannels
+func (s *Service) procFeeds(ctx context.Context, feeds []FeedInfo) (Stats, error) {
+	s.resetFileNames() // pick up files removed or changed since the last cycle
+
+	var allStats Stats
+	feedsStats := make([]feedStats, 0, len(feeds))
+
+	cancelFeed := context.CancelFunc(func() {})
+	defer func() { cancelFeed() }()
+
+	for _, feedInfo := range feeds {
+		if feedInfo.Paused {
+			log.Printf("[DEBUG] skip paused feed %s (%s)", feedInfo.ID, feedInfo.Name)
+			continue
+		}
+		cancelFeed()
+		var feedCtx context.Context
+		feedCtx, cancelFeed = s.feedContext(ctx)
+
+		entries, err := s.listEntries(feedCtx, feedInfo)
+		if err != nil {
+			s.event("WARN", "fetch", fmt.Sprintf("failed to get channel entries for %s: %s", feedInfo.ID, err),
+				feedFields(feedInfo).with("error", err.Error()))
+			s.setFetchError(feedInfo.ID, ytfeed.FetchError{Error: err.Error(), TS: time.Now()})
+			continue
+		}
+		s.setFetchError(feedInfo.ID, ytfeed.FetchError{}) // fetched, clear the last error if any
+		s.event("INFO", "fetch", fmt.Sprintf("got %d entries for %s, limit to %d", len(entries), feedInfo.Name, s.keep(feedInfo)),
+			feedFields(feedInfo).with("entries", len(entries)).with("keep", s.keep(feedInfo)))
+		changed, fst := false, Stats{}
+		for i, entry := range entries {
+
+			// exit right away if context is done
+			select {
+			case <-ctx.Done():
+				return allStats, ctx.Err()
+			default:
+			}
+
+			// move to the next feed if the time budget is exhausted, the rest of entries left for the next cycle
+			if feedCtx.Err() != nil {
+				s.event("WARN", "budget", fmt.Sprintf("time budget %v exhausted for %s, %d entries left for the next cycle",
+					s.FeedTimeout, feedInfo.Name, len(entries)-i), feedFields(feedInfo).with("left", len(entries)-i))
+				break
+			}
+
+			fst.Entries++
+			if keep := s.keep(feedInfo); keep != KeepAll && fst.Processed >= keep {
+				break
+			}
+			isAllowed, err := s.isAllowed(entry, feedInfo)
+			if err != nil {
+				return allStats, errors.Wrapf(err, "failed to check if entry %s is relevant", entry.VideoID)
+			}
+			if !isAllowed {
+				s.event("DEBUG", "filter", "skipping filtered "+entry.String(), entryFields(feedInfo, entry))
+				fst.Ignored++
+				continue
+			}
+
+			ok, err := s.isNew(entry, feedInfo)
+			if err != nil {
+				return allStats, errors.Wrapf(err, "failed to check if entry %s exists", entry.VideoID)
+			}
+			if !ok {
+				fst.Skipped++
+				fst.Processed++
+				continue
+			}
+
+			// live streams and premieres can't be downloaded yet, defer them without marking as processed
+			if !entry.IsAvailable() {
+				s.event("INFO", "defer", fmt.Sprintf("defer %s entry %s, not available yet", entry.LiveStatus, entry.String()),
+					entryFields(feedInfo, entry).with("live_status", string(entry.LiveStatus)))
+				fst.Deferred++
+				continue
+			}
+
+			if s.isExpired(entry, feedInfo) {
+				fst.Ignored++
+				s.event("INFO", "skip", fmt.Sprintf("skipping entry %s as it is older than max age %v", entry.String(), feedInfo.MaxAge),
+					entryFields(feedInfo, entry).with("reason", "max_age"))
+				if procErr := s.Store.SetProcessed(entry); procErr != nil {
+					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+				}
+				continue
+			}
+
+			if s.beforeStart(entry, feedInfo) {
+				fst.Ignored++
+				s.event("INFO", "skip", fmt.Sprintf("skipping entry %s published before start date %s", entry.String(),
+					feedInfo.StartDate.Format("2006-01-02")), entryFields(feedInfo, entry).with("reason", "start_date"))
+				if procErr := s.Store.SetProcessed(entry); procErr != nil {
+					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+				}
+				continue
+			}
+
+			if short, reason := isShortEntry(entry, feedInfo); short {
+				fst.Ignored++
+				s.event("INFO", "skip", fmt.Sprintf("skipping short %s, detected by %s", entry.String(), reason),
+					entryFields(feedInfo, entry).with("reason", "short").with("detected_by", reason))
+				if procErr := s.Store.SetProcessed(entry); procErr != nil {
+					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+				}
+				continue
+			}
+
+			// got new entry, but with very old timestamp. skip it if we have already reached max capacity
+			// (this is to eliminate the initial load) and this entry is older than the oldest one we have.
+			// Also marks it as processed as we don't want to process it again
+			oldestEntry := s.oldestEntry()
+			if entry.Published.Before(oldestEntry.Published) && s.countAllEntries() >= s.totalEntriesToKeep() {
+				fst.Ignored++
+				s.event("INFO", "skip", fmt.Sprintf("skipping entry %s as it is older than the oldest one we have %s",
+					entry.String(), oldestEntry.String()), entryFields(feedInfo, entry).with("reason", "too_old"))
+				if procErr := s.Store.SetProcessed(entry); procErr != nil {
+					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+				}
+				continue
+			}
+
+			// spread a large backlog over several cycles, the rest of entries left unprocessed for the next cycle
+			if limit := s.maxPerCycle(feedInfo); limit > 0 && fst.Added >= limit {
+				s.event("INFO", "throttle", fmt.Sprintf("max %d downloads per cycle reached for %s, %d entries left for the next cycle",
+					limit, feedInfo.Name, len(entries)-i), feedFields(feedInfo).with("left", len(entries)-i).with("limit", limit))
+				break
+			}
+
+			// stop downloads until the next budget period, the rest of entries left for later cycles
+			if s.budgetExhausted(time.Now()) {
+				break
+			}
+
+			entry = s.applyOverride(sanitizeTitle(entry, feedInfo), feedInfo, false)
+			s.event("INFO", "new", fmt.Sprintf("new entry [%d] %s, %s, %s, %s", i+1, entry.VideoID, entry.Title,
+				feedInfo.Name, entry.String()), entryFields(feedInfo, entry))
+
+			_, fsize, saved, err := s.downloadEntry(feedCtx, entry, feedInfo)
+			if err == ytfeed.ErrNotAvailable {
+				fst.Deferred++
+				continue
+			}
+			if err != nil {
+				return allStats, err
+			}
+			if !saved {
+				fst.Ignored++
+				continue
+			}
+			fst.Processed++
+			fst.Bytes += fsize
+			changed = true
+			fst.Added++
+		}
+		if fst.Bytes > 0 {
+			lifetime := s.Store.CountBytes(feedInfo.ID)
+			s.event("INFO", "downloaded", fmt.Sprintf("downloaded %s for %s, lifetime: %s", humanize.Bytes(uint64(fst.Bytes)),
+				feedInfo.Name, humanize.Bytes(uint64(lifetime))),
+				feedFields(feedInfo).with("bytes", fst.Bytes).with("lifetime_bytes", lifetime))
+		}
+
+		if changed {
+			s.numberEpisodes(feedInfo)
+		}
+		if changed || feedInfo.MaxAge > 0 {
+			fst.Removed = s.removeOld(feedInfo)
+		}
+		if changed || fst.Removed > 0 {
+			// save rss feed to fs if there are new or removed entries
+			rss, rssErr := s.feedRSS(feedInfo)
+			if rssErr != nil {
+				log.Printf("[WARN] failed to generate rss for %s: %s", feedInfo.Name, rssErr)
+			} else {
+				if err := s.StoreRSS(feedInfo.ID, rss); err != nil {
+					log.Printf("[WARN] failed to save rss for %s: %s", feedInfo.Name, err)
+				}
+			}
+		}
+		s.event("DEBUG", "feed_processed", fmt.Sprintf("feed %s processed, %s", feedInfo.Name, fst.String()),
+			feedFields(feedInfo).with("stats", fst))
+		allStats.add(fst)
+		feedsStats = append(feedsStats, feedStats{ID: feedInfo.ID, Name: feedInfo.Name, Stats: fst})
+	}
+
+	lifetime, lifetimeBytes, feedSize := s.Store.CountProcessed(), s.Store.CountBytes(""), s.countAllEntries()
+	s.event("INFO", "cycle_processed", fmt.Sprintf("all channels processed - channels: %d, %s, lifetime: %d, "+
+		"lifetime bytes: %s, feed size: %d", len(feeds), allStats.String(), lifetime, humanize.Bytes(uint64(lifetimeBytes)),
+		feedSize),
+		Fields{"channels": len(feeds), "stats": allStats, "lifetime": lifetime, "lifetime_bytes": lifetimeBytes,
+			"feed_size": feedSize})
+
+	newestEntry := s.newestEntry()
+	log.Printf("[INFO] last entry: %s", newestEntry.String())
+
+	if s.CompletionWebhook != "" {
+		go s.sendCompletionWebhook(ctx, allStats, feedsStats)
+	}
+
+	return allStats, nil
+}
+
+// Backfill imports the whole history of the feed, page by page, from the newest to the oldest entry.
+// Downloads and stores up to limit new entries, limit <= 0 means no limit. Already stored and processed entries
+// are skipped, so backfill can be interrupted and resumed later. Old entries are not removed by backfill, but
+// the regular update will prune the feed down to its keep value, so the feed should have keep set to KeepAll (-1).
+func (s *Service) Backfill(ctx context.Context, feedID string, limit int) (added int, err error) {
+	feedInfo, found := s.findFeed(feedID)
+	if !found {
+		return 0, errors.Errorf("feed %s not found", feedID)
+	}
+	if s.keep(feedInfo) != KeepAll {
+		log.Printf("[WARN] backfill for %s with keep %d, old entries will be removed on the next update",
+			feedInfo.Name, s.keep(feedInfo))
+	}
+	log.Printf("[INFO] backfill started for %s, limit: %d", feedInfo.Name, limit)
+
+	defer func() {
+		if added == 0 {
+			return
+		}
+		s.numberEpisodes(feedInfo)
+		rss, rssErr := s.RSSFeed(feedInfo, RSSFilter{})
+		if rssErr != nil {
+			log.Printf("[WARN] failed to generate rss for %s: %s", feedInfo.Name, rssErr)
+			return
+		}
+		if saveErr := s.StoreRSS(feedInfo.ID, rss); saveErr != nil {
+			log.Printf("[WARN] failed to save rss for %s: %s", feedInfo.Name, saveErr)
+		}
+	}()
+
+	for page := 0; ; page++ {
+		entries, err := s.ChannelService.GetPage(s.sourceContext(ctx, feedInfo), feedInfo.ID, feedInfo.Type, page,
+			feedInfo.APIKey)
+		if err != nil {
+			return added, errors.Wrapf(err, "failed to get page %d for %s", page, feedInfo.ID)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		log.Printf("[DEBUG] backfill page %d for %s, %d entries", page, feedInfo.Name, len(entries))
+
+		for _, entry := range entries {
+			if limit > 0 && added >= limit {
+				log.Printf("[INFO] backfill for %s reached limit %d", feedInfo.Name, limit)
+				return added, nil
+			}
+			isAllowed, err := s.isAllowed(entry, feedInfo)
+			if err != nil {
+				return added, errors.Wrapf(err, "failed to check if entry %s is relevant", entry.VideoID)
+			}
+			if !isAllowed {
+				continue
+			}
+			ok, err := s.isNew(entry, feedInfo)
+			if err != nil {
+				return added, errors.Wrapf(err, "failed to check if entry %s exists", entry.VideoID)
+			}
+			if !ok {
+				continue
+			}
+			if !entry.IsAvailable() {
+				log.Printf("[INFO] backfill skips %s entry %s, not available yet", entry.LiveStatus, entry.String())
+				continue
+			}
+			if s.beforeStart(entry, feedInfo) {
+				continue
+			}
+			if short, _ := isShortEntry(entry, feedInfo); short {
+				log.Printf("[INFO] backfill skips short %s", entry.String())
+				continue
+			}
+
+			if added > 0 && s.BackfillDelay > 0 {
+				select {
+				case <-ctx.Done():
+					return added, ctx.Err()
+				case <-time.After(s.BackfillDelay):
+				}
+			}
+			if ctx.Err() != nil {
+				return added, ctx.Err()
+			}
+			if s.budgetExhausted(time.Now()) {
+				return added, ErrBudgetExhausted
+			}
+
+			log.Printf("[INFO] backfill entry %s, %s, %s", entry.VideoID, entry.Title, feedInfo.Name)
+			_, _, saved, err := s.downloadEntry(ctx, entry, feedInfo)
+			if err == ytfeed.ErrNotAvailable {
+				continue
+			}
+			if err != nil {
+				return added, err
+			}
+			if saved {
+				added++
+			}
+		}
+	}
+	log.Printf("[INFO] backfill completed for %s, added: %d", feedInfo.Name, added)
+	return added, nil
+}
+
+// downloadEntry downloads audio for the new entry, updates metadata and saves the entry to the store.
+// Returns saved=false if the entry was skipped, i.e. download failed or the file is too short.
+// Returns ytfeed.ErrNotAvailable for upcoming and live entries, such entries are not marked as processed.
+// Any other error returned on store failures only, the caller should stop processing in this case.
+func (s *Service) downloadEntry(ctx context.Context, entry ytfeed.Entry, fi FeedInfo) (res ytfeed.Entry, fsize int64, saved bool, err error) {
+	done, ok := s.startDownload(entry, fi)
+	if !ok {
+		s.event("INFO", "skip", fmt.Sprintf("download of %s in progress already", entry.VideoID),
+			entryFields(fi, entry).with("reason", "in_progress"))
+		return entry, 0, false, nil
+	}
+	defer done()
+
+	file, release := s.existingFile(entry, fi), func() {}
+	if file == "" {
+		var ok bool
+		if release, ok = s.acquireDownload(ctx, entry, fi); !ok {
+			s.event("INFO", "interrupted", fmt.Sprintf("download of %s interrupted in queue, %v", entry.VideoID, ctx.Err()),
+				entryFields(fi, entry))
+			return entry, 0, false, nil
+		}
+	}
+	downCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.ShutdownGrace > 0 {
+		downCtx, cancel = graceContext(ctx, s.ShutdownGrace)
+	}
+	if s.DownloadTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		downCtx, cancelTimeout = context.WithTimeout(downCtx, s.DownloadTimeout)
+		defer cancelTimeout()
+	}
+	downErr := error(nil)
+	getOpts := ytfeed.GetOptions{MaxSize: int64(fi.MaxFileSize), CopyAudio: fi.CopyAudio, Video: fi.Mode == ModeVideo,
+		MatchFilter: fi.MembersOnly.matchFilter()}
+	if file != "" {
+		log.Printf("[INFO] found downloaded file %s for %s, skip download", file, entry.VideoID)
+	} else if optsDl, ok := s.Downloader.(OptionsDownloader); ok && getOpts != (ytfeed.GetOptions{}) {
+		file, downErr = optsDl.GetOpts(downCtx, s.downloadID(entry, fi), s.feedFileName(entry, fi), getOpts)
+	} else {
+		file, downErr = s.Downloader.Get(downCtx, s.downloadID(entry, fi), s.feedFileName(entry, fi))
+	}
+	release()
+	timedOut := downCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	cancel()
+	if ctx.Err() != nil && downErr == nil {
+		log.Printf("[INFO] download of %s finished after shutdown request", entry.VideoID)
+	}
+	if downErr != nil {
+		if timedOut {
+			s.event("WARN", "timeout", fmt.Sprintf("download of %s timed out after %v", entry.VideoID, s.DownloadTimeout),
+				entryFields(fi, entry))
+			s.addFailure(fi, entry, fmt.Sprintf("timed out after %v", s.DownloadTimeout))
+			return entry, 0, false, nil
+		}
+		if downErr == ytfeed.ErrNotAvailable { // upcoming premiere or live stream, will be retried later
+			log.Printf("[INFO] defer entry %s, not available for download yet", entry.String())
+			return entry, 0, false, downErr
+		}
+		if downErr == ytfeed.ErrTooLarge { // size reported before the download exceeds the limit
+			s.skipTooLarge(entry, fi, "")
+			return entry, 0, false, nil
+		}
+		if downErr == ytfeed.ErrFiltered || (downErr == ytfeed.ErrMembersOnly && fi.MembersOnly == MOSkip) {
+			// filtered out by "skip" mode or not downloadable members-only video is members-only, by "only" is public
+			s.skipMembersOnly(entry, fi, "", fi.MembersOnly == MOSkip)
+			return entry, 0, false, nil
+		}
+		if downErr == ytfeed.ErrSkip { // downloader decided to skip this entry
+			s.event("INFO", "skip", "skipping "+entry.String(), entryFields(fi, entry).with("reason", "downloader"))
+			return entry, 0, false, nil
+		}
+		if ctx.Err() != nil { // interrupted by shutdown or feed's time budget, not a failure, retried on the next run
+			s.event("INFO", "interrupted", fmt.Sprintf("download of %s interrupted, %v", entry.VideoID, ctx.Err()),
+				entryFields(fi, entry))
+			return entry, 0, false, nil
+		}
+		s.event("WARN", "download_failed", fmt.Sprintf("failed to download %s: %s", entry.VideoID, downErr),
+			entryFields(fi, entry).with("error", downErr.Error()))
+		s.addFailure(fi, entry, downErr.Error())
+		return entry, 0, false, nil
+	}
+
+	if short, duration := s.isShort(file, fi); short {
+		s.event("INFO", "skip", fmt.Sprintf("skip short file %s (%v): %s, %s", file, duration, entry.VideoID, entry.String()),
+			entryFields(fi, entry).with("reason", "short").with("duration", duration.Seconds()))
+		if procErr := s.Store.SetProcessed(entry); procErr != nil {
+			log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+		}
+		return entry, 0, false, nil
+	}
+
+	if fi.MaxFileSize > 0 {
+		if st, statErr := os.Stat(file); statErr == nil && st.Size() > int64(fi.MaxFileSize) {
+			s.skipTooLarge(entry, fi, file)
+			return entry, 0, false, nil
+		}
+	}
+
+	trimmed, trimErr := s.trimFile(ctx, file, fi)
+	if trimErr != nil {
+		log.Printf("[WARN] failed to trim %s, keep as is: %v", entry.VideoID, trimErr)
+	}
+
+	if fi.Dedup {
+		if dup, found := s.findDuplicate(entry, fi, s.DurationService.File(file)); found {
+			s.event("INFO", "skip", fmt.Sprintf("skip re-upload %s, same content as %s", entry.String(), dup.VideoID),
+				entryFields(fi, entry).with("reason", "duplicate").with("duplicate_of", dup.VideoID))
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				log.Printf("[WARN] failed to remove duplicate file %s, %v", file, err)
+			}
+			removeCompanions(file)
+			if procErr := s.Store.SetProcessed(entry); procErr != nil {
+				log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+			}
+			return entry, 0, false, nil
+		}
+	}
+
+	info := readInfo(file)
+	if !fi.MembersOnly.allows(info.Availability) {
+		s.skipMembersOnly(entry, fi, file, info.Availability == availabilityMembers)
+		return entry, 0, false, nil
+	}
+	if entry.Language == "" {
+		entry.Language = info.Language
+	}
+
+	chapters := s.chapters(entry, file, info, trimmed)
+	if fi.SplitChapters {
+		switch {
+		case s.Splitter == nil:
+			log.Printf("[WARN] splitter not set, can't split %s by chapters", entry.VideoID)
+		case len(chapters) == 0:
+			log.Printf("[DEBUG] no chapters in %s, keep as a single episode", entry.VideoID)
+		default:
+			parts, splitErr := s.splitFile(ctx, file, chapters)
+			if splitErr == nil {
+				return s.saveChapters(ctx, entry, fi, chapters, parts)
+			}
+			log.Printf("[WARN] failed to split %s by chapters, keep as a single episode: %v", entry.VideoID, splitErr)
+		}
+	}
+
+	entry, fsize = s.prepareEntry(entry, file, fi)
+	if entry.Duration == 0 && info.Duration > 0 { // duration of not mp3 audio (copy_audio) is known from metadata only
+		entry.Duration = int(info.Duration)
+	}
+	entry.Subtitles = s.subtitles(ctx, entry, fi)
+	if len(chapters) > 0 {
+		if chErr := writeChaptersFile(file, chapters); chErr != nil {
+			log.Printf("[WARN] failed to write chapters of %s, %v", entry.VideoID, chErr)
+		} else {
+			entry.Chapters = chapters
+		}
+	}
+	if saveErr := s.saveEntry(entry); saveErr != nil {
+		return entry, fsize, false, saveErr
+	}
+	if procErr := s.Store.SetProcessed(entry); procErr != nil {
+		log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+	}
+	entry = s.postDownload(ctx, entry, fi)
+	return entry, fsize, true, nil
+}
+
+// skipTooLarge rejects the entry larger than feed's MaxFileSize, the downloaded file (if any) removed.
+// The entry marked as processed, so it won't be downloaded again.
+func (s *Service) skipTooLarge(entry ytfeed.Entry, fi FeedInfo, file string) {
+	size := int64(0)
+	if file != "" {
+		if st, err := os.Stat(file); err == nil {
+			size = st.Size()
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN] failed to remove too large file %s, %v", file, err)
+		}
+		removeCompanions(file)
+	}
+	msg := fmt.Sprintf("skip too large %s, larger than %v", entry.String(), fi.MaxFileSize)
+	if size > 0 {
+		msg = fmt.Sprintf("skip too large %s, %s is larger than %v", entry.String(), humanize.Bytes(uint64(size)), fi.MaxFileSize)
+	}
+	s.event("INFO", "skip", msg, entryFields(fi, entry).with("reason", "too_large").with("bytes", size).
+		with("max_bytes", int64(fi.MaxFileSize)))
+	if procErr := s.Store.SetProcessed(entry); procErr != nil {
+		log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+	}
+}
+
+// skipMembersOnly marks entry excluded by feed's MembersOnly as processed, removes the downloaded file if any
+func (s *Service) skipMembersOnly(entry ytfeed.Entry, fi FeedInfo, file string, members bool) {
+	status := "public"
+	if members {
+		status = "members-only"
+	}
+	s.event("INFO", "skip", fmt.Sprintf("skip %s video %s, members_only: %s", status, entry.String(), fi.MembersOnly),
+		entryFields(fi, entry).with("reason", "members_only").with("members_only", members))
+	if file != "" {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN] failed to remove %s, %v", file, err)
+		}
+		removeCompanions(file)
+	}
+	if err := s.Store.SetProcessed(entry); err != nil {
+		log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, err)
+	}
+}
+
+// prepareEntry updates metadata of the downloaded file and the entry, counts downloaded bytes.
+// Returns updated entry and the file size.
+func (s *Service) prepareEntry(entry ytfeed.Entry, file string, fi FeedInfo) (res ytfeed.Entry, fsize int64) {
+	if strings.EqualFold(filepath.Ext(file), mediaExt) {
+		if tagsErr := s.updateMp3Tags(file, entry, fi); tagsErr != nil {
+			log.Printf("[WARN] failed to update metadata for %s: %s", entry.VideoID, tagsErr)
+		}
+	}
+
+	if fileInfo, statErr := os.Stat(file); statErr == nil {
+		fsize = fileInfo.Size()
+	} else {
+		log.Printf("[WARN] failed to get file size for %s: %v", file, statErr)
+	}
+
+	s.event("INFO", "download", fmt.Sprintf("downloaded %s (%s) to %s, size: %d, channel: %+v", entry.VideoID, entry.Title,
+		file, fsize, fi), entryFields(fi, entry).with("file", file).with("bytes", fsize))
+	if bytesErr := s.Store.AddBytes(fi.ID, fsize); bytesErr != nil {
+		log.Printf("[WARN] failed to update downloaded bytes for %s: %v", fi.ID, bytesErr)
+	}
+	s.addBudgetBytes(fsize)
+
+	entry = s.update(entry, file, fi)
+	checksum, sumErr := fileChecksum(file)
+	if sumErr != nil {
+		log.Printf("[WARN] failed to get checksum for %s: %v", file, sumErr)
+	}
+	entry.Checksum = checksum
+	entry.FileSize = fsize
+	return entry, fsize
+}
+
+// saveEntry saves prepared entry to the store
+func (s *Service) saveEntry(entry ytfeed.Entry) error {
+	ok, saveErr := s.Store.Save(entry)
+	if saveErr != nil {
+		return errors.Wrapf(saveErr, "failed to save entry %+v", entry)
+	}
+	if !ok {
+		log.Printf("[WARN] attempt to save dup entry %+v", entry)
+	}
+	s.addFileName(entry)
+	log.Printf("[INFO] saved %s (%s) to %s, sha256: %s", entry.VideoID, entry.Title, entry.File, entry.Checksum)
+	return nil
+}
+
+// chapters returns chapters of the downloaded file, from yt-dlp's metadata if available, or parsed
+// from the description. Chapters shifted back by trimmed duration cut from the beginning of the file.
+// Returns nil if the file has less than two chapters.
+func (s *Service) chapters(entry ytfeed.Entry, file string, info downloadInfo, trimmed time.Duration) []ytfeed.Chapter {
+	chapters := info.chapters()
+	if len(chapters) == 0 {
+		chapters = ytfeed.ParseChapters(string(entry.Media.Description))
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+	chapters = ytfeed.ShiftChapters(chapters, trimmed)
+	return ytfeed.PlanChapters(chapters, time.Duration(s.DurationService.File(file))*time.Second)
+}
+
+// trimFile cuts feed's TrimStart and TrimEnd from the file in place. Returns duration cut from the beginning,
+// 0 if nothing trimmed. On error the file is kept as is.
+func (s *Service) trimFile(ctx context.Context, file string, fi FeedInfo) (time.Duration, error) {
+	if fi.TrimStart <= 0 && fi.TrimEnd <= 0 {
+		return 0, nil
+	}
+	if s.Splitter == nil {
+		return 0, errors.New("splitter not set")
+	}
+	start, end := fi.TrimStart, time.Duration(0)
+	if start < 0 {
+		start = 0
+	}
+	if fi.TrimEnd > 0 {
+		duration := time.Duration(s.DurationService.File(file)) * time.Second
+		if duration == 0 {
+			return 0, errors.Errorf("unknown duration of %s", file)
+		}
+		end = duration - fi.TrimEnd
+		if end <= start {
+			return 0, errors.Errorf("%s is too short (%v) to trim", file, duration)
+		}
+	}
+
+	base, ext := strings.TrimSuffix(file, filepath.Ext(file)), filepath.Ext(file)
+	tmpFile := base + ".trim" + ext
+	if err := s.Splitter.Split(ctx, file, tmpFile, start, end); err != nil {
+		_ = os.Remove(tmpFile)
+		return 0, errors.Wrapf(err, "failed to trim %s", file)
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		_ = os.Remove(tmpFile)
+		return 0, errors.Wrapf(err, "failed to replace %s with trimmed file", file)
+	}
+	log.Printf("[INFO] trimmed %s, start: %v, end: %v", file, fi.TrimStart, fi.TrimEnd)
+	return start, nil
+}
+
+// subtitles downloads subtitles for the entry if enabled for the feed, returns the subtitles file.
+// Missing subtitles and download failures are not errors, just no subtitles.
+func (s *Service) subtitles(ctx context.Context, entry ytfeed.Entry, fi FeedInfo) string {
+	if fi.Subtitles == "" {
+		return ""
+	}
+	lang := fi.Subtitles
+	if lang == "auto" {
+		lang = entry.Language
+		if lang == "" {
+			lang = fi.Language
+		}
+		lang = strings.Split(strings.ToLower(lang), "-")[0] // youtube uses short codes, i.e. "en" for "en-us"
+		if lang == "" {
+			lang = "en"
+		}
+	}
+	file, err := s.Downloader.Subtitles(ctx, s.downloadID(entry, fi), s.feedFileName(entry, fi), lang)
+	if err == ytfeed.ErrSkip {
+		log.Printf("[INFO] no %q subtitles for %s", lang, entry.String())
+		return ""
+	}
+	if err != nil {
+		log.Printf("[WARN] failed to download %q subtitles for %s, %v", lang, entry.String(), err)
+		return ""
+	}
+	log.Printf("[INFO] downloaded %q subtitles for %s to %s", lang, entry.VideoID, file)
+	return file
+}
+
+// chaptersFile returns name of podcast chapters json file for the audio file
+func chaptersFile(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ".chapters.json"
+}
+
+// writeChaptersFile writes chapters in podcast namespace json format next to the audio file
+func writeChaptersFile(file string, chapters []ytfeed.Chapter) error {
+	type chapter struct {
+		StartTime float64 `json:"startTime"`
+		EndTime   float64 `json:"endTime,omitempty"`
+		Title     string  `json:"title"`
+	}
+	data := struct {
+		Version  string    `json:"version"`
+		Chapters []chapter `json:"chapters"`
+	}{Version: "1.2.0"}
+	for _, ch := range chapters {
+		data.Chapters = append(data.Chapters, chapter{StartTime: ch.Start.Seconds(), EndTime: ch.End.Seconds(), Title: ch.Title})
+	}
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal chapters")
+	}
+	return errors.Wrapf(os.WriteFile(chaptersFile(file), b, 0o644), "failed to write chapters for %s", file) // nolint
+}
+
+// removeCompanions removes subtitles and chapters files of the audio file, if any
+func removeCompanions(file string) {
+	for _, f := range []string{strings.TrimSuffix(file, filepath.Ext(file)) + ".vtt", chaptersFile(file)} {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN] failed to remove %s: %v", f, err)
+		}
+	}
+}
+
+// splitFile cuts the file to parts by chapters, the original file removed on success.
+// Returns list of part files, in the order of chapters.
+func (s *Service) splitFile(ctx context.Context, file string, chapters []ytfeed.Chapter) ([]string, error) {
+	base, ext := strings.TrimSuffix(file, filepath.Ext(file)), filepath.Ext(file)
+	parts := make([]string, 0, len(chapters))
+	for i, ch := range chapters {
+		partFile := fmt.Sprintf("%s-%02d%s", base, i+1, ext)
+		parts = append(parts, partFile)
+		if err := s.Splitter.Split(ctx, file, partFile, ch.Start, ch.End); err != nil {
+			for _, p := range parts {
+				_ = os.Remove(p)
+			}
+			return nil, errors.Wrapf(err, "failed to cut chapter %d", i+1)
+		}
+	}
+	if err := os.Remove(file); err != nil {
+		log.Printf("[WARN] failed to remove %s after split, %v", file, err)
+	}
+	return parts, nil
+}
+
+// saveChapters saves each part of the split file as a separate entry, with video id and title derived from
+// the original ones. The original entry marked as processed to prevent download again.
+func (s *Service) saveChapters(ctx context.Context, entry ytfeed.Entry, fi FeedInfo, chapters []ytfeed.Chapter,
+	parts []string) (res ytfeed.Entry, fsize int64, saved bool, err error) {
+
+	for i, ch := range chapters {
+		part := entry
+		part.VideoID = fmt.Sprintf("%s-ch%02d", entry.VideoID, i+1)
+		part.Title = entry.Title + " - " + ch.Title
+		part, size := s.prepareEntry(part, parts[i], fi)
+		part.Published = part.Published.Add(time.Duration(i) * time.Second) // keep chapters order in the feed
+		if saveErr := s.saveEntry(part); saveErr != nil {
+			return entry, fsize, false, saveErr
+		}
+		part = s.postDownload(ctx, part, fi)
+		fsize += size
+	}
+	log.Printf("[INFO] split %s (%s) to %d chapters", entry.VideoID, entry.Title, len(chapters))
+
+	if procErr := s.Store.SetProcessed(entry); procErr != nil {
+		log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
+	}
+	return entry, fsize, true, nil
+}
+
+// VerifyResult lists entries found by VerifyFiles
+type VerifyResult struct {
+	Checked    int            // verified files
+	Corrupted  []ytfeed.Entry // files with checksum mismatch, re-downloaded if possible
+	Failed     []ytfeed.Entry // corrupted files failed to re-download, kept as is
+	Unreadable []ytfeed.Entry // missing, remote or unreadable files, reported only
+}
+
+// VerifyFiles re-checks stored files against their checksums. Corrupted files re-downloaded right away by video id,
+// the old file kept aside and restored if the download failed, so the episode is not lost if the video can't be
+// fetched anymore. Files which can't be read (missing, moved to remote storage, i/o errors) are reported only.
+// Entries saved before checksums were introduced can't be verified and only checked for the file presence.
+func (s *Service) VerifyFiles(ctx context.Context) (res VerifyResult, err error) {
+	for _, fi := range s.feeds() {
+		entries, err := s.Store.Load(fi.ID, KeepAll)
+		if err != nil {
+			return res, errors.Wrapf(err, "failed to load entries for %s", fi.ID)
+		}
+		repaired := false
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			res.Checked++
+			checksum, sumErr := fileChecksum(entry.File)
+			if sumErr != nil {
+				log.Printf("[WARN] can't verify %s, %v", entry.String(), sumErr)
+				res.Unreadable = append(res.Unreadable, entry)
+				continue
+			}
+			if entry.Checksum == "" || checksum == entry.Checksum {
+				continue
+			}
+			log.Printf("[WARN] checksum mismatch for %s, expected %s, got %s", entry.String(), entry.Checksum, checksum)
+			res.Corrupted = append(res.Corrupted, entry)
+			ok, repErr := s.repairEntry(ctx, fi, entry)
+			if repErr != nil {
+				return res, repErr
+			}
+			if !ok {
+				res.Failed = append(res.Failed, entry)
+				continue
+			}
+			repaired = true
+		}
+		if repaired {
+			if err := s.storeFeedRSS(fi); err != nil {
+				log.Printf("[WARN] failed to update rss for %s, %v", fi.ID, err)
+			}
+		}
+	}
+	log.Printf("[INFO] verified %d files, corrupted: %d, failed to repair: %d, unreadable: %d",
+		res.Checked, len(res.Corrupted), len(res.Failed), len(res.Unreadable))
+	return res, nil
+}
+
+// repairEntry re-downloads the corrupted file of the stored entry. The corrupted file moved aside and the entry
+// restored along with the file if the download failed or skipped. Returns error on store failures only.
+func (s *Service) repairEntry(ctx context.Context, fi FeedInfo, entry ytfeed.Entry) (bool, error) {
+	backup := entry.File + ".corrupted"
+	if err := os.Rename(entry.File, backup); err != nil {
+		log.Printf("[WARN] failed to move aside corrupted file %s, %v", entry.File, err)
+		return false, nil
+	}
+	if err := s.Store.Remove(entry); err != nil { // store doesn't overwrite entries, the new one saved by download
+		return false, errors.Wrapf(err, "failed to remove corrupted entry %s", entry.VideoID)
+	}
+	fresh := entry
+	fresh.File, fresh.Duration, fresh.Checksum, fresh.FileSize, fresh.Subtitles, fresh.Chapters = "", 0, "", 0, "", nil
+	_, _, saved, err := s.downloadEntry(ctx, fresh, fi)
+	if err != nil && err != ytfeed.ErrNotAvailable {
+		return false, errors.Wrapf(err, "failed to re-download %s", entry.VideoID)
+	}
+	if err != nil || !saved {
+		if rnErr := os.Rename(backup, entry.File); rnErr != nil {
+			log.Printf("[WARN] failed to restore corrupted file %s, %v", entry.File, rnErr)
+		}
+		if _, saveErr := s.Store.Save(entry); saveErr != nil {
+			return false, errors.Wrapf(saveErr, "failed to restore entry %s", entry.VideoID)
+		}
+		s.event("WARN", "repair_failed", fmt.Sprintf("failed to re-download corrupted %s, kept as is", entry.String()),
+			entryFields(fi, entry).with("file", entry.File))
+		return false, nil
+	}
+	if rmErr := os.Remove(backup); rmErr != nil {
+		log.Printf("[WARN] failed to remove corrupted file %s, %v", backup, rmErr)
+	}
+	s.event("INFO", "repaired", fmt.Sprintf("re-downloaded corrupted %s", entry.String()),
+		entryFields(fi, entry).with("file", entry.File))
+	return true, nil
+}
+
+// RegenerateAll rewrites rss files of all feeds from the stored entries, regardless of new entries.
+// Needed after changes in config or templates. Feeds without entries are skipped, failed feeds don't stop
+// the rest. Returns the number of rewritten feeds.
+func (s *Service) RegenerateAll() (int, error) {
+	errs := new(multierror.Error)
+	count := 0
+	for _, fi := range s.feeds() {
+		rss, err := s.RSSFeed(fi, RSSFilter{})
+		if err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "failed to generate rss for %s", fi.ID))
+			continue
+		}
+		if rss == "" {
+			log.Printf("[INFO] no entries for %s (%s), rss not regenerated", fi.ID, fi.Name)
+			continue
+		}
+		if err = s.StoreRSS(fi.ID, rss); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "failed to save rss for %s", fi.ID))
+			continue
+		}
+		count++
+	}
+	log.Printf("[INFO] regenerated rss for %d of %d feeds", count, len(s.Feeds))
+	return count, errs.ErrorOrNil()
+}
+
+// RemoveEntry deleted entry from store. Doesn't removes file
+func (s *Service) RemoveEntry(entry ytfeed.Entry) error {
+	if err := s.Store.ResetProcessed(entry); err != nil {
+		return errors.Wrapf(err, "failed to reset processed entry %s", entry.VideoID)
+	}
+	if err := s.Store.Remove(entry); err != nil {
+		return errors.Wrapf(err, "failed to remove entry %s", entry.VideoID)
+	}
+	return nil
+}
+
+// ErrNotFound returned by DeleteEpisode, Redownload and UpdateFeed if the feed or the entry is not found
+var ErrNotFound = errors.New("not found")
+
+// DeleteEpisode removes the entry of the feed from the store, deletes its files and regenerates the feed's rss.
+// The entry is kept marked as processed, so it won't be downloaded again on the next update.
+func (s *Service) DeleteEpisode(feedID, videoID string) (ytfeed.Entry, error) {
+	fi, entry, err := s.findEntry(feedID, videoID)
+	if err != nil {
+		return ytfeed.Entry{}, err
+	}
+
+	if err = s.Store.SetProcessed(entry); err != nil {
+		return entry, errors.Wrapf(err, "failed to set processed %s", entry.VideoID)
+	}
+	if err = s.Store.Remove(entry); err != nil {
+		return entry, errors.Wrapf(err, "failed to remove entry %s", entry.VideoID)
+	}
+	removeEntryFile(entry)
+	log.Printf("[INFO] deleted episode %s from %s (%s)", entry.String(), fi.ID, fi.Name)
+
+	if err = s.storeFeedRSS(fi); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// PinEpisode sets or clears pinned flag of the stored episode. Pinned episodes are never removed by keep and
+// max age limits of the feed and stay in rss. Returns the updated entry
+func (s *Service) PinEpisode(feedID, videoID string, pinned bool) (ytfeed.Entry, error) {
+	fi, entry, err := s.findEntry(feedID, videoID)
+	if err != nil {
+		return ytfeed.Entry{}, err
+	}
+	if entry.Pinned == pinned {
+		return entry, nil
+	}
+	entry.Pinned = pinned
+	if err = s.Store.Remove(entry); err != nil {
+		return entry, errors.Wrapf(err, "failed to remove %s for pin update", entry.VideoID)
+	}
+	if _, err = s.Store.Save(entry); err != nil {
+		return entry, errors.Wrapf(err, "failed to save %s with pin update", entry.VideoID)
+	}
+	log.Printf("[INFO] episode %s of %s (%s) pinned: %v", entry.String(), fi.ID, fi.Name, pinned)
+
+	if !pinned { // unpinned episode may be over the keep limit now
+		s.removeOld(fi)
+	}
+	if err = s.storeFeedRSS(fi); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// Redownload replaces the file of the stored episode with a fresh download, i.e. to fix a broken audio track.
+// The existing file is deleted, processed status and recent failures of the episode are cleared and the episode
+// downloaded at once. Returns the entry with the new file. If the download fails the episode stays removed but
+// not processed, so the regular update retries it while the video is listed by the channel.
+func (s *Service) Redownload(ctx context.Context, feedID, videoID string) (ytfeed.Entry, error) {
+	fi, entry, err := s.findEntry(feedID, videoID)
+	if err != nil {
+		return ytfeed.Entry{}, err
+	}
+
+	if err = s.Store.Remove(entry); err != nil {
+		return entry, errors.Wrapf(err, "failed to remove entry %s", entry.VideoID)
+	}
+	if err = s.Store.ResetProcessed(entry); err != nil {
+		return entry, errors.Wrapf(err, "failed to reset processed entry %s", entry.VideoID)
+	}
+	removeEntryFile(entry)
+	s.failures.remove(fi.ID, entry.VideoID)
+	s.event("INFO", "redownload", fmt.Sprintf("re-download %s, removed %s", entry.String(), entry.File),
+		entryFields(fi, entry).with("file", entry.File))
+
+	// drop everything made from the old file, the rest of metadata (guid, original date, etc.) kept as is
+	entry.File, entry.Duration, entry.Checksum, entry.FileSize, entry.Subtitles, entry.Chapters = "", 0, "", 0, "", nil
+	res, _, saved, err := s.downloadEntry(ctx, entry, fi)
+	if err != nil && err != ytfeed.ErrNotAvailable {
+		return entry, errors.Wrapf(err, "failed to re-download %s", entry.VideoID)
+	}
+	if err == nil && !saved {
+		err = errors.New("download failed or skipped")
+	}
+
+	if rssErr := s.storeFeedRSS(fi); rssErr != nil {
+		return res, rssErr
+	}
+	if err != nil {
+		return res, errors.Wrapf(err, "failed to re-download %s, will be retried by the next update", entry.VideoID)
+	}
+	return res, nil
 }
 
-// RSSFeed generates RSS feed for given channel
-func (s *Service) RSSFeed(fi FeedInfo) (string, error) {
-	entries, err := s.Store.Load(fi.ID, s.keep(fi))
+// findEntry returns configured feed and its stored entry by video id, ErrNotFound if any of them not found
+func (s *Service) findEntry(feedID, videoID string) (FeedInfo, ytfeed.Entry, error) {
+	fi, found := s.findFeed(feedID)
+	if !found {
+		return FeedInfo{}, ytfeed.Entry{}, errors.Wrapf(ErrNotFound, "feed %s", feedID)
+	}
+	entries, err := s.Store.Load(fi.ID, -1)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get channel entries")
+		return fi, ytfeed.Entry{}, errors.Wrapf(err, "failed to load entries for %s", fi.ID)
 	}
-
-	if len(entries) == 0 {
-		return "", nil
+	for _, e := range entries {
+		if e.VideoID == videoID {
+			return fi, e, nil
+		}
 	}
+	return fi, ytfeed.Entry{}, errors.Wrapf(ErrNotFound, "entry %s in %s", videoID, feedID)
+}
 
-	items := []rssfeed.Item{}
-	for _, entry := range entries {
-
-		fileURL := s.RootURL + "/" + path.Base(entry.File)
-
-		var fileSize int
-		if fileInfo, fiErr := os.Stat(entry.File); fiErr != nil {
-			log.Printf("[WARN] failed to get file size for %s (%s %s): %v", entry.File, entry.VideoID, entry.Title, fiErr)
-		} else {
-			fileSize = int(fileInfo.Size())
-		}
-
-		duration := ""
-		if entry.Duration > 0 {
-			duration = fmt.Sprintf("%d", entry.Duration)
-		}
-
-		items = append(items, rssfeed.Item{
-			Title:       entry.Title,
-			Description: entry.Media.Description,
-			Link:        entry.Link.Href,
-			PubDate:     entry.Published.In(time.UTC).Format(time.RFC1123Z),
-			GUID:        entry.ChannelID + "::" + entry.VideoID,
-			Author:      entry.Author.Name,
-			Enclosure: rssfeed.Enclosure{
-				URL:    fileURL,
-				Type:   "audio/mpeg",
-				Length: fileSize,
-			},
-			Duration: duration,
-			DT:       time.Now(),
-		})
+// removeEntryFile deletes the file of the entry with its companion files, if any
+func removeEntryFile(entry ytfeed.Entry) {
+	if entry.File == "" {
+		return
 	}
-
-	rss := rssfeed.Rss2{
-		Version:        "2.0",
-		NsItunes:       "http://www.itunes.com/dtds/podcast-1.0.dtd",
-		NsMedia:        "http://search.yahoo.com/mrss/",
-		ItemList:       items,
-		Title:          fi.Name,
-		Description:    "generated by feed-master",
-		Link:           entries[0].Author.URI,
-		PubDate:        items[0].PubDate,
-		LastBuildDate:  time.Now().Format(time.RFC1123Z),
-		Language:       fi.Language,
-		ItunesAuthor:   entries[0].Author.Name,
-		ItunesExplicit: "no",
+	if err := os.Remove(entry.File); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] failed to remove file %s, %v", entry.File, err)
 	}
+	removeCompanions(entry.File)
+}
 
-	// set image from channel as rss thumbnail
-	// TODO: we may want to load it locally in case if youtube doesn't like such remote usage of images
-	if image := entries[0].Media.Thumbnail.URL; image != "" {
-		rss.ItunesImage = &rssfeed.ItunesImg{URL: image}
-		rss.MediaThumbnail = &rssfeed.MediaThumbnail{URL: image}
+// storeFeedRSS regenerates and saves rss of the feed
+func (s *Service) storeFeedRSS(fi FeedInfo) error {
+	rss, err := s.RSSFeed(fi, RSSFilter{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate rss for %s", fi.ID)
 	}
-
-	if fi.Type == ytfeed.FTPlaylist {
-		rss.Link = "https://www.youtube.com/playlist?list=" + fi.ID
+	if err = s.StoreRSS(fi.ID, rss); err != nil {
+		return errors.Wrapf(err, "failed to save rss for %s", fi.ID)
 	}
+	return nil
+}
 
-	b, err := xml.MarshalIndent(&rss, "", "  ")
-	if err != nil {
-		return "", errors.Wrap(err, "failed to marshal rss")
+// findFeed returns configured feed by id
+func (s *Service) findFeed(feedID string) (FeedInfo, bool) {
+	for _, fi := range s.feeds() {
+		if fi.ID == feedID {
+			return fi, true
+		}
 	}
-
-	res := string(b)
-	// this hack to avoid having different items for marshal and unmarshal due to "itunes" namespace
-	res = strings.Replace(res, "<duration>", "<itunes:duration>", -1)
-	res = strings.Replace(res, "</duration>", "</itunes:duration>", -1)
-	return res, nil
+	return FeedInfo{}, false
 }
 
-// procChannels processes all channels, downloads audio, updates metadata and stores RSS
-func (s *Service) procChannels(ctx context.Context) error {
+// downloadInfo is a part of yt-dlp's info json with metadata of the downloaded video
+type downloadInfo struct {
+	Language     string  `json:"language"`
+	Availability string  `json:"availability"` // i.e. public, unlisted or subscriber_only for members-only
+	Duration     float64 `json:"duration"`     // seconds
+	Chapters     []struct {
+		Title     string  `json:"title"`
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+	} `json:"chapters"`
+}
 
-	var allStats stats
+func (d downloadInfo) chapters() []ytfeed.Chapter {
+	res := make([]ytfeed.Chapter, 0, len(d.Chapters))
+	for _, ch := range d.Chapters {
+		res = append(res, ytfeed.Chapter{Title: ch.Title,
+			Start: time.Duration(ch.StartTime * float64(time.Second)), End: time.Duration(ch.EndTime * float64(time.Second))})
+	}
+	return res
+}
 
-	for _, feedInfo := range s.Feeds {
-		entries, err := s.ChannelService.Get(ctx, feedInfo.ID, feedInfo.Type)
+// readInfo returns metadata from yt-dlp's info json written next to the file with --write-info-json.
+// The info json is removed after reading. Returns empty info if info json is missing or invalid.
+func readInfo(file string) (info downloadInfo) {
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+	// yt-dlp names info json after the output template, i.e. "name.info.json" or "name.tmp.info.json" for "-o name.tmp"
+	for _, infoFile := range []string{base + ".info.json", base + ".tmp.info.json"} {
+		data, err := os.ReadFile(infoFile) // nolint
 		if err != nil {
-			log.Printf("[WARN] failed to get channel entries for %s: %s", feedInfo.ID, err)
 			continue
 		}
-		log.Printf("[INFO] got %d entries for %s, limit to %d", len(entries), feedInfo.Name, s.keep(feedInfo))
-		changed, processed := false, 0
-		for i, entry := range entries {
-
-			// exit right away if context is done
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-
-			allStats.entries++
-			if processed >= s.keep(feedInfo) {
-				break
-			}
-			isAllowed, err := s.isAllowed(entry, feedInfo)
-			if err != nil {
-				return errors.Wrapf(err, "failed to check if entry %s is relevant", entry.VideoID)
-			}
-			if !isAllowed {
-				log.Printf("[DEBUG] skipping filtered %s", entry.String())
-				allStats.ignored++
-				continue
-			}
-
-			ok, err := s.isNew(entry, feedInfo)
-			if err != nil {
-				return errors.Wrapf(err, "failed to check if entry %s exists", entry.VideoID)
-			}
-			if !ok {
-				allStats.skipped++
-				processed++
-				continue
-			}
-
-			// got new entry, but with very old timestamp. skip it if we have already reached max capacity
-			// (this is to eliminate the initial load) and this entry is older than the oldest one we have.
-			// Also marks it as processed as we don't want to process it again
-			oldestEntry := s.oldestEntry()
-			if entry.Published.Before(oldestEntry.Published) && s.countAllEntries() >= s.totalEntriesToKeep() {
-				allStats.ignored++
-				log.Printf("[INFO] skipping entry %s as it is older than the oldest one we have %s",
-					entry.String(), oldestEntry.String())
-				if procErr := s.Store.SetProcessed(entry); procErr != nil {
-					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
-				}
-				continue
-			}
-
-			log.Printf("[INFO] new entry [%d] %s, %s, %s, %s", i+1, entry.VideoID, entry.Title, feedInfo.Name, entry.String())
-
-			file, downErr := s.Downloader.Get(ctx, entry.VideoID, s.makeFileName(entry))
-			if downErr != nil {
-				allStats.ignored++
-				if downErr == ytfeed.ErrSkip { // downloader decided to skip this entry
-					log.Printf("[INFO] skipping %s", entry.String())
-					continue
-				}
-				log.Printf("[WARN] failed to download %s: %s", entry.VideoID, downErr)
-				continue
-			}
-
-			if short, duration := s.isShort(file); short {
-				allStats.ignored++
-				log.Printf("[INFO] skip short file %s (%v): %s, %s", file, duration, entry.VideoID, entry.String())
-				if procErr := s.Store.SetProcessed(entry); procErr != nil {
-					log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
-				}
-				continue
-			}
-
-			// update metadata
-			if tagsErr := s.updateMp3Tags(file, entry, feedInfo); tagsErr != nil {
-				log.Printf("[WARN] failed to update metadata for %s: %s", entry.VideoID, tagsErr)
-			}
-
-			processed++
-
-			fsize := 0
-			if fi, err := os.Stat(file); err == nil {
-				fsize = int(fi.Size())
-			} else {
-				log.Printf("[WARN] failed to get file size for %s: %v", file, err)
-			}
-
-			log.Printf("[INFO] downloaded %s (%s) to %s, size: %d, channel: %+v", entry.VideoID, entry.Title, file, fsize, feedInfo)
-
-			entry = s.update(entry, file, feedInfo)
-
-			ok, saveErr := s.Store.Save(entry)
-			if saveErr != nil {
-				return errors.Wrapf(saveErr, "failed to save entry %+v", entry)
-			}
-			if !ok {
-				log.Printf("[WARN] attempt to save dup entry %+v", entry)
-			}
-			changed = true
-			if procErr := s.Store.SetProcessed(entry); procErr != nil {
-				log.Printf("[WARN] failed to set processed status for %s: %v", entry.VideoID, procErr)
-			}
-			allStats.added++
-			log.Printf("[INFO] saved %s (%s) to %s, channel: %+v", entry.VideoID, entry.Title, file, feedInfo)
+		if err = os.Remove(infoFile); err != nil {
+			log.Printf("[WARN] failed to remove %s, %v", infoFile, err)
 		}
-		allStats.processed += processed
+		if err = json.Unmarshal(data, &info); err != nil {
+			log.Printf("[WARN] failed to parse %s, %v", infoFile, err)
+			return downloadInfo{}
+		}
+		return info
+	}
+	return downloadInfo{}
+}
 
-		if changed {
-			removed := s.removeOld(feedInfo)
-			allStats.removed += removed
+// feedContext returns context limited by FeedTimeout, so a slow feed can't stall the others
+func (s *Service) feedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.FeedTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.FeedTimeout)
+}
 
-			// save rss feed to fs if there are new entries
-			rss, rssErr := s.RSSFeed(feedInfo)
-			if rssErr != nil {
-				log.Printf("[WARN] failed to generate rss for %s: %s", feedInfo.Name, rssErr)
-			} else {
-				if err := s.RSSFileStore.Save(feedInfo.ID, rss); err != nil {
-					log.Printf("[WARN] failed to save rss for %s: %s", feedInfo.Name, err)
-				}
-			}
+// graceContext returns context not cancelled with ctx right away, but grace period after ctx is done.
+// Used to let the download in progress finish on shutdown.
+func graceContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	gctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-gctx.Done():
+			return
+		case <-ctx.Done():
 		}
-	}
+		select {
+		case <-gctx.Done():
+		case <-time.After(grace):
+			log.Printf("[WARN] shutdown grace period %v expired, interrupting download", grace)
+			cancel()
+		}
+	}()
+	return gctx, cancel
+}
 
-	log.Printf("[INFO] all channels processed - channels: %d, %s, lifetime: %d, feed size: %d",
-		len(s.Feeds), allStats.String(), s.Store.CountProcessed(), s.countAllEntries())
+// minProcessedMaxAge is the lowest allowed ProcessedMaxAge, shorter retention risks re-download loops
+const minProcessedMaxAge = 30 * 24 * time.Hour
 
-	newestEntry := s.newestEntry()
-	log.Printf("[INFO] last entry: %s", newestEntry.String())
+// pruneInterval is the interval between prunes of processed markers
+const pruneInterval = 24 * time.Hour
 
-	return nil
+// pruneProcessed removes processed markers older than ProcessedMaxAge, but not younger than minProcessedMaxAge
+func (s *Service) pruneProcessed() {
+	maxAge := s.ProcessedMaxAge
+	if maxAge < minProcessedMaxAge {
+		log.Printf("[WARN] processed max age %v is too short, %v used", maxAge, minProcessedMaxAge)
+		maxAge = minProcessedMaxAge
+	}
+	count, err := s.Store.PruneProcessed(maxAge)
+	if err != nil {
+		log.Printf("[WARN] failed to prune processed, %v", err)
+		return
+	}
+	s.event("INFO", "prune", fmt.Sprintf("pruned %d processed markers older than %v", count, maxAge),
+		Fields{"pruned": count, "max_age": maxAge.String()})
 }
 
-// StoreRSS saves RSS feed to file
-func (s *Service) StoreRSS(chanID, rss string) error {
-	return s.RSSFileStore.Save(chanID, rss)
+// setFetchError stores the last fetch error of the feed, empty error clears it
+func (s *Service) setFetchError(feedID string, fe ytfeed.FetchError) {
+	if err := s.Store.SetFetchError(feedID, fe); err != nil {
+		log.Printf("[WARN] failed to store fetch error of %s, %v", feedID, err)
+	}
 }
 
-// RemoveEntry deleted entry from store. Doesn't removes file
-func (s *Service) RemoveEntry(entry ytfeed.Entry) error {
-	if err := s.Store.ResetProcessed(entry); err != nil {
-		return errors.Wrapf(err, "failed to reset processed entry %s", entry.VideoID)
-	}
-	if err := s.Store.Remove(entry); err != nil {
-		return errors.Wrapf(err, "failed to remove entry %s", entry.VideoID)
+// FetchErrors returns the last fetch error by feed id, for feeds failed to fetch since the last successful fetch
+func (s *Service) FetchErrors() map[string]ytfeed.FetchError {
+	res, err := s.Store.FetchErrors()
+	if err != nil {
+		log.Printf("[WARN] failed to load fetch errors, %v", err)
+		return map[string]ytfeed.FetchError{}
 	}
-	return nil
+	return res
 }
 
 // isNew checks if entry already processed
@@ -405,7 +1999,13 @@ func (s *Service) isAllowed(entry ytfeed.Entry, fi FeedInfo) (ok bool, err error
 	return matchedIncludeFilter && !matchedExcludeFilter, nil
 }
 
-func (s *Service) isShort(file string) (bool, time.Duration) {
+// isShort checks duration of the downloaded file, up to the feed's shorts duration with SkipShorts
+// or shorter than the service's SkipShorts
+func (s *Service) isShort(file string, fi FeedInfo) (bool, time.Duration) {
+	if fi.SkipShorts {
+		duration := time.Duration(s.DurationService.File(file)) * time.Second
+		return duration > 0 && duration <= fi.shortsDuration(), duration
+	}
 	if s.SkipShorts.Seconds() > 0 {
 		// skip shorts if duration is less than SkipShorts
 		duration := s.DurationService.File(file)
@@ -420,6 +2020,9 @@ func (s *Service) isShort(file string) (bool, time.Duration) {
 func (s *Service) update(entry ytfeed.Entry, file string, fi FeedInfo) ytfeed.Entry {
 	entry.File = file
 
+	if entry.OriginalPublished.IsZero() {
+		entry.OriginalPublished = entry.Published
+	}
 	// only reset time if published not too long ago
 	// this is done to avoid initial set of entries added with a new channel to the top of the feed
 	if time.Since(entry.Published) < time.Hour*24 {
@@ -431,9 +2034,7 @@ func (s *Service) update(entry ytfeed.Entry, file string, fi FeedInfo) ytfeed.En
 		log.Printf("[DEBUG] keep published time for %s, %s", entry.VideoID, entry.Published.Format(time.RFC3339))
 	}
 
-	if !strings.Contains(entry.Title, fi.Name) { // if title doesn't contains channel name add it
-		entry.Title = fi.Name + ": " + entry.Title
-	}
+	entry.Title = fi.TitlePrefix.Apply(entry.Title, fi.Name)
 
 	entry.Duration = s.DurationService.File(file)
 	log.Printf("[DEBUG] updated entry: %s", entry.String())
@@ -442,44 +2043,364 @@ func (s *Service) update(entry ytfeed.Entry, file string, fi FeedInfo) ytfeed.En
 
 // removeOld deletes old entries from store and corresponding files
 func (s *Service) removeOld(fi FeedInfo) int {
-	removed := 0
+	removed := s.removeExpired(fi)
 	keep := s.keep(fi)
+	if keep == KeepAll {
+		log.Printf("[DEBUG] keep all entries for %s (%s), nothing to remove over the limit", fi.ID, fi.Name)
+		return removed
+	}
 	files, err := s.Store.RemoveOld(fi.ID, keep+1)
 	if err != nil { // even with error we get a list of files to remove
 		log.Printf("[WARN] failed to remove some old meta data for %s, %v", fi.ID, err)
 	}
 
+	removed += s.removeFiles(files, func(f string) {
+		s.event("INFO", "remove", fmt.Sprintf("removed %s for %s (%s), over keep limit %d", f, fi.ID, fi.Name, keep),
+			feedFields(fi).with("file", f).with("reason", "keep"))
+	})
+	return removed
+}
+
+// removeFiles removes files with their companions, up to RemoveConcurrency files at once. Calls onRemoved
+// for each removed file, concurrently if RemoveConcurrency > 1. Returns the number of removed files.
+func (s *Service) removeFiles(files []string, onRemoved func(file string)) int {
+	concurrency := s.RemoveConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var removed int32
+	swg := syncs.NewSizedGroup(concurrency, syncs.Preemptive)
 	for _, f := range files {
-		if e := os.Remove(f); e != nil {
-			log.Printf("[WARN] failed to remove file %s: %v", f, e)
+		f := f
+		swg.Go(func(context.Context) {
+			removeCompanions(f)
+			if e := os.Remove(f); e != nil {
+				log.Printf("[WARN] failed to remove file %s: %v", f, e)
+				return
+			}
+			atomic.AddInt32(&removed, 1)
+			onRemoved(f)
+		})
+	}
+	swg.Wait()
+	return int(atomic.LoadInt32(&removed))
+}
+
+// removeExpired removes entries older than feed's MaxAge along with their files, pinned entries are kept.
+// Removed entries stay marked as processed, so they won't be downloaded again.
+func (s *Service) removeExpired(fi FeedInfo) int {
+	if fi.MaxAge <= 0 {
+		return 0
+	}
+	entries, err := s.Store.Load(fi.ID, -1)
+	if err != nil {
+		log.Printf("[WARN] failed to load entries of %s to remove expired, %v", fi.ID, err)
+		return 0
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.Pinned || !s.isExpired(entry, fi) {
+			continue
+		}
+		if err := s.Store.Remove(entry); err != nil {
+			log.Printf("[WARN] failed to remove expired entry %s, %v", entry.VideoID, err)
 			continue
 		}
+		if entry.File != "" {
+			removeCompanions(entry.File)
+			if e := os.Remove(entry.File); e != nil && !os.IsNotExist(e) {
+				log.Printf("[WARN] failed to remove file %s: %v", entry.File, e)
+			}
+		}
 		removed++
-		log.Printf("[INFO] removed %s for %s (%s)", f, fi.ID, fi.Name)
+		s.event("INFO", "remove", fmt.Sprintf("removed %s for %s (%s), older than max age %v", entry.File, fi.ID, fi.Name,
+			fi.MaxAge), entryFields(fi, entry).with("file", entry.File).with("reason", "max_age"))
 	}
 	return removed
 }
 
+// isExpired checks if entry was published earlier than feed's MaxAge ago
+func (s *Service) isExpired(entry ytfeed.Entry, fi FeedInfo) bool {
+	if fi.MaxAge <= 0 || entry.Published.IsZero() {
+		return false
+	}
+	return entry.Published.Before(time.Now().Add(-fi.MaxAge))
+}
+
+// beforeStart checks if the entry published before the feed's start date. Entries without published time are not
+func (s *Service) beforeStart(entry ytfeed.Entry, fi FeedInfo) bool {
+	if fi.StartDate.IsZero() || entry.Published.IsZero() {
+		return false
+	}
+	return entry.Published.Before(fi.StartDate)
+}
+
+// keep returns the number of entries to keep for the feed, or KeepAll if entries should never be removed
 func (s *Service) keep(fi FeedInfo) int {
 	keep := s.KeepPerChannel
-	if fi.Keep > 0 {
+	if fi.Keep > 0 || fi.Keep == KeepAll {
 		keep = fi.Keep
 	}
 	return keep
 }
 
+// feedItems returns the number of the newest entries in rss of the feed, FeedItems if set and within keep
+func (s *Service) feedItems(fi FeedInfo) int {
+	keep := s.keep(fi)
+	if fi.FeedItems > 0 && (keep == KeepAll || fi.FeedItems < keep) {
+		return fi.FeedItems
+	}
+	return keep
+}
+
+// rssEntries returns entries of feed's rss, up to feedItems newest ones and pinned entries beyond the limit
+func (s *Service) rssEntries(fi FeedInfo) ([]ytfeed.Entry, error) {
+	limit := s.feedItems(fi)
+	entries, err := s.Store.Load(fi.ID, limit)
+	if err != nil || limit <= 0 || len(entries) < limit {
+		return entries, err
+	}
+	// older entries may be pinned, all loaded to find them. Pinned entries don't count in the limit
+	all, err := s.Store.Load(fi.ID, KeepAll)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ytfeed.Entry, 0, len(entries))
+	count := 0
+	for _, e := range all {
+		switch {
+		case e.Pinned:
+			res = append(res, e)
+		case count < limit:
+			res = append(res, e)
+			count++
+		}
+	}
+	return res, nil
+}
+
+// maxPerCycle returns the limit of new downloads of the feed per update cycle, 0 means no limit
+func (s *Service) maxPerCycle(fi FeedInfo) int {
+	switch {
+	case fi.MaxPerCycle > 0:
+		return fi.MaxPerCycle
+	case fi.MaxPerCycle < 0:
+		return 0
+	}
+	return s.MaxPerCycle
+}
+
+// downloadID returns id passed to downloader. It is video id for youtube and full video url for other sources
+func (s *Service) downloadID(entry ytfeed.Entry, fi FeedInfo) string {
+	if fi.Type == ytfeed.FTPeerTube && entry.Link.Href != "" {
+		return entry.Link.Href
+	}
+	return entry.VideoID
+}
+
+// mediaTypes maps extensions of audio and video files to mime types
+var mediaTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".opus": "audio/ogg",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".webm": "audio/webm",
+	".flac": "audio/flac",
+	".wav":  "audio/wav",
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+}
+
+// MediaType returns mime type of the audio or video file by its extension, empty if not a media file
+func MediaType(file string) string {
+	return mediaTypes[strings.ToLower(filepath.Ext(file))]
+}
+
+// enclosureType returns mime type of the entry's file for rss enclosure, the feed's override wins over
+// the type inferred from the file extension. Unknown types reported as audio/mpeg
+func enclosureType(file string, fi FeedInfo) string {
+	if fi.EnclosureMIME != "" {
+		return fi.EnclosureMIME
+	}
+	if t := MediaType(file); t != "" {
+		return t
+	}
+	if fi.Mode == ModeVideo {
+		return "video/mp4"
+	}
+	return "audio/mpeg"
+}
+
+// mediaExt is the extension of downloaded audio files, added by the downloader
+const mediaExt = ".mp3"
+
+// makeFileName returns file name (without extension) for the entry. By default, it is hash of entry's UID,
+// truncated to FileNameHashLen and extended in case of collision with a file of another entry.
+// With FileNameTemplate set, the name is made from the template and sanitized to be filesystem and shell safe.
+// In case of collision with a file of another entry, short hash of entry's UID is added to the name.
 func (s *Service) makeFileName(entry ytfeed.Entry) string {
-	h := sha1.New()
-	if _, err := h.Write([]byte(entry.UID())); err != nil {
-		return uuid.New().String()
+	hash := s.fileNameHash(entry)
+	if s.FileNameTemplate == "" {
+		size := s.FileNameHashLen
+		if size <= 0 || size > len(hash) {
+			size = len(hash)
+		}
+		for size < len(hash) && s.isFileNameTaken(hash[:size], entry) {
+			log.Printf("[DEBUG] file name collision for %s, extend hash to %d chars", entry.VideoID, size*2)
+			size *= 2
+		}
+		if size > len(hash) {
+			size = len(hash)
+		}
+		return hash[:size]
+	}
+
+	tmpl, err := template.New("fname").Parse(s.FileNameTemplate)
+	if err != nil {
+		log.Printf("[WARN] failed to parse file name template %q, %v", s.FileNameTemplate, err)
+		return hash
+	}
+	tmplParams := struct {
+		Title     string
+		Date      string
+		ID        string
+		ChannelID string
+		Hash      string
+	}{
+		Title:     entry.Title,
+		Date:      entry.Published.Format("2006-01-02"),
+		ID:        entry.VideoID,
+		ChannelID: entry.ChannelID,
+		Hash:      hash[:8],
+	}
+	b := bytes.Buffer{}
+	if err = tmpl.Execute(&b, tmplParams); err != nil {
+		log.Printf("[WARN] failed to execute file name template %q, %v", s.FileNameTemplate, err)
+		return hash
+	}
+
+	fname := sanitizeFileName(b.String(), maxFileNameLen)
+	if fname == "" {
+		return hash
+	}
+	if s.isFileNameTaken(fname, entry) {
+		fname = sanitizeFileName(fname, maxFileNameLen-9) + "-" + hash[:8]
+		log.Printf("[DEBUG] file name collision for %s, use %s", entry.VideoID, fname)
+	}
+	return fname
+}
+
+// mediaFileName returns file name of the entry's audio, with extension
+func (s *Service) mediaFileName(entry ytfeed.Entry) string {
+	return s.makeFileName(entry) + mediaExt
+}
+
+// subDir returns directory of feed's files relative to the files location, feed's id by default
+func (s *Service) subDir(fi FeedInfo) string {
+	return fi.FilesDir()
+}
+
+// feedFileName returns file name (without extension) for the entry, relative to the files location
+func (s *Service) feedFileName(entry ytfeed.Entry, fi FeedInfo) string {
+	return filepath.Join(s.subDir(fi), s.makeFileName(entry))
+}
+
+// relativeFile returns slash separated path of the file relative to the files location, used in urls.
+// Files outside the location (or with location not set) are served from the root by the base name.
+func (s *Service) relativeFile(file string) string {
+	if s.FilesLocation == "" {
+		return path.Base(filepath.ToSlash(file))
+	}
+	rel, err := filepath.Rel(s.FilesLocation, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path.Base(filepath.ToSlash(file))
+	}
+	return filepath.ToSlash(rel)
+}
+
+// fileNameHash returns hex encoded hash of entry's UID with FileNameHash function
+func (s *Service) fileNameHash(entry ytfeed.Entry) string {
+	if s.FileNameHash == "sha1" {
+		return legacyFileName(entry)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(entry.UID())))
+}
+
+// legacyFileName returns file name (without extension) made by the legacy scheme, full sha1 of entry's UID
+func legacyFileName(entry ytfeed.Entry) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(entry.UID()))) // nolint
+}
+
+// existingFile returns the entry's audio file if it was downloaded before, i.e. the store was lost or reset.
+// Both current and legacy file names are checked, in the feed's directory and in the files location itself.
+// Returns empty string if not found or FilesLocation not set.
+func (s *Service) existingFile(entry ytfeed.Entry, fi FeedInfo) string {
+	if s.FilesLocation == "" {
+		return ""
+	}
+	names := []string{filepath.Join(s.subDir(fi), s.mediaFileName(entry)), s.mediaFileName(entry), legacyFileName(entry) + mediaExt}
+	if fi.Mode == ModeVideo {
+		names = append(names, filepath.Join(s.subDir(fi), s.makeFileName(entry)+".mp4"))
+	}
+	if fi.CopyAudio {
+		for _, ext := range ytfeed.NativeAudioExts {
+			names = append(names, filepath.Join(s.subDir(fi), s.makeFileName(entry)+ext))
+		}
+	}
+	for _, name := range names {
+		file := filepath.Join(s.FilesLocation, name)
+		if fi, err := os.Stat(file); err == nil && fi.Mode().IsRegular() && fi.Size() > 0 {
+			return file
+		}
 	}
-	return fmt.Sprintf("%x", h.Sum(nil))
+	return ""
 }
 
-// totalEntriesToKeep returns total number of entries to keep, summing all channels' keep values
+// maxFileNameLen is the max length (in bytes) of file name, leaves enough room for extensions and temp suffixes
+const maxFileNameLen = 200
+
+// sanitizeFileName makes file name safe for filesystem and for use in shell command (dl template) unquoted.
+// Only unicode letters, digits, '-', '_' and '.' are kept, everything else replaced by '_'.
+// The result is limited to maxLen bytes, without breaking multibyte runes.
+func sanitizeFileName(name string, maxLen int) string {
+	res := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	for _, dup := range []struct{ from, to string }{{"__", "_"}, {"_-", "-"}, {"-_", "-"}} {
+		for strings.Contains(res, dup.from) {
+			res = strings.ReplaceAll(res, dup.from, dup.to)
+		}
+	}
+	res = strings.Trim(res, "_.-")
+
+	if len(res) > maxLen {
+		cut := 0
+		for i := range res {
+			if i > maxLen {
+				break
+			}
+			cut = i
+		}
+		res = strings.Trim(res[:cut], "_.-")
+	}
+	return res
+}
+
+// totalEntriesToKeep returns total number of entries to keep, summing all channels' keep values.
+// If any of channels keeps all entries, the total is unlimited (math.MaxInt)
 func (s *Service) totalEntriesToKeep() (res int) {
-	for _, fi := range s.Feeds {
-		res += s.keep(fi)
+	for _, fi := range s.feeds() {
+		keep := s.keep(fi)
+		if keep == KeepAll {
+			return math.MaxInt
+		}
+		res += keep
 	}
 	return res
 }
@@ -487,7 +2408,7 @@ func (s *Service) totalEntriesToKeep() (res int) {
 // countAllEntries returns total number of entries across all channels, respects keep settings
 func (s *Service) countAllEntries() int {
 	var result int
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		if entries, err := s.Store.Load(fi.ID, s.keep(fi)); err == nil {
 			result += len(entries)
 		}
@@ -498,7 +2419,7 @@ func (s *Service) countAllEntries() int {
 // newestEntry returns the newest entry across all channels, respects keep settings
 func (s *Service) newestEntry() ytfeed.Entry {
 	entries := []ytfeed.Entry{}
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		if recs, err := s.Store.Load(fi.ID, 1); err == nil {
 			entries = append(entries, recs...)
 		}
@@ -515,7 +2436,7 @@ func (s *Service) newestEntry() ytfeed.Entry {
 // oldestEntry returns the oldest entry from all channels, respecting keep settings
 func (s *Service) oldestEntry() ytfeed.Entry {
 	entries := []ytfeed.Entry{}
-	for _, fi := range s.Feeds {
+	for _, fi := range s.feeds() {
 		if recs, err := s.Store.Load(fi.ID, s.keep(fi)); err == nil {
 			entries = append(entries, recs...)
 		}
@@ -549,16 +2470,32 @@ func (s *Service) updateMp3Tags(file string, entry ytfeed.Entry, fi FeedInfo) er
 	return nil
 }
 
-type stats struct {
-	entries   int
-	processed int
-	added     int
-	removed   int
-	ignored   int
-	skipped   int
+// Stats of the processing cycle, counts of feed entries by outcome and downloaded bytes
+type Stats struct {
+	Entries   int   `json:"entries"`
+	Processed int   `json:"processed"`
+	Added     int   `json:"added"`
+	Removed   int   `json:"removed"`
+	Ignored   int   `json:"ignored"`
+	Skipped   int   `json:"skipped"`
+	Deferred  int   `json:"deferred"`
+	Bytes     int64 `json:"bytes"`
+}
+
+func (st *Stats) add(other Stats) {
+	st.Entries += other.Entries
+	st.Processed += other.Processed
+	st.Added += other.Added
+	st.Removed += other.Removed
+	st.Ignored += other.Ignored
+	st.Skipped += other.Skipped
+	st.Deferred += other.Deferred
+	st.Bytes += other.Bytes
 }
 
-func (st stats) String() string {
-	return fmt.Sprintf("entries: %d, processed: %d, updated: %d, removed: %d, ignored: %d, skipped: %d",
-		st.entries, st.processed, st.added, st.removed, st.ignored, st.skipped)
+// String returns stats in human-readable form, used for logging
+func (st Stats) String() string {
+	return fmt.Sprintf("entries: %d, processed: %d, updated: %d, removed: %d, ignored: %d, skipped: %d, deferred: %d, "+
+		"downloaded: %s", st.Entries, st.Processed, st.Added, st.Removed, st.Ignored, st.Skipped, st.Deferred,
+		humanize.Bytes(uint64(st.Bytes)))
 }
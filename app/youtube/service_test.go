@@ -1,17 +1,27 @@
 package youtube
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
 
+	rssfeed "github.com/umputun/feed-master/app/feed"
 	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
 	"github.com/umputun/feed-master/app/youtube/store"
 
@@ -21,7 +31,7 @@ import (
 func TestService_Do(t *testing.T) {
 
 	chans := &mocks.ChannelServiceMock{
-		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type) ([]ytfeed.Entry, error) {
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
 			return []ytfeed.Entry{
 				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
 				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
@@ -74,6 +84,9 @@ func TestService_Do(t *testing.T) {
 	assert.Equal(t, ytfeed.FTPlaylist, chans.GetCalls()[1].FeedType)
 	assert.Equal(t, "channel1", chans.GetCalls()[2].ChanID)
 	assert.Equal(t, "channel2", chans.GetCalls()[3].ChanID)
+	for _, c := range chans.GetCalls() {
+		assert.True(t, c.PublishedAfter.IsZero(), "no cutoff, all listed entries checked")
+	}
 
 	res, err := boltStore.Load("channel1", 10)
 	require.NoError(t, err)
@@ -105,17 +118,17 @@ func TestService_Do(t *testing.T) {
 	assert.Contains(t, string(rssData), "<itunes:duration>1234</itunes:duration>")
 
 	require.Equal(t, 4, len(duration.FileCalls()))
-	assert.Equal(t, "/tmp/e4650bb3d770eed60faad7ffbed5f33ffb1b89fa.mp3", duration.FileCalls()[0].Fname)
-	assert.Equal(t, "/tmp/4308c33c7ddb107c2d0c13a905e4c6962001bab4.mp3", duration.FileCalls()[1].Fname)
-	assert.Equal(t, "/tmp/3be877c750abb87daee80c005fe87e7a3f824fed.mp3", duration.FileCalls()[2].Fname)
-	assert.Equal(t, "/tmp/648f79b3a05ececb8a37600aa0aee332f0374e01.mp3", duration.FileCalls()[3].Fname)
+	assert.Equal(t, "/tmp/channel1/19ab8246c87333b5080cd735dcebfad8c8a6c7622a2568aec633f70cf504bf17.mp3", duration.FileCalls()[0].Fname)
+	assert.Equal(t, "/tmp/channel1/267a51e3063d142707b5a8e7b4c194ae0b9194b57c94b5206eac1dd47cd88071.mp3", duration.FileCalls()[1].Fname)
+	assert.Equal(t, "/tmp/channel2/a1038212838d60e91b400ae8017fbdfb34f07d699b5aba3d00ef46d6843065c6.mp3", duration.FileCalls()[2].Fname)
+	assert.Equal(t, "/tmp/channel2/7fae4dc62a06d625069f472c3814034092ac003e377e99137b4555ac54185ac8.mp3", duration.FileCalls()[3].Fname)
 }
 
 // nolint:dupl // test if very similar to TestService_RSSFeed
 func TestService_DoIsAllowedFilter(t *testing.T) {
 
 	chans := &mocks.ChannelServiceMock{
-		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type) ([]ytfeed.Entry, error) {
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
 			return []ytfeed.Entry{
 				{ChannelID: chanID, VideoID: "vid1", Title: "Prefix1: title1", Published: time.Now()},
 				{ChannelID: chanID, VideoID: "vid2", Title: "Prefix2: title2", Published: time.Now()},
@@ -158,66 +171,1789 @@ func TestService_DoIsAllowedFilter(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*900)
 	defer cancel()
 
-	err = svc.Do(ctx)
-	assert.EqualError(t, err, "context deadline exceeded")
+	err = svc.Do(ctx)
+	assert.EqualError(t, err, "context deadline exceeded")
+
+	require.Equal(t, 4, len(chans.GetCalls()))
+	assert.Equal(t, "channel1", chans.GetCalls()[0].ChanID)
+	assert.Equal(t, ytfeed.FTChannel, chans.GetCalls()[0].FeedType)
+	assert.Equal(t, "channel2", chans.GetCalls()[1].ChanID)
+	assert.Equal(t, ytfeed.FTChannel, chans.GetCalls()[1].FeedType)
+	assert.Equal(t, "channel1", chans.GetCalls()[2].ChanID)
+	assert.Equal(t, "channel2", chans.GetCalls()[3].ChanID)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(res), "one entry for channel1, skipped irrelevant ones")
+	assert.Equal(t, "vid2", res[0].VideoID)
+
+	res, err = boltStore.Load("channel2", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(res), "two entries for channel2, skipped irrelevant one")
+	assert.Equal(t, "vid2", res[0].VideoID)
+	assert.Equal(t, "vid1", res[1].VideoID)
+
+	require.Equal(t, 3, len(downloader.GetCalls()))
+	require.Equal(t, "vid2", downloader.GetCalls()[0].ID)
+	require.Equal(t, "vid1", downloader.GetCalls()[1].ID)
+	require.Equal(t, "vid2", downloader.GetCalls()[2].ID)
+	require.True(t, downloader.GetCalls()[0].Fname != "")
+
+	rssData, err := os.ReadFile("/tmp/channel1.xml")
+	require.NoError(t, err)
+	t.Logf("%s", string(rssData))
+	assert.Contains(t, string(rssData), "<guid>channel1::vid2</guid>")
+	assert.Contains(t, string(rssData), "<itunes:duration>1234</itunes:duration>")
+
+	rssData, err = os.ReadFile("/tmp/channel2.xml")
+	require.NoError(t, err)
+	t.Logf("%s", string(rssData))
+	assert.Contains(t, string(rssData), "<guid>channel2::vid2</guid>")
+	assert.Contains(t, string(rssData), "<guid>channel2::vid1</guid>")
+	assert.Contains(t, string(rssData), "<itunes:duration>1234</itunes:duration>")
+
+	require.Equal(t, 3, len(duration.FileCalls()))
+	assert.Equal(t, "/tmp/channel1/267a51e3063d142707b5a8e7b4c194ae0b9194b57c94b5206eac1dd47cd88071.mp3", duration.FileCalls()[0].Fname)
+	assert.Equal(t, "/tmp/channel2/a1038212838d60e91b400ae8017fbdfb34f07d699b5aba3d00ef46d6843065c6.mp3", duration.FileCalls()[1].Fname)
+	assert.Equal(t, "/tmp/channel2/7fae4dc62a06d625069f472c3814034092ac003e377e99137b4555ac54185ac8.mp3", duration.FileCalls()[2].Fname)
+}
+
+func TestService_Backfill(t *testing.T) {
+	pages := [][]ytfeed.Entry{
+		{{ChannelID: "channel1", VideoID: "vid5", Published: time.Now()}, {ChannelID: "channel1", VideoID: "vid4", Published: time.Now().Add(-time.Hour)}},
+		{{ChannelID: "channel1", VideoID: "vid3", Published: time.Now().Add(-2 * time.Hour)}, {ChannelID: "channel1", VideoID: "vid2", Published: time.Now().Add(-3 * time.Hour)}},
+		{{ChannelID: "channel1", VideoID: "vid1", Published: time.Now().Add(-4 * time.Hour)}},
+	}
+	chans := &mocks.ChannelServiceMock{
+		GetPageFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, page int, apiKey string) ([]ytfeed.Entry, error) {
+			if page >= len(pages) {
+				return nil, nil
+			}
+			return pages[page], nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			return "/tmp/" + fname + ".mp3", nil
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-backfill.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTPeerTube, Keep: KeepAll}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		RSSFileStore:    RSSFileStore{Enabled: true, Location: "/tmp"},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	added, err := svc.Backfill(context.Background(), "channel1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, added, "limited to 3 entries")
+	require.Equal(t, 3, len(downloader.GetCalls()))
+	assert.Equal(t, 2, len(chans.GetPageCalls()), "stopped on the second page")
+
+	// resume without limit, should download the rest and go through all pages
+	added, err = svc.Backfill(context.Background(), "channel1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, added, "only entries not downloaded before")
+	require.Equal(t, 5, len(downloader.GetCalls()))
+	assert.Equal(t, "vid2", downloader.GetCalls()[3].ID)
+	assert.Equal(t, "vid1", downloader.GetCalls()[4].ID)
+	assert.Equal(t, 6, len(chans.GetPageCalls()), "all pages requested, including the empty one")
+	assert.Equal(t, 3, chans.GetPageCalls()[5].Page)
+	assert.Equal(t, ytfeed.FTPeerTube, chans.GetPageCalls()[5].FeedType)
+
+	res, err := boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(res))
+
+	rssData, err := os.ReadFile("/tmp/channel1.xml")
+	require.NoError(t, err)
+	assert.Contains(t, string(rssData), "<guid>channel1::vid1</guid>")
+	assert.Contains(t, string(rssData), "<guid>channel1::vid5</guid>")
+
+	_, err = svc.Backfill(context.Background(), "bad", 0)
+	assert.EqualError(t, err, "feed bad not found")
+}
+
+func TestService_DoDeferLive(t *testing.T) {
+	live := true
+	published := time.Now().Add(-time.Hour)
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid3", Title: "premiere", Published: published},
+				{ChannelID: chanID, VideoID: "vid2", Title: "upcoming", Published: published.Add(-time.Hour)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: published},
+			}
+			if live {
+				res[1].LiveStatus = ytfeed.LSUpcoming
+			}
+			return res, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			if id == "vid3" && live {
+				return "", ytfeed.ErrNotAvailable // premiere, not detected by the channel listing
+			}
+			return "/tmp/" + fname + ".mp3", nil
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-live.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Entries: 3, Processed: 1, Added: 1, Deferred: 2}, st)
+	require.Equal(t, 2, len(downloader.GetCalls()), "upcoming entry not downloaded")
+	assert.Equal(t, "vid3", downloader.GetCalls()[0].ID)
+	assert.Equal(t, "vid1", downloader.GetCalls()[1].ID)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid1", res[0].VideoID)
+	for _, vid := range []string{"vid2", "vid3"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: vid})
+		require.NoError(t, err)
+		assert.False(t, found, "%s deferred, not marked processed", vid)
+	}
+
+	// entries went live and available now
+	live = false
+	st, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.Added)
+	assert.Equal(t, 0, st.Deferred)
+	require.Equal(t, 4, len(downloader.GetCalls()))
+	assert.Equal(t, "vid3", downloader.GetCalls()[2].ID)
+	assert.Equal(t, "vid2", downloader.GetCalls()[3].ID)
+	res, err = boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(res))
+}
+
+func TestService_DownloadTimeout(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid3", Title: "title3", Published: time.Now()},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			if id == "vid2" { // stuck download
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+			return "/tmp/" + fname + ".mp3", nil
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-timeout.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		DownloadTimeout: 50 * time.Millisecond,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Entries: 3, Processed: 2, Added: 2, Ignored: 1}, st)
+	require.Equal(t, 3, len(downloader.GetCalls()), "processing continued after timed out download")
+
+	found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"})
+	require.NoError(t, err)
+	assert.False(t, found, "timed out entry not marked processed")
+
+	failures := svc.Failures("channel1")
+	require.Equal(t, 1, len(failures))
+	assert.Equal(t, "vid2", failures[0].VideoID)
+	assert.Equal(t, "timed out after 50ms", failures[0].Reason)
+}
+
+// optsDownloader is DownloaderService supporting download options, records passed options
+type optsDownloader struct {
+	*mocks.DownloaderServiceMock
+	opts []ytfeed.GetOptions
+}
+
+func (d *optsDownloader) GetOpts(ctx context.Context, id, fname string, opts ytfeed.GetOptions) (string, error) {
+	d.opts = append(d.opts, opts)
+	return d.Get(ctx, id, fname)
+}
+
+func TestService_DoCopyAudio(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()}}, nil
+		},
+	}
+	downloader := &optsDownloader{DownloaderServiceMock: &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname)
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			require.NoError(t, os.WriteFile(file+".info.json", []byte(`{"duration": 321.5}`), 0o600))
+			return file + ".m4a", os.WriteFile(file+".m4a", []byte("native audio"), 0o600)
+		},
+	}}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, CopyAudio: true}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost:8080/yt",
+		FilesLocation:   dir,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 0 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added)
+	assert.Equal(t, []ytfeed.GetOptions{{CopyAudio: true}}, downloader.opts)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, ".m4a", filepath.Ext(res[0].File))
+	assert.Equal(t, 321, res[0].Duration, "duration from info json")
+	data, err := os.ReadFile(res[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, "native audio", string(data), "no mp3 tags added")
+	assert.Equal(t, res[0].File, svc.existingFile(res[0], svc.Feeds[0]), "native audio file found")
+
+	rss, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, `type="audio/mp4"`)
+}
+
+func TestService_DoVideo(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()}}, nil
+		},
+	}
+	downloader := &optsDownloader{DownloaderServiceMock: &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp4")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("video content"), 0o600)
+		},
+	}}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, Mode: ModeVideo, MediaRSS: true}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost:8080/yt",
+		FilesLocation:   dir,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added)
+	assert.Equal(t, []ytfeed.GetOptions{{Video: true}}, downloader.opts)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, ".mp4", filepath.Ext(res[0].File))
+	data, err := os.ReadFile(res[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, "video content", string(data), "no mp3 tags added")
+	assert.Equal(t, res[0].File, svc.existingFile(res[0], svc.Feeds[0]), "video file found")
+	assert.Equal(t, "", svc.existingFile(res[0], FeedInfo{ID: "channel1"}), "not looked up for audio feed")
+
+	rss, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, `length="13" type="video/mp4"></enclosure>`)
+	assert.Contains(t, rss, `type="video/mp4" medium="video"`)
+}
+
+func TestService_FeedTimeout(t *testing.T) {
+	ts := time.Now().Truncate(time.Second)
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: ts},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: ts.Add(-time.Hour)},
+				{ChannelID: chanID, VideoID: "vid3", Title: "title3", Published: ts.Add(-2 * time.Hour)},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			if fname == filepath.Join("channel1", (&Service{}).makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"})) {
+				<-ctx.Done() // stuck download burns the whole budget of channel1
+				return "", ctx.Err()
+			}
+			return "/tmp/" + fname + ".mp3", nil
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-feed-timeout.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds: []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		FeedTimeout:     100 * time.Millisecond,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(downloader.GetCalls()), "vid3 of channel1 not tried, channel2 processed")
+	assert.Equal(t, Stats{Entries: 5, Processed: 4, Added: 4, Ignored: 1}, st)
+
+	for _, vid := range []string{"vid1", "vid2", "vid3"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel2", VideoID: vid})
+		require.NoError(t, err)
+		assert.True(t, found, "channel2 %s processed", vid)
+	}
+	for _, vid := range []string{"vid2", "vid3"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: vid})
+		require.NoError(t, err)
+		assert.False(t, found, "channel1 %s left for the next cycle", vid)
+	}
+}
+
+func TestService_RetryFailedOldEntry(t *testing.T) {
+	old := time.Now().Add(-7 * 24 * time.Hour)
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return ytfeed.FilterPublishedAfter([]ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: old},
+			}, publishedAfter), nil
+		},
+	}
+	dir, fail := t.TempDir(), true
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			if id == "vid1" && fail {
+				return "", errors.New("http error 503")
+			}
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content"), 0o600)
+		},
+	}
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1"}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added, "vid1 failed")
+
+	fail = false
+	st, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added, "vid1 older than the newest stored entry retried")
+	entries, err := boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+}
+
+func TestService_MaxPerCycle(t *testing.T) {
+	ts := time.Now().Add(-48 * time.Hour)
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{}
+			for i := 5; i > 0; i-- {
+				res = append(res, ytfeed.Entry{ChannelID: chanID, VideoID: fmt.Sprintf("vid%d", i), Title: fmt.Sprintf("title%d", i),
+					Published: ts.Add(time.Duration(i) * time.Minute)})
+			}
+			return res, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			return "/tmp/" + fname + ".mp3", nil
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-max-per-cycle.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds: []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel, MaxPerCycle: -1}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		MaxPerCycle:     2,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Entries: 8, Processed: 7, Added: 7}, st, "2 of channel1, all 5 of channel2")
+	found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid3"})
+	require.NoError(t, err)
+	assert.False(t, found, "vid3 of channel1 left for the next cycle")
+
+	st, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.Added, "next 2 of channel1")
+	st, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added, "the last one of channel1")
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(res))
+	assert.Equal(t, 10, len(downloader.GetCalls()))
+}
+
+func TestService_maxPerCycle(t *testing.T) {
+	svc := Service{MaxPerCycle: 3}
+	assert.Equal(t, 3, svc.maxPerCycle(FeedInfo{}))
+	assert.Equal(t, 5, svc.maxPerCycle(FeedInfo{MaxPerCycle: 5}))
+	assert.Equal(t, 0, svc.maxPerCycle(FeedInfo{MaxPerCycle: -1}), "no limit for the feed")
+	assert.Equal(t, 0, (&Service{}).maxPerCycle(FeedInfo{}), "no limit by default")
+}
+
+func TestService_feedItems(t *testing.T) {
+	svc := Service{KeepPerChannel: 10}
+	assert.Equal(t, 10, svc.feedItems(FeedInfo{}), "keep if not set")
+	assert.Equal(t, 5, svc.feedItems(FeedInfo{FeedItems: 5}))
+	assert.Equal(t, 20, svc.feedItems(FeedInfo{Keep: 100, FeedItems: 20}))
+	assert.Equal(t, 10, svc.feedItems(FeedInfo{FeedItems: 20}), "limited by keep")
+	assert.Equal(t, 20, svc.feedItems(FeedInfo{Keep: KeepAll, FeedItems: 20}))
+	assert.Equal(t, KeepAll, svc.feedItems(FeedInfo{Keep: KeepAll}))
+}
+
+func TestService_RSSFeedItems(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", File: "/tmp/file1.mp3", Published: time.Now()}}, nil
+		},
+		RemoveOldFunc: func(channelID string, keep int) ([]string, error) { return nil, nil },
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+	fi := FeedInfo{ID: "channel1", Name: "name1", Keep: 100, FeedItems: 20}
+
+	_, err := svc.RSSFeed(fi, RSSFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(storeSvc.LoadCalls()))
+	assert.Equal(t, 20, storeSvc.LoadCalls()[0].Max, "rss limited by feed_items")
+
+	svc.removeOld(fi)
+	require.Equal(t, 1, len(storeSvc.RemoveOldCalls()))
+	assert.Equal(t, 101, storeSvc.RemoveOldCalls()[0].Keep, "files removed by keep")
+}
+
+func TestService_RSSFeedItemsStored(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	for i := 1; i <= 8; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("vid%d.mp3", i))
+		require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+		_, err = boltStore.Save(ytfeed.Entry{ChannelID: "channel1", VideoID: fmt.Sprintf("vid%d", i),
+			Title: fmt.Sprintf("title%d", i), File: file, Published: time.Now().Add(-time.Duration(i) * time.Hour)})
+		require.NoError(t, err)
+	}
+	svc := Service{Store: boltStore, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+	fi := FeedInfo{ID: "channel1", Name: "name1", Keep: 5, FeedItems: 2}
+
+	assert.Equal(t, 2, svc.removeOld(fi), "over keep removed")
+	entries, err := boltStore.Load("channel1", KeepAll)
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(entries), "storage retains entries up to keep, not limited by feed_items")
+
+	rss, err := svc.RSSFeed(fi, RSSFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(rss, "<item>"), "rss limited by feed_items")
+	assert.Contains(t, rss, "title1")
+	assert.Contains(t, rss, "title2")
+	assert.NotContains(t, rss, "title3")
+}
+
+func TestService_RSSFeedDates(t *testing.T) {
+	published := time.Date(2022, 4, 2, 10, 20, 30, 0, time.FixedZone("EDT", -4*3600))
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3",
+				Published: published}}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+	fi := FeedInfo{ID: "channel1", Name: "name1"}
+
+	rss, err := svc.RSSFeed(fi, RSSFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(rss, "<pubDate>Sat, 02 Apr 2022 14:20:30 +0000</pubDate>"), "channel and item")
+	assert.Regexp(t, `<lastBuildDate>\w{3}, \d{2} \w{3} \d{4} \d{2}:\d{2}:\d{2} \+0000</lastBuildDate>`, rss)
+
+	svc.RFC822Dates = true
+	rss, err = svc.RSSFeed(fi, RSSFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(rss, "<pubDate>02 Apr 22 14:20 +0000</pubDate>"), "channel and item")
+	assert.Regexp(t, `<lastBuildDate>\d{2} \w{3} \d{2} \d{2}:\d{2} \+0000</lastBuildDate>`, rss)
+}
+
+func TestService_ShutdownGrace(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+			}, nil
+		},
+	}
+
+	prep := func(t *testing.T, grace, downloadTime time.Duration) (*Service, *mocks.DownloaderServiceMock, *store.BoltDB) {
+		started := make(chan struct{})
+		downloader := &mocks.DownloaderServiceMock{
+			GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+				close(started)
+				select {
+				case <-time.After(downloadTime):
+					return "/tmp/" + fname + ".mp3", nil
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+			},
+		}
+		tmpfile := filepath.Join(t.TempDir(), "test-shutdown.db")
+		db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+		require.NoError(t, err)
+		boltStore := &store.BoltDB{DB: db}
+		svc := &Service{
+			Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}},
+			Downloader:      downloader,
+			ChannelService:  chans,
+			Store:           boltStore,
+			KeepPerChannel:  10,
+			RSSFileStore:    RSSFileStore{Enabled: false},
+			DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+			ShutdownGrace:   grace,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started // cancel in the middle of the first download
+			cancel()
+		}()
+		_, err = svc.procChannels(ctx)
+		assert.Equal(t, context.Canceled, err)
+		return svc, downloader, boltStore
+	}
+
+	t.Run("download finished within grace", func(t *testing.T) {
+		_, downloader, boltStore := prep(t, time.Second, 50*time.Millisecond)
+		require.Equal(t, 1, len(downloader.GetCalls()), "no new downloads after cancellation")
+		res, err := boltStore.Load("channel1", 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(res))
+		assert.Equal(t, "vid1", res[0].VideoID)
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"})
+		require.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("download interrupted after grace", func(t *testing.T) {
+		svc, downloader, boltStore := prep(t, 10*time.Millisecond, time.Minute)
+		require.Equal(t, 1, len(downloader.GetCalls()))
+		_, err := boltStore.Load("channel1", 10)
+		assert.EqualError(t, err, "no bucket for channel1", "nothing saved")
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"})
+		require.NoError(t, err)
+		assert.False(t, found, "interrupted entry not marked processed")
+		assert.Empty(t, svc.Failures(""), "interruption is not a failure")
+	})
+}
+
+func TestService_VerifyFiles(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid3", Title: "title3", Published: time.Now()},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, id+".mp3")
+			return file, os.WriteFile(file, []byte("content of "+id), 0o600)
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-verify.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+	for _, e := range res {
+		assert.Len(t, e.Checksum, 64, "sha256 stored for %s", e.VideoID)
+		fi, err := os.Stat(e.File)
+		require.NoError(t, err)
+		assert.Equal(t, fi.Size(), e.FileSize, "size with tags stored for %s", e.VideoID)
+	}
+	vres, err := svc.VerifyFiles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{Checked: 3}, vres, "all files are good")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vid1.mp3"), []byte("content"), 0o600))    // truncated
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vid2.mp3"), []byte("content of"), 0o600)) // truncated
+	require.NoError(t, os.Remove(filepath.Join(dir, "vid3.mp3")))
+	downloader.GetFunc = func(ctx context.Context, id string, fname string) (string, error) {
+		if id == "vid1" {
+			return "", errors.New("video removed")
+		}
+		file := filepath.Join(dir, id+".mp3")
+		return file, os.WriteFile(file, []byte("content of "+id), 0o600)
+	}
+	vres, err = svc.VerifyFiles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, vres.Checked)
+	require.Equal(t, 2, len(vres.Corrupted))
+	assert.ElementsMatch(t, []string{"vid1", "vid2"}, []string{vres.Corrupted[0].VideoID, vres.Corrupted[1].VideoID})
+	require.Equal(t, 1, len(vres.Failed))
+	assert.Equal(t, "vid1", vres.Failed[0].VideoID)
+	require.Equal(t, 1, len(vres.Unreadable))
+	assert.Equal(t, "vid3", vres.Unreadable[0].VideoID)
+
+	res, err = boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res), "no entries removed")
+	for _, e := range res {
+		data, err := os.ReadFile(filepath.Join(dir, e.VideoID+".mp3"))
+		switch e.VideoID {
+		case "vid1":
+			require.NoError(t, err)
+			assert.Equal(t, "content", string(data), "corrupted file restored if can't be downloaded")
+		case "vid2":
+			require.NoError(t, err)
+			assert.True(t, strings.HasSuffix(string(data), "content of vid2"), "corrupted file re-downloaded")
+			checksum, err := fileChecksum(e.File)
+			require.NoError(t, err)
+			assert.Equal(t, checksum, e.Checksum, "checksum of re-downloaded file stored")
+		case "vid3":
+			assert.True(t, os.IsNotExist(err), "missing file reported only")
+		}
+	}
+	_, err = os.Stat(filepath.Join(dir, "vid1.mp3.corrupted"))
+	assert.True(t, os.IsNotExist(err), "no leftovers")
+	_, err = os.Stat(filepath.Join(dir, "vid2.mp3.corrupted"))
+	assert.True(t, os.IsNotExist(err), "no leftovers")
+}
+
+func TestService_DeleteEpisode(t *testing.T) {
+	dir := t.TempDir()
+	tmpfile := filepath.Join(dir, "test-delete.db")
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	file := filepath.Join(dir, "vid1.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vid1.vtt"), []byte("WEBVTT"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vid2.mp3"), []byte("content"), 0o600))
+	entry1 := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: file, Published: time.Now()}
+	entry2 := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "title2", File: filepath.Join(dir, "vid2.mp3"),
+		Published: time.Now().Add(-time.Hour)}
+	for _, e := range []ytfeed.Entry{entry1, entry2} {
+		_, err = boltStore.Save(e)
+		require.NoError(t, err)
+	}
+
+	svc := Service{
+		Feeds:          []FeedInfo{{ID: "channel1", Name: "name1"}},
+		Store:          boltStore,
+		KeepPerChannel: 10,
+		RSSFileStore:   RSSFileStore{Enabled: true, Location: dir},
+	}
+
+	_, err = svc.DeleteEpisode("channel1", "vid3")
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown episode, %v", err)
+	_, err = svc.DeleteEpisode("channel2", "vid1")
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown feed, %v", err)
+
+	res, err := svc.DeleteEpisode("channel1", "vid1")
+	require.NoError(t, err)
+	assert.Equal(t, "title1", res.Title)
+
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err), "file removed")
+	_, err = os.Stat(filepath.Join(dir, "vid1.vtt"))
+	assert.True(t, os.IsNotExist(err), "subtitles removed")
+
+	entries, err := boltStore.Load("channel1", -1)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "vid2", entries[0].VideoID)
+
+	found, _, err := boltStore.CheckProcessed(entry1)
+	require.NoError(t, err)
+	assert.True(t, found, "kept as processed, won't be downloaded again")
+
+	rss, err := os.ReadFile(filepath.Join(dir, "channel1.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(rss), "title2")
+	assert.NotContains(t, string(rss), "title1")
+
+	_, err = svc.DeleteEpisode("channel1", "vid1")
+	assert.True(t, errors.Is(err, ErrNotFound), "already deleted, %v", err)
+}
+
+func TestService_PinEpisode(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test-pin.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	for i := 1; i <= 4; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("vid%d.mp3", i))
+		require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+		_, err = boltStore.Save(ytfeed.Entry{ChannelID: "channel1", VideoID: fmt.Sprintf("vid%d", i),
+			Title: fmt.Sprintf("title%d", i), File: file, Published: time.Now().Add(-time.Duration(i) * 24 * time.Hour)})
+		require.NoError(t, err)
+	}
+
+	svc := Service{
+		Feeds:          []FeedInfo{{ID: "channel1", Name: "name1", Keep: 2, FeedItems: 1, MaxAge: 72 * time.Hour}},
+		Store:          boltStore,
+		KeepPerChannel: 10,
+		RSSFileStore:   RSSFileStore{Enabled: true, Location: dir},
+	}
+
+	_, err = svc.PinEpisode("channel1", "vid5", true)
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown episode, %v", err)
+
+	res, err := svc.PinEpisode("channel1", "vid4", true)
+	require.NoError(t, err)
+	assert.True(t, res.Pinned)
+
+	assert.Equal(t, 1, svc.removeOld(svc.Feeds[0]), "only vid3 over the keep limit removed")
+	videos := func() (res []string) {
+		entries, e := boltStore.Load("channel1", KeepAll)
+		require.NoError(t, e)
+		for _, e := range entries {
+			res = append(res, e.VideoID)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"vid1", "vid2", "vid4"}, videos(), "pinned vid4 kept despite max age and keep")
+	_, err = os.Stat(filepath.Join(dir, "vid4.mp3"))
+	assert.NoError(t, err, "pinned file kept")
+
+	rss, err := os.ReadFile(filepath.Join(dir, "channel1.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(rss), "title1")
+	assert.NotContains(t, string(rss), "title2", "over feed_items")
+	assert.Contains(t, string(rss), "title4", "pinned in rss")
+
+	res, err = svc.PinEpisode("channel1", "vid4", false)
+	require.NoError(t, err)
+	assert.False(t, res.Pinned)
+	assert.Equal(t, []string{"vid1", "vid2"}, videos(), "unpinned vid4 removed")
+	rss, err = os.ReadFile(filepath.Join(dir, "channel1.xml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(rss), "title4")
+}
+
+func TestService_Redownload(t *testing.T) {
+	dir := t.TempDir()
+	db, err := bolt.Open(filepath.Join(dir, "test-redownload.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	oldFile := filepath.Join(dir, "old.mp3")
+	require.NoError(t, os.WriteFile(oldFile, []byte("broken"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.vtt"), []byte("WEBVTT"), 0o600))
+	entry := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: oldFile, Checksum: "bad",
+		FileSize: 6, GUID: "vid1", Published: time.Now()}
+	_, err = boltStore.Save(entry)
+	require.NoError(t, err)
+	require.NoError(t, boltStore.SetProcessed(entry))
+
+	failDownload := false
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			if failDownload {
+				return "", errors.New("blah")
+			}
+			file := filepath.Join(dir, fname+".mp3")
+			if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+				return "", err
+			}
+			return file, os.WriteFile(file, []byte("fresh content"), 0o600)
+		},
+	}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1"}},
+		Store:           boltStore,
+		Downloader:      downloader,
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: true, Location: dir},
+	}
+	svc.addFailure(svc.Feeds[0], entry, "old failure")
+
+	_, err = svc.Redownload(context.Background(), "channel1", "vid2")
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown episode, %v", err)
+	_, err = svc.Redownload(context.Background(), "channel2", "vid1")
+	assert.True(t, errors.Is(err, ErrNotFound), "unknown feed, %v", err)
+
+	res, err := svc.Redownload(context.Background(), "channel1", "vid1")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(downloader.GetCalls()))
+	assert.NotEqual(t, oldFile, res.File)
+	assert.Greater(t, res.FileSize, int64(13), "fresh content with tags")
+	assert.Equal(t, 1234, res.Duration)
+	assert.NotEqual(t, "bad", res.Checksum)
+	assert.Equal(t, "vid1", res.GUID, "metadata kept")
+	_, err = os.Stat(oldFile)
+	assert.True(t, os.IsNotExist(err), "old file removed")
+	_, err = os.Stat(filepath.Join(dir, "old.vtt"))
+	assert.True(t, os.IsNotExist(err), "old subtitles removed")
+	assert.Empty(t, svc.Failures("channel1"), "failures cleared")
+
+	entries, err := boltStore.Load("channel1", -1)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, res.File, entries[0].File)
+	found, _, err := boltStore.CheckProcessed(entry)
+	require.NoError(t, err)
+	assert.True(t, found)
+	rss, err := os.ReadFile(filepath.Join(dir, "channel1.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(rss), "title1")
+
+	failDownload = true
+	_, err = svc.Redownload(context.Background(), "channel1", "vid1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "will be retried by the next update")
+	entries, err = boltStore.Load("channel1", -1)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	found, _, err = boltStore.CheckProcessed(entry)
+	require.NoError(t, err)
+	assert.False(t, found, "not processed, retried by the regular update")
+	assert.Equal(t, 1, len(svc.Failures("channel1")))
+}
+
+// nolint:dupl // test if very similar to TestService_RSSFeed
+func TestService_RSSFeed(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3"},
+				{ChannelID: "channel1", VideoID: "vid2", Title: "title2", File: "/tmp/file2.mp3"},
+			}
+			res[0].Link.Href = "http://example.com/v1"
+			res[1].Link.Href = "http://example.com/v2"
+			res[0].Author.URI = "http://example.com/c1"
+			res[0].Media.Thumbnail.URL = "http://example.com/thumb.jpg"
+			return res, nil
+		},
+	}
+
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTPlaylist},
+		},
+		Store:          storeSvc,
+		RootURL:        "http://localhost:8080/yt",
+		KeepPerChannel: 10,
+		SkipShorts:     time.Second * 60,
+	}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}, RSSFilter{})
+	require.NoError(t, err)
+	t.Logf("%v", res)
+
+	assert.Contains(t, res, `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:podcast="https://podcastindex.org/namespace/1.0">`)
+	assert.Contains(t, res, `<atom:link href="http://localhost:8080/rss/channel1" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3"`)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3"`)
+	assert.Contains(t, res, `<guid>channel1::vid1</guid>`)
+	assert.Contains(t, res, `<guid>channel1::vid2</guid>`)
+	assert.NotContains(t, res, `<guid>channel1::vid3</guid>`, "skipped short video")
+	assert.Contains(t, res, `<link>http://example.com/v1</link>`)
+	assert.Contains(t, res, `<link>http://example.com/v2</link>`)
+	assert.Contains(t, res, `<link>http://example.com/c1</link>`)
+	assert.Contains(t, res, `<itunes:image href="http://example.com/thumb.jpg"></itunes:image>`)
+	assert.Contains(t, res, `<media:thumbnail url="http://example.com/thumb.jpg"></media:thumbnail>`)
+
+}
+
+func TestService_RSSFeedPodcastGUID(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3"}}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+	assert.Contains(t, res, `<podcast:guid>7cac282c-26c4-592c-8e9e-74385d79d576</podcast:guid>`)
+	assert.Contains(t, res, `<podcast:locked>no</podcast:locked>`)
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Locked: true}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `<podcast:guid>7cac282c-26c4-592c-8e9e-74385d79d576</podcast:guid>`, "stable guid")
+	assert.Contains(t, res, `<podcast:locked>yes</podcast:locked>`)
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel2", Name: "name2"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.NotContains(t, res, `<podcast:guid>7cac282c-26c4-592c-8e9e-74385d79d576</podcast:guid>`, "guid differs per feed")
+	assert.Equal(t, podcastGUID("channel2"), podcastGUID("channel2"))
+	assert.Contains(t, res, `<podcast:guid>`+podcastGUID("channel2")+`</podcast:guid>`)
+}
+
+func TestService_selfLink(t *testing.T) {
+	svc := Service{RootURL: "http://example.com/yt/media/"}
+	assert.Equal(t, &rssfeed.AtomLink{Href: "http://example.com/yt/rss/channel1", Rel: "self", Type: "application/rss+xml"},
+		svc.selfLink("channel1"), "sibling of media path")
+	svc.RootURL = "http://example.com"
+	assert.Equal(t, "http://example.com/rss/channel1", svc.selfLink("channel1").Href)
+	svc.RSSURL = "https://feeds.example.com/yt/rss/"
+	assert.Equal(t, "https://feeds.example.com/yt/rss/all", svc.selfLink("all").Href, "rss url preferred")
+	assert.Nil(t, (&Service{}).selfLink("channel1"))
+}
+
+func TestService_RSSFeedMediaBaseURL(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: "/srv/yt/file1.mp3",
+				Subtitles: "/srv/yt/file1.en.vtt"}}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://example.com/yt/media", MediaBaseURL: "https://cdn.example.com/yt",
+		FilesLocation: "/srv/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `<enclosure url="https://cdn.example.com/yt/file1.mp3"`)
+	assert.Contains(t, res, `<podcast:transcript url="https://cdn.example.com/yt/file1.en.vtt"`)
+	assert.Contains(t, res, `<atom:link href="http://example.com/yt/rss/channel1"`, "feed hosted with root url")
+
+	svc.MediaBaseURL = ""
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `<enclosure url="http://example.com/yt/media/file1.mp3"`, "fallback to root url")
+}
+
+func TestService_RSSFeedEnclosureMIME(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3"},
+				{ChannelID: channelID, VideoID: "vid2", Title: "title2", File: "/tmp/file2.M4A"},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3" length="0" type="audio/mpeg"></enclosure>`)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file2.M4A" length="0" type="audio/mp4"></enclosure>`)
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", EnclosureMIME: "audio/x-m4a"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3" length="0" type="audio/x-m4a"></enclosure>`)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file2.M4A" length="0" type="audio/x-m4a"></enclosure>`)
+}
+
+func TestEnclosureType(t *testing.T) {
+	tbl := []struct {
+		file, override, mime string
+		mode                 FeedMode
+	}{
+		{"/srv/file.mp3", "", "audio/mpeg", ""},
+		{"/srv/file.m4a", "", "audio/mp4", ""},
+		{"/srv/file.opus", "", "audio/ogg", ""},
+		{"/srv/file.webm", "", "audio/webm", ""},
+		{"/srv/file", "", "audio/mpeg", ""},
+		{"/srv/file.bin", "", "audio/mpeg", ""},
+		{"/srv/file.mp3", "audio/x-mp3", "audio/x-mp3", ""},
+		{"/srv/file.opus", "audio/opus", "audio/opus", ""},
+		{"/srv/file.mp4", "", "video/mp4", ModeVideo},
+		{"/srv/file.MP4", "", "video/mp4", ""},
+		{"/srv/file", "", "video/mp4", ModeVideo},
+		{"/srv/file.mp4", "video/x-m4v", "video/x-m4v", ModeVideo},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.mime, enclosureType(tt.file, FeedInfo{EnclosureMIME: tt.override, Mode: tt.mode}), tt.file)
+	}
+	assert.Equal(t, "", MediaType("/srv/file.vtt"))
+}
+
+func TestService_RSSFeedSince(t *testing.T) {
+	ts := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid3", Title: "title3", File: "/tmp/file3.mp3", Published: ts.Add(2 * time.Hour)},
+				{ChannelID: channelID, VideoID: "vid2", Title: "title2", File: "/tmp/file2.mp3", Published: ts.Add(time.Hour)},
+				{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3", Published: ts},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+	fi := FeedInfo{ID: "channel1", Name: "name1"}
+
+	res, err := svc.RSSFeed(fi, RSSFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(res, "<item>"), "no cutoff")
+
+	res, err = svc.RSSFeed(fi, RSSFilter{Since: ts})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(res, "<item>"))
+	assert.Contains(t, res, "<guid>channel1::vid3</guid>")
+	assert.Contains(t, res, "<guid>channel1::vid2</guid>")
+
+	res, err = svc.RSSFeed(fi, RSSFilter{Since: ts.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 0, strings.Count(res, "<item>"), "nothing new")
+	assert.Contains(t, res, "<title>name1</title>", "still valid feed")
+}
+
+func TestService_RSSFeedMinDuration(t *testing.T) {
+	ts := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid4", Title: "full", File: "/tmp/file4.mp3", Published: ts.Add(3 * time.Hour),
+					Duration: 3600},
+				{ChannelID: channelID, VideoID: "vid3", Title: "clip", File: "/tmp/file3.mp3", Published: ts.Add(2 * time.Hour),
+					Duration: 300},
+				{ChannelID: channelID, VideoID: "vid2", Title: "unknown", File: "/tmp/file2.mp3", Published: ts.Add(time.Hour)},
+				{ChannelID: channelID, VideoID: "vid1", Title: "exact", File: "/tmp/file1.mp3", Published: ts, Duration: 1200},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+	fi := FeedInfo{ID: "channel1", Name: "name1"}
+
+	res, err := svc.RSSFeed(fi, RSSFilter{MinDuration: 20 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(res, "<item>"))
+	assert.NotContains(t, res, "<guid>channel1::vid3</guid>", "shorter")
+	assert.Contains(t, res, "<guid>channel1::vid2</guid>", "unknown duration kept")
+
+	res, err = svc.RSSFeed(fi, RSSFilter{MinDuration: 20 * time.Minute, Since: ts})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(res, "<item>"), "combined with since")
+	assert.Contains(t, res, "<guid>channel1::vid4</guid>")
+	assert.Contains(t, res, "<guid>channel1::vid2</guid>")
+}
+
+func TestService_RSSFeedStoredSize(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.mp3"), []byte("some data"), 0o600))
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid1", File: filepath.Join(dir, "file1.mp3"), FileSize: 12345},
+				{ChannelID: "channel1", VideoID: "vid2", File: filepath.Join(dir, "file2.mp3"), FileSize: 67890},
+				{ChannelID: "channel1", VideoID: "vid3", File: filepath.Join(dir, "file3.mp3")},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3" length="9"`, "live size")
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file2.mp3" length="67890"`, "stored size, stat failed")
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file3.mp3" length="0"`, "no stored size")
+}
+
+func TestService_Subtitles(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid3", Title: "title3", Published: time.Now()},
+			}, nil
+		},
+	}
+	dir := t.TempDir()
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content of "+id), 0o600)
+		},
+		SubtitlesFunc: func(ctx context.Context, id, fname, lang string) (string, error) {
+			switch id {
+			case "vid1":
+				file := filepath.Join(dir, fname+".vtt")
+				return file, os.WriteFile(file, []byte("WEBVTT"), 0o600)
+			case "vid2":
+				return "", ytfeed.ErrSkip
+			}
+			return "", errors.New("failed")
+		},
+	}
+
+	tmpfile := filepath.Join(t.TempDir(), "test-subs.db")
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds: []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, Language: "de-DE",
+			Subtitles: "auto"}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost:8080/yt",
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, st.Added, "missing subtitles don't fail download")
+	require.Equal(t, 3, len(downloader.SubtitlesCalls()))
+	assert.Equal(t, "de", downloader.SubtitlesCalls()[0].Lang, "auto uses feed's language")
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+	var withSubs ytfeed.Entry
+	for _, e := range res {
+		if e.VideoID == "vid1" {
+			withSubs = e
+			continue
+		}
+		assert.Empty(t, e.Subtitles, e.VideoID)
+	}
+	assert.Equal(t, strings.TrimSuffix(withSubs.File, ".mp3")+".vtt", withSubs.Subtitles)
+
+	rss, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+	assert.Contains(t, rss, fmt.Sprintf(`<podcast:transcript url="http://localhost:8080/yt/%s" type="text/vtt" language="de-DE"></podcast:transcript>`,
+		filepath.Base(withSubs.Subtitles)))
+	assert.Equal(t, 1, strings.Count(rss, "<podcast:transcript"))
+
+	removeCompanions(withSubs.File)
+	_, err = os.Stat(withSubs.Subtitles)
+	assert.True(t, os.IsNotExist(err), "subtitles removed")
+	removeCompanions(withSubs.File) // no error for missing subtitles
+}
+
+func TestService_PodcastChapters(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+			}
+			res[0].Media.Description = "0:00 Intro\n1:30 Main topic\n10:00 Outro"
+			res[1].Media.Description = "no chapters"
+			return res, nil
+		},
+	}
+	dir := t.TempDir()
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, id+".mp3")
+			return file, os.WriteFile(file, []byte("content of "+id), 0o600)
+		},
+	}
+
+	tmpfile := filepath.Join(t.TempDir(), "test-chapters.db")
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RootURL:         "http://localhost:8080/yt",
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1200 }},
+	}
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res))
+	byID := map[string]ytfeed.Entry{}
+	for _, e := range res {
+		byID[e.VideoID] = e
+	}
+	assert.Equal(t, []ytfeed.Chapter{{Title: "Intro", Start: 0, End: 90 * time.Second},
+		{Title: "Main topic", Start: 90 * time.Second, End: 10 * time.Minute}, {Title: "Outro", Start: 10 * time.Minute}},
+		byID["vid1"].Chapters)
+	assert.Empty(t, byID["vid2"].Chapters)
+
+	data, err := os.ReadFile(filepath.Join(dir, "vid1.chapters.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version": "1.2.0", "chapters": [{"startTime": 0, "endTime": 90, "title": "Intro"},
+		{"startTime": 90, "endTime": 600, "title": "Main topic"}, {"startTime": 600, "title": "Outro"}]}`, string(data))
+	_, err = os.Stat(filepath.Join(dir, "vid2.chapters.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	rss, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, rss, `xmlns:podcast="https://podcastindex.org/namespace/1.0"`)
+	assert.Contains(t, rss, `<podcast:chapters url="http://localhost:8080/yt/vid1.chapters.json" type="application/json+chapters"></podcast:chapters>`)
+	assert.Equal(t, 1, strings.Count(rss, "<podcast:chapters"), "no chapters tag for vid2")
+
+	removeCompanions(byID["vid1"].File)
+	_, err = os.Stat(filepath.Join(dir, "vid1.chapters.json"))
+	assert.True(t, os.IsNotExist(err), "chapters removed")
+}
+
+func TestService_RSSFeedLanguage(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid1", File: "/tmp/file1.mp3", Language: "de"},
+				{ChannelID: "channel1", VideoID: "vid2", File: "/tmp/file2.mp3"},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, Language: "en-us"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `xmlns:dc="http://purl.org/dc/elements/1.1/"`)
+	assert.Contains(t, res, `<language>en-us</language>`)
+	assert.Contains(t, res, `<dc:language>de</dc:language>`, "entry's language")
+	assert.Contains(t, res, `<dc:language>en-us</dc:language>`, "fallback to feed's language")
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(res, `<dc:language>`))
+}
+
+func TestService_RSSFeedOriginalDate(t *testing.T) {
+	orig := time.Date(2022, 4, 6, 10, 20, 30, 0, time.UTC)
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid1", File: "/tmp/file1.mp3", Published: time.Now(), OriginalPublished: orig},
+				{ChannelID: "channel1", VideoID: "vid2", File: "/tmp/file2.mp3", Published: time.Now()},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, OriginalDate: true}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `xmlns:dc="http://purl.org/dc/elements/1.1/"`)
+	assert.Contains(t, res, `<dc:date>2022-04-06T10:20:30Z</dc:date>`)
+	assert.Equal(t, 1, strings.Count(res, `<dc:date>`), "no date for entry without original published time")
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}, RSSFilter{})
+	require.NoError(t, err)
+	assert.NotContains(t, res, `<dc:date>`, "disabled")
+}
+
+func TestService_RSSFeedMediaRSS(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			e1 := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", File: "/tmp/file1.mp3", Published: time.Now(),
+				Duration: 1234, FileSize: 5678}
+			e1.Media.Thumbnail.URL = "https://i.ytimg.com/vi/vid1/hqdefault.jpg"
+			e2 := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", File: "/tmp/file2.m4a", Published: time.Now()}
+			return []ytfeed.Entry{e1, e2}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, MediaRSS: true}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, `xmlns:media="http://search.yahoo.com/mrss/"`)
+	assert.Contains(t, res, `<media:content url="http://localhost:8080/yt/file1.mp3" fileSize="5678" type="audio/mpeg" `+
+		`medium="audio" duration="1234"></media:content>`)
+	assert.Contains(t, res, `<media:thumbnail url="https://i.ytimg.com/vi/vid1/hqdefault.jpg"></media:thumbnail>`)
+	assert.Contains(t, res, `<media:content url="http://localhost:8080/yt/file2.m4a" type="audio/mp4" medium="audio">`+
+		`</media:content>`, "no size and duration attributes if unknown")
+	assert.Equal(t, 2, strings.Count(res, `<media:thumbnail `), "channel's and vid1's thumbnails, none for vid2")
+	assert.Equal(t, 2, strings.Count(res, `<enclosure `), "enclosures kept")
+
+	res, err = svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel}, RSSFilter{})
+	require.NoError(t, err)
+	assert.NotContains(t, res, `<media:content`, "disabled")
+	assert.Equal(t, 1, strings.Count(res, `<media:thumbnail `), "channel's thumbnail only")
+}
+
+func TestReadInfo(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file1.mp3")
+	assert.Equal(t, downloadInfo{}, readInfo(file), "no info json")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.info.json"), []byte(`{"id":"vid1","language":"es",
+		"chapters":[{"start_time":0.0,"end_time":90.5,"title":"Intro"},{"start_time":90.5,"end_time":300.0,"title":"Main"}]}`), 0o600))
+	info := readInfo(file)
+	assert.Equal(t, "es", info.Language)
+	assert.Equal(t, []ytfeed.Chapter{{Title: "Intro", Start: 0, End: 90500 * time.Millisecond},
+		{Title: "Main", Start: 90500 * time.Millisecond, End: 300 * time.Second}}, info.chapters())
+	_, err := os.Stat(filepath.Join(dir, "file1.info.json"))
+	assert.True(t, os.IsNotExist(err), "info json removed")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.tmp.info.json"), []byte(`{"id":"vid1","language":"pt"}`), 0o600))
+	info = readInfo(file)
+	assert.Equal(t, "pt", info.Language)
+	assert.Empty(t, info.chapters())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.info.json"), []byte(`{"id":"vid1","language":null}`), 0o600))
+	assert.Equal(t, "", readInfo(file).Language)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.info.json"), []byte(`bad json`), 0o600))
+	assert.Equal(t, downloadInfo{}, readInfo(file))
+}
+
+func TestService_SplitChapters(t *testing.T) {
+	descr := map[string]string{
+		"vid1": "about\n0:00 Intro\n10:00 Main topic\n25:00 Outro", // split to 3 chapters
+		"vid2": "no chapters",
+		"vid3": "0:00 Intro\n10:00 Main topic", // split failed
+	}
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid3", Title: "title3", Published: time.Now()},
+			}
+			for i := range res {
+				res[i].Media.Description = template.HTML(descr[res[i].VideoID]) // nolint
+			}
+			return res, nil
+		},
+	}
+	dir := t.TempDir()
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, id+".mp3")
+			return file, os.WriteFile(file, []byte("content of "+id), 0o600)
+		},
+	}
+	splitter := &mocks.SplitterServiceMock{
+		SplitFunc: func(ctx context.Context, src, dst string, start, end time.Duration) error {
+			if strings.HasSuffix(src, "vid3.mp3") && start > 0 {
+				return errors.New("ffmpeg failed")
+			}
+			return os.WriteFile(dst, []byte(fmt.Sprintf("%s %v-%v", src, start, end)), 0o600)
+		},
+	}
+
+	tmpfile := filepath.Join(t.TempDir(), "test-split.db")
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, SplitChapters: true}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1800 }},
+		Splitter:        splitter,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, st.Added)
+
+	require.Equal(t, 5, len(splitter.SplitCalls()), "3 chapters of vid1, 2 attempts for vid3")
+	assert.Equal(t, filepath.Join(dir, "vid1-02.mp3"), splitter.SplitCalls()[1].Dst)
+	assert.Equal(t, 10*time.Minute, splitter.SplitCalls()[1].Start)
+	assert.Equal(t, 25*time.Minute, splitter.SplitCalls()[1].End)
+	assert.Equal(t, time.Duration(0), splitter.SplitCalls()[2].End, "last chapter till the end")
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(res))
+	byID := map[string]ytfeed.Entry{}
+	for _, e := range res {
+		byID[e.VideoID] = e
+	}
+	assert.Equal(t, "name1: title1 - Intro", byID["vid1-ch01"].Title)
+	assert.Equal(t, "name1: title1 - Main topic", byID["vid1-ch02"].Title)
+	assert.Equal(t, "name1: title1 - Outro", byID["vid1-ch03"].Title)
+	assert.Equal(t, filepath.Join(dir, "vid1-03.mp3"), byID["vid1-ch03"].File)
+	assert.True(t, byID["vid1-ch02"].Published.After(byID["vid1-ch01"].Published), "chapters order kept")
+	assert.Equal(t, filepath.Join(dir, "vid2.mp3"), byID["vid2"].File, "no chapters, single episode")
+	assert.Equal(t, filepath.Join(dir, "vid3.mp3"), byID["vid3"].File, "split failed, single episode")
+
+	_, err = os.Stat(filepath.Join(dir, "vid1.mp3"))
+	assert.True(t, os.IsNotExist(err), "original file removed after split")
+	_, err = os.Stat(filepath.Join(dir, "vid3-01.mp3"))
+	assert.True(t, os.IsNotExist(err), "parts removed after failed split")
+	_, err = os.Stat(filepath.Join(dir, "vid3.mp3"))
+	assert.NoError(t, err)
+
+	found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"})
+	require.NoError(t, err)
+	assert.True(t, found, "original entry processed")
+}
+
+func TestService_FetchErrors(t *testing.T) {
+	failing := true
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			if chanID == "channel2" && failing {
+				return nil, errors.New("auth error")
+			}
+			return []ytfeed.Entry{}, nil
+		},
+	}
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel},
+		},
+		ChannelService: chans,
+		Store:          &store.BoltDB{DB: db},
+		KeepPerChannel: 10,
+	}
+	assert.Equal(t, map[string]ytfeed.FetchError{}, svc.FetchErrors())
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	res := svc.FetchErrors()
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "auth error", res["channel2"].Error)
+	assert.True(t, time.Since(res["channel2"].TS) < time.Minute)
+
+	failing = false
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ytfeed.FetchError{}, svc.FetchErrors(), "cleared on successful fetch")
+}
+
+func TestService_pruneProcessed(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		PruneProcessedFunc: func(maxAge time.Duration) (int, error) { return 5, nil },
+		LoadFunc:           func(channelID string, max int) ([]ytfeed.Entry, error) { return nil, nil },
+		RemoveOldFunc:      func(channelID string, keep int) ([]string, error) { return nil, nil },
+		CountBytesFunc:     func(channelID string) int64 { return 0 },
+		CountProcessedFunc: func() int { return 0 },
+		SetFetchErrorFunc:  func(channelID string, fe ytfeed.FetchError) error { return nil },
+	}
+	svc := Service{Store: storeSvc, ProcessedMaxAge: 24 * time.Hour}
+	svc.pruneProcessed()
+	svc.ProcessedMaxAge = 60 * 24 * time.Hour
+	svc.pruneProcessed()
+	require.Equal(t, 2, len(storeSvc.PruneProcessedCalls()))
+	assert.Equal(t, minProcessedMaxAge, storeSvc.PruneProcessedCalls()[0].MaxAge, "raised to min")
+	assert.Equal(t, 60*24*time.Hour, storeSvc.PruneProcessedCalls()[1].MaxAge)
+
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{}, nil
+		},
+	}
+	svc = Service{Feeds: []FeedInfo{{ID: "channel1", Name: "name1"}}, ChannelService: chans, Store: storeSvc,
+		CheckDuration: 50 * time.Millisecond, ProcessedMaxAge: 60 * 24 * time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	assert.EqualError(t, svc.Do(ctx), "context deadline exceeded")
+	assert.True(t, len(chans.GetCalls()) > 2)
+	assert.Equal(t, 3, len(storeSvc.PruneProcessedCalls()), "pruned once a day, on the first cycle only")
+}
+
+func TestService_Trim(t *testing.T) {
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			entry := ytfeed.Entry{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()}
+			entry.Media.Description = "0:00 Intro\n1:00 Part one\n20:00 Part two"
+			return []ytfeed.Entry{entry}, nil
+		},
+	}
+	dir := t.TempDir()
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, id+".mp3")
+			return file, os.WriteFile(file, []byte("original content of "+id), 0o600)
+		},
+	}
+	splitter := &mocks.SplitterServiceMock{
+		SplitFunc: func(ctx context.Context, src, dst string, start, end time.Duration) error {
+			return os.WriteFile(dst, []byte("trimmed"), 0o600)
+		},
+	}
+	duration := &mocks.DurationServiceMock{FileFunc: func(fname string) int {
+		data, err := os.ReadFile(fname) // nolint
+		require.NoError(t, err)
+		if string(data) == "trimmed" {
+			return 1800 - 90 - 30
+		}
+		return 1800
+	}}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+	svc := Service{
+		Feeds: []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, SubDir: ".",
+			TrimStart: 90 * time.Second, TrimEnd: 30 * time.Second}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		DurationService: duration,
+		Splitter:        splitter,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added)
+
+	require.Equal(t, 1, len(splitter.SplitCalls()))
+	call := splitter.SplitCalls()[0]
+	assert.Equal(t, filepath.Join(dir, "vid1.mp3"), call.Src)
+	assert.Equal(t, filepath.Join(dir, "vid1.trim.mp3"), call.Dst)
+	assert.Equal(t, 90*time.Second, call.Start)
+	assert.Equal(t, 1770*time.Second, call.End)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, 1680, res[0].Duration, "duration of trimmed file")
+	assert.Equal(t, int64(len("trimmed")), res[0].FileSize, "size of trimmed file")
+	require.Equal(t, 2, len(res[0].Chapters), "intro trimmed, chapters shifted")
+	assert.Equal(t, "Part one", res[0].Chapters[0].Title)
+	assert.Equal(t, time.Duration(0), res[0].Chapters[0].Start)
+	assert.Equal(t, 1110*time.Second, res[0].Chapters[1].Start)
+
+	data, err := os.ReadFile(filepath.Join(dir, "vid1.mp3"))
+	require.NoError(t, err)
+	assert.Equal(t, "trimmed", string(data), "file replaced")
+	_, err = os.Stat(filepath.Join(dir, "vid1.trim.mp3"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestService_trimFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "vid1.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("original"), 0o600))
+	splitter := &mocks.SplitterServiceMock{
+		SplitFunc: func(ctx context.Context, src, dst string, start, end time.Duration) error {
+			if start == 5*time.Second {
+				return errors.New("ffmpeg failed")
+			}
+			return os.WriteFile(dst, []byte("trimmed"), 0o600)
+		},
+	}
+	svc := Service{Splitter: splitter, DurationService: &mocks.DurationServiceMock{FileFunc: func(string) int { return 60 }}}
+
+	trimmed, err := svc.trimFile(context.Background(), file, FeedInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), trimmed)
+	assert.Equal(t, 0, len(splitter.SplitCalls()), "no-op without trim")
 
-	require.Equal(t, 4, len(chans.GetCalls()))
-	assert.Equal(t, "channel1", chans.GetCalls()[0].ChanID)
-	assert.Equal(t, ytfeed.FTChannel, chans.GetCalls()[0].FeedType)
-	assert.Equal(t, "channel2", chans.GetCalls()[1].ChanID)
-	assert.Equal(t, ytfeed.FTChannel, chans.GetCalls()[1].FeedType)
-	assert.Equal(t, "channel1", chans.GetCalls()[2].ChanID)
-	assert.Equal(t, "channel2", chans.GetCalls()[3].ChanID)
+	_, err = svc.trimFile(context.Background(), file, FeedInfo{TrimStart: 40 * time.Second, TrimEnd: 20 * time.Second})
+	assert.EqualError(t, err, file+" is too short (1m0s) to trim")
 
-	res, err := boltStore.Load("channel1", 10)
+	_, err = svc.trimFile(context.Background(), file, FeedInfo{TrimStart: 5 * time.Second})
+	assert.EqualError(t, err, "failed to trim "+file+": ffmpeg failed")
+	data, err := os.ReadFile(file) // nolint
 	require.NoError(t, err)
-	assert.Equal(t, 1, len(res), "one entry for channel1, skipped irrelevant ones")
-	assert.Equal(t, "vid2", res[0].VideoID)
+	assert.Equal(t, "original", string(data), "kept on error")
 
-	res, err = boltStore.Load("channel2", 10)
+	trimmed, err = svc.trimFile(context.Background(), file, FeedInfo{TrimEnd: 10 * time.Second})
 	require.NoError(t, err)
-	assert.Equal(t, 2, len(res), "two entries for channel2, skipped irrelevant one")
-	assert.Equal(t, "vid2", res[0].VideoID)
-	assert.Equal(t, "vid1", res[1].VideoID)
+	assert.Equal(t, time.Duration(0), trimmed, "nothing trimmed from the start")
+	require.Equal(t, 2, len(splitter.SplitCalls()))
+	assert.Equal(t, time.Duration(0), splitter.SplitCalls()[1].Start)
+	assert.Equal(t, 50*time.Second, splitter.SplitCalls()[1].End)
+	data, err = os.ReadFile(file) // nolint
+	require.NoError(t, err)
+	assert.Equal(t, "trimmed", string(data))
+
+	svc = Service{DurationService: &mocks.DurationServiceMock{FileFunc: func(string) int { return 0 }}}
+	_, err = svc.trimFile(context.Background(), file, FeedInfo{TrimStart: time.Second})
+	assert.EqualError(t, err, "splitter not set")
+	svc.Splitter = splitter
+	_, err = svc.trimFile(context.Background(), file, FeedInfo{TrimEnd: time.Second})
+	assert.EqualError(t, err, "unknown duration of "+file)
+}
 
-	require.Equal(t, 3, len(downloader.GetCalls()))
-	require.Equal(t, "vid2", downloader.GetCalls()[0].ID)
-	require.Equal(t, "vid1", downloader.GetCalls()[1].ID)
-	require.Equal(t, "vid2", downloader.GetCalls()[2].ID)
-	require.True(t, downloader.GetCalls()[0].Fname != "")
+type fakeSigner struct{ token string }
 
-	rssData, err := os.ReadFile("/tmp/channel1.xml")
+func (f fakeSigner) Sign(fileURL string) (string, error) { return fileURL + "?token=" + f.token, nil }
+
+func TestService_RSSFeedSigned(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: "/tmp/" + channelID + "-file1.mp3"},
+			}, nil
+		},
+	}
+
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel},
+		},
+		Store:          storeSvc,
+		RootURL:        "http://localhost:8080/yt",
+		KeepPerChannel: 10,
+		URLSigners:     map[string]URLSigner{"channel1": fakeSigner{token: "secret123"}},
+	}
+
+	res, err := svc.RSSFeed(svc.Feeds[0], RSSFilter{})
 	require.NoError(t, err)
-	t.Logf("%s", string(rssData))
-	assert.Contains(t, string(rssData), "<guid>channel1::vid2</guid>")
-	assert.Contains(t, string(rssData), "<itunes:duration>1234</itunes:duration>")
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/channel1-file1.mp3?token=secret123"`)
 
-	rssData, err = os.ReadFile("/tmp/channel2.xml")
+	res, err = svc.RSSFeed(svc.Feeds[1], RSSFilter{})
 	require.NoError(t, err)
-	t.Logf("%s", string(rssData))
-	assert.Contains(t, string(rssData), "<guid>channel2::vid2</guid>")
-	assert.Contains(t, string(rssData), "<guid>channel2::vid1</guid>")
-	assert.Contains(t, string(rssData), "<itunes:duration>1234</itunes:duration>")
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/channel2-file1.mp3"`, "unsigned by default")
+}
 
-	require.Equal(t, 3, len(duration.FileCalls()))
-	assert.Equal(t, "/tmp/4308c33c7ddb107c2d0c13a905e4c6962001bab4.mp3", duration.FileCalls()[0].Fname)
-	assert.Equal(t, "/tmp/3be877c750abb87daee80c005fe87e7a3f824fed.mp3", duration.FileCalls()[1].Fname)
-	assert.Equal(t, "/tmp/648f79b3a05ececb8a37600aa0aee332f0374e01.mp3", duration.FileCalls()[2].Fname)
+func TestService_AggregateRSSStoredSize(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid2", File: "/remote/c1v2.mp3", FileSize: 777, Published: time.Now()},
+				{ChannelID: "channel1", VideoID: "vid1", File: "/remote/c1v1.mp3", Published: time.Now().Add(-time.Hour)},
+			}, nil
+		},
+	}
+	svc := Service{Feeds: []FeedInfo{{ID: "channel1", Name: "name1"}}, Store: storeSvc, RootURL: "http://localhost:8080/yt",
+		KeepPerChannel: 10}
+
+	res, err := svc.AggregateRSS(0)
+	require.NoError(t, err)
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/c1v2.mp3" length="777"`, "file in remote storage")
+	assert.NotContains(t, res, "<guid>channel1::vid1</guid>", "no file and no stored size")
 }
 
 // nolint:dupl // test if very similar to TestService_RSSFeed
-func TestService_RSSFeed(t *testing.T) {
+func TestService_AggregateRSS(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"c1v1.mp3", "c1v2.mp3", "c2v1.mp3", "c2v2.mp3"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte("data"), 0o600))
+	}
+	ts := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
 	storeSvc := &mocks.StoreServiceMock{
 		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
-			res := []ytfeed.Entry{
-				{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: "/tmp/file1.mp3"},
-				{ChannelID: "channel1", VideoID: "vid2", Title: "title2", File: "/tmp/file2.mp3"},
+			if channelID == "channel1" {
+				res := []ytfeed.Entry{
+					{ChannelID: "channel1", VideoID: "vid2", File: filepath.Join(dir, "c1v2.mp3"), Published: ts.Add(3 * time.Hour)},
+					{ChannelID: "channel1", VideoID: "vid1", File: filepath.Join(dir, "c1v1.mp3"), Published: ts},
+				}
+				res[0].Author.Name = "author1"
+				return res, nil
 			}
-			res[0].Link.Href = "http://example.com/v1"
-			res[1].Link.Href = "http://example.com/v2"
-			res[0].Author.URI = "http://example.com/c1"
-			res[0].Media.Thumbnail.URL = "http://example.com/thumb.jpg"
-			return res, nil
+			return []ytfeed.Entry{
+				{ChannelID: "channel2", VideoID: "vid3", File: filepath.Join(dir, "missing.mp3"), Published: ts.Add(4 * time.Hour)},
+				{ChannelID: "channel2", VideoID: "vid2", File: filepath.Join(dir, "c2v2.mp3"), Published: ts.Add(2 * time.Hour)},
+				{ChannelID: "channel2", VideoID: "vid1", File: filepath.Join(dir, "c2v1.mp3"), Published: ts.Add(time.Hour)},
+			}, nil
 		},
 	}
 
@@ -229,25 +1965,100 @@ func TestService_RSSFeed(t *testing.T) {
 		Store:          storeSvc,
 		RootURL:        "http://localhost:8080/yt",
 		KeepPerChannel: 10,
-		SkipShorts:     time.Second * 60,
 	}
 
-	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel})
+	res, err := svc.AggregateRSS(0)
 	require.NoError(t, err)
 	t.Logf("%v", res)
+	guids := regexp.MustCompile(`<guid>(.*)</guid>`).FindAllStringSubmatch(res, -1)
+	require.Equal(t, 4, len(guids), "missing file skipped")
+	assert.Equal(t, "channel1::vid2", guids[0][1])
+	assert.Equal(t, "channel2::vid2", guids[1][1])
+	assert.Equal(t, "channel2::vid1", guids[2][1])
+	assert.Equal(t, "channel1::vid1", guids[3][1])
+	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/c1v2.mp3" length="4" type="audio/mpeg"></enclosure>`)
+	assert.Contains(t, res, `<atom:link href="http://localhost:8080/rss/all" rel="self" type="application/rss+xml"></atom:link>`)
+	categories := regexp.MustCompile(`<category>(.*)</category>`).FindAllStringSubmatch(res, -1)
+	require.Equal(t, 4, len(categories))
+	assert.Equal(t, "name1", categories[0][1], "source channel name")
+	assert.Equal(t, "name2", categories[1][1], "source channel name")
+	authors := regexp.MustCompile(`<author>(.*)</author>`).FindAllStringSubmatch(res, -1)
+	require.Equal(t, 4, len(authors))
+	assert.Equal(t, "author1", authors[0][1], "entry's author kept")
+	assert.Equal(t, "name2", authors[1][1], "channel name if no author")
+
+	res, err = svc.AggregateRSS(2)
+	require.NoError(t, err)
+	guids = regexp.MustCompile(`<guid>(.*)</guid>`).FindAllStringSubmatch(res, -1)
+	require.Equal(t, 2, len(guids), "capped to 2 items")
+	assert.Equal(t, "channel1::vid2", guids[0][1])
+	assert.Equal(t, "channel2::vid2", guids[1][1])
 
-	assert.Contains(t, res, `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">`)
-	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3"`)
-	assert.Contains(t, res, `<enclosure url="http://localhost:8080/yt/file1.mp3"`)
-	assert.Contains(t, res, `<guid>channel1::vid1</guid>`)
-	assert.Contains(t, res, `<guid>channel1::vid2</guid>`)
-	assert.NotContains(t, res, `<guid>channel1::vid3</guid>`, "skipped short video")
-	assert.Contains(t, res, `<link>http://example.com/v1</link>`)
-	assert.Contains(t, res, `<link>http://example.com/v2</link>`)
-	assert.Contains(t, res, `<link>http://example.com/c1</link>`)
-	assert.Contains(t, res, `<itunes:image href="http://example.com/thumb.jpg"></itunes:image>`)
-	assert.Contains(t, res, `<media:thumbnail url="http://example.com/thumb.jpg"></media:thumbnail>`)
+	svc.Feeds[0].BasicAuth = BasicAuth{User: "user", Passwd: "passwd"}
+	res, err = svc.AggregateRSS(0)
+	require.NoError(t, err)
+	guids = regexp.MustCompile(`<guid>(.*)</guid>`).FindAllStringSubmatch(res, -1)
+	require.Equal(t, 2, len(guids), "private channel1 excluded")
+	assert.Equal(t, "channel2::vid2", guids[0][1])
+}
+
+func TestService_RegenerateAll(t *testing.T) {
+	dir, rssDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c1v1.mp3"), []byte("data"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c2v1.mp3"), []byte("data"), 0o600))
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			switch channelID {
+			case "channel1":
+				return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", Title: "title1", File: filepath.Join(dir, "c1v1.mp3")}}, nil
+			case "channel2":
+				return []ytfeed.Entry{{ChannelID: channelID, VideoID: "vid1", Title: "title2", File: filepath.Join(dir, "c2v1.mp3")}}, nil
+			case "channel3":
+				return nil, nil
+			}
+			return nil, errors.New("no bucket for " + channelID)
+		},
+	}
+	// stale rss files, should be rewritten
+	require.NoError(t, os.WriteFile(filepath.Join(rssDir, "channel1.xml"), []byte("stale"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(rssDir, "channel2.xml"), []byte("stale"), 0o600))
+
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel, DescriptionTmpl: "new template {{.VideoID}}"},
+			{ID: "channel3", Name: "name3", Type: ytfeed.FTChannel},
+		},
+		Store:          storeSvc,
+		RootURL:        "http://localhost:8080/yt",
+		KeepPerChannel: 10,
+		RSSFileStore:   RSSFileStore{Enabled: true, Location: rssDir},
+	}
 
+	count, err := svc.RegenerateAll()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "feed without entries skipped")
+
+	data, err := os.ReadFile(filepath.Join(rssDir, "channel1.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<guid>channel1::vid1</guid>")
+	assert.Contains(t, string(data), "<title>name1</title>")
+	data, err = os.ReadFile(filepath.Join(rssDir, "channel2.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<guid>channel2::vid1</guid>")
+	assert.Contains(t, string(data), "new template vid1")
+	_, err = os.Stat(filepath.Join(rssDir, "channel3.xml"))
+	assert.True(t, os.IsNotExist(err), "no rss for empty feed")
+
+	// failed feed doesn't stop the rest
+	svc.Feeds = append([]FeedInfo{{ID: "bad", Name: "bad"}}, svc.Feeds...)
+	require.NoError(t, os.Remove(filepath.Join(rssDir, "channel1.xml")))
+	count, err = svc.RegenerateAll()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to generate rss for bad")
+	assert.Equal(t, 2, count)
+	_, err = os.Stat(filepath.Join(rssDir, "channel1.xml"))
+	assert.NoError(t, err)
 }
 
 // nolint:dupl // test if very similar to TestService_RSSFeed
@@ -275,7 +2086,7 @@ func TestService_RSSFeedPlayList(t *testing.T) {
 		KeepPerChannel: 10,
 	}
 
-	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTPlaylist})
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1", Type: ytfeed.FTPlaylist}, RSSFilter{})
 	require.NoError(t, err)
 	t.Logf("%v", res)
 
@@ -316,13 +2127,211 @@ func TestService_makeFileName(t *testing.T) {
 		},
 	}
 
+	svc := Service{FileNameHash: "sha1"}
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.res, svc.makeFileName(tt.entry))
+		})
+	}
+
+	svc = Service{}
+	assert.Equal(t, "19ab8246c87333b5080cd735dcebfad8c8a6c7622a2568aec633f70cf504bf17",
+		svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}), "sha256 by default")
+	assert.Equal(t, "19ab8246c87333b5080cd735dcebfad8c8a6c7622a2568aec633f70cf504bf17.mp3",
+		svc.mediaFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}), "with extension")
+}
+
+func TestService_makeFileNameHashLen(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			// vid9 took the truncated name of vid1
+			return []ytfeed.Entry{{ChannelID: "channel1", VideoID: "vid9", File: "/tmp/yt/19ab8246c8733.mp3"}}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, Feeds: []FeedInfo{{ID: "channel1"}}, FileNameHashLen: 12}
+	assert.Equal(t, "267a51e3063d", svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"}))
+	assert.Equal(t, "267a51e3063d.mp3", svc.mediaFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"}))
+
+	svc.FileNameHashLen = 13
+	assert.Equal(t, "19ab8246c87333b5080cd735dc", svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}),
+		"collision, extended")
+	assert.Equal(t, "79bd327c52162", svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid9"}),
+		"no collision")
+
+	svc.FileNameHashLen = 100
+	assert.Equal(t, "19ab8246c87333b5080cd735dcebfad8c8a6c7622a2568aec633f70cf504bf17",
+		svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}), "limited by hash size")
+
+	svc.FileNameHash = "sha1"
+	svc.FileNameHashLen = 8
+	assert.Equal(t, "4308c33c", svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"}))
+	assert.Equal(t, 1, len(storeSvc.LoadCalls()), "stored entries loaded once, until the next cycle")
+
+	svc.resetFileNames()
+	svc.FileNameHashLen = 0
+	assert.Equal(t, "4308c33c7ddb107c2d0c13a905e4c6962001bab4", svc.makeFileName(ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2"}))
+	assert.Equal(t, 1, len(storeSvc.LoadCalls()), "no collision check for full hash")
+}
+
+func TestService_FileFeed(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			if channelID == "channel1" {
+				return []ytfeed.Entry{{ChannelID: "channel1", VideoID: "vid1", File: "/srv/yt/flat1.mp3"}}, nil
+			}
+			return []ytfeed.Entry{{ChannelID: "channel2", VideoID: "vid2", File: "/srv/yt/channel2/file2.mp3"}}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, FilesLocation: "/srv/yt",
+		Feeds: []FeedInfo{{ID: "channel1", Name: "name1", SubDir: "."}, {ID: "channel2", Name: "name2"}}}
+
+	fi, ok := svc.FileFeed("/flat1.mp3")
+	require.True(t, ok)
+	assert.Equal(t, "name1", fi.Name)
+	fi, ok = svc.FileFeed("/flat1.vtt")
+	require.True(t, ok, "subtitles of the media file")
+	assert.Equal(t, "channel1", fi.ID)
+	_, ok = svc.FileFeed("/flat1.chapters.json")
+	assert.True(t, ok, "chapters of the media file")
+	fi, ok = svc.FileFeed("/channel2/file2.mp3")
+	require.True(t, ok)
+	assert.Equal(t, "channel2", fi.ID)
+	_, ok = svc.FileFeed("/file2.mp3")
+	assert.False(t, ok, "not in the feed's dir")
+	_, ok = svc.FileFeed("/unknown.mp3")
+	assert.False(t, ok)
+
+	svc.addFileName(ytfeed.Entry{ChannelID: "channel2", VideoID: "vid3", File: "/srv/yt/flat3.mp3"})
+	fi, ok = svc.FileFeed("/flat3.mp3")
+	require.True(t, ok, "saved entry added")
+	assert.Equal(t, "channel2", fi.ID)
+	assert.Equal(t, 2, len(storeSvc.LoadCalls()), "stored entries of each feed loaded once")
+}
+
+func TestService_existingFile(t *testing.T) {
+	dir := t.TempDir()
+	svc := Service{FilesLocation: dir, FileNameHashLen: 16}
+
+	entry, fi := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}, FeedInfo{ID: "channel1"}
+	assert.Equal(t, "", svc.existingFile(entry, fi), "nothing downloaded")
+
+	legacy := filepath.Join(dir, "e4650bb3d770eed60faad7ffbed5f33ffb1b89fa.mp3")
+	require.NoError(t, os.WriteFile(legacy, []byte("data"), 0o600))
+	assert.Equal(t, legacy, svc.existingFile(entry, fi), "legacy name")
+
+	current := filepath.Join(dir, "19ab8246c87333b5.mp3")
+	require.NoError(t, os.WriteFile(current, []byte("data"), 0o600))
+	assert.Equal(t, current, svc.existingFile(entry, fi), "current name preferred")
+
+	inSubDir := filepath.Join(dir, "channel1", "19ab8246c87333b5.mp3")
+	require.NoError(t, os.MkdirAll(filepath.Dir(inSubDir), 0o750))
+	require.NoError(t, os.WriteFile(inSubDir, []byte("data"), 0o600))
+	assert.Equal(t, inSubDir, svc.existingFile(entry, fi), "feed's sub dir preferred")
+	assert.Equal(t, current, svc.existingFile(entry, FeedInfo{ID: "channel1", SubDir: "."}), "no sub dir")
+	require.NoError(t, os.Remove(inSubDir))
+
+	require.NoError(t, os.WriteFile(current, nil, 0o600))
+	assert.Equal(t, legacy, svc.existingFile(entry, fi), "empty file ignored")
+
+	assert.Equal(t, "", (&Service{}).existingFile(entry, fi), "no files location")
+}
+
+func TestService_subDir(t *testing.T) {
 	svc := Service{}
+	assert.Equal(t, "channel1", svc.subDir(FeedInfo{ID: "channel1"}))
+	assert.Equal(t, "joinpeertube_framatube.org", svc.subDir(FeedInfo{ID: "joinpeertube@framatube.org"}))
+	assert.Equal(t, "podcasts/news", svc.subDir(FeedInfo{ID: "channel1", SubDir: "podcasts/news/"}))
+	assert.Equal(t, ".", svc.subDir(FeedInfo{ID: "channel1", SubDir: "."}))
+
+	entry := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1"}
+	assert.Equal(t, filepath.Join("channel1", svc.makeFileName(entry)), svc.feedFileName(entry, FeedInfo{ID: "channel1"}))
+	assert.Equal(t, svc.makeFileName(entry), svc.feedFileName(entry, FeedInfo{ID: "channel1", SubDir: "."}))
+}
+
+func TestService_relativeFile(t *testing.T) {
+	svc := Service{FilesLocation: "/srv/yt"}
+	assert.Equal(t, "channel1/abc.mp3", svc.relativeFile("/srv/yt/channel1/abc.mp3"))
+	assert.Equal(t, "abc.mp3", svc.relativeFile("/srv/yt/abc.mp3"), "legacy flat layout")
+	assert.Equal(t, "abc.mp3", svc.relativeFile("/other/abc.mp3"), "outside of files location")
+	assert.Equal(t, "abc.mp3", (&Service{}).relativeFile("/srv/yt/channel1/abc.mp3"), "no files location")
+
+	svc = Service{FilesLocation: "var/yt"}
+	assert.Equal(t, "chan1/abc.mp3", svc.relativeFile("var/yt/chan1/abc.mp3"))
+}
+
+func TestService_makeFileNameWithTemplate(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			if channelID != "channel1" {
+				return nil, nil
+			}
+			return []ytfeed.Entry{
+				{ChannelID: "channel1", VideoID: "vid1", File: "/tmp/yt/title1-2022-04-11.mp3"},
+				{ChannelID: "channel1", VideoID: "vid5", File: "/tmp/yt/some_title-2022-04-11-vid5.mp3"},
+			}, nil
+		},
+	}
+	dt := time.Date(2022, 4, 11, 11, 35, 17, 0, time.UTC)
+
+	tbl := []struct {
+		tmpl  string
+		entry ytfeed.Entry
+		res   string
+	}{
+		{"", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "title1"}, "e4650bb3d770eed60faad7ffbed5f33ffb1b89fa"},
+		{"{{.Title}}-{{.Date}}-{{.ID}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "Some Title", Published: dt},
+			"Some_Title-2022-04-11-vid2"},
+		{"{{.Title}}-{{.Date}}-{{.ID}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "a/b\\c: \"d\" $(rm -rf) | e?", Published: dt},
+			"a_b_c_d_rm-rf_e-2022-04-11-vid2"},
+		{"{{.Title}}-{{.Date}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "Привет, мир! 😀", Published: dt},
+			"Привет_мир-2022-04-11"},
+		{"{{.Title}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "///"}, "4308c33c7ddb107c2d0c13a905e4c6962001bab4"},
+		{"{{.Title}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "title2"}, "4308c33c7ddb107c2d0c13a905e4c6962001bab4"},
+		{"{{.Title}}-{{.Hash}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "title2"}, "title2-4308c33c"},
+		// collision with the file of another entry (vid1), hash added
+		{"{{.Title}}-{{.Date}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "title1", Published: dt},
+			"title1-2022-04-11-4308c33c"},
+		// same entry (vid1) owns the file, no collision
+		{"{{.Title}}-{{.Date}}", ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "title1", Published: dt},
+			"title1-2022-04-11"},
+	}
+
 	for i, tt := range tbl {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			svc := Service{Store: storeSvc, FileNameTemplate: tt.tmpl, FileNameHash: "sha1",
+				Feeds: []FeedInfo{{ID: "channel1"}, {ID: "channel2"}}}
 			assert.Equal(t, tt.res, svc.makeFileName(tt.entry))
+			assert.Equal(t, tt.res, svc.makeFileName(tt.entry), "stable name")
+		})
+	}
+}
+
+func TestService_sanitizeFileName(t *testing.T) {
+	tbl := []struct {
+		inp    string
+		maxLen int
+		res    string
+	}{
+		{"simple", 100, "simple"},
+		{"with spaces and / slashes \\ ", 100, "with_spaces_and_slashes"},
+		{"../../etc/passwd", 100, "etc_passwd"},
+		{"-leading dash", 100, "leading_dash"},
+		{"Ünïcödé тест 日本語", 100, "Ünïcödé_тест_日本語"},
+		{"tab\tnew\nline", 100, "tab_new_line"},
+		{"1234567890", 5, "12345"},
+		{"тест", 5, "те"}, // multibyte runes not broken
+		{"abc def", 4, "abc"},
+		{"", 100, ""},
+	}
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.res, sanitizeFileName(tt.inp, tt.maxLen))
 		})
 	}
 
+	long := sanitizeFileName(strings.Repeat("я", 300), maxFileNameLen)
+	assert.True(t, len(long) <= maxFileNameLen)
+	assert.True(t, utf8.ValidString(long))
 }
 
 func TestService_update(t *testing.T) {
@@ -348,9 +2357,22 @@ func TestService_update(t *testing.T) {
 		t.Logf("%+v", res)
 		assert.Equal(t, 1234, res.Duration)
 		assert.True(t, time.Since(res.Published) < time.Second, "published time was reset")
+		assert.True(t, inpEntry.Published.Equal(res.OriginalPublished), "original published time kept")
 		assert.Equal(t, "feed1: something", res.Title)
 	}
 
+	{ // update of the old entry keeps published time
+		inpEntry := ytfeed.Entry{
+			ChannelID: "chan1",
+			VideoID:   "vid1",
+			Published: time.Now().Add(time.Hour * -48),
+			Title:     "something",
+		}
+		res := svc.update(inpEntry, "/tmp/audio.mp3", FeedInfo{ID: "f1", Name: "feed1"})
+		assert.True(t, inpEntry.Published.Equal(res.Published), "published time not reset")
+		assert.True(t, inpEntry.Published.Equal(res.OriginalPublished))
+	}
+
 	{ // update with altering title for dedup
 		inpEntry := ytfeed.Entry{
 			ChannelID: "chan1",
@@ -368,6 +2390,38 @@ func TestService_update(t *testing.T) {
 
 }
 
+func TestTitlePrefix_Apply(t *testing.T) {
+	tbl := []struct {
+		tp          TitlePrefix
+		title, name string
+		res         string
+	}{
+		{TitlePrefix{}, "something", "feed1", "feed1: something"},
+		{TitlePrefix{}, "feed1 something", "feed1", "feed1 something"},
+		{TitlePrefix{}, "something", "", "something"},
+		{TitlePrefix{Disabled: true}, "something", "feed1", "something"},
+		{TitlePrefix{Separator: " | "}, "something", "feed1", "feed1 | something"},
+		{TitlePrefix{Append: true}, "something", "feed1", "something - feed1"},
+		{TitlePrefix{Append: true, Separator: " @ "}, "something", "feed1", "something @ feed1"},
+		{TitlePrefix{Append: true}, "something by feed1", "feed1", "something by feed1"},
+		{TitlePrefix{}, "United we stand", "TED", "TED: United we stand"},
+		{TitlePrefix{}, "Talk by ted", "TED", "Talk by ted"},
+		{TitlePrefix{}, "TEDx talk", "TED", "TED: TEDx talk"},
+		{TitlePrefix{}, "(ted) talk", "TED", "(ted) talk"},
+		{TitlePrefix{}, "Живой ГВОЗДЬ - выпуск", "Живой Гвоздь", "Живой ГВОЗДЬ - выпуск"},
+		{TitlePrefix{}, "talk [TED]", "[TED]", "talk [TED]"},
+		{TitlePrefix{}, "talk x[TED]x", "[TED]", "talk x[TED]x"},
+		{TitlePrefix{}, "a.b talk", "a.b", "a.b talk"},
+		{TitlePrefix{}, "axb talk", "a.b", "a.b: axb talk"},
+	}
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.res, tt.tp.Apply(tt.title, tt.name))
+		})
+	}
+}
+
 func TestService_totalEntriesToKeep(t *testing.T) {
 	svc := Service{
 		Feeds: []FeedInfo{
@@ -379,6 +2433,167 @@ func TestService_totalEntriesToKeep(t *testing.T) {
 	}
 
 	assert.Equal(t, 15, svc.totalEntriesToKeep())
+
+	svc.Feeds = append(svc.Feeds, FeedInfo{ID: "channel3", Name: "name3", Keep: KeepAll})
+	assert.Equal(t, math.MaxInt, svc.totalEntriesToKeep())
+}
+
+func TestService_keepAll(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		RemoveOldFunc: func(channelID string, keep int) ([]string, error) {
+			return nil, nil
+		},
+	}
+	svc := Service{Store: storeSvc, KeepPerChannel: 10}
+
+	assert.Equal(t, KeepAll, svc.keep(FeedInfo{ID: "channel1", Keep: KeepAll}))
+	assert.Equal(t, 10, svc.keep(FeedInfo{ID: "channel1"}))
+	assert.Equal(t, 5, svc.keep(FeedInfo{ID: "channel1", Keep: 5}))
+
+	assert.Equal(t, 0, svc.removeOld(FeedInfo{ID: "channel1", Keep: KeepAll}))
+	assert.Equal(t, 0, len(storeSvc.RemoveOldCalls()), "store not called for keep-all feed")
+
+	assert.Equal(t, 0, svc.removeOld(FeedInfo{ID: "channel1", Keep: 5}))
+	require.Equal(t, 1, len(storeSvc.RemoveOldCalls()))
+	assert.Equal(t, 6, storeSvc.RemoveOldCalls()[0].Keep)
+}
+
+func TestService_removeOldMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{}
+	for _, vid := range []string{"vid1", "vid2", "vid3"} {
+		files[vid] = filepath.Join(dir, vid+".mp3")
+		require.NoError(t, os.WriteFile(files[vid], []byte("content"), 0o600))
+	}
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid1", File: files["vid1"], Published: time.Now().Add(-time.Hour)},
+				{ChannelID: channelID, VideoID: "vid2", File: files["vid2"], Published: time.Now().Add(-48 * time.Hour)},
+				{ChannelID: channelID, VideoID: "vid3", File: files["vid3"], Published: time.Now().Add(-72 * time.Hour)},
+			}, nil
+		},
+		RemoveFunc: func(entry ytfeed.Entry) error { return nil },
+		RemoveOldFunc: func(channelID string, keep int) ([]string, error) {
+			return []string{files["vid2"]}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, KeepPerChannel: 10}
+
+	assert.Equal(t, 0, svc.removeOld(FeedInfo{ID: "channel1", Keep: KeepAll}), "no max age")
+	assert.Equal(t, 0, len(storeSvc.RemoveCalls()))
+
+	assert.Equal(t, 2, svc.removeOld(FeedInfo{ID: "channel1", Keep: KeepAll, MaxAge: 24 * time.Hour}))
+	require.Equal(t, 2, len(storeSvc.RemoveCalls()))
+	assert.Equal(t, "vid2", storeSvc.RemoveCalls()[0].Entry.VideoID)
+	assert.Equal(t, "vid3", storeSvc.RemoveCalls()[1].Entry.VideoID)
+	assert.Equal(t, 0, len(storeSvc.RemoveOldCalls()), "keep-all feed not limited by count")
+	_, err := os.Stat(files["vid1"])
+	assert.NoError(t, err, "recent file kept")
+	_, err = os.Stat(files["vid3"])
+	assert.True(t, os.IsNotExist(err), "expired file removed")
+
+	// both rules combined, vid2 is over the keep limit and expired
+	require.NoError(t, os.WriteFile(files["vid2"], []byte("content"), 0o600))
+	assert.Equal(t, 2, svc.removeOld(FeedInfo{ID: "channel1", Keep: 1, MaxAge: 60 * time.Hour}))
+	assert.Equal(t, 3, len(storeSvc.RemoveCalls()), "vid3 expired")
+	require.Equal(t, 1, len(storeSvc.RemoveOldCalls()))
+	assert.Equal(t, 2, storeSvc.RemoveOldCalls()[0].Keep)
+}
+
+func TestService_removeOldConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{}
+	for i := 0; i < 50; i++ {
+		f := filepath.Join(dir, fmt.Sprintf("vid%02d.mp3", i))
+		if i%10 != 9 { // every 10th file is missing
+			require.NoError(t, os.WriteFile(f, []byte("content"), 0o600))
+			require.NoError(t, os.WriteFile(chaptersFile(f), []byte("{}"), 0o600))
+		}
+		files = append(files, f)
+	}
+	storeSvc := &mocks.StoreServiceMock{
+		RemoveOldFunc: func(channelID string, keep int) ([]string, error) { return files, nil },
+	}
+	buf := bytes.Buffer{}
+	svc := Service{Store: storeSvc, KeepPerChannel: 10, RemoveConcurrency: 8, Logger: &JSONLogger{Out: &buf}}
+
+	assert.Equal(t, 45, svc.removeOld(FeedInfo{ID: "channel1", Name: "name1"}))
+	left, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, left, "all files and companions removed")
+	assert.Equal(t, 45, strings.Count(buf.String(), `"action":"remove"`), "each removed file logged")
+
+	svc.RemoveConcurrency = 0
+	assert.Equal(t, 0, svc.removeOld(FeedInfo{ID: "channel1", Name: "name1"}), "removed already")
+}
+
+func TestService_isExpired(t *testing.T) {
+	svc := Service{}
+	fi := FeedInfo{ID: "channel1", MaxAge: time.Hour}
+	assert.False(t, svc.isExpired(ytfeed.Entry{Published: time.Now().Add(-time.Minute)}, fi))
+	assert.True(t, svc.isExpired(ytfeed.Entry{Published: time.Now().Add(-2 * time.Hour)}, fi))
+	assert.False(t, svc.isExpired(ytfeed.Entry{}, fi), "no published time")
+	assert.False(t, svc.isExpired(ytfeed.Entry{Published: time.Now().Add(-2 * time.Hour)}, FeedInfo{}), "no max age")
+}
+
+func TestService_beforeStart(t *testing.T) {
+	svc := Service{}
+	fi := FeedInfo{ID: "channel1", StartDate: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)}
+	assert.False(t, svc.beforeStart(ytfeed.Entry{Published: time.Date(2023, 1, 15, 10, 0, 0, 0, time.UTC)}, fi))
+	assert.True(t, svc.beforeStart(ytfeed.Entry{Published: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)}, fi))
+	assert.False(t, svc.beforeStart(ytfeed.Entry{}, fi), "no published time")
+	assert.False(t, svc.beforeStart(ytfeed.Entry{Published: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)}, FeedInfo{}),
+		"no start date")
+
+	var yfi FeedInfo
+	require.NoError(t, yaml.Unmarshal([]byte("{id: c1, start_date: 2023-01-15}"), &yfi))
+	assert.Equal(t, fi.StartDate, yfi.StartDate.UTC())
+}
+
+func TestService_DoStartDate(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid3", Title: "new episode", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "old episode", Published: time.Now().AddDate(0, -2, 0)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "2015 episode", Published: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content"), 0o600)
+		},
+	}
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", StartDate: time.Now().AddDate(0, -1, 0)}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		DurationService: &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added)
+	assert.Equal(t, 2, st.Ignored)
+	require.Equal(t, 1, len(downloader.GetCalls()))
+	assert.Equal(t, "vid3", downloader.GetCalls()[0].ID)
+	for _, vid := range []string{"vid1", "vid2"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: vid})
+		require.NoError(t, err)
+		assert.True(t, found, "%s before start date marked processed", vid)
+	}
 }
 
 func TestService_countAllEntries(t *testing.T) {
@@ -499,3 +2714,68 @@ func TestService_newestEntry(t *testing.T) {
 	assert.Equal(t, 1, storeSvc.LoadCalls()[0].Max)
 	assert.Equal(t, 1, storeSvc.LoadCalls()[1].Max)
 }
+
+func TestService_downloadID(t *testing.T) {
+	svc := Service{}
+	entry := ytfeed.Entry{ChannelID: "chan1", VideoID: "vid1"}
+	entry.Link.Href = "https://framatube.org/videos/watch/vid1"
+	assert.Equal(t, "vid1", svc.downloadID(entry, FeedInfo{ID: "chan1", Type: ytfeed.FTChannel}))
+	assert.Equal(t, "https://framatube.org/videos/watch/vid1", svc.downloadID(entry, FeedInfo{ID: "chan1", Type: ytfeed.FTPeerTube}))
+}
+
+func TestStats_String(t *testing.T) {
+	st := Stats{Entries: 10, Processed: 5, Added: 2, Removed: 1, Ignored: 1, Skipped: 3, Deferred: 2, Bytes: 12345678}
+	assert.Equal(t, "entries: 10, processed: 5, updated: 2, removed: 1, ignored: 1, skipped: 3, deferred: 2, downloaded: 12 MB",
+		st.String())
+}
+
+func TestService_itemDescription(t *testing.T) {
+	svc := Service{}
+	entry := ytfeed.Entry{ChannelID: "chan1", VideoID: "vid1", Title: "title1",
+		Published: time.Date(2022, 4, 11, 11, 35, 17, 0, time.UTC)}
+	entry.Media.Description = "some description"
+	entry.Link.Href = "https://www.youtube.com/watch?v=vid1"
+
+	assert.Equal(t, "some description", string(svc.itemDescription(entry, FeedInfo{ID: "chan1"})))
+	assert.Equal(t, "some description", string(svc.itemDescription(entry, FeedInfo{ID: "chan1", DescriptionTmpl: DefaultDescriptionTmpl})))
+
+	fi := FeedInfo{ID: "chan1", DescriptionTmpl: `{{.Media.Description}}<br>{{.Link.Href}} {{.Published.Format "2006-01-02"}}`}
+	assert.Equal(t, "some description<br>https://www.youtube.com/watch?v=vid1 2022-04-11", string(svc.itemDescription(entry, fi)))
+
+	fi = FeedInfo{ID: "chan1", DescriptionTmpl: `{{.Blah}}`}
+	assert.Equal(t, "some description", string(svc.itemDescription(entry, fi)), "fallback on failed template")
+
+	fi = FeedInfo{ID: "chan1", MaxDescriptionLen: 10}
+	assert.Equal(t, "some…", string(svc.itemDescription(entry, fi)))
+	fi.DescriptionTmpl = `{{.Media.Description}}<br>{{.Link.Href}}`
+	assert.Equal(t, "some…<br>https://www.youtube.com/watch?v=vid1", string(svc.itemDescription(entry, fi)),
+		"the video's description truncated only")
+	assert.Equal(t, "some description", string(entry.Media.Description), "entry not changed")
+}
+
+func TestTruncateText(t *testing.T) {
+	tbl := []struct {
+		text   string
+		maxLen int
+		res    string
+	}{
+		{"some long description", 0, "some long description"},
+		{"some long description", -1, "some long description"},
+		{"some long description", 21, "some long description"},
+		{"some long description", 100, "some long description"},
+		{"some long description", 20, "some long…"},
+		{"some long description", 10, "some long…"},
+		{"some long description", 9, "some long…"},
+		{"some long description", 7, "some…"},
+		{"some, long description", 6, "some…"},
+		{"links:\nhttps://example.com/1 https://example.com/2", 20, "links…"},
+		{"description", 4, "desc…"},
+		{"длинное описание видео", 16, "длинное описание…"},
+		{"длинное описание видео", 12, "длинное…"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.res, truncateText(tt.text, tt.maxLen))
+		})
+	}
+}
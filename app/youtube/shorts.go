@@ -0,0 +1,34 @@
+package youtube
+
+import (
+	"strings"
+	"time"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// DefaultShortsDuration is the max duration of youtube shorts, used by FeedInfo.SkipShorts if ShortsDuration not set
+const DefaultShortsDuration = 3 * time.Minute
+
+// shortsDuration returns the max duration of shorts for the feed
+func (fi FeedInfo) shortsDuration() time.Duration {
+	if fi.ShortsDuration > 0 {
+		return fi.ShortsDuration
+	}
+	return DefaultShortsDuration
+}
+
+// isShortEntry checks if the entry is youtube short by its /shorts/ link or by duration, if known before the download.
+// Returns the reason of detection, "link" or "duration"
+func isShortEntry(entry ytfeed.Entry, fi FeedInfo) (short bool, reason string) {
+	if !fi.SkipShorts {
+		return false, ""
+	}
+	if strings.Contains(entry.Link.Href, "/shorts/") {
+		return true, "link"
+	}
+	if entry.Duration > 0 && time.Duration(entry.Duration)*time.Second <= fi.shortsDuration() {
+		return true, "duration"
+	}
+	return false, ""
+}
@@ -0,0 +1,120 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestIsShortEntry(t *testing.T) {
+	entry := func(link string, duration int) ytfeed.Entry {
+		e := ytfeed.Entry{VideoID: "vid1", Duration: duration}
+		e.Link.Href = link
+		return e
+	}
+	tbl := []struct {
+		name   string
+		entry  ytfeed.Entry
+		fi     FeedInfo
+		short  bool
+		reason string
+	}{
+		{"disabled", entry("https://www.youtube.com/shorts/vid1", 30), FeedInfo{}, false, ""},
+		{"shorts link", entry("https://www.youtube.com/shorts/vid1", 0), FeedInfo{SkipShorts: true}, true, "link"},
+		{"regular link", entry("https://www.youtube.com/watch?v=vid1", 0), FeedInfo{SkipShorts: true}, false, ""},
+		{"short duration", entry("https://www.youtube.com/watch?v=vid1", 59), FeedInfo{SkipShorts: true}, true, "duration"},
+		{"max duration", entry("https://www.youtube.com/watch?v=vid1", 180), FeedInfo{SkipShorts: true}, true, "duration"},
+		{"long", entry("https://www.youtube.com/watch?v=vid1", 181), FeedInfo{SkipShorts: true}, false, ""},
+		{"custom duration", entry("https://www.youtube.com/watch?v=vid1", 90),
+			FeedInfo{SkipShorts: true, ShortsDuration: time.Minute}, false, ""},
+		{"custom duration short", entry("https://www.youtube.com/watch?v=vid1", 60),
+			FeedInfo{SkipShorts: true, ShortsDuration: time.Minute}, true, "duration"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			short, reason := isShortEntry(tt.entry, tt.fi)
+			assert.Equal(t, tt.short, short)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestService_DoSkipShorts(t *testing.T) {
+	dir := t.TempDir()
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			res := []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid4", Title: "short by link", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid3", Title: "short by duration", Published: time.Now().Add(-time.Minute), Duration: 45},
+				{ChannelID: chanID, VideoID: "vid2", Title: "short file", Published: time.Now().Add(-2 * time.Minute)},
+				{ChannelID: chanID, VideoID: "vid1", Title: "episode", Published: time.Now().Add(-time.Hour)},
+			}
+			res[0].Link.Href = "https://www.youtube.com/shorts/vid4"
+			for i := 1; i < len(res); i++ {
+				res[i].Link.Href = "https://www.youtube.com/watch?v=" + res[i].VideoID
+			}
+			return res, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			file := filepath.Join(dir, fname+"-"+id+".mp3")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o750))
+			return file, os.WriteFile(file, []byte("content"), 0o600)
+		},
+	}
+	durations := &mocks.DurationServiceMock{FileFunc: func(fname string) int {
+		if strings.HasSuffix(fname, "-vid2.mp3") {
+			return 170 // duration unknown before the download
+		}
+		return 1234
+	}}
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	defer db.Close()
+	boltStore := &store.BoltDB{DB: db}
+
+	svc := Service{
+		Feeds:           []FeedInfo{{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel, SkipShorts: true}},
+		Downloader:      downloader,
+		ChannelService:  chans,
+		Store:           boltStore,
+		KeepPerChannel:  10,
+		RSSFileStore:    RSSFileStore{Enabled: false},
+		DurationService: durations,
+	}
+
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, st.Added)
+	assert.Equal(t, 3, st.Ignored)
+	require.Equal(t, 2, len(downloader.GetCalls()), "shorts detected by link and duration not downloaded")
+	assert.Equal(t, "vid2", downloader.GetCalls()[0].ID)
+	assert.Equal(t, "vid1", downloader.GetCalls()[1].ID)
+
+	res, err := boltStore.Load("channel1", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid1", res[0].VideoID)
+	for _, vid := range []string{"vid2", "vid3", "vid4"} {
+		found, _, err := boltStore.CheckProcessed(ytfeed.Entry{ChannelID: "channel1", VideoID: vid})
+		require.NoError(t, err)
+		assert.True(t, found, "short %s marked processed", vid)
+	}
+
+	_, err = svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(downloader.GetCalls()), "shorts not retried")
+}
@@ -0,0 +1,87 @@
+package youtube
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// URLSigner makes signed or tokenized enclosure urls, for hosting requiring auth to serve files
+type URLSigner interface {
+	Sign(fileURL string) (string, error)
+}
+
+// URLSigning defines per-feed signing of enclosure urls with HMACSigner, no signing if Secret is empty
+type URLSigning struct {
+	Secret string        `yaml:"secret" json:"-"`
+	TTL    time.Duration `yaml:"ttl"` // validity period of the signed url, default 7 days
+}
+
+// HMACSigner signs urls with hmac-sha256 of url's path and expiration time, separated by new line.
+// Adds "expires" (unix time) and "signature" (hex encoded) query params.
+type HMACSigner struct {
+	Secret string
+	TTL    time.Duration
+
+	now func() time.Time // for tests
+}
+
+// Sign adds expiration and signature to the url
+func (h *HMACSigner) Sign(fileURL string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse url %s", fileURL)
+	}
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	ttl := h.TTL
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	expires := strconv.FormatInt(now().Add(ttl).Unix(), 10)
+
+	q := u.Query()
+	q.Set("expires", expires)
+	q.Set("signature", h.signature(u.Path, expires))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Verify checks the url signed by Sign, error returned if signature is missing or invalid, or the url expired
+func (h *HMACSigner) Verify(u *url.URL) error {
+	q := u.Query()
+	expires, signature := q.Get("expires"), q.Get("signature")
+	if expires == "" || signature == "" {
+		return errors.New("no signature")
+	}
+	if !hmac.Equal([]byte(signature), []byte(h.signature(u.Path, expires))) {
+		return errors.New("invalid signature")
+	}
+	ts, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid expiration %q", expires)
+	}
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	if now().Unix() > ts {
+		return errors.Errorf("expired at %s", time.Unix(ts, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// signature returns hex encoded hmac-sha256 of the path and expiration time separated by new line, the path can't
+// contain it, so digits can't be moved between the path and expiration time keeping the signature
+func (h *HMACSigner) signature(path, expires string) string {
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	_, _ = mac.Write([]byte(path + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,82 @@
+package youtube
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACSigner_Sign(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	s := HMACSigner{Secret: "secret", TTL: time.Hour, now: func() time.Time { return now }}
+
+	res, err := s.Sign("http://localhost:8080/yt/file1.mp3?foo=bar")
+	require.NoError(t, err)
+	u, err := url.Parse(res)
+	require.NoError(t, err)
+	assert.Equal(t, "/yt/file1.mp3", u.Path)
+	assert.Equal(t, "bar", u.Query().Get("foo"))
+	assert.Equal(t, "1651402800", u.Query().Get("expires"))
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, _ = mac.Write([]byte("/yt/file1.mp3" + "\n" + "1651402800"))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), u.Query().Get("signature"))
+
+	s.TTL = 0
+	res, err = s.Sign("http://localhost:8080/yt/file1.mp3")
+	require.NoError(t, err)
+	u, err = url.Parse(res)
+	require.NoError(t, err)
+	assert.Equal(t, "1652004000", u.Query().Get("expires"), "default ttl is 7 days")
+
+	_, err = s.Sign("http://local host:8080/yt/file1.mp3")
+	assert.Error(t, err)
+}
+
+func TestHMACSigner_Verify(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	s := HMACSigner{Secret: "secret", TTL: time.Hour, now: func() time.Time { return now }}
+	signed, err := s.Sign("http://localhost:8080/yt/file1.mp3")
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.NoError(t, s.Verify(u))
+
+	tbl := []struct {
+		name string
+		url  string
+	}{
+		{"unsigned", "http://localhost:8080/yt/file1.mp3"},
+		{"other file", "http://localhost:8080/yt/file2.mp3?" + u.RawQuery},
+		{"extended", "http://localhost:8080/yt/file1.mp3?expires=1651406400&signature=" + u.Query().Get("signature")},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			assert.Error(t, s.Verify(u))
+		})
+	}
+
+	// digit of the path moved to expiration time, the same signature without separator
+	signed, err = s.Sign("http://localhost:8080/yt/file12")
+	require.NoError(t, err)
+	u2, err := url.Parse(signed)
+	require.NoError(t, err)
+	shifted, err := url.Parse("http://localhost:8080/yt/file1?expires=2" + u2.Query().Get("expires") +
+		"&signature=" + u2.Query().Get("signature"))
+	require.NoError(t, err)
+	assert.EqualError(t, s.Verify(shifted), "invalid signature")
+
+	other := HMACSigner{Secret: "other", now: s.now}
+	assert.EqualError(t, other.Verify(u), "invalid signature")
+
+	now = now.Add(time.Hour + time.Second)
+	assert.EqualError(t, s.Verify(u), "expired at 2022-05-01T11:00:00Z")
+}
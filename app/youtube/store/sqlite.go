@@ -0,0 +1,429 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite" // pure go sqlite driver, no cgo required
+
+	"github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// SQLite store for metadata related to downloaded YouTube audio, alternative to BoltDB.
+// Entries are kept as json along with the indexed columns, so the store can be queried by external tools.
+type SQLite struct {
+	DB *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	channel_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	video_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	published DATETIME NOT NULL,
+	file TEXT NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (channel_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_entries_video ON entries (channel_id, video_id);
+CREATE TABLE IF NOT EXISTS processed (
+	key TEXT PRIMARY KEY,
+	channel_id TEXT NOT NULL,
+	video_id TEXT NOT NULL,
+	published TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS bytes (
+	channel_id TEXT PRIMARY KEY,
+	size INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS period_bytes (
+	period TEXT PRIMARY KEY,
+	size INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS fetch_errors (
+	channel_id TEXT PRIMARY KEY,
+	error TEXT NOT NULL,
+	ts DATETIME NOT NULL
+);
+CREATE TABLE IF NOT EXISTS listings (
+	feed_key TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS feed_updates (
+	feed_id TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+`
+
+// NewSQLite makes SQLite store with the given db file, creates the schema if missing
+func NewSQLite(file string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open sqlite %s", file)
+	}
+	db.SetMaxOpenConns(1) // sqlite supports a single writer only
+	if _, err = db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "failed to create schema in %s", file)
+	}
+	return &SQLite{DB: db}, nil
+}
+
+// Save entry, skip if found
+func (s *SQLite) Save(entry feed.Entry) (bool, error) {
+	key, err := (&BoltDB{}).key(entry)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to generate key for %s", entry.VideoID)
+	}
+	jdata, err := json.Marshal(&entry)
+	if err != nil {
+		return false, errors.Wrapf(err, "marshal entry %s", entry.VideoID)
+	}
+
+	res, err := s.DB.Exec(`INSERT OR IGNORE INTO entries (channel_id, key, video_id, title, published, file, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, entry.ChannelID, string(key), entry.VideoID, entry.Title,
+		entry.Published.UTC(), entry.File, string(jdata))
+	if err != nil {
+		return false, errors.Wrapf(err, "save entry %s", entry.VideoID)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrapf(err, "save entry %s", entry.VideoID)
+	}
+	if n > 0 {
+		log.Printf("[INFO] save %s - %s", string(key), entry.String())
+	}
+	return n > 0, nil
+}
+
+// Exist checks if entry exists
+func (s *SQLite) Exist(entry feed.Entry) (bool, error) {
+	key, err := (&BoltDB{}).key(entry)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to generate key for %s", entry.VideoID)
+	}
+	var count int
+	err = s.DB.QueryRow(`SELECT COUNT(*) FROM entries WHERE channel_id = ? AND key = ?`,
+		entry.ChannelID, string(key)).Scan(&count)
+	return count > 0, err
+}
+
+// Load entries for a given channel, up to max in reverse order (from newest to oldest).
+// Negative max means no limit, i.e. all entries will be loaded.
+func (s *SQLite) Load(channelID string, max int) ([]feed.Entry, error) {
+	rows, err := s.DB.Query(`SELECT data FROM entries WHERE channel_id = ? ORDER BY published DESC, key DESC LIMIT ?`,
+		channelID, max)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load entries for %s", channelID)
+	}
+	return s.scanEntries(rows)
+}
+
+// Last returns last (newest) entry across all channels
+func (s *SQLite) Last() (feed.Entry, error) {
+	rows, err := s.DB.Query(`SELECT data FROM entries ORDER BY published DESC, key DESC LIMIT 1`)
+	if err != nil {
+		return feed.Entry{}, errors.Wrap(err, "failed to load last entry")
+	}
+	entries, err := s.scanEntries(rows)
+	if err != nil {
+		return feed.Entry{}, err
+	}
+	if len(entries) == 0 {
+		return feed.Entry{}, errors.New("no entries")
+	}
+	return entries[0], nil
+}
+
+// RemoveOld removes old entries and returns the list of removed entry.File
+// the caller should delete the files. Pinned entries are kept and not counted
+func (s *SQLite) RemoveOld(channelID string, keep int) ([]string, error) {
+	rows, err := s.DB.Query(`SELECT key, file, data FROM entries WHERE channel_id = ? ORDER BY published DESC, key DESC`,
+		channelID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get old entries for %s", channelID)
+	}
+	type rec struct{ key, file string }
+	var old []rec
+	recs := 0
+	for rows.Next() {
+		var r rec
+		var data string
+		if err = rows.Scan(&r.key, &r.file, &data); err != nil {
+			_ = rows.Close()
+			return nil, errors.Wrapf(err, "failed to scan old entry for %s", channelID)
+		}
+		var item feed.Entry
+		if err = json.Unmarshal([]byte(data), &item); err != nil {
+			log.Printf("[WARN] failed to unmarshal %s, %v", r.key, err)
+			continue
+		}
+		if item.Pinned {
+			continue
+		}
+		if recs++; recs > keep {
+			old = append(old, r)
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return nil, errors.Wrapf(err, "failed to get old entries for %s", channelID)
+	}
+
+	var res []string
+	errs := new(multierror.Error)
+	for _, r := range old {
+		if _, err := s.DB.Exec(`DELETE FROM entries WHERE channel_id = ? AND key = ?`, channelID, r.key); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "failed to delete %s (%s)", r.key, r.file))
+			continue
+		}
+		res = append(res, r.file)
+	}
+	return res, errs.ErrorOrNil()
+}
+
+// Remove entry matched by vidoID and channelID
+func (s *SQLite) Remove(entry feed.Entry) error {
+	if _, err := s.DB.Exec(`DELETE FROM entries WHERE channel_id = ? AND video_id = ?`,
+		entry.ChannelID, entry.VideoID); err != nil {
+		return errors.Wrapf(err, "failed to delete %s", entry.VideoID)
+	}
+	log.Printf("[INFO] delete %s", entry.String())
+	return nil
+}
+
+// SetProcessed sets processed status with ts for a given channel+video
+func (s *SQLite) SetProcessed(entry feed.Entry) error {
+	key, err := (&BoltDB{}).procKey(entry)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate key for %s", entry.VideoID)
+	}
+	res, err := s.DB.Exec(`INSERT OR IGNORE INTO processed (key, channel_id, video_id, published) VALUES (?, ?, ?, ?)`,
+		string(key), entry.ChannelID, entry.VideoID, entry.Published.Format(time.RFC3339))
+	if err != nil {
+		return errors.Wrapf(err, "save processed %s", entry.VideoID)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("[INFO] set processed %s - %s", string(key), entry.String())
+	}
+	return nil
+}
+
+// ResetProcessed resets processed status for a given channel+video
+func (s *SQLite) ResetProcessed(entry feed.Entry) error {
+	key, err := (&BoltDB{}).procKey(entry)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate key for %s", entry.VideoID)
+	}
+	if _, err = s.DB.Exec(`DELETE FROM processed WHERE key = ?`, string(key)); err != nil {
+		return errors.Wrapf(err, "reset processed %s", entry.VideoID)
+	}
+	return nil
+}
+
+// CheckProcessed get processed status and returns timestamp for a given channel+video
+// returns found=true if was set before and also the timestamp from stored entry.Published
+func (s *SQLite) CheckProcessed(entry feed.Entry) (found bool, ts time.Time, err error) {
+	key, err := (&BoltDB{}).procKey(entry)
+	if err != nil {
+		return false, time.Time{}, errors.Wrapf(err, "failed to generate key for %s", entry.VideoID)
+	}
+	var published string
+	err = s.DB.QueryRow(`SELECT published FROM processed WHERE key = ?`, string(key)).Scan(&published)
+	if err == sql.ErrNoRows {
+		return false, time.Time{}, nil
+	}
+	if err != nil {
+		return false, time.Time{}, errors.Wrapf(err, "failed to check processed %s", entry.VideoID)
+	}
+	ts, err = time.Parse(time.RFC3339, published)
+	return true, ts, err
+}
+
+// PruneProcessed removes processed status of entries published more than maxAge ago, returns the number of removed
+func (s *SQLite) PruneProcessed(maxAge time.Duration) (int, error) {
+	// published kept as RFC3339 with zone offset, compared as julian day to be independent of the zone
+	res, err := s.DB.Exec(`DELETE FROM processed WHERE julianday(published) < julianday(?)`,
+		time.Now().Add(-maxAge).UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to prune processed")
+	}
+	n, err := res.RowsAffected()
+	return int(n), errors.Wrap(err, "failed to count pruned processed")
+}
+
+// CountProcessed returns the number of processed entries
+func (s *SQLite) CountProcessed() (count int) {
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM processed`).Scan(&count); err != nil {
+		log.Printf("[WARN] failed to count processed, %v", err)
+	}
+	return count
+}
+
+// ListProcessed returns processed entries
+func (s *SQLite) ListProcessed() (res []string, err error) {
+	rows, err := s.DB.Query(`SELECT key, published FROM processed ORDER BY key DESC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list processed")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, published string
+		if err = rows.Scan(&key, &published); err != nil {
+			return nil, errors.Wrap(err, "failed to scan processed")
+		}
+		res = append(res, key+" / "+published)
+	}
+	return res, rows.Err()
+}
+
+// AddBytes increments lifetime counter of downloaded bytes for a given channel
+func (s *SQLite) AddBytes(channelID string, size int64) error {
+	_, err := s.DB.Exec(`INSERT INTO bytes (channel_id, size) VALUES (?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET size = size + excluded.size`, channelID, size)
+	return errors.Wrapf(err, "save bytes counter for %s", channelID)
+}
+
+// CountBytes returns lifetime downloaded bytes for a given channel, or the total for all channels if channelID is empty
+func (s *SQLite) CountBytes(channelID string) (count int64) {
+	query, args := `SELECT COALESCE(SUM(size), 0) FROM bytes`, []interface{}{}
+	if channelID != "" {
+		query, args = query+` WHERE channel_id = ?`, append(args, channelID)
+	}
+	if err := s.DB.QueryRow(query, args...).Scan(&count); err != nil {
+		log.Printf("[WARN] failed to count bytes for %q, %v", channelID, err)
+	}
+	return count
+}
+
+// AddPeriodBytes increments counter of downloaded bytes for a given period of download budget, i.e. "2023-01"
+func (s *SQLite) AddPeriodBytes(period string, size int64) error {
+	_, err := s.DB.Exec(`INSERT INTO period_bytes (period, size) VALUES (?, ?)
+		ON CONFLICT(period) DO UPDATE SET size = size + excluded.size`, period, size)
+	return errors.Wrapf(err, "save bytes counter for period %s", period)
+}
+
+// CountPeriodBytes returns downloaded bytes for a given period of download budget, 0 for a new period
+func (s *SQLite) CountPeriodBytes(period string) (count int64) {
+	err := s.DB.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM period_bytes WHERE period = ?`, period).Scan(&count)
+	if err != nil {
+		log.Printf("[WARN] failed to count bytes for period %q, %v", period, err)
+	}
+	return count
+}
+
+// SetFetchError keeps the last fetch error of the channel, empty error clears it
+func (s *SQLite) SetFetchError(channelID string, fe feed.FetchError) error {
+	if fe.Error == "" {
+		_, err := s.DB.Exec(`DELETE FROM fetch_errors WHERE channel_id = ?`, channelID)
+		return errors.Wrapf(err, "clear fetch error of %s", channelID)
+	}
+	_, err := s.DB.Exec(`INSERT INTO fetch_errors (channel_id, error, ts) VALUES (?, ?, ?)
+		ON CONFLICT(channel_id) DO UPDATE SET error = excluded.error, ts = excluded.ts`, channelID, fe.Error, fe.TS.UTC())
+	return errors.Wrapf(err, "save fetch error of %s", channelID)
+}
+
+// FetchErrors returns last fetch errors by channel id
+func (s *SQLite) FetchErrors() (map[string]feed.FetchError, error) {
+	rows, err := s.DB.Query(`SELECT channel_id, error, ts FROM fetch_errors`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query fetch errors")
+	}
+	defer rows.Close()
+	res := map[string]feed.FetchError{}
+	for rows.Next() {
+		var channelID string
+		var fe feed.FetchError
+		if err := rows.Scan(&channelID, &fe.Error, &fe.TS); err != nil {
+			return nil, errors.Wrap(err, "failed to scan fetch error")
+		}
+		res[channelID] = fe
+	}
+	return res, rows.Err()
+}
+
+// SetListing keeps the last fetched listing of the feed, replaces the previous one
+func (s *SQLite) SetListing(feedKey string, l feed.Listing) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return errors.Wrapf(err, "marshal listing of %s", feedKey)
+	}
+	_, err = s.DB.Exec(`INSERT INTO listings (feed_key, data) VALUES (?, ?)
+		ON CONFLICT(feed_key) DO UPDATE SET data = excluded.data`, feedKey, string(data))
+	return errors.Wrapf(err, "save listing of %s", feedKey)
+}
+
+// Listing returns the last fetched listing of the feed, zero listing if not stored
+func (s *SQLite) Listing(feedKey string) (res feed.Listing, err error) {
+	var data string
+	err = s.DB.QueryRow(`SELECT data FROM listings WHERE feed_key = ?`, feedKey).Scan(&data)
+	if err == sql.ErrNoRows {
+		return res, nil
+	}
+	if err != nil {
+		return res, errors.Wrapf(err, "failed to query listing of %s", feedKey)
+	}
+	return res, errors.Wrapf(json.Unmarshal([]byte(data), &res), "unmarshal listing of %s", feedKey)
+}
+
+// SetFeedUpdate keeps runtime changes of the feed's settings, replaces the previous ones
+func (s *SQLite) SetFeedUpdate(feedID string, upd feed.FeedUpdate) error {
+	data, err := json.Marshal(upd)
+	if err != nil {
+		return errors.Wrapf(err, "marshal feed update of %s", feedID)
+	}
+	_, err = s.DB.Exec(`INSERT INTO feed_updates (feed_id, data) VALUES (?, ?)
+		ON CONFLICT(feed_id) DO UPDATE SET data = excluded.data`, feedID, string(data))
+	return errors.Wrapf(err, "save feed update of %s", feedID)
+}
+
+// FeedUpdates returns runtime changes of feeds' settings by feed id
+func (s *SQLite) FeedUpdates() (map[string]feed.FeedUpdate, error) {
+	rows, err := s.DB.Query(`SELECT feed_id, data FROM feed_updates`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query feed updates")
+	}
+	defer rows.Close()
+	res := map[string]feed.FeedUpdate{}
+	for rows.Next() {
+		var feedID, data string
+		if err := rows.Scan(&feedID, &data); err != nil {
+			return nil, errors.Wrap(err, "failed to scan feed update")
+		}
+		var upd feed.FeedUpdate
+		if err := json.Unmarshal([]byte(data), &upd); err != nil {
+			log.Printf("[WARN] failed to unmarshal feed update of %s, %v", feedID, err)
+			continue
+		}
+		res[feedID] = upd
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLite) scanEntries(rows *sql.Rows) ([]feed.Entry, error) {
+	defer rows.Close()
+	var result []feed.Entry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, errors.Wrap(err, "failed to scan entry")
+		}
+		var item feed.Entry
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			log.Printf("[WARN] failed to unmarshal %q: %v", strings.TrimSpace(data), err)
+			continue
+		}
+		result = append(result, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read entries: %w", err)
+	}
+	return result, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	log "github.com/go-pkgz/lgr"
@@ -17,6 +18,11 @@ import (
 )
 
 var processedBkt = []byte("processed")
+var bytesBkt = []byte("bytes")
+var periodBytesBkt = []byte("period_bytes")
+var fetchErrorsBkt = []byte("fetch_errors")
+var listingsBkt = []byte("listings")
+var feedUpdatesBkt = []byte("feed_updates")
 
 // BoltDB store for metadata related to downloaded YouTube audio.
 type BoltDB struct {
@@ -86,7 +92,8 @@ func (s *BoltDB) Exist(entry feed.Entry) (bool, error) {
 	return found, err
 }
 
-// Load entries from bolt for a given channel, up to max in reverse order (from newest to oldest)
+// Load entries from bolt for a given channel, up to max in reverse order (from newest to oldest).
+// Negative max means no limit, i.e. all entries will be loaded.
 func (s *BoltDB) Load(channelID string, max int) ([]feed.Entry, error) {
 	var result []feed.Entry
 
@@ -102,7 +109,7 @@ func (s *BoltDB) Load(channelID string, max int) ([]feed.Entry, error) {
 				log.Printf("[WARN] failed to unmarshal %s, %q: %v", channelID, string(v), err)
 				continue
 			}
-			if len(result) >= max {
+			if max >= 0 && len(result) >= max {
 				break
 			}
 			result = append(result, item)
@@ -134,7 +141,7 @@ func (s *BoltDB) Last() (feed.Entry, error) {
 }
 
 // RemoveOld removes old entries from bolt and returns the list of removed entry.File
-// the caller should delete the files
+// the caller should delete the files. Pinned entries are kept and not counted
 // important: this method returns the list of removed keys even if there was an error
 func (s *BoltDB) RemoveOld(channelID string, keep int) ([]string, error) {
 	deleted := 0
@@ -149,20 +156,24 @@ func (s *BoltDB) RemoveOld(channelID string, keep int) ([]string, error) {
 		recs := 0
 		c := bucket.Cursor()
 		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var item feed.Entry
+			if err := json.Unmarshal(v, &item); err != nil {
+				log.Printf("[WARN] failed to unmarshal, %v", err)
+				continue
+			}
+			if item.Pinned {
+				continue // pinned entries kept and not counted
+			}
 			recs++
-			if recs > keep {
-				var item feed.Entry
-				if err := json.Unmarshal(v, &item); err != nil {
-					log.Printf("[WARN] failed to unmarshal, %v", err)
-					continue
-				}
-				if err := bucket.Delete(k); err != nil {
-					errs = multierror.Append(errs, errors.Wrapf(err, "failed to delete %s (%s)", string(k), item.File))
-					continue
-				}
-				res = append(res, item.File)
-				deleted++
+			if recs <= keep {
+				continue
+			}
+			if err := bucket.Delete(k); err != nil {
+				errs = multierror.Append(errs, errors.Wrapf(err, "failed to delete %s (%s)", string(k), item.File))
+				continue
 			}
+			res = append(res, item.File)
+			deleted++
 		}
 		return errs.ErrorOrNil()
 	})
@@ -190,8 +201,8 @@ func (s *BoltDB) Remove(entry feed.Entry) error {
 					return errors.Wrapf(err, "failed to delete %s (%s)", string(k), item.VideoID)
 				}
 				log.Printf("[INFO] delete %s - %s", string(k), item.String())
+				return nil
 			}
-			return nil
 		}
 		return nil
 	})
@@ -286,6 +297,36 @@ func (s *BoltDB) CheckProcessed(entry feed.Entry) (found bool, ts time.Time, err
 	return found, ts, err
 }
 
+// PruneProcessed removes processed status of entries published more than maxAge ago, returns the number of removed
+func (s *BoltDB) PruneProcessed(maxAge time.Duration) (count int, err error) {
+	cutoff := time.Now().Add(-maxAge)
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(processedBkt)
+		if bucket == nil {
+			return nil
+		}
+		var keys [][]byte
+		e := bucket.ForEach(func(k, v []byte) error {
+			ts, tsErr := time.Parse(time.RFC3339, string(v))
+			if tsErr == nil && ts.Before(cutoff) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if e != nil {
+			return errors.Wrap(e, "list processed")
+		}
+		for _, k := range keys {
+			if e := bucket.Delete(k); e != nil {
+				return errors.Wrapf(e, "prune processed %s", string(k))
+			}
+		}
+		count = len(keys)
+		return nil
+	})
+	return count, err
+}
+
 // CountProcessed returns the number of processed entries stored in processedBkt
 func (s *BoltDB) CountProcessed() (count int) {
 
@@ -301,6 +342,200 @@ func (s *BoltDB) CountProcessed() (count int) {
 	return count
 }
 
+// AddBytes increments lifetime counter of downloaded bytes for a given channel
+func (s *BoltDB) AddBytes(channelID string, size int64) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(bytesBkt)
+		if e != nil {
+			return errors.Wrapf(e, "create bucket %s", bytesBkt)
+		}
+		var total int64
+		if v := bucket.Get([]byte(channelID)); v != nil {
+			if total, e = strconv.ParseInt(string(v), 10, 64); e != nil {
+				log.Printf("[WARN] invalid bytes counter for %s, %q: %v", channelID, string(v), e)
+			}
+		}
+		total += size
+		if e = bucket.Put([]byte(channelID), []byte(strconv.FormatInt(total, 10))); e != nil {
+			return errors.Wrapf(e, "save bytes counter for %s", channelID)
+		}
+		return nil
+	})
+}
+
+// CountBytes returns lifetime downloaded bytes for a given channel, or the total for all channels if channelID is empty
+func (s *BoltDB) CountBytes(channelID string) (count int64) {
+	_ = s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bytesBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if channelID != "" && string(k) != channelID {
+				return nil
+			}
+			if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+				count += n
+			}
+			return nil
+		})
+	})
+	return count
+}
+
+// AddPeriodBytes increments counter of downloaded bytes for a given period of download budget, i.e. "2023-01"
+func (s *BoltDB) AddPeriodBytes(period string, size int64) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(periodBytesBkt)
+		if e != nil {
+			return errors.Wrapf(e, "create bucket %s", periodBytesBkt)
+		}
+		var total int64
+		if v := bucket.Get([]byte(period)); v != nil {
+			if total, e = strconv.ParseInt(string(v), 10, 64); e != nil {
+				log.Printf("[WARN] invalid bytes counter for period %s, %q: %v", period, string(v), e)
+			}
+		}
+		total += size
+		if e = bucket.Put([]byte(period), []byte(strconv.FormatInt(total, 10))); e != nil {
+			return errors.Wrapf(e, "save bytes counter for period %s", period)
+		}
+		return nil
+	})
+}
+
+// CountPeriodBytes returns downloaded bytes for a given period of download budget, 0 for a new period
+func (s *BoltDB) CountPeriodBytes(period string) (count int64) {
+	_ = s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(periodBytesBkt)
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(period)); v != nil {
+			count, _ = strconv.ParseInt(string(v), 10, 64)
+		}
+		return nil
+	})
+	return count
+}
+
+// SetFetchError keeps the last fetch error of the channel, empty error clears it
+func (s *BoltDB) SetFetchError(channelID string, fe feed.FetchError) error {
+	if fe.Error == "" {
+		found := false
+		_ = s.DB.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(fetchErrorsBkt)
+			found = bucket != nil && bucket.Get([]byte(channelID)) != nil
+			return nil
+		})
+		if !found {
+			return nil // nothing to clear, skip write transaction
+		}
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(fetchErrorsBkt)
+		if e != nil {
+			return errors.Wrapf(e, "create bucket %s", fetchErrorsBkt)
+		}
+		if fe.Error == "" {
+			return errors.Wrapf(bucket.Delete([]byte(channelID)), "clear fetch error of %s", channelID)
+		}
+		jdata, e := json.Marshal(fe)
+		if e != nil {
+			return errors.Wrapf(e, "marshal fetch error of %s", channelID)
+		}
+		return errors.Wrapf(bucket.Put([]byte(channelID), jdata), "save fetch error of %s", channelID)
+	})
+}
+
+// FetchErrors returns last fetch errors by channel id
+func (s *BoltDB) FetchErrors() (map[string]feed.FetchError, error) {
+	res := map[string]feed.FetchError{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(fetchErrorsBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var fe feed.FetchError
+			if e := json.Unmarshal(v, &fe); e != nil {
+				log.Printf("[WARN] failed to unmarshal fetch error of %s, %v", string(k), e)
+				return nil
+			}
+			res[string(k)] = fe
+			return nil
+		})
+	})
+	return res, err
+}
+
+// SetListing keeps the last fetched listing of the feed, replaces the previous one
+func (s *BoltDB) SetListing(feedKey string, l feed.Listing) error {
+	jdata, err := json.Marshal(l)
+	if err != nil {
+		return errors.Wrapf(err, "marshal listing of %s", feedKey)
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(listingsBkt)
+		if e != nil {
+			return errors.Wrapf(e, "create bucket %s", listingsBkt)
+		}
+		return errors.Wrapf(bucket.Put([]byte(feedKey), jdata), "save listing of %s", feedKey)
+	})
+}
+
+// Listing returns the last fetched listing of the feed, zero listing if not stored
+func (s *BoltDB) Listing(feedKey string) (res feed.Listing, err error) {
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(listingsBkt)
+		if bucket == nil {
+			return nil
+		}
+		v := bucket.Get([]byte(feedKey))
+		if v == nil {
+			return nil
+		}
+		return errors.Wrapf(json.Unmarshal(v, &res), "unmarshal listing of %s", feedKey)
+	})
+	return res, err
+}
+
+// SetFeedUpdate keeps runtime changes of the feed's settings, replaces the previous ones
+func (s *BoltDB) SetFeedUpdate(feedID string, upd feed.FeedUpdate) error {
+	jdata, err := json.Marshal(upd)
+	if err != nil {
+		return errors.Wrapf(err, "marshal feed update of %s", feedID)
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(feedUpdatesBkt)
+		if e != nil {
+			return errors.Wrapf(e, "create bucket %s", feedUpdatesBkt)
+		}
+		return errors.Wrapf(bucket.Put([]byte(feedID), jdata), "save feed update of %s", feedID)
+	})
+}
+
+// FeedUpdates returns runtime changes of feeds' settings by feed id
+func (s *BoltDB) FeedUpdates() (map[string]feed.FeedUpdate, error) {
+	res := map[string]feed.FeedUpdate{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(feedUpdatesBkt)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var upd feed.FeedUpdate
+			if e := json.Unmarshal(v, &upd); e != nil {
+				log.Printf("[WARN] failed to unmarshal feed update of %s, %v", string(k), e)
+				return nil
+			}
+			res[string(k)] = upd
+			return nil
+		})
+	})
+	return res, err
+}
+
 // ListProcessed returns processed entries stored in processedBkt
 func (s *BoltDB) ListProcessed() (res []string, err error) {
 
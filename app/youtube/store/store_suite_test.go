@@ -0,0 +1,322 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// storeService is the set of methods shared by all store implementations
+type storeService interface {
+	Save(entry feed.Entry) (bool, error)
+	Load(channelID string, max int) ([]feed.Entry, error)
+	Exist(entry feed.Entry) (bool, error)
+	Last() (feed.Entry, error)
+	RemoveOld(channelID string, keep int) ([]string, error)
+	Remove(entry feed.Entry) error
+	SetProcessed(entry feed.Entry) error
+	ResetProcessed(entry feed.Entry) error
+	CheckProcessed(entry feed.Entry) (found bool, ts time.Time, err error)
+	CountProcessed() (count int)
+	PruneProcessed(maxAge time.Duration) (count int, err error)
+	ListProcessed() (res []string, err error)
+	AddBytes(channelID string, size int64) error
+	CountBytes(channelID string) (count int64)
+	AddPeriodBytes(period string, size int64) error
+	CountPeriodBytes(period string) (count int64)
+	SetFetchError(channelID string, fe feed.FetchError) error
+	FetchErrors() (map[string]feed.FetchError, error)
+	SetListing(feedKey string, l feed.Listing) error
+	Listing(feedKey string) (feed.Listing, error)
+	SetFeedUpdate(feedID string, upd feed.FeedUpdate) error
+	FeedUpdates() (map[string]feed.FeedUpdate, error)
+}
+
+// TestStores runs the same suite against all store implementations
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) storeService{
+		"bolt": func(t *testing.T) storeService {
+			db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, &bolt.Options{Timeout: 1 * time.Second})
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+			return &BoltDB{DB: db, Channels: []string{"chan1", "chan2"}}
+		},
+		"sqlite": func(t *testing.T) storeService {
+			s, err := NewSQLite(filepath.Join(t.TempDir(), "test.sqlite"))
+			if err != nil && strings.Contains(err.Error(), "CGO_ENABLED=0") {
+				t.Skip("sqlite requires cgo")
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() { s.DB.Close() })
+			return s
+		},
+	}
+
+	for name, makeStore := range stores {
+		makeStore := makeStore
+		t.Run(name+"/save and load", func(t *testing.T) { testStoreSaveAndLoad(t, makeStore(t)) })
+		t.Run(name+"/remove", func(t *testing.T) { testStoreRemove(t, makeStore(t)) })
+		t.Run(name+"/remove old pinned", func(t *testing.T) { testStoreRemoveOldPinned(t, makeStore(t)) })
+		t.Run(name+"/processed", func(t *testing.T) { testStoreProcessed(t, makeStore(t)) })
+		t.Run(name+"/prune processed", func(t *testing.T) { testStorePruneProcessed(t, makeStore(t)) })
+		t.Run(name+"/bytes", func(t *testing.T) { testStoreBytes(t, makeStore(t)) })
+		t.Run(name+"/fetch errors", func(t *testing.T) { testStoreFetchErrors(t, makeStore(t)) })
+		t.Run(name+"/listings", func(t *testing.T) { testStoreListings(t, makeStore(t)) })
+		t.Run(name+"/feed_updates", func(t *testing.T) { testStoreFeedUpdates(t, makeStore(t)) })
+	}
+}
+
+func suiteEntries() []feed.Entry {
+	ts := time.Date(2022, time.March, 21, 16, 45, 22, 0, time.UTC)
+	res := []feed.Entry{
+		{ChannelID: "chan1", VideoID: "vid1", Title: "title1", Published: ts, File: "/tmp/f1.mp3", Duration: 10},
+		{ChannelID: "chan1", VideoID: "vid2", Title: "title2", Published: ts.Add(time.Hour), File: "/tmp/f2.mp3"},
+		{ChannelID: "chan1", VideoID: "vid3", Title: "title3", Published: ts.Add(2 * time.Hour), File: "/tmp/f3.mp3"},
+		{ChannelID: "chan2", VideoID: "vid4", Title: "title4", Published: ts.Add(3 * time.Hour), File: "/tmp/f4.mp3"},
+	}
+	res[0].Link.Href = "https://example.com/vid1"
+	res[0].Checksum = "abcd"
+	res[0].OriginalPublished = ts.Add(-48 * time.Hour)
+	return res
+}
+
+func testStoreSaveAndLoad(t *testing.T, s storeService) {
+	entries := suiteEntries()
+	for _, e := range entries {
+		created, err := s.Save(e)
+		require.NoError(t, err)
+		assert.True(t, created)
+	}
+	created, err := s.Save(entries[0])
+	require.NoError(t, err)
+	assert.False(t, created, "duplicate")
+
+	res, err := s.Load("chan1", 100)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res))
+	assert.Equal(t, "vid3", res[0].VideoID)
+	assert.Equal(t, "vid2", res[1].VideoID)
+	assert.Equal(t, entries[0], res[2], "all fields restored")
+
+	res, err = s.Load("chan1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(res))
+
+	res, err = s.Load("chan1", -1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(res))
+
+	found, err := s.Exist(entries[1])
+	require.NoError(t, err)
+	assert.True(t, found)
+	found, err = s.Exist(feed.Entry{ChannelID: "chan1", VideoID: "vid5", Published: time.Now()})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	last, err := s.Last()
+	require.NoError(t, err)
+	assert.Equal(t, "vid4", last.VideoID)
+
+	removed, err := s.RemoveOld("chan1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/f2.mp3", "/tmp/f1.mp3"}, removed)
+	res, err = s.Load("chan1", 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid3", res[0].VideoID)
+}
+
+func testStoreRemove(t *testing.T, s storeService) {
+	for _, e := range suiteEntries() {
+		_, err := s.Save(e)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, s.Remove(feed.Entry{ChannelID: "chan1", VideoID: "vid2"}))
+	res, err := s.Load("chan1", 100)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res))
+	assert.Equal(t, "vid3", res[0].VideoID)
+	assert.Equal(t, "vid1", res[1].VideoID)
+
+	require.NoError(t, s.Remove(feed.Entry{ChannelID: "chan1", VideoID: "vid3"}))
+	res, err = s.Load("chan1", 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res))
+	assert.Equal(t, "vid1", res[0].VideoID)
+}
+
+func testStoreRemoveOldPinned(t *testing.T, s storeService) {
+	entries := suiteEntries()
+	entries[0].Pinned = true
+	entries[2].Pinned = true
+	for _, e := range entries {
+		_, err := s.Save(e)
+		require.NoError(t, err)
+	}
+
+	removed, err := s.RemoveOld("chan1", 1)
+	require.NoError(t, err)
+	assert.Empty(t, removed, "pinned entries not counted in keep")
+
+	removed, err = s.RemoveOld("chan1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/f2.mp3"}, removed)
+	res, err := s.Load("chan1", 100)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res))
+	assert.Equal(t, "vid3", res[0].VideoID)
+	assert.Equal(t, "vid1", res[1].VideoID)
+	assert.True(t, res[1].Pinned)
+}
+
+func testStoreProcessed(t *testing.T, s storeService) {
+	entries := suiteEntries()
+	found, _, err := s.CheckProcessed(entries[0])
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, s.SetProcessed(entries[0]))
+	require.NoError(t, s.SetProcessed(entries[0]))
+	require.NoError(t, s.SetProcessed(entries[1]))
+	found, ts, err := s.CheckProcessed(entries[0])
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, entries[0].Published, ts.UTC())
+	assert.Equal(t, 2, s.CountProcessed())
+	list, err := s.ListProcessed()
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(list))
+
+	require.NoError(t, s.ResetProcessed(entries[0]))
+	found, _, err = s.CheckProcessed(entries[0])
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 1, s.CountProcessed())
+}
+
+func testStoreBytes(t *testing.T, s storeService) {
+	assert.Equal(t, int64(0), s.CountBytes(""))
+	require.NoError(t, s.AddBytes("chan1", 100))
+	require.NoError(t, s.AddBytes("chan1", 50))
+	require.NoError(t, s.AddBytes("chan2", 10))
+	assert.Equal(t, int64(150), s.CountBytes("chan1"))
+	assert.Equal(t, int64(10), s.CountBytes("chan2"))
+	assert.Equal(t, int64(0), s.CountBytes("chan3"))
+	assert.Equal(t, int64(160), s.CountBytes(""))
+
+	assert.Equal(t, int64(0), s.CountPeriodBytes("2023-01"))
+	require.NoError(t, s.AddPeriodBytes("2023-01", 100))
+	require.NoError(t, s.AddPeriodBytes("2023-01", 20))
+	require.NoError(t, s.AddPeriodBytes("2023-02", 5))
+	assert.Equal(t, int64(120), s.CountPeriodBytes("2023-01"))
+	assert.Equal(t, int64(5), s.CountPeriodBytes("2023-02"))
+	assert.Equal(t, int64(160), s.CountBytes(""), "lifetime counter not changed")
+}
+
+func testStorePruneProcessed(t *testing.T, s storeService) {
+	count, err := s.PruneProcessed(time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "nothing processed")
+
+	now := time.Now().Truncate(time.Second)
+	moscow := time.FixedZone("MSK", 3*60*60)
+	entries := []feed.Entry{
+		{ChannelID: "chan1", VideoID: "vid1", Published: now.Add(-72 * time.Hour)},
+		{ChannelID: "chan1", VideoID: "vid2", Published: now.Add(-47 * time.Hour).In(moscow)},
+		{ChannelID: "chan2", VideoID: "vid3", Published: now.Add(-49 * time.Hour).In(moscow)},
+		{ChannelID: "chan2", VideoID: "vid4", Published: now.Add(-time.Hour)},
+	}
+	for _, e := range entries {
+		require.NoError(t, s.SetProcessed(e))
+	}
+
+	count, err = s.PruneProcessed(48 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, s.CountProcessed())
+	for i, pruned := range []bool{true, false, true, false} {
+		found, _, err := s.CheckProcessed(entries[i])
+		require.NoError(t, err)
+		assert.Equal(t, !pruned, found, entries[i].VideoID)
+	}
+
+	count, err = s.PruneProcessed(48 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "pruned already")
+}
+
+func testStoreFetchErrors(t *testing.T, s storeService) {
+	res, err := s.FetchErrors()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]feed.FetchError{}, res)
+	require.NoError(t, s.SetFetchError("chan1", feed.FetchError{}), "clear without error")
+
+	ts := time.Date(2022, 4, 6, 10, 20, 30, 0, time.UTC)
+	require.NoError(t, s.SetFetchError("chan1", feed.FetchError{Error: "err1", TS: ts}))
+	require.NoError(t, s.SetFetchError("chan2", feed.FetchError{Error: "err2", TS: ts}))
+	require.NoError(t, s.SetFetchError("chan1", feed.FetchError{Error: "err3", TS: ts.Add(time.Hour)}))
+	res, err = s.FetchErrors()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res))
+	assert.Equal(t, "err3", res["chan1"].Error, "replaced")
+	assert.True(t, ts.Add(time.Hour).Equal(res["chan1"].TS))
+	assert.Equal(t, "err2", res["chan2"].Error)
+
+	require.NoError(t, s.SetFetchError("chan1", feed.FetchError{}))
+	res, err = s.FetchErrors()
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(res))
+	_, found := res["chan1"]
+	assert.False(t, found, "chan1 cleared")
+}
+
+func testStoreListings(t *testing.T, s storeService) {
+	res, err := s.Listing("chan1::channel")
+	require.NoError(t, err)
+	assert.True(t, res.TS.IsZero(), "not stored")
+	assert.Empty(t, res.Entries)
+
+	ts := time.Date(2022, 4, 6, 10, 20, 30, 0, time.UTC)
+	entries := suiteEntries()
+	require.NoError(t, s.SetListing("chan1::channel", feed.Listing{Entries: entries[:2], TS: ts}))
+	require.NoError(t, s.SetListing("chan1::shorts", feed.Listing{Entries: entries[2:3], TS: ts}))
+	require.NoError(t, s.SetListing("chan1::channel", feed.Listing{Entries: entries[:1], TS: ts.Add(time.Hour)}))
+
+	res, err = s.Listing("chan1::channel")
+	require.NoError(t, err)
+	assert.True(t, ts.Add(time.Hour).Equal(res.TS), "replaced")
+	require.Equal(t, 1, len(res.Entries))
+	assert.Equal(t, "vid1", res.Entries[0].VideoID)
+	assert.Equal(t, "title1", res.Entries[0].Title)
+
+	res, err = s.Listing("chan1::shorts")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res.Entries))
+	assert.Equal(t, "vid3", res.Entries[0].VideoID)
+}
+
+func testStoreFeedUpdates(t *testing.T, s storeService) {
+	res, err := s.FeedUpdates()
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	keep, name := 5, "new name"
+	require.NoError(t, s.SetFeedUpdate("chan1", feed.FeedUpdate{Keep: &keep}))
+	require.NoError(t, s.SetFeedUpdate("chan2", feed.FeedUpdate{Name: &name}))
+	require.NoError(t, s.SetFeedUpdate("chan1", feed.FeedUpdate{Keep: &keep, Name: &name}))
+
+	res, err = s.FeedUpdates()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]feed.FeedUpdate{
+		"chan1": {Keep: &keep, Name: &name},
+		"chan2": {Name: &name},
+	}, res)
+}
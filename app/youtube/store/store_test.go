@@ -61,6 +61,10 @@ func TestStore_SaveAndLoad(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 1, len(res))
 	assert.Equal(t, "vid2", res[0].VideoID)
+
+	res, err = s.Load("chan1", -1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(res), "all entries loaded for negative max")
 }
 
 func TestStore_Remove(t *testing.T) {
@@ -313,3 +317,22 @@ func TestBoltDB_Last(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "vid3", res.VideoID)
 }
+
+func TestBoltDB_Bytes(t *testing.T) {
+	tmpfile := filepath.Join(os.TempDir(), "test.db")
+	defer os.Remove(tmpfile)
+
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+	s := BoltDB{DB: db}
+
+	assert.Equal(t, int64(0), s.CountBytes(""))
+	require.NoError(t, s.AddBytes("chan1", 100))
+	require.NoError(t, s.AddBytes("chan1", 200))
+	require.NoError(t, s.AddBytes("chan2", 1000))
+
+	assert.Equal(t, int64(300), s.CountBytes("chan1"))
+	assert.Equal(t, int64(1000), s.CountBytes("chan2"))
+	assert.Equal(t, int64(0), s.CountBytes("chan3"))
+	assert.Equal(t, int64(1300), s.CountBytes(""))
+}
@@ -0,0 +1,45 @@
+package youtube
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SubscriberToken makes token for the feed with embedded "since" time, signed with feed's subscriber secret.
+// The feed requested with such token contains only entries published after since.
+// Token format is "<since unix time>.<hex hmac-sha256 of feed id and since>".
+func SubscriberToken(secret, feedID string, since time.Time) string {
+	ts := strconv.FormatInt(since.Unix(), 10)
+	return ts + "." + subscriberSignature(secret, feedID, ts)
+}
+
+// ParseSubscriberToken checks the token signature for the feed and returns embedded since time
+func ParseSubscriberToken(secret, feedID, token string) (time.Time, error) {
+	if secret == "" {
+		return time.Time{}, errors.Errorf("subscriber tokens not enabled for %s", feedID)
+	}
+	elems := strings.SplitN(token, ".", 2)
+	if len(elems) != 2 {
+		return time.Time{}, errors.Errorf("invalid subscriber token %q", token)
+	}
+	ts, err := strconv.ParseInt(elems[0], 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid time in subscriber token %q", token)
+	}
+	if !hmac.Equal([]byte(elems[1]), []byte(subscriberSignature(secret, feedID, elems[0]))) {
+		return time.Time{}, errors.Errorf("bad signature of subscriber token for %s", feedID)
+	}
+	return time.Unix(ts, 0), nil
+}
+
+func subscriberSignature(secret, feedID, ts string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(feedID + "." + ts))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,36 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriberToken(t *testing.T) {
+	since := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	token := SubscriberToken("secret", "channel1", since)
+	assert.Equal(t, "1651399200.", token[:11])
+
+	ts, err := ParseSubscriberToken("secret", "channel1", token)
+	require.NoError(t, err)
+	assert.True(t, since.Equal(ts), ts.String())
+
+	tbl := []struct {
+		name, secret, feedID, token, err string
+	}{
+		{"other feed", "secret", "channel2", token, "bad signature of subscriber token for channel2"},
+		{"other secret", "secret2", "channel1", token, "bad signature of subscriber token for channel1"},
+		{"disabled", "", "channel1", token, "subscriber tokens not enabled for channel1"},
+		{"changed time", "secret", "channel1", "1651399100" + token[10:], "bad signature of subscriber token for channel1"},
+		{"no signature", "secret", "channel1", "1651399200", `invalid subscriber token "1651399200"`},
+		{"bad time", "secret", "channel1", "abc.def", `invalid time in subscriber token "abc.def": strconv.ParseInt: parsing "abc": invalid syntax`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSubscriberToken(tt.secret, tt.feedID, tt.token)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package youtube
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// TestDownloader is implemented by downloaders able to download a video to the given directory and report
+// the command's output, see Service.TestDownload
+type TestDownloader interface {
+	Test(ctx context.Context, id, dir string) (file, output string, err error)
+}
+
+// TestDownloadResult is the outcome of a test download
+type TestDownloadResult struct {
+	URL     string        // url or youtube's id of the video
+	OK      bool          // file downloaded
+	Error   string        // download error, if failed
+	Size    int64         // size of the downloaded file
+	Elapsed time.Duration // time of the download
+	Output  string        // output of the download command, the tail of it if longer than maxTestOutput
+}
+
+// ErrInvalidURL returned by TestDownload for urls not safe to pass to the download command
+var ErrInvalidURL = errors.New("invalid url")
+
+// maxTestOutput limits output of the download command kept in TestDownloadResult
+const maxTestOutput = 64 * 1024
+
+// testURLRe matches http urls without characters special for the shell, the url passed to the download command
+var testURLRe = regexp.MustCompile(`^https?://[A-Za-z0-9._~:/?=%+,@-]+$`)
+
+// TestDownload downloads the video by url or youtube's id to a temporary directory, removed after the test, and
+// reports the outcome with the download command's output. The store is not touched. Used to troubleshoot downloads,
+// i.e. region or cookies issues. Urls with characters special for the shell, like & or quotes, rejected.
+// A failed download is reported in the result, error returned for invalid url or if the test can't be made.
+func (s *Service) TestDownload(ctx context.Context, url string) (TestDownloadResult, error) {
+	res := TestDownloadResult{URL: url}
+	if !testURLRe.MatchString(url) && !bareVideoIDRe.MatchString(url) {
+		return res, errors.Wrapf(ErrInvalidURL, "%q, http(s) url without shell special characters or video id expected", url)
+	}
+	td, ok := s.Downloader.(TestDownloader)
+	if !ok {
+		return res, errors.New("downloader doesn't support test downloads")
+	}
+	dir, err := os.MkdirTemp("", "feed-master-test-")
+	if err != nil {
+		return res, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(dir) // nolint
+
+	entry, fi := ytfeed.Entry{VideoID: url}, FeedInfo{ID: "test-download", Name: "test download"}
+	release, ok := s.acquireDownload(ctx, entry, fi)
+	if !ok {
+		return res, errors.Wrap(ctx.Err(), "test download interrupted in queue")
+	}
+	defer release()
+	if s.DownloadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.DownloadTimeout)
+		defer cancel()
+	}
+
+	log.Printf("[INFO] test download of %s", url)
+	st := time.Now()
+	file, out, err := td.Test(ctx, url, dir)
+	res.Elapsed = time.Since(st)
+	if len(out) > maxTestOutput {
+		out = out[len(out)-maxTestOutput:]
+	}
+	res.Output = out
+	if err != nil {
+		res.Error = err.Error()
+		log.Printf("[WARN] test download of %s failed, %v", url, err)
+		return res, nil
+	}
+	if fs, statErr := os.Stat(file); statErr == nil {
+		res.Size = fs.Size()
+	}
+	res.OK = true
+	log.Printf("[INFO] test download of %s completed, %d bytes in %v", url, res.Size, res.Elapsed)
+	return res, nil
+}
@@ -0,0 +1,79 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+// testingDownloader is DownloaderService making test downloads to the given directory
+type testingDownloader struct {
+	*mocks.DownloaderServiceMock
+	output string
+	err    error
+	dirs   []string
+}
+
+func (d *testingDownloader) Test(ctx context.Context, id, dir string) (file, output string, err error) {
+	d.dirs = append(d.dirs, dir)
+	file = filepath.Join(dir, "test.mp3")
+	if d.err != nil {
+		return file, d.output, d.err
+	}
+	return file, d.output, os.WriteFile(file, []byte("some content"), 0o600)
+}
+
+func TestService_TestDownload(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{} // store not touched, calls of the mock without funcs panic
+
+	t.Run("downloaded", func(t *testing.T) {
+		dl := &testingDownloader{DownloaderServiceMock: &mocks.DownloaderServiceMock{}, output: "[download] 100%"}
+		svc := Service{Downloader: dl, Store: storeSvc}
+		res, err := svc.TestDownload(context.Background(), "https://www.youtube.com/watch?v=abcdefghijk")
+		require.NoError(t, err)
+		assert.True(t, res.OK)
+		assert.Equal(t, "", res.Error)
+		assert.Equal(t, int64(12), res.Size)
+		assert.Equal(t, "[download] 100%", res.Output)
+		require.Equal(t, 1, len(dl.dirs))
+		_, err = os.Stat(dl.dirs[0])
+		assert.True(t, os.IsNotExist(err), "temp dir removed")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		dl := &testingDownloader{DownloaderServiceMock: &mocks.DownloaderServiceMock{},
+			output: strings.Repeat("x", maxTestOutput) + "ERROR: video unavailable in your country", err: errors.New("exit 1")}
+		svc := Service{Downloader: dl, Store: storeSvc}
+		res, err := svc.TestDownload(context.Background(), "abcdefghijk")
+		require.NoError(t, err)
+		assert.False(t, res.OK)
+		assert.Equal(t, "exit 1", res.Error)
+		assert.Equal(t, maxTestOutput, len(res.Output))
+		assert.True(t, strings.HasSuffix(res.Output, "in your country"), "tail of output kept")
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		dl := &testingDownloader{DownloaderServiceMock: &mocks.DownloaderServiceMock{}}
+		svc := Service{Downloader: dl, Store: storeSvc}
+		for _, u := range []string{"", "ftp://example.com/v", "https://example.com/v?a=1&b=2", `https://example.com/"$(id)"`,
+			"https://example.com/v;reboot", "abc"} {
+			_, err := svc.TestDownload(context.Background(), u)
+			assert.Error(t, err, u)
+		}
+		assert.Empty(t, dl.dirs)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		svc := Service{Downloader: &mocks.DownloaderServiceMock{}, Store: storeSvc}
+		_, err := svc.TestDownload(context.Background(), "abcdefghijk")
+		assert.EqualError(t, err, "downloader doesn't support test downloads")
+	})
+}
@@ -0,0 +1,74 @@
+package youtube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+)
+
+// RepeatedTitles defines how identical titles of the feed's episodes made distinct in rss
+type RepeatedTitles string
+
+// enum for repeated titles modes
+const (
+	RTKeep   = RepeatedTitles("")       // titles kept as is
+	RTNumber = RepeatedTitles("number") // " #N" added, numbered from the oldest episode with the title
+	RTDate   = RepeatedTitles("date")   // " (YYYY-MM-DD)" added, original published date of the episode
+)
+
+// UnmarshalYAML checks mode of repeated titles is known, case insensitive
+func (rt *RepeatedTitles) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	res := RepeatedTitles(strings.ToLower(strings.TrimSpace(s)))
+	switch res {
+	case RTKeep, RTNumber, RTDate:
+		*rt = res
+		return nil
+	}
+	return errors.Errorf("unknown repeated_titles %q", s)
+}
+
+// distinctTitles returns titles of entries sharing the same title (case insensitive) with a number or date added,
+// by entry's uid (channel id::video id). Entries with unique titles are not included.
+func distinctTitles(entries []ytfeed.Entry, mode RepeatedTitles) map[string]string {
+	if mode == RTKeep {
+		return nil
+	}
+	groups := map[string][]ytfeed.Entry{}
+	for _, e := range entries {
+		key := strings.ToLower(strings.TrimSpace(e.Title))
+		groups[key] = append(groups[key], e)
+	}
+
+	res := map[string]string{}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool { return originalPublished(group[i]).Before(originalPublished(group[j])) })
+		for i, e := range group {
+			suffix := fmt.Sprintf(" #%d", i+1)
+			if mode == RTDate {
+				suffix = " (" + originalPublished(e).Format("2006-01-02") + ")"
+			}
+			res[e.UID()] = strings.TrimSpace(e.Title) + suffix
+		}
+	}
+	return res
+}
+
+// originalPublished returns the original upload time of the entry, published time if not known
+func originalPublished(e ytfeed.Entry) time.Time {
+	if !e.OriginalPublished.IsZero() {
+		return e.OriginalPublished
+	}
+	return e.Published
+}
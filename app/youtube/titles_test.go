@@ -0,0 +1,71 @@
+package youtube
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+func TestDistinctTitles(t *testing.T) {
+	ts := time.Date(2022, 4, 6, 10, 0, 0, 0, time.UTC)
+	entries := []ytfeed.Entry{
+		{ChannelID: "c1", VideoID: "vid4", Title: "Weekly Show", Published: ts.Add(21 * 24 * time.Hour)},
+		{ChannelID: "c1", VideoID: "vid3", Title: "Special", Published: ts.Add(14 * 24 * time.Hour)},
+		{ChannelID: "c1", VideoID: "vid2", Title: "weekly show ", Published: ts.Add(7 * 24 * time.Hour)},
+		{ChannelID: "c1", VideoID: "vid1", Title: "Weekly Show", Published: ts.Add(30 * 24 * time.Hour), OriginalPublished: ts},
+	}
+
+	assert.Nil(t, distinctTitles(entries, RTKeep))
+	assert.Equal(t, map[string]string{"c1::vid1": "Weekly Show #1", "c1::vid2": "weekly show #2", "c1::vid4": "Weekly Show #3"},
+		distinctTitles(entries, RTNumber), "numbered by original published time")
+	assert.Equal(t, map[string]string{"c1::vid1": "Weekly Show (2022-04-06)", "c1::vid2": "weekly show (2022-04-13)",
+		"c1::vid4": "Weekly Show (2022-04-27)"}, distinctTitles(entries, RTDate))
+	assert.Empty(t, distinctTitles(entries[:2], RTNumber), "unique titles")
+}
+
+func TestRepeatedTitles_UnmarshalYAML(t *testing.T) {
+	var fi FeedInfo
+	require.NoError(t, yaml.Unmarshal([]byte("{id: c1, repeated_titles: Number}"), &fi))
+	assert.Equal(t, RTNumber, fi.RepeatedTitles)
+	require.NoError(t, yaml.Unmarshal([]byte("{id: c1, repeated_titles: date}"), &fi))
+	assert.Equal(t, RTDate, fi.RepeatedTitles)
+	err := yaml.Unmarshal([]byte("{id: c1, repeated_titles: counter}"), &fi)
+	assert.EqualError(t, err, `unknown repeated_titles "counter"`)
+}
+
+func TestService_RSSFeedRepeatedTitles(t *testing.T) {
+	ts := time.Date(2022, 4, 6, 10, 0, 0, 0, time.UTC)
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid3", Title: "Weekly", File: "/tmp/file3.mp3", Published: ts.Add(2 * time.Hour)},
+				{ChannelID: channelID, VideoID: "vid2", Title: "Other", File: "/tmp/file2.mp3", Published: ts.Add(time.Hour)},
+				{ChannelID: channelID, VideoID: "vid1", Title: "Weekly", File: "/tmp/file1.mp3", Published: ts},
+			}, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.RSSFeed(FeedInfo{ID: "channel1", Name: "name1"}, RSSFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(res, "<title>Weekly</title>"), "opt-in, kept as is by default")
+
+	fi := FeedInfo{ID: "channel1", Name: "name1", RepeatedTitles: RTNumber}
+	res, err = svc.RSSFeed(fi, RSSFilter{})
+	require.NoError(t, err)
+	assert.Contains(t, res, "<title>Weekly #1</title>")
+	assert.Contains(t, res, "<title>Weekly #2</title>")
+	assert.Contains(t, res, "<title>Other</title>")
+
+	res, err = svc.RSSFeed(fi, RSSFilter{Since: ts.Add(90 * time.Minute)})
+	require.NoError(t, err)
+	assert.Contains(t, res, "<title>Weekly #2</title>", "numbered over all entries")
+	assert.NotContains(t, res, "<title>Weekly #1</title>")
+}
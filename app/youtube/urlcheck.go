@@ -0,0 +1,89 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/repeater"
+	"github.com/pkg/errors"
+)
+
+const urlCheckAttempts = 3
+
+// checkEnclosures sends HEAD requests to enclosure urls of up to max stored entries, the newest entry of each feed
+// first. Catches misconfigured RootURL (MediaBaseURL) or files location, i.e. urls not mapped to the files. Each url retried a few
+// times, as the check runs on startup and the server may be not ready yet. Returns unreachable urls.
+func (s *Service) checkEnclosures(ctx context.Context, max int) (failed []string) {
+	urls := s.sampleURLs(max)
+	retryDelay := s.urlCheckRetryDelay
+	if retryDelay == 0 {
+		retryDelay = time.Second * 5
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, u := range urls {
+		rp := repeater.NewDefault(urlCheckAttempts, retryDelay)
+		err := rp.Do(ctx, func() error {
+			req, e := http.NewRequestWithContext(ctx, "HEAD", u, http.NoBody)
+			if e != nil {
+				return errors.Wrapf(e, "failed to create request for %s", u)
+			}
+			resp, e := client.Do(req)
+			if e != nil {
+				return errors.Wrapf(e, "failed to check %s", u)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return errors.Errorf("unexpected status %s from %s", resp.Status, u)
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return failed
+		}
+		if err != nil {
+			s.event("WARN", "url_check", fmt.Sprintf("enclosure url is not reachable, check root url and files location, %v", err),
+				Fields{"url": u, "error": err.Error()})
+			failed = append(failed, u)
+		}
+	}
+	s.event("INFO", "url_check", fmt.Sprintf("checked %d enclosure urls, %d unreachable", len(urls), len(failed)),
+		Fields{"checked": len(urls), "failed": len(failed)})
+	return failed
+}
+
+// sampleURLs returns enclosure urls of up to max stored entries, round-robin over feeds from the newest entries
+func (s *Service) sampleURLs(max int) []string {
+	byFeed := make([][]string, 0, len(s.Feeds))
+	for _, fi := range s.feeds() {
+		entries, err := s.Store.Load(fi.ID, max)
+		if err != nil {
+			continue // nothing stored for the feed yet
+		}
+		urls := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			urls = append(urls, s.fileURL(entry.File, fi))
+		}
+		byFeed = append(byFeed, urls)
+	}
+
+	res := []string{}
+	for i := 0; len(res) < max; i++ {
+		added := false
+		for _, urls := range byFeed {
+			if i < len(urls) && len(res) < max {
+				res = append(res, urls[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	if len(res) == 0 {
+		log.Printf("[DEBUG] no stored entries to check enclosure urls")
+	}
+	return res
+}
@@ -0,0 +1,86 @@
+package youtube
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+func TestService_checkEnclosures(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "HEAD", r.Method)
+		if r.URL.Path == "/yt/channel1/file1.mp3" || r.URL.Path == "/yt/channel1/file2.mp3" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: channelID, VideoID: "vid1", File: "/srv/files/" + channelID + "/file1.mp3"},
+				{ChannelID: channelID, VideoID: "vid2", File: "/srv/files/" + channelID + "/file2.mp3"},
+			}, nil
+		},
+	}
+	svc := Service{
+		Feeds:              []FeedInfo{{ID: "channel1", Name: "name1"}},
+		Store:              storeSvc,
+		RootURL:            ts.URL + "/yt",
+		FilesLocation:      "/srv/files",
+		urlCheckRetryDelay: time.Millisecond,
+	}
+
+	assert.Empty(t, svc.checkEnclosures(context.Background(), 10), "all reachable")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// misconfigured root url, files not mapped
+	atomic.StoreInt32(&requests, 0)
+	svc.RootURL = ts.URL + "/files"
+	failed := svc.checkEnclosures(context.Background(), 10)
+	assert.Equal(t, []string{ts.URL + "/files/channel1/file1.mp3", ts.URL + "/files/channel1/file2.mp3"}, failed)
+	assert.Equal(t, int32(2*urlCheckAttempts), atomic.LoadInt32(&requests), "retried")
+
+	// unreachable server
+	svc.RootURL = "http://127.0.0.1:1/yt"
+	assert.Equal(t, 1, len(svc.checkEnclosures(context.Background(), 1)), "bounded")
+}
+
+func TestService_sampleURLs(t *testing.T) {
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			switch channelID {
+			case "channel1":
+				return []ytfeed.Entry{{File: "/files/c1-new.mp3"}, {File: "/files/c1-old.mp3"}, {File: "/files/c1-older.mp3"}}, nil
+			case "channel2":
+				return []ytfeed.Entry{{File: "/files/c2-new.mp3"}}, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	svc := Service{
+		Feeds:         []FeedInfo{{ID: "channel1"}, {ID: "unknown"}, {ID: "channel2"}},
+		Store:         storeSvc,
+		RootURL:       "http://example.com/yt",
+		FilesLocation: "/files",
+	}
+	assert.Equal(t, []string{"http://example.com/yt/c1-new.mp3", "http://example.com/yt/c2-new.mp3",
+		"http://example.com/yt/c1-old.mp3"}, svc.sampleURLs(3), "newest of each feed first")
+	assert.Equal(t, 4, len(svc.sampleURLs(10)), "all stored")
+	assert.Equal(t, []string{"http://example.com/yt/c1-new.mp3"}, svc.sampleURLs(1))
+	require.Equal(t, 9, len(storeSvc.LoadCalls()), "3 feeds loaded for each of 3 samples")
+	assert.Equal(t, 3, storeSvc.LoadCalls()[0].Max, "loaded up to max per feed")
+}
@@ -0,0 +1,139 @@
+package youtube
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// validationRSS is a subset of rss 2.0 elements checked by Validate, parsed from the generated rss
+type validationRSS struct {
+	Version string `xml:"version,attr"`
+	Channel struct {
+		Title       string   `xml:"title"`
+		Links       []string `xml:"link"` // atom:link has the same local name, the first one is rss link
+		Description string   `xml:"description"`
+		Language    string   `xml:"language"`
+		PubDate     string   `xml:"pubDate"`
+		Items       []struct {
+			Title     string `xml:"title"`
+			GUID      string `xml:"guid"`
+			PubDate   string `xml:"pubDate"`
+			Enclosure *struct {
+				URL    string `xml:"url,attr"`
+				Length string `xml:"length,attr"`
+				Type   string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// Validate generates rss of the feed and checks it against rss spec and podcast apps requirements, i.e. required
+// channel elements, dates and enclosures. Returns the list of found problems, empty if the feed is valid.
+// Error returned if the rss can't be generated.
+func (s *Service) Validate(fi FeedInfo) ([]string, error) {
+	rss, err := s.RSSFeed(fi, RSSFilter{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate rss for %s", fi.ID)
+	}
+	if rss == "" {
+		return []string{"empty feed, no entries"}, nil
+	}
+	return validateRSS(rss), nil
+}
+
+// validateRSS checks rss document, returns the list of problems
+func validateRSS(rss string) []string {
+	var doc validationRSS
+	if err := xml.Unmarshal([]byte(rss), &doc); err != nil {
+		return []string{fmt.Sprintf("invalid xml, %v", err)}
+	}
+	res := []string{}
+	problem := func(format string, args ...interface{}) { res = append(res, fmt.Sprintf(format, args...)) }
+
+	if doc.Version != "2.0" {
+		problem("rss version %q, should be 2.0", doc.Version)
+	}
+	ch := doc.Channel
+	if strings.TrimSpace(ch.Title) == "" {
+		problem("channel: empty title")
+	}
+	if len(ch.Links) == 0 || strings.TrimSpace(ch.Links[0]) == "" {
+		problem("channel: empty link")
+	}
+	if strings.TrimSpace(ch.Description) == "" {
+		problem("channel: empty description")
+	}
+	switch {
+	case ch.Language == "":
+		problem("channel: empty language")
+	case !langRe.MatchString(ch.Language):
+		problem("channel: invalid language %q", ch.Language)
+	}
+	if ch.PubDate != "" {
+		if err := checkRSSDate(ch.PubDate); err != nil {
+			problem("channel: %v", err)
+		}
+	}
+	if len(ch.Items) == 0 {
+		problem("channel: no items")
+	}
+
+	guids := map[string]int{}
+	for i, item := range ch.Items {
+		name := fmt.Sprintf("item %d", i+1)
+		if item.GUID != "" {
+			name = fmt.Sprintf("item %d (%s)", i+1, item.GUID)
+		}
+		if strings.TrimSpace(item.Title) == "" {
+			problem("%s: empty title", name)
+		}
+		switch prev, dup := guids[item.GUID]; {
+		case item.GUID == "":
+			problem("%s: empty guid", name)
+		case dup:
+			problem("%s: duplicate guid of item %d", name, prev)
+		default:
+			guids[item.GUID] = i + 1
+		}
+		if err := checkRSSDate(item.PubDate); err != nil {
+			problem("%s: %v", name, err)
+		}
+		if item.Enclosure == nil {
+			problem("%s: no enclosure", name)
+			continue
+		}
+		if u, err := url.Parse(item.Enclosure.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problem("%s: invalid enclosure url %q", name, item.Enclosure.URL)
+		}
+		if length, err := strconv.ParseInt(item.Enclosure.Length, 10, 64); err != nil || length <= 0 {
+			problem("%s: invalid enclosure length %q", name, item.Enclosure.Length)
+		}
+		if item.Enclosure.Type == "" {
+			problem("%s: empty enclosure type", name)
+		}
+	}
+	return res
+}
+
+// checkRSSDate verifies the date is in RFC 822 format (with 4 digits year) and set
+func checkRSSDate(date string) error {
+	if date == "" {
+		return errors.New("empty pubDate")
+	}
+	ts, err := time.Parse(time.RFC1123Z, date)
+	if err != nil {
+		if ts, err = time.Parse(time.RFC1123, date); err != nil {
+			return errors.Errorf("invalid pubDate %q", date)
+		}
+	}
+	if ts.Year() < 1900 {
+		return errors.Errorf("unset pubDate %q", date)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package youtube
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+)
+
+func TestValidateRSS(t *testing.T) {
+	item := func(guid, pubDate, enclosure string) string {
+		return "<item><title>title " + guid + "</title><guid>" + guid + "</guid><pubDate>" + pubDate + "</pubDate>" +
+			enclosure + "</item>"
+	}
+	doc := func(channel string, items ...string) string {
+		return `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>` + channel + strings.Join(items, "") +
+			"</channel></rss>"
+	}
+	const (
+		channel   = `<title>name</title><link>https://example.com</link><atom:link href="https://example.com/rss" rel="self"></atom:link><description>desc</description><language>en</language>` // nolint
+		date      = "Sat, 02 Apr 2022 10:20:30 +0000"
+		enclosure = `<enclosure url="https://example.com/1.mp3" length="123" type="audio/mpeg"></enclosure>`
+	)
+
+	tbl := []struct {
+		name string
+		rss  string
+		res  []string
+	}{
+		{"valid", doc(channel, item("g1", date, enclosure), item("g2", "Sat, 02 Apr 2022 10:20:30 UTC", enclosure)), []string{}},
+		{"not xml", "blah", []string{"invalid xml, EOF"}},
+		{"empty channel", `<rss version="1.0"><channel></channel></rss>`, []string{`rss version "1.0", should be 2.0`,
+			"channel: empty title", "channel: empty link", "channel: empty description", "channel: empty language",
+			"channel: no items"}},
+		{"bad language and date", doc(strings.Replace(channel, ">en<", ">english!<", 1)+"<pubDate>2022-04-02</pubDate>",
+			item("g1", date, enclosure)), []string{`channel: invalid language "english!"`, `channel: invalid pubDate "2022-04-02"`}},
+		{"bad items", doc(channel,
+			item("g1", "", enclosure),
+			item("g1", "Mon, 01 Jan 0001 00:00:00 +0000", enclosure),
+			item("", date, ""),
+			item("g3", date, `<enclosure url="/1.mp3" length="0" type=""></enclosure>`),
+			item("g4", date, `<enclosure url="https://example.com/1.mp3" type="audio/mpeg"></enclosure>`),
+			"<item><guid>g5</guid><pubDate>"+date+"</pubDate>"+enclosure+"</item>",
+		), []string{
+			"item 1 (g1): empty pubDate",
+			"item 2 (g1): duplicate guid of item 1",
+			`item 2 (g1): unset pubDate "Mon, 01 Jan 0001 00:00:00 +0000"`,
+			"item 3: empty guid",
+			"item 3: no enclosure",
+			`item 4 (g3): invalid enclosure url "/1.mp3"`,
+			`item 4 (g3): invalid enclosure length "0"`,
+			"item 4 (g3): empty enclosure type",
+			`item 5 (g4): invalid enclosure length ""`,
+			"item 6 (g5): empty title",
+		}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.res, validateRSS(tt.rss))
+		})
+	}
+}
+
+func TestService_Validate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vid1.mp3")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	entry1 := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid1", Title: "title1", File: file, Published: time.Now()}
+	entry1.Author.URI = "https://www.youtube.com/channel/channel1"
+	entry2 := ytfeed.Entry{ChannelID: "channel1", VideoID: "vid2", Title: "title2", File: "/no/such/file.mp3",
+		Published: time.Now().Add(-time.Hour)}
+	entries := []ytfeed.Entry{entry1}
+	storeSvc := &mocks.StoreServiceMock{
+		LoadFunc: func(channelID string, max int) ([]ytfeed.Entry, error) {
+			if channelID == "channel2" {
+				return nil, nil
+			}
+			return entries, nil
+		},
+	}
+	svc := Service{Store: storeSvc, RootURL: "http://localhost:8080/yt", KeepPerChannel: 10}
+
+	res, err := svc.Validate(FeedInfo{ID: "channel1", Name: "name1", Language: "en-us"})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	entries = []ytfeed.Entry{entry1, entry2}
+	res, err = svc.Validate(FeedInfo{ID: "channel1", Name: "name1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"channel: empty language", `item 2 (channel1::vid2): invalid enclosure length "0"`}, res,
+		"no language and missing file without stored size")
+
+	res, err = svc.Validate(FeedInfo{ID: "channel2", Name: "name2", Language: "en-us"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"empty feed, no entries"}, res)
+}
@@ -0,0 +1,71 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/repeater"
+	"github.com/pkg/errors"
+)
+
+// feedStats is a per-feed breakdown of the processing cycle stats
+type feedStats struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Stats
+}
+
+// webhookPayload is posted to CompletionWebhook at the end of each processing cycle
+type webhookPayload struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Stats     Stats       `json:"stats"`
+	Feeds     []feedStats `json:"feeds"`
+}
+
+const webhookAttempts = 3
+
+// sendCompletionWebhook posts cycle stats to CompletionWebhook, retrying on failures.
+// Errors are logged only, delivery failure doesn't affect processing.
+func (s *Service) sendCompletionWebhook(ctx context.Context, all Stats, feeds []feedStats) {
+	if feeds == nil {
+		feeds = []feedStats{}
+	}
+	payload := webhookPayload{Timestamp: time.Now(), Stats: all, Feeds: feeds}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[WARN] failed to marshal webhook payload, %v", err)
+		return
+	}
+
+	retryDelay := s.webhookRetryDelay
+	if retryDelay == 0 {
+		retryDelay = time.Second * 5
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	rp := repeater.NewDefault(webhookAttempts, retryDelay)
+	err = rp.Do(ctx, func() error {
+		req, e := http.NewRequestWithContext(ctx, "POST", s.CompletionWebhook, bytes.NewReader(body))
+		if e != nil {
+			return errors.Wrapf(e, "failed to create request for %s", s.CompletionWebhook)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, e := client.Do(req)
+		if e != nil {
+			return errors.Wrapf(e, "failed to post to %s", s.CompletionWebhook)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return errors.Errorf("unexpected status %s from %s", resp.Status, s.CompletionWebhook)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[WARN] failed to deliver completion webhook, %v", err)
+		return
+	}
+	log.Printf("[DEBUG] completion webhook delivered to %s", s.CompletionWebhook)
+}
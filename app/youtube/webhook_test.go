@@ -0,0 +1,87 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	ytfeed "github.com/umputun/feed-master/app/youtube/feed"
+	"github.com/umputun/feed-master/app/youtube/mocks"
+	"github.com/umputun/feed-master/app/youtube/store"
+)
+
+func TestService_CompletionWebhook(t *testing.T) {
+	var calls int32
+	payloads := make(chan webhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if atomic.AddInt32(&calls, 1) == 1 { // fail the first attempt
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var p webhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		payloads <- p
+	}))
+	defer ts.Close()
+
+	chans := &mocks.ChannelServiceMock{
+		GetFunc: func(ctx context.Context, chanID string, feedType ytfeed.Type, publishedAfter time.Time, apiKey string) ([]ytfeed.Entry, error) {
+			return []ytfeed.Entry{
+				{ChannelID: chanID, VideoID: "vid1", Title: "title1", Published: time.Now()},
+				{ChannelID: chanID, VideoID: "vid2", Title: "title2", Published: time.Now()},
+			}, nil
+		},
+	}
+	downloader := &mocks.DownloaderServiceMock{
+		GetFunc: func(ctx context.Context, id string, fname string) (string, error) {
+			return "/tmp/" + fname + ".mp3", nil
+		},
+	}
+
+	tmpfile := filepath.Join(os.TempDir(), "test-webhook.db")
+	defer os.Remove(tmpfile)
+	db, err := bolt.Open(tmpfile, 0o600, &bolt.Options{Timeout: 1 * time.Second})
+	require.NoError(t, err)
+
+	svc := Service{
+		Feeds: []FeedInfo{
+			{ID: "channel1", Name: "name1", Type: ytfeed.FTChannel},
+			{ID: "channel2", Name: "name2", Type: ytfeed.FTChannel, Keep: 1},
+		},
+		Downloader:        downloader,
+		ChannelService:    chans,
+		Store:             &store.BoltDB{DB: db},
+		KeepPerChannel:    10,
+		RSSFileStore:      RSSFileStore{Enabled: false},
+		DurationService:   &mocks.DurationServiceMock{FileFunc: func(fname string) int { return 1234 }},
+		CompletionWebhook: ts.URL,
+		webhookRetryDelay: time.Millisecond,
+	}
+	st, err := svc.procChannels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Entries: 4, Processed: 3, Added: 3}, st)
+
+	select {
+	case p := <-payloads:
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "delivered on the second attempt")
+		assert.True(t, time.Since(p.Timestamp) < time.Minute)
+		assert.Equal(t, st, p.Stats)
+		require.Equal(t, 2, len(p.Feeds))
+		assert.Equal(t, feedStats{ID: "channel1", Name: "name1", Stats: Stats{Entries: 2, Processed: 2, Added: 2}}, p.Feeds[0])
+		assert.Equal(t, feedStats{ID: "channel2", Name: "name2", Stats: Stats{Entries: 2, Processed: 1, Added: 1}}, p.Feeds[1])
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+}